@@ -28,6 +28,7 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
 
 	"istio.io/istio/istioctl/pkg/util/formatting"
 	"istio.io/istio/istioctl/pkg/util/handlers"
@@ -74,6 +75,7 @@ var (
 	suppress          []string
 	analysisTimeout   time.Duration
 	recursive         bool
+	clusterDump       bool
 
 	fileExtensions = []string{".json", ".yaml", ".yml"}
 )
@@ -95,6 +97,9 @@ func Analyze() *cobra.Command {
   # Analyze yaml files without connecting to a live cluster
   istioctl analyze --use-kube=false a.yaml b.yaml my-app-config/
 
+  # Analyze a dump of a cluster, such as kubectl get -o yaml output or an extracted bug report, without connecting to it
+  istioctl analyze --cluster-dump bug-report/
+
   # Analyze the current live cluster and suppress PodMissingProxy for pod mypod in namespace 'testing'.
   istioctl analyze -S "IST0103=Pod mypod.testing"
 
@@ -118,6 +123,11 @@ func Analyze() *cobra.Command {
 				return nil
 			}
 
+			if clusterDump {
+				useKube = false
+				recursive = true
+			}
+
 			readers, err := gatherFiles(cmd, args)
 			if err != nil {
 				return err
@@ -314,6 +324,11 @@ func Analyze() *cobra.Command {
 		"The duration to wait before failing")
 	analysisCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "R", false,
 		"Process directory arguments recursively. Useful when you want to analyze related manifests organized within the same directory.")
+	analysisCmd.PersistentFlags().BoolVar(&clusterDump, "cluster-dump", false,
+		"Analyze the arguments as a dump of a cluster, such as the output of 'kubectl get -o yaml' or an extracted "+
+			"'istioctl bug-report' archive, without connecting to a live cluster. Implies --use-kube=false and --recursive. "+
+			"Files without a recognized extension are analyzed if they contain Kubernetes resources in YAML or JSON, "+
+			"and skipped silently otherwise.")
 	return analysisCmd
 }
 
@@ -344,7 +359,7 @@ func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error
 			}
 			readers = append(readers, dirReaders...)
 		} else {
-			if !isValidFile(f) {
+			if !isValidFile(f) && !(clusterDump && isKubeYAMLFile(f)) {
 				fmt.Fprintf(cmd.ErrOrStderr(), "Skipping file %v, recognized file extensions are: %v\n", f, fileExtensions)
 				continue
 			}
@@ -383,7 +398,12 @@ func gatherFilesInDirectory(cmd *cobra.Command, dir string) ([]local.ReaderSourc
 			return nil
 		}
 
-		if !isValidFile(path) {
+		// Dumps hold logs and other output besides resources, and resources in files without an extension.
+		if !isValidFile(path) && clusterDump {
+			if !isKubeYAMLFile(path) {
+				return nil
+			}
+		} else if !isValidFile(path) {
 			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping file %v, recognized file extensions are: %v\n", path, fileExtensions)
 			return nil
 		}
@@ -424,6 +444,29 @@ func isValidFile(f string) bool {
 	return false
 }
 
+// isKubeYAMLFile reports whether the file at path contains Kubernetes resources in YAML or JSON, that is whether
+// its first document that is not empty is an object with an apiVersion and a kind.
+func isKubeYAMLFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	decoder := kubeyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var obj map[string]interface{}
+		if err := decoder.Decode(&obj); err != nil {
+			return false
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		apiVersion, _ := obj["apiVersion"].(string)
+		kind, _ := obj["kind"].(string)
+		return apiVersion != "" && kind != ""
+	}
+}
+
 func AnalyzersAsString(analyzers []analysis.Analyzer) string {
 	nameToAnalyzer := make(map[string]analysis.Analyzer)
 	analyzerNames := make([]string, len(analyzers))
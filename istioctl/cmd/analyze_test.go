@@ -15,11 +15,14 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/gomega"
 
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/local"
 )
 
 func TestErrorOnIssuesFound(t *testing.T) {
@@ -63,3 +66,47 @@ func TestNoErrorIfMessageLevelsBelowThreshold(t *testing.T) {
 
 	g.Expect(err).To(BeNil())
 }
+
+func TestGatherFilesClusterDump(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"bug-report/cluster/k8s-resources":         "apiVersion: v1\nitems: []\nkind: List\n",
+		"bug-report/cluster/crs":                   "\napiVersion: v1\nitems: []\nkind: List\n",
+		"bug-report/cluster/events":                "{\n    \"apiVersion\": \"v1\",\n    \"items\": [],\n    \"kind\": \"List\"\n}\n",
+		"bug-report/cluster/mesh-config":           "# Source: istio\n---\nkind: ConfigMap\napiVersion: v1\n",
+		"bug-report/cluster/values":                "global:\n  apiVersion: v1\n",
+		"bug-report/cluster/secrets":               "NAMESPACE   NAME   TYPE   DATA   AGE\n",
+		"bug-report/istio/istiod-1/discovery.log":  "2021-10-15T08:54:11.442093Z\tinfo\tready\n",
+		"bug-report/analyze/all-namespaces.yaml":   "apiVersion: v1\nkind: Namespace\n",
+		"bug-report/cluster/kubectl-version.notes": "Client Version: v1.22.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		g.Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		g.Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+
+	// Registering the flags resets them, so they are set once the command exists.
+	cmd := Analyze()
+	defer func(dump, rec bool) { clusterDump, recursive = dump, rec }(clusterDump, recursive)
+	clusterDump, recursive = true, true
+
+	readers, err := gatherFiles(cmd, []string{dir})
+	g.Expect(err).To(BeNil())
+	g.Expect(readerNames(readers)).To(ConsistOf(
+		filepath.Join(dir, "bug-report/analyze/all-namespaces.yaml"),
+		filepath.Join(dir, "bug-report/cluster/crs"),
+		filepath.Join(dir, "bug-report/cluster/events"),
+		filepath.Join(dir, "bug-report/cluster/k8s-resources"),
+		filepath.Join(dir, "bug-report/cluster/mesh-config")))
+}
+
+func readerNames(readers []local.ReaderSource) []string {
+	var names []string
+	for _, r := range readers {
+		names = append(names, r.Name)
+	}
+	return names
+}
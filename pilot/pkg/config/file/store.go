@@ -282,10 +282,16 @@ func (s *KubeSource) parseContent(r *collection.Schemas, name, yamlText string)
 		}
 
 		chunk := bytes.TrimSpace(doc)
-		r, err := s.parseChunk(r, name, lineNum, chunk)
+		res, err := s.parseChunk(r, name, lineNum, chunk)
 		if err != nil {
 			var uerr *unknownSchemaError
-			if errors.As(err, &uerr) {
+			if errors.As(err, &uerr) && uerr.isList() {
+				items, listErrs := s.parseList(r, name, chunkCount, lineNum, chunk)
+				resources = append(resources, items...)
+				if listErrs != nil {
+					errs = multierror.Append(errs, listErrs)
+				}
+			} else if errors.As(err, &uerr) {
 				// Note the error to the debug log but continue
 				scope.Debugf("skipping unknown yaml chunk %s: %s", name, uerr.Error())
 			} else {
@@ -296,12 +302,87 @@ func (s *KubeSource) parseContent(r *collection.Schemas, name, yamlText string)
 			}
 			continue
 		}
-		resources = append(resources, r)
+		resources = append(resources, res)
 	}
 
 	return resources, errs
 }
 
+// parseList parses the items of a chunk holding a list of resources, such as the output of `kubectl get -o yaml`.
+// Items of unknown kinds are skipped, as for chunks.
+func (s *KubeSource) parseList(r *collection.Schemas, name string, chunkCount, lineNum int,
+	chunk []byte) ([]kubeResource, error) {
+	items, lines, err := listItems(chunk, lineNum)
+	if err != nil {
+		e := fmt.Errorf("error processing %s[%d]: %v", name, chunkCount, err)
+		scope.Warnf("%v - skipping", e)
+		return nil, e
+	}
+
+	var resources []kubeResource
+	var errs error
+	for i, item := range items {
+		res, err := s.parseChunk(r, name, lines[i], item)
+		if err != nil {
+			var uerr *unknownSchemaError
+			if errors.As(err, &uerr) {
+				scope.Debugf("skipping unknown yaml chunk %s: %s", name, uerr.Error())
+			} else {
+				e := fmt.Errorf("error processing %s[%d].items[%d]: %v", name, chunkCount, i, err)
+				scope.Warnf("%v - skipping", e)
+				scope.Debugf("Failed to parse yaml chunk: %v", string(item))
+				errs = multierror.Append(errs, e)
+			}
+			continue
+		}
+		resources = append(resources, res)
+	}
+	return resources, errs
+}
+
+// listItems returns the items of a chunk holding a list of resources, each with the line it starts on. Items are
+// re-encoded, so the lines of their fields are only exact for items written in block style, as kubectl writes them.
+func listItems(chunk []byte, lineNum int) (items [][]byte, lines []int, err error) {
+	// yaml.v3 panics on some malformed input that the YAML to JSON conversion accepts
+	defer func() {
+		if r := recover(); r != nil {
+			items, lines, err = nil, nil, fmt.Errorf("failed parsing list: %v", r)
+		}
+	}()
+
+	node := yamlv3.Node{}
+	if err := yamlv3.Unmarshal(chunk, &node); err != nil {
+		return nil, nil, fmt.Errorf("failed parsing list: %v", err)
+	}
+	if len(node.Content) != 1 || node.Content[0].Kind != yamlv3.MappingNode {
+		return nil, nil, fmt.Errorf("list is not a mapping")
+	}
+	var list *yamlv3.Node
+	fields := node.Content[0].Content
+	for i := 0; i < len(fields)-1; i += 2 {
+		if fields[i].Value == "items" {
+			list = fields[i+1]
+		}
+	}
+	if list == nil {
+		return nil, nil, nil
+	}
+	if list.Kind != yamlv3.SequenceNode {
+		return nil, nil, fmt.Errorf("list items are not a sequence")
+	}
+
+	for _, item := range list.Content {
+		by, err := yamlv3.Marshal(item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed encoding list item: %v", err)
+		}
+		items = append(items, by)
+		// minus one because both lineNum and yamlv3.Node.Line start at line 1
+		lines = append(lines, lineNum+item.Line-1)
+	}
+	return items, lines, nil
+}
+
 // unknownSchemaError represents a schema was not found for a group+version+kind.
 type unknownSchemaError struct {
 	group   string
@@ -309,6 +390,11 @@ type unknownSchemaError struct {
 	kind    string
 }
 
+// isList returns true if the unknown kind is a list of resources, such as v1/List.
+func (e unknownSchemaError) isList() bool {
+	return strings.HasSuffix(e.kind, "List")
+}
+
 func (e unknownSchemaError) Error() string {
 	return fmt.Sprintf("failed finding schema for group/version/kind: %s/%s/%s", e.group, e.version, e.kind)
 }
@@ -89,11 +89,17 @@ type Client struct {
 var _ model.ConfigStoreCache = &Client{}
 
 func New(client kube.Client, revision, domainSuffix string) (model.ConfigStoreCache, error) {
+	return NewForSchemas(context.Background(), client, revision, domainSuffix, defaultSchemas())
+}
+
+// defaultSchemas returns the schemas watched by New. Collections in collections.OnDemand, such as EndpointSlice, are
+// left out: watching them cluster-wide costs an informer event for every endpoint change.
+func defaultSchemas() collection.Schemas {
 	schemas := collections.Kube
 	if features.EnableGatewayAPI {
 		schemas = collections.PilotGatewayAPI
 	}
-	return NewForSchemas(context.Background(), client, revision, domainSuffix, schemas)
+	return schemas.Remove(collections.OnDemand.All()...)
 }
 
 func NewForSchemas(ctx context.Context, client kube.Client, revision, domainSuffix string, schemas collection.Schemas) (model.ConfigStoreCache, error) {
@@ -0,0 +1,43 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arbitraryclient
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func TestDefaultSchemasSkipOnDemand(t *testing.T) {
+	old := features.EnableGatewayAPI
+	defer func() { features.EnableGatewayAPI = old }()
+
+	if _, ok := collections.OnDemand.Find(collections.K8SDiscoveryK8SIoV1Endpointslices.Name().String()); !ok {
+		t.Fatalf("expected %v to be watched on demand", collections.K8SDiscoveryK8SIoV1Endpointslices.Name())
+	}
+	for _, enabled := range []bool{true, false} {
+		features.EnableGatewayAPI = enabled
+		schemas := defaultSchemas()
+		for _, s := range collections.OnDemand.All() {
+			if _, ok := schemas.Find(s.Name().String()); ok {
+				t.Errorf("gateway API %v: default schemas watch on-demand collection %v", enabled, s.Name())
+			}
+		}
+		if len(schemas.All()) == 0 {
+			t.Errorf("gateway API %v: default schemas are empty", enabled)
+		}
+	}
+}
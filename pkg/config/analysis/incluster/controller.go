@@ -57,8 +57,9 @@ func NewController(stop <-chan struct{}, rwConfigStore, configController model.C
 	for k := range analysis.ContainmentMapSchema(rwConfigStore.Schemas()) {
 		duplicates = append(duplicates, k)
 	}
+	// Collections in collections.OnDemand are too costly to watch cluster-wide for every analysis run.
 	store, err := arbitraryclient.NewForSchemas(ctx, kubeClient, "default",
-		domainSuffix, collections.All.Remove(duplicates...))
+		domainSuffix, collections.All.Remove(duplicates...).Remove(collections.OnDemand.All()...))
 	if err != nil {
 		return nil, fmt.Errorf("unable to load common types for analysis, releasing lease: %v", err)
 	}
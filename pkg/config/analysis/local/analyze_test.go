@@ -33,6 +33,8 @@ import (
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/kube"
 )
 
@@ -229,6 +231,51 @@ func TestAddReaderKubeSourceSkipsBadEntries(t *testing.T) {
 	g.Expect(err).To(Not(BeNil()))
 }
 
+func TestAddReaderKubeSourceExpandsLists(t *testing.T) {
+	g := NewWithT(t)
+
+	a := &testAnalyzer{
+		fn:     func(_ analysis.Context) {},
+		inputs: collection.Names{collections.K8SCoreV1Services.Name(), collections.IstioNetworkingV1Alpha3Virtualservices.Name()},
+	}
+	sa := NewSourceAnalyzer(schema.MustGet(), analysis.Combine("a", a), "", "", nil, true, timeout)
+
+	// As written by kubectl get -o yaml, with a kind the analyzer does not read
+	tmpfile := tempFileFromString(t, `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: reviews
+    namespace: bookinfo
+  spec:
+    ports:
+    - port: 9080
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: reviews-v1
+    namespace: bookinfo
+- apiVersion: networking.istio.io/v1alpha3
+  kind: VirtualService
+  metadata:
+    name: reviews
+    namespace: bookinfo
+  spec:
+    hosts:
+    - reviews
+kind: List
+metadata:
+  resourceVersion: ""
+`)
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+
+	err := sa.AddReaderKubeSource([]ReaderSource{{Name: "dump", Reader: tmpfile}})
+	g.Expect(err).To(BeNil())
+	g.Expect(sa.fileSource.Get(gvk.Service, "reviews", "bookinfo")).NotTo(BeNil())
+	g.Expect(sa.fileSource.Get(gvk.VirtualService, "reviews", "bookinfo")).NotTo(BeNil())
+}
+
 const (
 	yamlSeparator = "---\n"
 )
@@ -101,6 +101,48 @@ func (t Providers) RequiredInputsFor(outputs collection.Names) map[collection.Na
 	return inputs
 }
 
+// Inputs returns the sorted, de-duplicated names of all collections consumed by the providers.
+func (t Providers) Inputs() collection.Names {
+	return t.names(func(p Provider) collection.Schemas { return p.Inputs() })
+}
+
+// Outputs returns the sorted, de-duplicated names of all collections produced by the providers.
+func (t Providers) Outputs() collection.Names {
+	return t.names(func(p Provider) collection.Schemas { return p.Outputs() })
+}
+
+// SynthesizedOutputs returns the sorted names of collections that are produced by the providers but
+// never consumed as an input by any of them.
+func (t Providers) SynthesizedOutputs() collection.Names {
+	inputs := make(map[collection.Name]struct{})
+	for _, in := range t.Inputs() {
+		inputs[in] = struct{}{}
+	}
+	result := make(collection.Names, 0)
+	for _, out := range t.Outputs() {
+		if _, ok := inputs[out]; !ok {
+			result = append(result, out)
+		}
+	}
+	return result
+}
+
+func (t Providers) names(fn func(p Provider) collection.Schemas) collection.Names {
+	seen := make(map[collection.Name]struct{})
+	result := make(collection.Names, 0)
+	for _, xfp := range t {
+		for _, s := range fn(xfp).All() {
+			if _, ok := seen[s.Name()]; ok {
+				continue
+			}
+			seen[s.Name()] = struct{}{}
+			result = append(result, s.Name())
+		}
+	}
+	result.Sort()
+	return result
+}
+
 // NewSimpleTransformerProvider creates a basic transformer provider for a basic transformer
 func NewSimpleTransformerProvider(input, output collection.Schema, handleFn func(e event.Event, h event.Handler)) Provider {
 	inputs := collection.NewSchemasBuilder().MustAdd(input).Build()
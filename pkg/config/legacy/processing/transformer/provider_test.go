@@ -23,6 +23,7 @@ import (
 	util "istio.io/istio/pkg/config/legacy/processing"
 	basicmeta "istio.io/istio/pkg/config/legacy/testing/basicmeta"
 	fixtures "istio.io/istio/pkg/config/legacy/testing/fixtures"
+	"istio.io/istio/pkg/config/legacy/testing/k8smeta"
 	"istio.io/istio/pkg/config/schema/collection"
 )
 
@@ -46,3 +47,19 @@ func TestSimpleTransformerProvider(t *testing.T) {
 	fixtures.ExpectEqual(t, transformers[0].Inputs(), collection.SchemasFor(input))
 	fixtures.ExpectEqual(t, transformers[0].Outputs(), collection.SchemasFor(output))
 }
+
+func TestProviders_InputsOutputs(t *testing.T) {
+	g := NewWithT(t)
+
+	handleFn := func(e event.Event, h event.Handler) {}
+	providers := Providers{
+		NewSimpleTransformerProvider(basicmeta.K8SCollection1, basicmeta.Collection2, handleFn),
+		NewSimpleTransformerProvider(basicmeta.Collection2, k8smeta.K8SCoreV1Services, handleFn),
+		NewSimpleTransformerProvider(basicmeta.K8SCollection1, k8smeta.K8SCoreV1Services, handleFn),
+	}
+
+	g.Expect(providers.Inputs()).To(Equal(collection.Names{basicmeta.Collection2.Name(), basicmeta.K8SCollection1.Name()}))
+	g.Expect(providers.Outputs()).To(Equal(collection.Names{basicmeta.Collection2.Name(), k8smeta.K8SCoreV1Services.Name()}))
+	g.Expect(providers.SynthesizedOutputs()).To(Equal(collection.Names{k8smeta.K8SCoreV1Services.Name()}))
+	g.Expect(Providers{}.SynthesizedOutputs()).To(BeEmpty())
+}
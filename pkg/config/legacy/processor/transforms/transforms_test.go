@@ -0,0 +1,36 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transforms
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func TestProviders_EndpointSlice(t *testing.T) {
+	g := NewWithT(t)
+
+	providers := Providers(schema.MustGet())
+	slices := collections.K8SDiscoveryK8SIoV1Endpointslices.Name()
+
+	g.Expect(providers.Inputs()).To(ContainElement(slices))
+	g.Expect(providers.Outputs()).To(ContainElement(slices))
+	g.Expect(providers.RequiredInputsFor(collection.Names{slices})).To(Equal(map[collection.Name]struct{}{slices: {}}))
+}
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// collectionAliases maps collection names used by earlier releases to their current names. When a collection is
+// renamed, add its old name here, so that stored required collection lists and exclusion files keep working.
+// Aliases must map directly to a current name, not to another alias.
+var collectionAliases = map[collection.Name]collection.Name{
+	// The Kubernetes service APIs were renamed to the Gateway API.
+	"k8s/service_apis/v1alpha1/gatewayclasses": "k8s/gateway_api/v1alpha2/gatewayclasses",
+	"k8s/service_apis/v1alpha1/gateways":       "k8s/gateway_api/v1alpha2/gateways",
+	"k8s/service_apis/v1alpha1/httproutes":     "k8s/gateway_api/v1alpha2/httproutes",
+	"k8s/service_apis/v1alpha1/tcproutes":      "k8s/gateway_api/v1alpha2/tcproutes",
+	"k8s/service_apis/v1alpha1/tlsroutes":      "k8s/gateway_api/v1alpha2/tlsroutes",
+}
+
+// CollectionAliases returns the deprecated collection names that are still accepted, mapped to their current
+// names. Aliases are resolved in required collections and in collection: exclusion entries.
+func CollectionAliases() map[collection.Name]collection.Name {
+	out := make(map[collection.Name]collection.Name, len(collectionAliases))
+	for alias, name := range collectionAliases {
+		out[alias] = name
+	}
+	return out
+}
+
+// resolveAlias returns the current name of n, and whether n is an alias.
+func resolveAlias(n collection.Name) (collection.Name, bool) {
+	if name, ok := collectionAliases[n]; ok {
+		return name, true
+	}
+	return n, false
+}
+
+// resolveAliases returns names with every alias replaced by its current name.
+func resolveAliases(names collection.Names) collection.Names {
+	out := make(collection.Names, 0, len(names))
+	for _, n := range names {
+		name, _ := resolveAlias(n)
+		out = append(out, name)
+	}
+	return out
+}
+
+// aliasWarnings returns a WarningDeprecatedAlias warning for every alias in requiredCols and in the collection:
+// exclusion entries.
+func aliasWarnings(requiredCols collection.Names, entries []string) []FilterWarning {
+	var warnings []FilterWarning
+	for _, n := range requiredCols {
+		if name, ok := resolveAlias(n); ok {
+			warnings = append(warnings, FilterWarning{
+				Code:       WarningDeprecatedAlias,
+				Collection: name,
+				Message:    fmt.Sprintf("required collection %s is deprecated; use %s", n, name),
+			})
+		}
+	}
+	for _, entry := range entries {
+		e, reason := parseExclusion(entry)
+		if reason != "" || e.Collection == "" || e.alias == "" {
+			continue
+		}
+		warnings = append(warnings, FilterWarning{
+			Code:       WarningDeprecatedAlias,
+			Entry:      entry,
+			Collection: e.Collection,
+			Message:    fmt.Sprintf("entry %s refers to deprecated collection name %s; use %s", entry, e.alias, e.Collection),
+		})
+	}
+	return warnings
+}
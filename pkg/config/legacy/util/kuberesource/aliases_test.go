@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestCollectionAliases(t *testing.T) {
+	g := NewWithT(t)
+
+	const (
+		oldGateways    collection.Name = "k8s/service_apis/v1alpha1/gateways"
+		oldHTTPRoutes  collection.Name = "k8s/service_apis/v1alpha1/httproutes"
+		gateways       collection.Name = "k8s/gateway_api/v1alpha2/gateways"
+		httpRoutes     collection.Name = "k8s/gateway_api/v1alpha2/httproutes"
+		oldGatewaysRef                 = "collection:" + string(oldGateways)
+	)
+	g.Expect(CollectionAliases()).To(HaveKeyWithValue(oldGateways, gateways))
+	for alias, name := range CollectionAliases() {
+		_, chained := CollectionAliases()[name]
+		g.Expect(chained).To(BeFalse(), "alias %s maps to another alias", alias)
+	}
+
+	in := collection.SchemasFor(
+		newNamedTestSchema(gateways.String(), "gateway.networking.k8s.io", "v1alpha2", "Gateway"),
+		newNamedTestSchema(httpRoutes.String(), "gateway.networking.k8s.io", "v1alpha2", "HTTPRoute"),
+		testService,
+	)
+
+	// The required collection and the exclusion entry are both given by their deprecated names.
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{},
+		collection.Names{oldGateways, oldHTTPRoutes}, WithExcludedResourceKinds(oldGatewaysRef))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.EnabledCollectionNames()).To(Equal(collection.Names{httpRoutes}))
+	g.Expect(reasonOf(result, gateways)).To(Equal(ReasonExcludedKind))
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonNotUpstream))
+
+	g.Expect(result.Warnings).To(Equal([]FilterWarning{
+		{
+			Code:       WarningDeprecatedAlias,
+			Collection: gateways,
+			Message:    "required collection k8s/service_apis/v1alpha1/gateways is deprecated; use k8s/gateway_api/v1alpha2/gateways",
+		},
+		{
+			Code:       WarningDeprecatedAlias,
+			Collection: httpRoutes,
+			Message:    "required collection k8s/service_apis/v1alpha1/httproutes is deprecated; use k8s/gateway_api/v1alpha2/httproutes",
+		},
+		{
+			Code:       WarningDeprecatedAlias,
+			Entry:      oldGatewaysRef,
+			Collection: gateways,
+			Message: "entry collection:k8s/service_apis/v1alpha1/gateways refers to deprecated collection name " +
+				"k8s/service_apis/v1alpha1/gateways; use k8s/gateway_api/v1alpha2/gateways",
+		},
+	}))
+
+	// Current names resolve to themselves, without warnings.
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, collection.Names{gateways},
+		WithExcludedResourceKinds("collection:"+gateways.String()))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Warnings).To(BeEmpty())
+}
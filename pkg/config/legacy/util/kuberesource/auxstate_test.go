@@ -0,0 +1,160 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// auxState is the auxiliary per-collection state of a result, keyed by collection name.
+type auxState struct {
+	selectorHints   map[collection.Name]SelectorHint
+	collectionHints map[collection.Name]CollectionHint
+	lazy            collection.Names
+	keptForStatus   collection.Names
+}
+
+func auxStateOf(r *FilterResult) auxState {
+	out := auxState{selectorHints: r.SelectorHints, collectionHints: r.CollectionHints, lazy: r.LazyCollections}
+	for _, e := range r.Report.Entries {
+		if e.Reason == ReasonKeptForStatus {
+			out.keptForStatus = append(out.keptForStatus, e.Collection)
+		}
+	}
+	return out
+}
+
+var (
+	auxPodHint        = SelectorHint{LabelSelector: "app"}
+	auxDeploymentHint = CollectionHint{ResyncPeriod: time.Minute, PageSize: 100}
+)
+
+// auxOptions set a selector hint on Pod, a collection hint on Deployment, VirtualService as a lazy kind and
+// AuthorizationPolicy, which is not upstream of the required collections, as a status writer.
+func auxOptions() []FilterOption {
+	return []FilterOption{
+		WithSelectorHint(KindPod, auxPodHint),
+		WithCollectionHint(testDeployment.Name(), auxDeploymentHint),
+		WithLazyKinds("VirtualService"),
+		WithStatusWriters(testAuthzPolicy.Name()),
+	}
+}
+
+var (
+	auxInput    = collection.SchemasFor(testService, testPod, testDeployment, testConfigMap, testVirtualService, testAuthzPolicy)
+	auxRequired = collection.Names{testService.Name(), testPod.Name(), testDeployment.Name(), testConfigMap.Name(),
+		testVirtualService.Name()}
+)
+
+func TestAuxiliaryState_ApplyDelta(t *testing.T) {
+	cases := []struct {
+		name  string
+		delta ConfigDelta
+		want  auxState
+	}{
+		{
+			// The delta only touches ConfigMap: the state of every other collection is carried forward.
+			name:  "untouched",
+			delta: ConfigDelta{AddedExclusions: []string{KindConfigMap}},
+			want: auxState{
+				selectorHints:   map[collection.Name]SelectorHint{testPod.Name(): auxPodHint},
+				collectionHints: map[collection.Name]CollectionHint{testDeployment.Name(): auxDeploymentHint},
+				lazy:            collection.Names{testVirtualService.Name()},
+				keptForStatus:   collection.Names{testAuthzPolicy.Name()},
+			},
+		},
+		{
+			// The delta disables the hinted and lazy collections: their state is recomputed, and dropped.
+			name:  "touched",
+			delta: ConfigDelta{AddedExclusions: []string{KindPod, KindDeployment, "VirtualService"}},
+			want: auxState{
+				selectorHints:   map[collection.Name]SelectorHint{},
+				collectionHints: map[collection.Name]CollectionHint{},
+				keptForStatus:   collection.Names{testAuthzPolicy.Name()},
+			},
+		},
+		{
+			// Excluding the status writer fails the next full Apply, but a negated entry re-includes it.
+			name:  "status writer re-included",
+			delta: ConfigDelta{AddedExclusions: []string{"security.istio.io/*", "!AuthorizationPolicy"}},
+			want: auxState{
+				selectorHints:   map[collection.Name]SelectorHint{testPod.Name(): auxPodHint},
+				collectionHints: map[collection.Name]CollectionHint{testDeployment.Name(): auxDeploymentHint},
+				lazy:            collection.Names{testVirtualService.Name()},
+				keptForStatus:   collection.Names{testAuthzPolicy.Name()},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, auxRequired, auxOptions()...)
+			prev, err := f.Apply(auxInput)
+			g.Expect(err).To(BeNil())
+
+			next, err := f.ApplyDelta(prev, c.delta)
+			g.Expect(err).To(BeNil())
+			g.Expect(auxStateOf(next)).To(Equal(c.want))
+
+			// A full Apply with the same configuration computes the same state.
+			full, err := FilterCollections(auxInput, kuberesourcetest.ScriptedProviders{}, auxRequired,
+				append(auxOptions(), WithExcludedResourceKinds(c.delta.AddedExclusions...))...)
+			g.Expect(err).To(BeNil())
+			g.Expect(auxStateOf(next)).To(Equal(auxStateOf(full)))
+
+			// Reverting the delta restores the state of prev, and prev is left untouched.
+			reverted, err := f.ApplyDelta(next, ConfigDelta{RemovedExclusions: c.delta.AddedExclusions})
+			g.Expect(err).To(BeNil())
+			g.Expect(auxStateOf(reverted)).To(Equal(auxStateOf(prev)))
+			g.Expect(prev.SelectorHints).To(HaveKey(testPod.Name()))
+		})
+	}
+}
+
+func TestAuxiliaryState_ApplyAll(t *testing.T) {
+	g := NewWithT(t)
+
+	// The second set lacks Pod and Deployment; the third has no status writer to keep.
+	sets := map[string]collection.Schemas{
+		"full":     auxInput,
+		"no-pods":  collection.SchemasFor(testService, testConfigMap, testVirtualService, testAuthzPolicy),
+		"no-authz": collection.SchemasFor(testService, testPod, testDeployment, testConfigMap, testVirtualService),
+	}
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, auxRequired, auxOptions()...)
+	results, err := f.ApplyAll(sets)
+	g.Expect(err).To(BeNil())
+
+	for name, in := range sets {
+		single, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, auxRequired, auxOptions()...).Apply(in)
+		g.Expect(err).To(BeNil())
+		g.Expect(auxStateOf(results[name])).To(Equal(auxStateOf(single)), name)
+	}
+	g.Expect(results["full"].SelectorHints).To(HaveKey(testPod.Name()))
+	g.Expect(results["full"].CollectionHints).To(HaveKey(testDeployment.Name()))
+	g.Expect(results["no-pods"].SelectorHints).To(BeEmpty())
+	g.Expect(results["no-pods"].CollectionHints).To(BeEmpty())
+	g.Expect(results["no-pods"].LazyCollections).To(Equal(collection.Names{testVirtualService.Name()}))
+	g.Expect(auxStateOf(results["no-authz"]).keptForStatus).To(BeEmpty())
+
+	// The results do not share state: changing one leaves the others intact.
+	results["full"].SelectorHints[testService.Name()] = SelectorHint{LabelSelector: "changed"}
+	g.Expect(results["no-authz"].SelectorHints).NotTo(HaveKey(testService.Name()))
+}
@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"context"
+	"fmt"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// AvailabilityProbe reports whether the API server serves the given resource type, typically by querying
+// the discovery client. An error means availability could not be determined.
+type AvailabilityProbe func(gvk config.GroupVersionKind) (bool, error)
+
+// ContextAvailabilityProbe is an AvailabilityProbe that is given the context of the Apply call, and should
+// return early once it is done.
+type ContextAvailabilityProbe func(ctx context.Context, gvk config.GroupVersionKind) (bool, error)
+
+// ProbeFailurePolicy decides what happens to collections whose availability could not be determined.
+type ProbeFailurePolicy int
+
+const (
+	// FailOpen keeps undetermined collections enabled.
+	FailOpen ProbeFailurePolicy = iota
+
+	// FailClosed disables undetermined collections.
+	FailClosed
+)
+
+// availabilityCheck probes collections for availability, retrying errors as configured.
+type availabilityCheck struct {
+	probe       ContextAvailabilityProbe
+	attempts    int
+	isRetryable func(error) bool
+	policy      ProbeFailurePolicy
+}
+
+// check probes gvk, returning the availability and the last error if it remained undetermined. Once ctx is
+// done, no further attempt is made and the context's error is returned.
+func (a *availabilityCheck) check(ctx context.Context, gvk config.GroupVersionKind) (bool, error) {
+	attempts := a.attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return false, ctxErr
+		}
+		var available bool
+		available, err = a.probe(ctx, gvk)
+		if err == nil {
+			return available, nil
+		}
+		if a.isRetryable != nil && !a.isRetryable(err) {
+			break
+		}
+	}
+	return false, err
+}
+
+// setUndetermined records err as the reason the availability of gvk could not be determined.
+func (r *FilterResult) setUndetermined(gvk config.GroupVersionKind, err error) {
+	if r.undetermined == nil {
+		r.undetermined = make(map[config.GroupVersionKind]error)
+	}
+	r.undetermined[gvk] = err
+}
+
+// applyAvailability refines the decision for a schema based on the availability probe. Availability
+// already known to the result, from an earlier pass or a previous result, is reused instead of probing again.
+func (f *CollectionFilter) applyAvailability(ctx context.Context, s collection.Schema, d Decision, result *FilterResult) Decision {
+	a := f.opts.availability
+	if a == nil || a.probe == nil {
+		return d
+	}
+
+	gvk := s.Resource().GroupVersionKind()
+	available, known := result.availability[gvk]
+	if !known {
+		if prev, ok := f.opts.knownAvailability[gvk]; ok {
+			available, known = prev, true
+		}
+	}
+	if !known {
+		err, deferred := f.opts.deferredProbes[gvk]
+		if !deferred {
+			available, err = a.check(ctx, gvk)
+		}
+		if err != nil {
+			result.setUndetermined(gvk, err)
+			result.Warnings = append(result.Warnings, FilterWarning{
+				Code:       WarningUndetermined,
+				Collection: s.Name(),
+				Message:    fmt.Sprintf("unable to determine availability of %v for collection %s: %v", gvk, s.Name(), err),
+			})
+			if a.policy == FailClosed {
+				return d.disabledFor(ReasonUndetermined)
+			}
+			if !d.Disabled {
+				d = Decision{Reason: ReasonUndetermined}
+			}
+			return d
+		}
+	}
+
+	result.availability[gvk] = available
+	if !available {
+		return d.disabledFor(ReasonResourceUnavailable)
+	}
+	return d
+}
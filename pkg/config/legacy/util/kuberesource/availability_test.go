@@ -0,0 +1,255 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var errTransient = errors.New("transient")
+
+// flakyProbe fails the first failures calls for each kind in flaky, and reports kinds in missing as unavailable.
+type flakyProbe struct {
+	failures int
+	flaky    map[string]bool
+	missing  map[string]bool
+	calls    map[string]int
+}
+
+func newFlakyProbe(failures int, flaky ...string) *flakyProbe {
+	p := &flakyProbe{failures: failures, flaky: map[string]bool{}, missing: map[string]bool{}, calls: map[string]int{}}
+	for _, k := range flaky {
+		p.flaky[k] = true
+	}
+	return p
+}
+
+func (p *flakyProbe) probe(gvk config.GroupVersionKind) (bool, error) {
+	p.calls[gvk.Kind]++
+	if p.flaky[gvk.Kind] && p.calls[gvk.Kind] <= p.failures {
+		return false, errTransient
+	}
+	return !p.missing[gvk.Kind], nil
+}
+
+func TestAvailability_RetrySucceeds(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testDeployment)
+	p := newFlakyProbe(2, KindDeployment)
+	p.missing[KindService] = true
+
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithAvailabilityProbe(p.probe), WithAvailabilityRetry(3, nil))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Warnings).To(BeEmpty())
+	g.Expect(p.calls[KindDeployment]).To(Equal(3))
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonEnabled))
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonResourceUnavailable))
+	g.Expect(result.EnabledCollectionNames()).To(Equal(collection.Names{testDeployment.Name()}))
+}
+
+func TestAvailability_NotRetryable(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testDeployment)
+	p := newFlakyProbe(2, KindDeployment)
+
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithAvailabilityProbe(p.probe), WithAvailabilityRetry(3, func(err error) bool { return false }))
+	g.Expect(err).To(BeNil())
+	g.Expect(p.calls[KindDeployment]).To(Equal(1))
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonUndetermined))
+	g.Expect(result.EnabledCollectionNames()).To(Equal(collection.Names{testDeployment.Name()}))
+}
+
+func TestAvailability_FailClosed(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testDeployment)
+	p := newFlakyProbe(5, KindDeployment)
+
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithAvailabilityProbe(p.probe), WithAvailabilityRetry(2, nil), WithProbeFailurePolicy(FailClosed))
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonUndetermined))
+	g.Expect(result.EnabledCollectionNames()).To(BeEmpty())
+}
+
+func TestAvailability_StateReprobesUndetermined(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testDeployment)
+	p := newFlakyProbe(2, KindDeployment)
+	opts := []FilterOption{WithAvailabilityProbe(p.probe), WithAvailabilityRetry(2, nil)}
+
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+	g.Expect(err).To(BeNil())
+
+	result := state.Current()
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonUndetermined))
+	g.Expect(result.EnabledCollectionNames()).To(ContainElement(testDeployment.Name()))
+	g.Expect(result.Warnings).To(HaveLen(1))
+	g.Expect(result.Warnings[0].Code).To(Equal(WarningUndetermined))
+	g.Expect(result.Warnings[0].Collection).To(Equal(testDeployment.Name()))
+	g.Expect(p.calls).To(Equal(map[string]int{KindService: 1, KindDeployment: 2}))
+
+	result, err = state.Update(opts...)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonEnabled))
+	g.Expect(result.Warnings).To(BeEmpty())
+	// Service was determined by the first pass, so only Deployment is probed again.
+	g.Expect(p.calls).To(Equal(map[string]int{KindService: 1, KindDeployment: 3}))
+}
+
+func TestAvailability_StateReprobeBackoff(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testDeployment)
+	p := newFlakyProbe(3, KindDeployment)
+	clock := &fakeClock{t: time.Unix(0, 0).UTC()}
+	opts := []FilterOption{WithAvailabilityProbe(p.probe), WithReprobeBackoff(10*time.Second, 15*time.Second)}
+
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts, WithClock(clock))...)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(state.Current(), testDeployment.Name())).To(Equal(ReasonUndetermined))
+	g.Expect(p.calls).To(Equal(map[string]int{KindService: 1, KindDeployment: 1}))
+
+	// Until the re-probe is due, updates keep Deployment undetermined without probing it.
+	result, err := state.Update(opts...)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonUndetermined))
+	g.Expect(result.Warnings).To(HaveLen(1))
+	g.Expect(result.Warnings[0].Code).To(Equal(WarningUndetermined))
+	clock.Advance(10*time.Second - time.Nanosecond)
+	g.Expect(p.calls[KindDeployment]).To(Equal(1))
+
+	// The re-probe runs on its own once due, and its delay doubles up to the maximum after every failure.
+	clock.Advance(time.Nanosecond)
+	g.Expect(p.calls[KindDeployment]).To(Equal(2))
+	clock.Advance(15*time.Second - time.Nanosecond)
+	g.Expect(p.calls[KindDeployment]).To(Equal(2))
+	clock.Advance(time.Nanosecond)
+	g.Expect(p.calls[KindDeployment]).To(Equal(3))
+	g.Expect(reasonOf(state.Current(), testDeployment.Name())).To(Equal(ReasonUndetermined))
+	clock.Advance(15 * time.Second)
+	g.Expect(p.calls).To(Equal(map[string]int{KindService: 1, KindDeployment: 4}))
+	g.Expect(reasonOf(state.Current(), testDeployment.Name())).To(Equal(ReasonEnabled))
+	g.Expect(clock.pending()).To(Equal(0))
+
+	// Close stops re-probing.
+	p = newFlakyProbe(1, KindDeployment)
+	opts = []FilterOption{WithAvailabilityProbe(p.probe), WithReprobeBackoff(10*time.Second, 0)}
+	state, err = NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts, WithClock(clock))...)
+	g.Expect(err).To(BeNil())
+	g.Expect(clock.pending()).To(Equal(1))
+	state.Close()
+	g.Expect(clock.pending()).To(Equal(0))
+}
+
+// blockingProbe blocks its first call until released, reporting every kind as available.
+type blockingProbe struct {
+	started  chan struct{}
+	released chan struct{}
+	calls    int
+}
+
+func newBlockingProbe() *blockingProbe {
+	return &blockingProbe{started: make(chan struct{}), released: make(chan struct{})}
+}
+
+func (p *blockingProbe) probe(gvk config.GroupVersionKind) (bool, error) {
+	p.calls++
+	if p.calls == 1 {
+		close(p.started)
+		<-p.released
+	}
+	return true, nil
+}
+
+func TestAvailability_CanceledMidProbe(t *testing.T) {
+	for _, policy := range []ProbeFailurePolicy{FailOpen, FailClosed} {
+		g := NewWithT(t)
+
+		in := collection.SchemasFor(testDeployment, testConfigMap, testEndpointSlice)
+		p := newBlockingProbe()
+		f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+			WithAvailabilityProbe(p.probe), WithProbeFailurePolicy(policy))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var result *FilterResult
+		var err error
+		done := make(chan struct{})
+		go func() {
+			result, err = f.ApplyContext(ctx, in)
+			close(done)
+		}()
+		<-p.started
+		cancel()
+		close(p.released)
+		<-done
+		g.Expect(err).To(BeNil())
+
+		// Only the probe in flight completed; the remaining collections were not probed.
+		g.Expect(p.calls).To(Equal(1))
+		undetermined := 0
+		for _, e := range result.Report.Entries {
+			if e.Reason != ReasonUndetermined {
+				g.Expect(e.Reason).To(Equal(ReasonEnabled))
+				continue
+			}
+			undetermined++
+			g.Expect(e.Disabled).To(Equal(policy == FailClosed))
+		}
+		g.Expect(undetermined).To(Equal(2))
+		g.Expect(result.Warnings).To(HaveLen(2))
+		g.Expect(result.Warnings[0].Code).To(Equal(WarningUndetermined))
+		g.Expect(result.Warnings[0].Message).To(ContainSubstring(context.Canceled.Error()))
+	}
+}
+
+func TestAvailability_ContextProbeDeadline(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testDeployment, testConfigMap)
+	calls := 0
+	probe := func(ctx context.Context, gvk config.GroupVersionKind) (bool, error) {
+		calls++
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	result, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithContextAvailabilityProbe(probe), WithAvailabilityRetry(3, nil)).ApplyContext(ctx, in)
+	g.Expect(err).To(BeNil())
+	// The deadline stops the retries as well as the probes of the remaining collections.
+	g.Expect(calls).To(Equal(1))
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonUndetermined))
+	g.Expect(reasonOf(result, testConfigMap.Name())).To(Equal(ReasonUndetermined))
+	g.Expect(result.EnabledCollectionNames()).To(HaveLen(2))
+}
@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WithDenyNewCollections disables, with ReasonNotInBaseline, every collection the filter would otherwise enable
+// that is not in baseline: the collections the caller has reviewed, for example the enabled collections of the
+// previous version. Collections an upgrade adds then stay disabled until they are added to the baseline, and a
+// single WarningNotInBaseline warning lists them. Kinds required for service discovery are exempt. Deprecated
+// collection names in baseline are resolved to their current names. UpgradeDiff.UpdatedBaseline computes the
+// baseline to persist once the added collections have been reviewed.
+func WithDenyNewCollections(baseline []collection.Name) FilterOption {
+	return func(o *filterOptions) {
+		o.reviewedBaseline = make(map[collection.Name]struct{}, len(baseline))
+		for _, n := range resolveAliases(baseline) {
+			o.reviewedBaseline[n] = struct{}{}
+		}
+	}
+}
+
+// applyBaseline disables s, which the filter decided d for, if it is enabled but not in the reviewed baseline.
+func (f *CollectionFilter) applyBaseline(s collection.Schema, d Decision) Decision {
+	if f.opts.reviewedBaseline == nil || d.Disabled || f.opts.isDiscoveryKind(s.Resource()) {
+		return d
+	}
+	if _, ok := f.opts.reviewedBaseline[s.Name()]; ok {
+		return d
+	}
+	return d.disabledFor(ReasonNotInBaseline)
+}
+
+// baselineWarnings returns a WarningNotInBaseline warning listing the collections the report disables for not
+// being in the reviewed baseline, if any.
+func baselineWarnings(report *FilterReport) []FilterWarning {
+	var denied []string
+	for _, e := range report.Entries {
+		if e.Disabled && e.Reason == ReasonNotInBaseline {
+			denied = append(denied, e.Collection.String())
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	return []FilterWarning{{
+		Code: WarningNotInBaseline,
+		Message: fmt.Sprintf("%d collections are disabled because they are not in the reviewed baseline; review "+
+			"them and add them to the baseline to enable them: %s", len(denied), strings.Join(denied, ", ")),
+	}}
+}
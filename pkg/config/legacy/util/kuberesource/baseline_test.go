@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestWithDenyNewCollections(t *testing.T) {
+	g := NewWithT(t)
+
+	telemetry := newTestSchema("telemetry.istio.io", "v1alpha1", "Telemetry")
+	oldSchemas := collection.SchemasFor(testService, testPod, testConfigMap, testVirtualService)
+	newSchemas := collection.SchemasFor(testService, testPod, testConfigMap, testVirtualService, testEndpointSlice,
+		telemetry)
+	cfg := FilterConfig{
+		ExcludedResourceKinds: []string{KindConfigMap},
+		Features:              FeatureRequirements{ServiceDiscovery: true},
+	}
+
+	oldResult, err := cfg.Apply(oldSchemas, transformer.Providers{})
+	g.Expect(err).To(BeNil())
+	baseline := oldResult.EnabledCollectionNames()
+
+	// After the upgrade, the new Telemetry collection is held back; EndpointSlice is exempt, since service
+	// discovery requires it, and ConfigMap stays excluded.
+	newResult, err := FilterCollections(newSchemas, transformer.Providers{}, newSchemas.CollectionNames(),
+		append(cfg.Options(), WithDenyNewCollections(baseline))...)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(newResult, telemetry.Name())).To(Equal(ReasonNotInBaseline))
+	g.Expect(newResult.Schemas.MustFind(telemetry.Name().String()).IsDisabled()).To(BeTrue())
+	g.Expect(reasonOf(newResult, testEndpointSlice.Name())).To(Equal(ReasonEnabled))
+	g.Expect(newResult.Schemas.MustFind(testEndpointSlice.Name().String()).IsDisabled()).To(BeFalse())
+	g.Expect(reasonOf(newResult, testConfigMap.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(reasonOf(newResult, testVirtualService.Name())).To(Equal(ReasonEnabled))
+
+	var baselineWarnings []FilterWarning
+	for _, w := range newResult.Warnings {
+		if w.Code == WarningNotInBaseline {
+			baselineWarnings = append(baselineWarnings, w)
+		}
+	}
+	g.Expect(baselineWarnings).To(HaveLen(1))
+	g.Expect(baselineWarnings[0].Message).To(ContainSubstring(telemetry.Name().String()))
+	g.Expect(baselineWarnings[0].Message).NotTo(ContainSubstring(testEndpointSlice.Name().String()))
+
+	// The upgrade diff lists both added collections, and accepting them enables Telemetry.
+	diff, err := DiffAcrossSchemaSets(oldSchemas, newSchemas, cfg)
+	g.Expect(err).To(BeNil())
+	g.Expect(diff.Added).To(Equal(collection.Names{testEndpointSlice.Name(), telemetry.Name()}))
+	updated := diff.UpdatedBaseline(baseline)
+	g.Expect(updated).To(ContainElements(telemetry.Name(), testService.Name()))
+
+	accepted, err := FilterCollections(newSchemas, transformer.Providers{}, newSchemas.CollectionNames(),
+		append(cfg.Options(), WithDenyNewCollections(updated))...)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(accepted, telemetry.Name())).To(Equal(ReasonEnabled))
+	for _, w := range accepted.Warnings {
+		g.Expect(w.Code).NotTo(Equal(WarningNotInBaseline))
+	}
+	g.Expect(accepted.Fingerprint).NotTo(Equal(newResult.Fingerprint))
+}
+
+func TestWithDenyNewCollections_EmptyBaseline(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testVirtualService)
+	result, err := FilterCollections(in, transformer.Providers{}, in.CollectionNames(), WithDenyNewCollections(nil))
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testVirtualService.Name())).To(Equal(ReasonNotInBaseline))
+	// Service discovery is disabled, but Service is still a kind it requires.
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonEnabled))
+
+	_, err = NewCollectionFilter(transformer.Providers{}, in.CollectionNames(), WithDenyNewCollections(nil)).Config()
+	g.Expect(err).NotTo(BeNil())
+}
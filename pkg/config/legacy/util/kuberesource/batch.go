@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// BatchError is returned by ApplyAll when some schema sets could not be filtered.
+type BatchError struct {
+	// Errors are the errors Apply returned, by set name.
+	Errors map[string]error
+}
+
+func (e *BatchError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("schema set %s: %v", name, e.Errors[name]))
+	}
+	return fmt.Sprintf("%d of the schema sets could not be filtered: %s", len(e.Errors), strings.Join(parts, "; "))
+}
+
+// SetWarning is a FilterWarning raised while filtering the named schema set.
+type SetWarning struct {
+	Set string `json:"set"`
+	FilterWarning
+}
+
+func (w SetWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Set, w.FilterWarning)
+}
+
+// ApplyAll filters every schema set in sets with the same compiled configuration, as test matrices and control
+// planes serving several tenants with their own CRD catalogs do. The exclusion matcher and the upstream
+// collections are computed once, when the filter is compiled, and shared by every set. An error for one set does
+// not prevent results for the others: the sets that could be filtered are returned, and the errors for the rest
+// are returned as a *BatchError keyed by set name. See CombinedWarnings for the warnings of every set.
+func (f *CollectionFilter) ApplyAll(sets map[string]collection.Schemas) (map[string]*FilterResult, error) {
+	results := make(map[string]*FilterResult, len(sets))
+	errs := make(map[string]error)
+	for name, in := range sets {
+		result, err := f.ApplyContext(context.Background(), in)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+		results[name] = result
+	}
+	if len(errs) > 0 {
+		return results, &BatchError{Errors: errs}
+	}
+	return results, nil
+}
+
+// CombinedWarnings returns the warnings of results, as returned by ApplyAll, sorted by set name and in the order
+// each result reports them.
+func CombinedWarnings(results map[string]*FilterResult) []SetWarning {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out []SetWarning
+	for _, name := range names {
+		for _, w := range results[name].Warnings {
+			out = append(out, SetWarning{Set: name, FilterWarning: w})
+		}
+	}
+	return out
+}
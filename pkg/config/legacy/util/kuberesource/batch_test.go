@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestApplyAll(t *testing.T) {
+	g := NewWithT(t)
+
+	sets := map[string]collection.Schemas{
+		"core":    collection.SchemasFor(testService, testPod, testSecret),
+		"mesh":    collection.SchemasFor(testService, testVirtualService, testGateway),
+		"minimal": collection.SchemasFor(testNamespace),
+	}
+	required := collection.Names{testService.Name(), testPod.Name(), testSecret.Name(), testVirtualService.Name(),
+		testGateway.Name(), testNamespace.Name()}
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, required,
+		WithExcludedResourceKinds("Secret", "networking.istio.io/*", "collection:k8s/service_apis/v1alpha1/gateways"))
+
+	results, err := f.ApplyAll(sets)
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(3))
+	for name, in := range sets {
+		want, err := f.Apply(in)
+		g.Expect(err).To(BeNil())
+		g.Expect(results[name].Report).To(Equal(want.Report), name)
+	}
+	g.Expect(enabledNames(results["core"].Schemas)).To(ConsistOf(testService.Name().String(), testPod.Name().String()))
+	g.Expect(enabledNames(results["mesh"].Schemas)).To(ConsistOf(testService.Name().String()))
+	g.Expect(enabledNames(results["minimal"].Schemas)).To(ConsistOf(testNamespace.Name().String()))
+
+	// The deprecated alias is reported for every set, in set name order.
+	var warned []string
+	for _, w := range CombinedWarnings(results) {
+		g.Expect(w.Code).To(Equal(WarningDeprecatedAlias))
+		warned = append(warned, w.Set)
+	}
+	g.Expect(warned).To(Equal([]string{"core", "mesh", "minimal"}))
+}
+
+func TestApplyAll_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	sets := map[string]collection.Schemas{
+		"with-pods":    collection.SchemasFor(testService, testPod),
+		"without-pods": collection.SchemasFor(testService, testSecret),
+	}
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name()},
+		WithExcludedResourceKinds("Pod"), WithStatusWriters(testPod.Name()))
+
+	results, err := f.ApplyAll(sets)
+	g.Expect(results).To(HaveLen(1))
+	g.Expect(results).To(HaveKey("without-pods"))
+
+	var batchErr *BatchError
+	g.Expect(err).To(BeAssignableToTypeOf(batchErr))
+	batchErr = err.(*BatchError)
+	g.Expect(batchErr.Errors).To(HaveLen(1))
+	g.Expect(batchErr.Errors["with-pods"]).To(MatchError(ContainSubstring("status writer collection k8s/core/v1/pods")))
+	g.Expect(err.Error()).To(HavePrefix("1 of the schema sets could not be filtered: schema set with-pods: "))
+}
@@ -0,0 +1,224 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"testing"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// applyAllocsBudget is the maximum number of allocations a default-config Apply over the full schema set may
+// make. It is deliberately generous: it exists to catch accidental quadratic behavior, not small regressions.
+// If a change legitimately needs more, run
+//
+//	go test -run TestApplyAllocationBudget -v ./pkg/config/legacy/util/kuberesource/
+//
+// which logs the measured allocations, and raise the budget to about three times that in the same change, explaining
+// the increase in the commit message.
+const applyAllocsBudget = 1000
+
+func defaultBenchOptions() []FilterOption {
+	return []FilterOption{
+		WithExcludedResourceKinds(DefaultExcludedResourceKinds()...),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+	}
+}
+
+func heavyBenchOptions() []FilterOption {
+	exclusions := append(DefaultExcludedResourceKinds(),
+		"networking.istio.io/*", "!networking.istio.io/v1beta1/*", "security.istio.io/v1beta1/?eer*",
+		"*/*/*Policy", "!core/v1/*", "collection:k8s/core/v1/configmaps")
+	for i := 0; i < 100; i++ {
+		exclusions = append(exclusions, fmt.Sprintf("vendor%d.example.com/*/Widget%d", i, i))
+	}
+	return []FilterOption{
+		WithExcludedResourceKinds(exclusions...),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true, AmbientEnabled: true, InjectionEnabled: true}),
+		WithAvailabilityProbe(func(config.GroupVersionKind) (bool, error) { return true, nil }),
+		WithReasonHook(ReasonExcludedKind, func(collection.Schema, Decision) {}),
+		WithSelectorHint(KindPod, SelectorHint{LabelSelector: "app"}),
+		PreferNewestVersion(),
+	}
+}
+
+func BenchmarkApply(b *testing.B) {
+	in := schema.MustGet().AllCollections()
+	cases := []struct {
+		name string
+		opts []FilterOption
+	}{
+		{"default", defaultBenchOptions()},
+		{"heavy exclusions", heavyBenchOptions()},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), c.opts...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if _, err := f.Apply(in); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkApplyPerCluster filters the full schema set once per cluster, as a multicluster control plane does at
+// startup, and checks the results for consistency.
+func BenchmarkApplyPerCluster(b *testing.B) {
+	const clusters = 10
+	in := schema.MustGet().AllCollections()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		results := make(map[cluster.ID]*FilterResult, clusters)
+		for i := 0; i < clusters; i++ {
+			opts := defaultBenchOptions()
+			if i%2 == 1 {
+				opts = append(opts, WithExcludedResourceKinds("networking.istio.io/*"))
+			}
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			results[cluster.ID(fmt.Sprintf("cluster-%d", i))] = result
+		}
+		CrossClusterConsistencyCheck(results, kuberesourcetest.ScriptedProviders{})
+	}
+}
+
+// BenchmarkApplyDelta re-applies the filter with an exclusion toggled on and off, as dynamic reconfiguration does.
+func BenchmarkApplyDelta(b *testing.B) {
+	in := schema.MustGet().AllCollections()
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), defaultBenchOptions()...)
+	prev, err := f.Apply(in)
+	if err != nil {
+		b.Fatal(err)
+	}
+	deltas := []ConfigDelta{
+		{AddedExclusions: []string{"networking.istio.io/*"}},
+		{RemovedExclusions: []string{"networking.istio.io/*"}},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if prev, err = f.ApplyDelta(prev, deltas[n%len(deltas)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkValidateFilterConfig validates the heavy exclusion list, whose entries are resolved through the kind
+// index rather than by scanning the full schema set once per entry.
+func BenchmarkValidateFilterConfig(b *testing.B) {
+	in := schema.MustGet().AllCollections()
+	opts := heavyBenchOptions()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_ = ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{}, opts...)
+	}
+}
+
+// BenchmarkWouldChangeAnything evaluates a bare-kind entry against a previous result, whose kind index resolves it.
+func BenchmarkWouldChangeAnything(b *testing.B) {
+	in := schema.MustGet().AllCollections()
+	current, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), defaultBenchOptions()...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, _ = WouldChangeAnything(current, "Gateway")
+	}
+}
+
+func TestApplyAllocationBudget(t *testing.T) {
+	in := schema.MustGet().AllCollections()
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), defaultBenchOptions()...)
+	allocs := testing.AllocsPerRun(5, func() {
+		if _, err := f.Apply(in); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Logf("Apply over %d collections: %.0f allocations (budget %d)", len(in.All()), allocs, applyAllocsBudget)
+	if allocs > applyAllocsBudget {
+		t.Fatalf("Apply made %.0f allocations, over the budget of %d; see applyAllocsBudget", allocs, applyAllocsBudget)
+	}
+}
+
+// BenchmarkEnabledSetContains checks every collection against an EnabledSet, as consumers do per config event.
+func BenchmarkEnabledSetContains(b *testing.B) {
+	in := schema.MustGet().AllCollections()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), defaultBenchOptions()...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	set := NewEnabledSet(result, nil)
+	names := in.CollectionNames()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, name := range names {
+			_ = set.Contains(name)
+		}
+	}
+}
+
+// BenchmarkEnabledSetContainsIndex is BenchmarkEnabledSetContains with the indexes resolved once up front.
+func BenchmarkEnabledSetContainsIndex(b *testing.B) {
+	in := schema.MustGet().AllCollections()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), defaultBenchOptions()...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	set := NewEnabledSet(result, nil)
+	indexes := make([]int, 0, len(in.All()))
+	for _, name := range in.CollectionNames() {
+		i, _ := set.Indexer().Index(name)
+		indexes = append(indexes, i)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, i := range indexes {
+			_ = set.ContainsIndex(i)
+		}
+	}
+}
+
+// BenchmarkEnabledMapContains is BenchmarkEnabledSetContains with the naive map of enabled names, for comparison.
+func BenchmarkEnabledMapContains(b *testing.B) {
+	in := schema.MustGet().AllCollections()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), defaultBenchOptions()...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	enabled := make(map[collection.Name]struct{})
+	for _, n := range result.EnabledCollectionNames() {
+		enabled[n] = struct{}{}
+	}
+	names := in.CollectionNames()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, name := range names {
+			_, _ = enabled[name]
+		}
+	}
+}
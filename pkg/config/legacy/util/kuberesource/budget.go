@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// BudgetPolicy decides what happens when more CRD-backed collections are enabled than WithEnabledBudget allows.
+type BudgetPolicy int
+
+const (
+	// BudgetWarn reports a WarningBudgetExceeded warning.
+	BudgetWarn BudgetPolicy = iota
+
+	// BudgetStrict makes Apply fail with a BudgetExceededError.
+	BudgetStrict
+)
+
+// BudgetExceededError is returned by Apply when a strict budget is exceeded.
+type BudgetExceededError struct {
+	Budget int
+
+	// Enabled are the enabled CRD-backed collections, sorted by group and then name.
+	Enabled collection.Names
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%d CRD-backed collections are enabled, exceeding the budget of %d: %v", len(e.Enabled), e.Budget, e.Enabled)
+}
+
+// WithEnabledBudget caps the number of CRD-backed collections that may be enabled after filtering, since istiod
+// opens a watch for each of them. Builtin collections never count toward the budget. By default, exceeding it is
+// reported as a warning; see WithBudgetPolicy.
+func WithEnabledBudget(maxCRDCollections int) FilterOption {
+	return func(o *filterOptions) {
+		o.enabledBudget = &maxCRDCollections
+	}
+}
+
+// WithBudgetPolicy sets whether exceeding the budget set by WithEnabledBudget is reported as a warning
+// (BudgetWarn, the default) or makes Apply fail (BudgetStrict).
+func WithBudgetPolicy(p BudgetPolicy) FilterOption {
+	return func(o *filterOptions) {
+		o.budgetPolicy = p
+	}
+}
+
+// budgetErrors returns an error if the budget is invalid.
+func (o *filterOptions) budgetErrors() []error {
+	if o.enabledBudget != nil && *o.enabledBudget < 0 {
+		return []error{fmt.Errorf("enabled budget must not be negative, got %d", *o.enabledBudget)}
+	}
+	return nil
+}
+
+// checkBudget returns a BudgetExceededError if the enabled CRD-backed collections in schemas exceed the budget.
+func (f *CollectionFilter) checkBudget(schemas collection.Schemas) *BudgetExceededError {
+	if f.opts.enabledBudget == nil {
+		return nil
+	}
+	var enabled []collection.Schema
+	for _, s := range schemas.All() {
+		if !s.IsDisabled() && !isBuiltin(s.Resource()) {
+			enabled = append(enabled, s)
+		}
+	}
+	if len(enabled) <= *f.opts.enabledBudget {
+		return nil
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		gi, gj := enabled[i].Resource().Group(), enabled[j].Resource().Group()
+		if gi != gj {
+			return gi < gj
+		}
+		return enabled[i].Name() < enabled[j].Name()
+	})
+	err := &BudgetExceededError{Budget: *f.opts.enabledBudget}
+	for _, s := range enabled {
+		err.Enabled = append(err.Enabled, s.Name())
+	}
+	return err
+}
+
+// applyBudget warns about an exceeded budget in result, returning the error to fail with if the policy is strict.
+func (f *CollectionFilter) applyBudget(result *FilterResult) error {
+	err := f.checkBudget(result.Schemas)
+	if err == nil {
+		return nil
+	}
+	if f.opts.budgetPolicy == BudgetStrict {
+		return err
+	}
+	result.Warnings = append(result.Warnings, FilterWarning{
+		Code:    WarningBudgetExceeded,
+		Message: err.Error(),
+	})
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func TestWithEnabledBudget(t *testing.T) {
+	// The test schemas are CRD-backed; the Pods collection is builtin and never counts.
+	in := collection.SchemasFor(testService, testDeployment, testEndpointSlice, collections.K8SCoreV1Pods)
+	// Sorted by group, with the core group first.
+	enabled := collection.Names{testService.Name(), testDeployment.Name(), testEndpointSlice.Name()}
+
+	cases := []struct {
+		name   string
+		budget int
+		policy BudgetPolicy
+		fails  bool
+		warns  bool
+	}{
+		{name: "below", budget: 4, policy: BudgetStrict},
+		{name: "at", budget: 3, policy: BudgetStrict},
+		{name: "above, warning", budget: 2, policy: BudgetWarn, warns: true},
+		{name: "above, strict", budget: 2, policy: BudgetStrict, fails: true},
+		{name: "none allowed", budget: 0, policy: BudgetWarn, warns: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+				WithEnabledBudget(c.budget), WithBudgetPolicy(c.policy))
+			if c.fails {
+				g.Expect(err).To(Equal(&BudgetExceededError{Budget: c.budget, Enabled: enabled}))
+				g.Expect(err.Error()).To(Equal("3 CRD-backed collections are enabled, exceeding the budget of 2: " +
+					"[k8s/core/v1/services k8s/apps/v1/deployments k8s/discovery.k8s.io/v1/endpointslices]"))
+				return
+			}
+			g.Expect(err).To(BeNil())
+			if !c.warns {
+				g.Expect(result.Warnings).To(BeEmpty())
+				return
+			}
+			g.Expect(result.Warnings).To(Equal([]FilterWarning{{
+				Code:    WarningBudgetExceeded,
+				Message: (&BudgetExceededError{Budget: c.budget, Enabled: enabled}).Error(),
+			}}))
+		})
+	}
+}
+
+func TestWithEnabledBudget_Excluding(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testDeployment, testEndpointSlice)
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithEnabledBudget(2), WithBudgetPolicy(BudgetStrict))
+	prev, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames()).Apply(in)
+	g.Expect(err).To(BeNil())
+	_, err = f.Apply(in)
+	g.Expect(err).NotTo(BeNil())
+
+	// Excluding a kind brings the filter within budget; a delta that exceeds it again leaves the filter unchanged.
+	prev, err = f.ApplyDelta(prev, ConfigDelta{AddedExclusions: []string{KindDeployment}})
+	g.Expect(err).To(BeNil())
+	g.Expect(prev.Warnings).To(BeEmpty())
+	_, err = f.ApplyDelta(prev, ConfigDelta{RemovedExclusions: []string{KindDeployment}})
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(f.opts.excludedResourceKinds).To(Equal([]string{KindDeployment}))
+
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, nil, WithEnabledBudget(-1))
+	g.Expect(err).To(MatchError("enabled budget must not be negative, got -1"))
+}
@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock supplies a CollectionFilterState with time: the timestamp and sequence number of its updates, the
+// notification window of WithNotifyWindow and the re-probe backoff of WithReprobeBackoff. Embedders that pass
+// their own clock can correlate the update history with their own event timeline, and tests can advance it
+// deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Sequence returns the next number of a sequence that increases on every call.
+	Sequence() uint64
+
+	// AfterFunc calls f once d has elapsed, unless the returned function is called first, which reports whether it
+	// stopped the call. It must not call f before returning.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// RealClock returns a clock that reads the system time, with a sequence starting at 1.
+func RealClock() Clock {
+	return &realClock{}
+}
+
+type realClock struct {
+	seq uint64
+}
+
+func (c *realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *realClock) Sequence() uint64 {
+	return atomic.AddUint64(&c.seq, 1)
+}
+
+func (c *realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// WithClock sets the clock of a CollectionFilterState. The clock given with the initial configuration is kept by
+// later updates that give none. The default is RealClock. It has no effect on a single filter pass.
+func WithClock(c Clock) FilterOption {
+	return func(o *filterOptions) {
+		o.clock = c
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"errors"
+	"fmt"
+
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+	"istio.io/istio/pkg/config/validation"
+)
+
+// resolveCompact finds the full schema for a compacted collection. Only collections that can be resolved
+// are compacted, so that they can be restored if they are enabled again.
+var resolveCompact = func(name collection.Name) (collection.Schema, bool) {
+	return schema.MustGet().AllCollections().Find(name.String())
+}
+
+// compactSchema is a lightweight stand-in for a disabled collection. It retains only the identity of the
+// collection and its resource type.
+type compactSchema struct {
+	name     collection.Name
+	resource compactResource
+}
+
+var _ collection.Schema = &compactSchema{}
+
+// compactDisabled returns a compact stand-in for the disabled schema s, if its full schema can be restored.
+func compactDisabled(s collection.Schema) (collection.Schema, bool) {
+	if _, ok := s.(*compactSchema); ok {
+		return s, true
+	}
+	full, ok := resolveCompact(s.Name())
+	if !ok || full.Resource().GroupVersionKind() != s.Resource().GroupVersionKind() {
+		return s, false
+	}
+	r := s.Resource()
+	return &compactSchema{
+		name: s.Name(),
+		resource: compactResource{
+			gvk:           r.GroupVersionKind(),
+			plural:        r.Plural(),
+			protoPackage:  r.ProtoPackage(),
+			clusterScoped: r.IsClusterScoped(),
+		},
+	}, true
+}
+
+// ExpandCompact returns the full schema for s if it is a compact stand-in created by WithCompactDisabled. The
+// returned schema is enabled; other schemas are returned unchanged.
+func ExpandCompact(s collection.Schema) (collection.Schema, bool) {
+	if _, ok := s.(*compactSchema); !ok {
+		return s, false
+	}
+	full, ok := resolveCompact(s.Name())
+	if !ok {
+		return s, false
+	}
+	return full, true
+}
+
+// IsCompact returns true if s is a compact stand-in for a disabled collection.
+func IsCompact(s collection.Schema) bool {
+	_, ok := s.(*compactSchema)
+	return ok
+}
+
+// expandInput replaces compact stand-ins in in with their full schemas, so that they can be enabled again.
+func expandInput(in collection.Schemas) collection.Schemas {
+	var b *collection.SchemasBuilder
+	for i, s := range in.All() {
+		full, expanded := ExpandCompact(s)
+		if expanded && b == nil {
+			b = collection.NewSchemasBuilder()
+			for _, prev := range in.All()[:i] {
+				b.MustAdd(prev)
+			}
+		}
+		if b != nil {
+			b.MustAdd(full)
+		}
+	}
+	if b == nil {
+		return in
+	}
+	return b.Build()
+}
+
+func (c *compactSchema) String() string {
+	return fmt.Sprintf("[Schema](%s, compact)", c.name)
+}
+
+func (c *compactSchema) Name() collection.Name {
+	return c.name
+}
+
+func (c *compactSchema) VariableName() string {
+	return ""
+}
+
+func (c *compactSchema) Resource() resource.Schema {
+	return &c.resource
+}
+
+func (c *compactSchema) IsDisabled() bool {
+	return true
+}
+
+func (c *compactSchema) Disable() collection.Schema {
+	return c
+}
+
+func (c *compactSchema) Equal(o collection.Schema) bool {
+	return c.name == o.Name() && o.IsDisabled() && c.resource.Equal(o.Resource())
+}
+
+var errCompact = errors.New("resource schema of a compacted collection is not available")
+
+// compactResource implements resource.Schema with only identifying information. Operations that need the
+// full schema fail.
+type compactResource struct {
+	gvk           config.GroupVersionKind
+	plural        string
+	protoPackage  string
+	clusterScoped bool
+}
+
+var _ resource.Schema = &compactResource{}
+
+func (r *compactResource) String() string {
+	return fmt.Sprintf("[Schema](%s, compact)", r.gvk)
+}
+
+func (r *compactResource) GroupVersionKind() config.GroupVersionKind {
+	return r.gvk
+}
+
+func (r *compactResource) GroupVersionResource() k8sschema.GroupVersionResource {
+	return k8sschema.GroupVersionResource{Group: r.gvk.Group, Version: r.gvk.Version, Resource: r.plural}
+}
+
+func (r *compactResource) IsClusterScoped() bool {
+	return r.clusterScoped
+}
+
+func (r *compactResource) Kind() string {
+	return r.gvk.Kind
+}
+
+func (r *compactResource) Plural() string {
+	return r.plural
+}
+
+func (r *compactResource) Group() string {
+	return r.gvk.Group
+}
+
+func (r *compactResource) Version() string {
+	return r.gvk.Version
+}
+
+func (r *compactResource) APIVersion() string {
+	return r.gvk.Group + "/" + r.gvk.Version
+}
+
+func (r *compactResource) Proto() string {
+	return ""
+}
+
+func (r *compactResource) ProtoPackage() string {
+	return r.protoPackage
+}
+
+func (r *compactResource) NewInstance() (config.Spec, error) {
+	return nil, errCompact
+}
+
+func (r *compactResource) Status() (config.Status, error) {
+	return nil, errCompact
+}
+
+func (r *compactResource) StatusKind() string {
+	return ""
+}
+
+func (r *compactResource) StatusPackage() string {
+	return ""
+}
+
+func (r *compactResource) MustNewInstance() config.Spec {
+	panic(errCompact)
+}
+
+func (r *compactResource) Validate() error {
+	return nil
+}
+
+func (r *compactResource) ValidateConfig(config.Config) (validation.Warning, error) {
+	return nil, errCompact
+}
+
+func (r *compactResource) Equal(o resource.Schema) bool {
+	return r.gvk == o.GroupVersionKind() && r.plural == o.Plural() && r.clusterScoped == o.IsClusterScoped()
+}
+
+// inputSchemas returns the schemas r was computed from. A result with compacted collections does not keep its
+// input; it is restored from Schemas, with each collection expanded and disabled as it was in the input.
+func (r *FilterResult) inputSchemas() collection.Schemas {
+	if !r.inputCompacted {
+		return r.input
+	}
+	b := collection.NewSchemasBuilder()
+	for i, s := range r.Schemas.All() {
+		s, _ = ExpandCompact(s)
+		inputDisabled := r.Report.Entries[i].InputDisabled
+		switch {
+		case inputDisabled && !s.IsDisabled():
+			s = s.Disable()
+		case !inputDisabled && s.IsDisabled():
+			s = enabledCopy(s)
+		}
+		b.MustAdd(s)
+	}
+	return b.Build()
+}
+
+// compactInput returns in with each collection that was compacted in out replaced by its compact form, and each
+// previously compacted collection that is no longer compacted in out restored to its full form.
+func compactInput(in, out collection.Schemas) collection.Schemas {
+	b := collection.NewSchemasBuilder()
+	for _, s := range in.All() {
+		if o, ok := out.Find(s.Name().String()); ok && (IsCompact(o) || IsCompact(s)) {
+			s = o
+		}
+		b.MustAdd(s)
+	}
+	return b.Build()
+}
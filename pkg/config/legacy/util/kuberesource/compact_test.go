@@ -0,0 +1,232 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// withCompactResolver overrides the resolver used to restore compacted collections for the duration of a test.
+func withCompactResolver(t testing.TB, resolve func(collection.Name) (collection.Schema, bool)) {
+	old := resolveCompact
+	resolveCompact = resolve
+	t.Cleanup(func() {
+		resolveCompact = old
+	})
+}
+
+func TestWithCompactDisabled(t *testing.T) {
+	g := NewWithT(t)
+
+	global := collection.SchemasFor(testNode, testDeployment)
+	withCompactResolver(t, func(name collection.Name) (collection.Schema, bool) {
+		return global.Find(name.String())
+	})
+
+	in := collection.SchemasFor(testService, testNode, testDeployment, testSecret)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindNode, KindDeployment, KindSecret), WithCompactDisabled())
+	g.Expect(err).To(BeNil())
+
+	node := result.Schemas.MustFind(testNode.Name().String())
+	g.Expect(IsCompact(node)).To(BeTrue())
+	g.Expect(node.IsDisabled()).To(BeTrue())
+	g.Expect(node.Resource().GroupVersionKind()).To(Equal(testNode.Resource().GroupVersionKind()))
+	g.Expect(node.Resource().Plural()).To(Equal("nodes"))
+	_, err = node.Resource().NewInstance()
+	g.Expect(err).NotTo(BeNil())
+
+	full, ok := ExpandCompact(node)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(full).To(BeIdenticalTo(testNode))
+
+	// Secret is not part of the global set, so it cannot be restored and is not compacted.
+	secret := result.Schemas.MustFind(testSecret.Name().String())
+	g.Expect(IsCompact(secret)).To(BeFalse())
+	g.Expect(secret.IsDisabled()).To(BeTrue())
+
+	g.Expect(result.Schemas.MustFind(testService.Name().String())).To(BeIdenticalTo(testService))
+	g.Expect(result.Schemas.DisabledCollectionNames()).To(ConsistOf(testNode.Name(), testDeployment.Name(), testSecret.Name()))
+}
+
+func TestWithCompactDisabled_StateReenables(t *testing.T) {
+	g := NewWithT(t)
+
+	global := collection.SchemasFor(testNode, testDeployment)
+	withCompactResolver(t, func(name collection.Name) (collection.Schema, bool) {
+		return global.Find(name.String())
+	})
+
+	in := collection.SchemasFor(testService, testNode, testDeployment)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindNode, KindDeployment), WithCompactDisabled())
+	g.Expect(err).To(BeNil())
+	g.Expect(IsCompact(state.in.MustFind(testNode.Name().String()))).To(BeTrue())
+
+	result, err := state.Update(WithExcludedResourceKinds(KindDeployment), WithCompactDisabled())
+	g.Expect(err).To(BeNil())
+	node := result.Schemas.MustFind(testNode.Name().String())
+	g.Expect(IsCompact(node)).To(BeFalse())
+	g.Expect(node.IsDisabled()).To(BeFalse())
+	g.Expect(node).To(BeIdenticalTo(testNode))
+	g.Expect(IsCompact(result.Schemas.MustFind(testDeployment.Name().String()))).To(BeTrue())
+	g.Expect(state.in.MustFind(testNode.Name().String())).To(BeIdenticalTo(testNode))
+}
+
+func TestWithCompactDisabled_ApplyDelta(t *testing.T) {
+	g := NewWithT(t)
+
+	global := collection.SchemasFor(testNode, testDeployment, testPod)
+	withCompactResolver(t, func(name collection.Name) (collection.Schema, bool) {
+		return global.Find(name.String())
+	})
+
+	// Pod is disabled in the input, and stays disabled once it is no longer excluded.
+	in := collection.SchemasFor(testService, testNode, testDeployment, testPod.Disable())
+	opts := []FilterOption{WithCompactDisabled()}
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts, WithExcludedResourceKinds(KindNode, KindDeployment, KindPod))...)
+	prev, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+	g.Expect(prev.inputCompacted).To(BeTrue())
+	g.Expect(prev.inputSchemas().All()).To(HaveLen(len(in.All())))
+	for i, s := range prev.inputSchemas().All() {
+		g.Expect(s.Name()).To(Equal(in.All()[i].Name()))
+		g.Expect(IsCompact(s)).To(BeFalse())
+		g.Expect(s.IsDisabled()).To(Equal(in.All()[i].IsDisabled()), s.Name().String())
+	}
+
+	actual, err := f.ApplyDelta(prev, ConfigDelta{RemovedExclusions: []string{KindNode, KindPod}})
+	g.Expect(err).To(BeNil())
+	g.Expect(actual.Schemas.MustFind(testNode.Name().String())).To(BeIdenticalTo(testNode))
+	expected, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts, WithExcludedResourceKinds(KindDeployment))...)
+	g.Expect(err).To(BeNil())
+	expectSameResult(g, actual, expected)
+	g.Expect(reasonOf(actual, testPod.Name())).To(Equal(ReasonAlreadyDisabled))
+}
+
+// TestWithCompactDisabled_Retained guards the memory saving of compaction: a result must not keep the full
+// schemas it compacted, through its input, its index or otherwise.
+func TestWithCompactDisabled_Retained(t *testing.T) {
+	const size = 500
+	byName := make(map[collection.Name]int, size)
+	for i := 0; i < size; i++ {
+		byName[syntheticSchema(i).Name()] = i
+	}
+	withCompactResolver(t, func(name collection.Name) (collection.Schema, bool) {
+		i, ok := byName[name]
+		if !ok {
+			return nil, false
+		}
+		return syntheticSchema(i), true
+	})
+
+	retained := func(opts ...FilterOption) uint64 {
+		result := filterSynthetic(size, opts)
+		return heapRetainedBy(func() {
+			runtime.KeepAlive(result)
+			result = nil
+		})
+	}
+	full, compact := retained(), retained(WithCompactDisabled())
+	// The payload of the synthetic schemas alone is over 2MB; compaction must drop most of it.
+	if compact*4 > full {
+		t.Fatalf("result with compaction retains %d bytes, want less than a quarter of the %d bytes without", compact, full)
+	}
+}
+
+// syntheticSchema builds a schema whose resource carries a large payload, standing in for proto descriptors.
+func syntheticSchema(i int) collection.Schema {
+	kind := fmt.Sprintf("Kind%d", i)
+	return collection.Builder{
+		Name: fmt.Sprintf("k8s/vendor%d.example.com/v1/%ss", i, strings.ToLower(kind)),
+		Resource: resource.Builder{
+			Group:        fmt.Sprintf("vendor%d.example.com", i),
+			Version:      "v1",
+			Kind:         kind,
+			Plural:       strings.ToLower(kind) + "s",
+			Proto:        fmt.Sprintf("example.vendor%d.%s", i, strings.Repeat("x", 4096)),
+			ProtoPackage: "example.com/vendor",
+		}.BuildNoValidate(),
+	}.MustBuild()
+}
+
+// BenchmarkCompactDisabled reports the heap retained by a filter result over a large schema set in which every
+// CRD-backed collection is disabled, with and without compaction.
+func BenchmarkCompactDisabled(b *testing.B) {
+	const size = 2000
+	byName := make(map[collection.Name]int, size)
+	for i := 0; i < size; i++ {
+		byName[syntheticSchema(i).Name()] = i
+	}
+	withCompactResolver(b, func(name collection.Name) (collection.Schema, bool) {
+		i, ok := byName[name]
+		if !ok {
+			return nil, false
+		}
+		return syntheticSchema(i), true
+	})
+
+	for _, compact := range []bool{false, true} {
+		b.Run(fmt.Sprintf("compact=%v", compact), func(b *testing.B) {
+			var opts []FilterOption
+			if compact {
+				opts = append(opts, WithCompactDisabled())
+			}
+			var retained uint64
+			for n := 0; n < b.N; n++ {
+				result := filterSynthetic(size, opts)
+				retained += heapRetainedBy(func() {
+					runtime.KeepAlive(result)
+					result = nil
+				})
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
+
+func filterSynthetic(size int, opts []FilterOption) *FilterResult {
+	builder := collection.NewSchemasBuilder()
+	for i := 0; i < size; i++ {
+		builder.MustAdd(syntheticSchema(i))
+	}
+	result, _ := FilterCollections(builder.Build(), kuberesourcetest.ScriptedProviders{}, nil, opts...)
+	return result
+}
+
+// heapRetainedBy measures how much heap is freed by calling release.
+func heapRetainedBy(release func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	release()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	if after.HeapAlloc > before.HeapAlloc {
+		return 0
+	}
+	return before.HeapAlloc - after.HeapAlloc
+}
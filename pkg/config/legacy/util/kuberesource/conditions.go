@@ -0,0 +1,144 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Condition types produced by ReportToConditions.
+const (
+	ConditionCollectionsFiltered      = "CollectionsFiltered"
+	ConditionDiscoveryKindsExcluded   = "DiscoveryKindsExcluded"
+	ConditionUnknownExclusionEntries  = "UnknownExclusionEntries"
+	ConditionAvailabilityUndetermined = "AvailabilityUndetermined"
+	ConditionDegraded                 = "Degraded"
+)
+
+// Condition statuses, as used by Kubernetes conditions.
+const (
+	ConditionTrue  = "True"
+	ConditionFalse = "False"
+)
+
+// maxConditionItems is the number of items listed in a condition message before the rest are counted.
+const maxConditionItems = 5
+
+// StatusCondition is a Kubernetes condition-style summary of a filter outcome, suitable for publishing on the
+// status of a resource such as IstioOperator.
+type StatusCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// ReportToConditions summarizes report and warnings as conditions. Every condition type is always present, in
+// the same order, and messages list items in sorted order, truncated after a few items with a count of the
+// rest, so that publishing the conditions of an unchanged outcome does not churn the status.
+func ReportToConditions(report *FilterReport, warnings []FilterWarning) []StatusCondition {
+	var entries []ReportEntry
+	if report != nil {
+		entries = report.Entries
+	}
+
+	disabled := 0
+	discoveryKinds := make(map[string]struct{})
+	undetermined := make(map[string]struct{})
+	for _, e := range entries {
+		if e.Reason == ReasonUndetermined {
+			undetermined[e.Collection.String()] = struct{}{}
+		}
+		if !e.Disabled {
+			continue
+		}
+		disabled++
+		if isExcludedKind(e.Decision) && isServiceDiscoveryType(e.Group, e.Version, e.Kind) {
+			discoveryKinds[DisplayGroupKind(e.Group, e.Kind)] = struct{}{}
+		}
+	}
+	entriesWarned := make(map[string]struct{})
+	degraded := make(map[string]struct{})
+	for _, w := range warnings {
+		if w.Code == WarningNamespaceDisabled {
+			for _, d := range namespaceDependents {
+				degraded[d.Feature] = struct{}{}
+			}
+			continue
+		}
+		if w.Entry != "" {
+			entriesWarned[w.Entry] = struct{}{}
+		}
+	}
+
+	filtered := StatusCondition{
+		Type:    ConditionCollectionsFiltered,
+		Status:  ConditionFalse,
+		Reason:  "AllCollectionsEnabled",
+		Message: fmt.Sprintf("all %d collections are enabled", len(entries)),
+	}
+	if disabled > 0 {
+		filtered.Status, filtered.Reason = ConditionTrue, "CollectionsDisabled"
+		filtered.Message = fmt.Sprintf("%d of %d collections are disabled", disabled, len(entries))
+	}
+	return []StatusCondition{
+		filtered,
+		listCondition(ConditionDiscoveryKindsExcluded, "DiscoveryKindsExcluded", "NoDiscoveryKindsExcluded",
+			"kinds required for service discovery are excluded", discoveryKinds),
+		listCondition(ConditionUnknownExclusionEntries, "IneffectiveEntries", "AllEntriesEffective",
+			"exclusion entries have no effect", entriesWarned),
+		listCondition(ConditionAvailabilityUndetermined, "AvailabilityUndetermined", "AvailabilityDetermined",
+			"collections have undetermined availability", undetermined),
+		listCondition(ConditionDegraded, "NamespaceMetadataUnavailable", "NotDegraded",
+			"features that read namespace metadata will not work, as namespaces are not watched", degraded),
+	}
+}
+
+// isExcludedKind returns true if d disabled the collection because its kind is excluded, primarily or not.
+func isExcludedKind(d Decision) bool {
+	if d.Reason == ReasonExcludedKind {
+		return true
+	}
+	for _, r := range d.SecondaryReasons {
+		if r == ReasonExcludedKind {
+			return true
+		}
+	}
+	return false
+}
+
+// listCondition returns a condition that is true if items is not empty, with a message listing them.
+func listCondition(conditionType, trueReason, falseReason, what string, items map[string]struct{}) StatusCondition {
+	if len(items) == 0 {
+		return StatusCondition{Type: conditionType, Status: ConditionFalse, Reason: falseReason}
+	}
+	sorted := make([]string, 0, len(items))
+	for i := range items {
+		sorted = append(sorted, i)
+	}
+	sort.Strings(sorted)
+	list := strings.Join(sorted, ", ")
+	if len(sorted) > maxConditionItems {
+		list = fmt.Sprintf("%s and %d more", strings.Join(sorted[:maxConditionItems], ", "), len(sorted)-maxConditionItems)
+	}
+	return StatusCondition{
+		Type:    conditionType,
+		Status:  ConditionTrue,
+		Reason:  trueReason,
+		Message: fmt.Sprintf("%d %s: %s", len(sorted), what, list),
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestReportToConditions(t *testing.T) {
+	kube := schema.MustGet().KubeCollections()
+	synthetic := collection.SchemasFor(testConfigMap, testKubeGateway, testGateway, testMeshConfig, testService)
+	failingProbe := func(gvk config.GroupVersionKind) (bool, error) {
+		return false, errTransient
+	}
+
+	cases := []struct {
+		name      string
+		in        collection.Schemas
+		providers InputProviders
+		opts      []FilterOption
+		golden    string
+	}{
+		{
+			name:   "no filtering",
+			in:     testSchemas(),
+			golden: "testdata/conditions_none.golden",
+		},
+		{
+			name: "discovery kinds excluded",
+			in:   kube,
+			opts: []FilterOption{
+				WithExcludedResourceKinds(append(DefaultExcludedResourceKinds(), "networking.istio.io/*")...),
+			},
+			golden: "testdata/conditions_excluded.golden",
+		},
+		{
+			name:      "synthesized entries",
+			in:        synthetic,
+			providers: testProviderGraph(),
+			opts:      []FilterOption{WithExcludedResourceKinds("collection:"+testGateway.Name().String(), "MeshConfig")},
+			golden:    "testdata/conditions_synthesized.golden",
+		},
+		{
+			name: "namespace disabled",
+			in:   kube,
+			opts: []FilterOption{
+				WithExcludedResourceKinds(DefaultExcludedResourceKinds()...),
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+				WithDiscoveryOverrideOnly(KindService, KindSecret, KindPod, KindNode),
+			},
+			golden: "testdata/conditions_degraded.golden",
+		},
+		{
+			name:   "truncated",
+			in:     kube,
+			opts:   []FilterOption{WithAvailabilityProbe(failingProbe), WithProbeFailurePolicy(FailClosed)},
+			golden: "testdata/conditions_truncated.golden",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			providers := c.providers
+			if providers == nil {
+				providers = kuberesourcetest.ScriptedProviders{}
+			}
+			result, err := FilterCollections(c.in, providers, c.in.CollectionNames(), c.opts...)
+			g.Expect(err).To(BeNil())
+			conditions := ReportToConditions(result.Report, result.Warnings)
+			out, err := json.MarshalIndent(conditions, "", "  ")
+			g.Expect(err).To(BeNil())
+			testutil.CompareContent(append(out, '\n'), c.golden, t)
+
+			// Conditions are deterministic.
+			g.Expect(ReportToConditions(result.Report, result.Warnings)).To(Equal(conditions))
+		})
+	}
+
+	// A missing report has every condition false.
+	g := NewWithT(t)
+	for _, c := range ReportToConditions(nil, nil) {
+		g.Expect(c.Status).To(Equal(ConditionFalse), c.Type)
+	}
+}
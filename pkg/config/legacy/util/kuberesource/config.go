@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// FilterConfig is a declarative filter configuration that can be applied to different schema sets.
+type FilterConfig struct {
+	// RequiredCollections are the output collections that must be produced. If empty, every input collection
+	// is treated as required, so no collection is disabled for not being upstream of a required collection.
+	RequiredCollections collection.Names `json:"requiredCollections,omitempty"`
+
+	ExcludedResourceKinds []string            `json:"excludedResourceKinds,omitempty"`
+	Features              FeatureRequirements `json:"features"`
+
+	// Selectors are the selector hints to attach to enabled collections, keyed by kind; see WithSelectorHint.
+	Selectors map[string]SelectorHint `json:"selectors,omitempty"`
+
+	// KubeVersionGates are the Kubernetes versions exclusion entries apply to, keyed by entry as written; see
+	// WithEntryKubeVersions.
+	KubeVersionGates map[string]KubeVersionRange `json:"kubeVersionGates,omitempty"`
+
+	// Positions are the positions of the entries in the file the configuration was loaded from, if any.
+	Positions ConfigPositions `json:"-"`
+}
+
+// Options returns the FilterOptions equivalent to c.
+func (c FilterConfig) Options() []FilterOption {
+	opts := []FilterOption{
+		WithExcludedResourceKinds(c.ExcludedResourceKinds...),
+		WithFeatureRequirements(c.Features),
+	}
+	for kind, hint := range c.Selectors {
+		opts = append(opts, WithSelectorHint(kind, hint))
+	}
+	for entry, r := range c.KubeVersionGates {
+		opts = append(opts, WithEntryKubeVersions(entry, r))
+	}
+	if c.Positions.ExcludedResourceKinds != nil || c.Positions.KubeVersionGates != nil {
+		opts = append(opts, WithConfigPositions(c.Positions))
+	}
+	return opts
+}
+
+// Apply filters in according to c, using providers to resolve the required collections.
+func (c FilterConfig) Apply(in collection.Schemas, providers InputProviders) (*FilterResult, error) {
+	required := c.RequiredCollections
+	if len(required) == 0 {
+		required = in.CollectionNames()
+	}
+	return FilterCollections(in, providers, required, c.Options()...)
+}
@@ -0,0 +1,168 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ConflictPolicy decides what happens when an exclusion entry and a negated entry from different sources match
+// the same collections.
+type ConflictPolicy int
+
+const (
+	// ConflictWarn reports a WarningConflictingEntries warning.
+	ConflictWarn ConflictPolicy = iota
+
+	// ConflictStrict makes Apply fail.
+	ConflictStrict
+)
+
+// WithConflictPolicy sets whether an exclusion entry and a negated entry from different sources that match the
+// same collections are reported as a warning (ConflictWarn, the default) or make Apply fail (ConflictStrict).
+// Entries from the same source are not checked, since negating part of an exclusion is deliberate there.
+func WithConflictPolicy(p ConflictPolicy) FilterOption {
+	return func(o *filterOptions) {
+		o.conflictPolicy = p
+	}
+}
+
+// EntryConflict is an exclusion entry and a negated entry, configured in different sources, that both match the
+// same collections once normalized: after collection aliases are resolved, the core group is normalized and
+// globs are expanded against the schema set.
+type EntryConflict struct {
+	Excluded       string          `json:"excluded"`
+	ExcludedSource ExclusionSource `json:"excludedSource"`
+	Negated        string          `json:"negated"`
+	NegatedSource  ExclusionSource `json:"negatedSource"`
+
+	// Collections are the collections matched by both entries, in name order.
+	Collections collection.Names `json:"collections"`
+
+	// NegatedWins is true if the negated entry is evaluated after the exclusion entry, so that it decides for
+	// the collections unless a later entry matches them too. Entries are ordered by source precedence first.
+	NegatedWins bool `json:"negatedWins"`
+
+	// ExcludedPosition and NegatedPosition are the positions of the entries in the file they were loaded from,
+	// if they are known.
+	ExcludedPosition *Position `json:"excludedPosition,omitempty"`
+	NegatedPosition  *Position `json:"negatedPosition,omitempty"`
+}
+
+func (c EntryConflict) String() string {
+	winner, winnerSource := c.Excluded, c.ExcludedSource
+	if c.NegatedWins {
+		winner, winnerSource = c.Negated, c.NegatedSource
+	}
+	return fmt.Sprintf("entry %s from %s excludes %v, which entry %s from %s re-includes; %s from %s wins",
+		c.Excluded, c.ExcludedSource, c.Collections, c.Negated, c.NegatedSource, winner, winnerSource)
+}
+
+// entryConflicts returns the conflicts between the exclusion entries of o over the collections of idx, ordered by
+// the position of the exclusion entry and then of the negated entry.
+func (o *filterOptions) entryConflicts(idx *KindIndex) []EntryConflict {
+	m := compileExclusions(o.excludedResourceKinds)
+	if len(m.exclusions) < 2 {
+		return nil
+	}
+	entryMatches := make(map[collection.Name][]int)
+	for i, e := range m.exclusions {
+		single := &ExclusionMatcher{exclusions: []Exclusion{e}}
+		for _, s := range idx.candidates(e) {
+			if res := s.Resource(); single.decisive(s.Name(), res.Group(), res.Version(), res.Kind()) >= 0 {
+				entryMatches[s.Name()] = append(entryMatches[s.Name()], i)
+			}
+		}
+	}
+	type pair struct{ excluded, negated int }
+	matched := make(map[pair]collection.Names)
+	for _, s := range idx.schemas.All() {
+		matches := entryMatches[s.Name()]
+		for _, i := range matches {
+			for _, j := range matches {
+				a, b := m.exclusions[i], m.exclusions[j]
+				if a.Negated || !b.Negated || o.exclusionSources[i] == o.exclusionSources[j] {
+					continue
+				}
+				p := pair{excluded: i, negated: j}
+				matched[p] = append(matched[p], s.Name())
+			}
+		}
+	}
+
+	pairs := make([]pair, 0, len(matched))
+	for p := range matched {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a].excluded != pairs[b].excluded {
+			return pairs[a].excluded < pairs[b].excluded
+		}
+		return pairs[a].negated < pairs[b].negated
+	})
+	out := make([]EntryConflict, 0, len(pairs))
+	for _, p := range pairs {
+		names := matched[p]
+		names.Sort()
+		out = append(out, EntryConflict{
+			Excluded:       o.excludedResourceKinds[p.excluded],
+			ExcludedSource: o.exclusionSources[p.excluded],
+			Negated:        o.excludedResourceKinds[p.negated],
+			NegatedSource:  o.exclusionSources[p.negated],
+			Collections:    names,
+			NegatedWins:    p.negated > p.excluded,
+
+			ExcludedPosition: o.positions.entryPosition(o.excludedResourceKinds[p.excluded]),
+			NegatedPosition:  o.positions.entryPosition(o.excludedResourceKinds[p.negated]),
+		})
+	}
+	return out
+}
+
+// conflictWarnings returns a WarningConflictingEntries warning for every conflict.
+func conflictWarnings(conflicts []EntryConflict) []FilterWarning {
+	out := make([]FilterWarning, 0, len(conflicts))
+	for _, c := range conflicts {
+		out = append(out, FilterWarning{
+			Code:    WarningConflictingEntries,
+			Entry:   c.Negated,
+			Message: c.String(),
+		})
+	}
+	return out
+}
+
+// conflictErrors returns an error for every conflict, starting with the positions of its entries that are known.
+func conflictErrors(conflicts []EntryConflict) []error {
+	out := make([]error, 0, len(conflicts))
+	for _, c := range conflicts {
+		var positions []string
+		for _, pos := range []*Position{c.ExcludedPosition, c.NegatedPosition} {
+			if pos != nil {
+				positions = append(positions, pos.String())
+			}
+		}
+		if len(positions) > 0 {
+			out = append(out, fmt.Errorf("%s: conflicting exclusion entries: %s", strings.Join(positions, ", "), c))
+			continue
+		}
+		out = append(out, fmt.Errorf("conflicting exclusion entries: %s", c))
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var testGatewayAPIGatewayV1alpha2 = newNamedTestSchema("k8s/gateway_api/v1alpha2/gateways",
+	"gateway.networking.k8s.io", "v1alpha2", "Gateway")
+
+func TestEntryConflicts(t *testing.T) {
+	in := collection.SchemasFor(testService, testPod, testVirtualService, testKubeGateway, testGatewayAPIGatewayV1alpha2)
+	cases := []struct {
+		name      string
+		opts      []FilterOption
+		conflicts []EntryConflict
+	}{
+		{
+			name: "alias",
+			opts: []FilterOption{
+				WithExclusionsFrom(SourceMeshConfig, "collection:k8s/service_apis/v1alpha1/gateways"),
+				WithExclusionsFrom(SourceFlag, "!gateway.networking.k8s.io/Gateway"),
+			},
+			conflicts: []EntryConflict{{
+				Excluded:       "collection:k8s/service_apis/v1alpha1/gateways",
+				ExcludedSource: SourceMeshConfig,
+				Negated:        "!gateway.networking.k8s.io/Gateway",
+				NegatedSource:  SourceFlag,
+				Collections:    collection.Names{testGatewayAPIGatewayV1alpha2.Name()},
+				NegatedWins:    true,
+			}},
+		},
+		{
+			name: "group expansion",
+			opts: []FilterOption{
+				WithExclusionsFrom(SourceFlag, "networking.istio.io/*", "core/Pod"),
+				WithExclusionsFrom(SourceFile, "!VirtualService", "!*/v1/Pod"),
+			},
+			conflicts: []EntryConflict{
+				{
+					Excluded:       "networking.istio.io/*",
+					ExcludedSource: SourceFlag,
+					Negated:        "!VirtualService",
+					NegatedSource:  SourceFile,
+					Collections:    collection.Names{testVirtualService.Name()},
+				},
+				{
+					Excluded:       "core/Pod",
+					ExcludedSource: SourceFlag,
+					Negated:        "!*/v1/Pod",
+					NegatedSource:  SourceFile,
+					Collections:    collection.Names{testPod.Name()},
+				},
+			},
+		},
+		{
+			name: "same source",
+			opts: []FilterOption{WithExcludedResourceKinds("networking.istio.io/*", "!Gateway")},
+		},
+		{
+			name: "disjoint",
+			opts: []FilterOption{
+				WithExclusionsFrom(SourceMeshConfig, "Pod"),
+				WithExclusionsFrom(SourceFlag, "!Service"),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			conflicts := newFilterOptions(c.opts).entryConflicts(NewKindIndex(in))
+			if len(c.conflicts) == 0 {
+				g.Expect(conflicts).To(BeEmpty())
+				return
+			}
+			g.Expect(conflicts).To(Equal(c.conflicts))
+		})
+	}
+}
+
+func TestConflictPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod)
+	opts := []FilterOption{
+		WithExclusionsFrom(SourceMeshConfig, "!Pod"),
+		WithExclusionsFrom(SourceFlag, "core/*"),
+	}
+	const message = "entry core/* from Flag excludes [k8s/core/v1/pods], which entry !Pod from MeshConfig " +
+		"re-includes; core/* from Flag wins"
+
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Warnings).To(ConsistOf(FilterWarning{Code: WarningConflictingEntries, Entry: "!Pod", Message: message}))
+
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts, WithConflictPolicy(ConflictStrict))...)
+	g.Expect(err).To(MatchError(ContainSubstring("conflicting exclusion entries: " + message)))
+
+	errs := ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{}, opts...)
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0]).To(MatchError("conflicting exclusion entries: " + message))
+}
@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ExcludedCRDGroupKinds returns the CRD-backed kinds in result that have no enabled version, sorted by group and
+// kind. The CRD watch bootstrapper must not wait for the CRDs of these kinds to appear. Builtin kinds are never
+// listed, since they are not served through CRDs.
+func ExcludedCRDGroupKinds(result *FilterResult) []schema.GroupKind {
+	excluded, _ := crdGroupKinds(result)
+	return sortedGroupKinds(excluded)
+}
+
+// IncludedCRDGroupKinds returns the CRD-backed kinds that have no enabled version in prev but do in next, sorted by
+// group and kind. On a dynamic update, the CRD watch bootstrapper must start waiting for the CRDs of these kinds.
+// The kinds that must no longer be waited for are the ones in ExcludedCRDGroupKinds(next).
+func IncludedCRDGroupKinds(prev, next *FilterResult) []schema.GroupKind {
+	wasExcluded, _ := crdGroupKinds(prev)
+	_, enabled := crdGroupKinds(next)
+	included := make(map[string]struct{})
+	for k := range wasExcluded {
+		if _, ok := enabled[k]; ok {
+			included[k] = struct{}{}
+		}
+	}
+	return sortedGroupKinds(included)
+}
+
+// crdGroupKinds returns the keys of the CRD-backed kinds in result without an enabled version, and of those with
+// one.
+func crdGroupKinds(result *FilterResult) (excluded, enabled map[string]struct{}) {
+	excluded = make(map[string]struct{})
+	enabled = make(map[string]struct{})
+	for _, s := range result.Schemas.All() {
+		res := s.Resource()
+		if isBuiltin(res) {
+			continue
+		}
+		key := TypeKey(res.Group(), res.Kind())
+		if s.IsDisabled() {
+			excluded[key] = struct{}{}
+		} else {
+			enabled[key] = struct{}{}
+		}
+	}
+	for k := range enabled {
+		delete(excluded, k)
+	}
+	return excluded, enabled
+}
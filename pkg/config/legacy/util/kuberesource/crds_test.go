@@ -0,0 +1,74 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+)
+
+// expectedExcludedCRDs derives the excluded CRD kinds from the report, independently of ExcludedCRDGroupKinds.
+func expectedExcludedCRDs(result *FilterResult) []k8sschema.GroupKind {
+	enabled := make(map[k8sschema.GroupKind]bool)
+	for _, e := range result.Report.Entries {
+		s := result.Schemas.MustFind(e.Collection.String())
+		if isBuiltin(s.Resource()) {
+			continue
+		}
+		gk := k8sschema.GroupKind{Group: e.Group, Kind: e.Kind}
+		enabled[gk] = enabled[gk] || !e.Disabled
+	}
+	var out []k8sschema.GroupKind
+	for gk, on := range enabled {
+		if !on {
+			out = append(out, gk)
+		}
+	}
+	return out
+}
+
+func TestExcludedCRDGroupKinds(t *testing.T) {
+	g := NewWithT(t)
+
+	in := schema.MustGet().KubeCollections()
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("networking.istio.io/*", KindPod, "!networking.istio.io/v1alpha3/Gateway"))
+	prev, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+
+	excluded := ExcludedCRDGroupKinds(prev)
+	g.Expect(excluded).To(ConsistOf(expectedExcludedCRDs(prev)))
+	g.Expect(excluded).To(ContainElement(k8sschema.GroupKind{Group: "networking.istio.io", Kind: "VirtualService"}))
+	// Gateway keeps an enabled version, and Pod is builtin.
+	g.Expect(excluded).NotTo(ContainElement(k8sschema.GroupKind{Group: "networking.istio.io", Kind: "Gateway"}))
+	g.Expect(excluded).NotTo(ContainElement(k8sschema.GroupKind{Kind: KindPod}))
+
+	next, err := f.ApplyDelta(prev, ConfigDelta{
+		AddedExclusions:   []string{"security.istio.io/*"},
+		RemovedExclusions: []string{"networking.istio.io/*"},
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(ExcludedCRDGroupKinds(next)).To(ConsistOf(expectedExcludedCRDs(next)))
+	g.Expect(ExcludedCRDGroupKinds(next)).To(ContainElement(k8sschema.GroupKind{Group: "security.istio.io", Kind: "AuthorizationPolicy"}))
+
+	included := IncludedCRDGroupKinds(prev, next)
+	g.Expect(included).To(ConsistOf(excluded))
+	g.Expect(IncludedCRDGroupKinds(next, next)).To(BeEmpty())
+}
@@ -0,0 +1,82 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SchemaStatus is the debug representation of a collection: its report entry, and the source of the exclusion
+// entry that decided it, which tells default exclusions from those configured by the user.
+type SchemaStatus struct {
+	ReportEntry
+	Source ExclusionSource `json:"source,omitempty"`
+}
+
+// SchemasDebug is the JSON document served by SchemasHandler.
+type SchemasDebug struct {
+	Fingerprint string         `json:"fingerprint"`
+	Schemas     []SchemaStatus `json:"schemas"`
+}
+
+// SchemasDebugFor returns the debug representation of every collection of result, in report order.
+func SchemasDebugFor(result *FilterResult) SchemasDebug {
+	out := SchemasDebug{Fingerprint: result.Fingerprint, Schemas: make([]SchemaStatus, 0, len(result.Report.Entries))}
+	for _, e := range result.Report.Entries {
+		out.Schemas = append(out.Schemas, SchemaStatus{ReportEntry: e, Source: result.entrySource(e)})
+	}
+	return out
+}
+
+// SchemasHandler returns the handler of the /debug/schemas endpoint of istiod, which lists every collection
+// schema of the result current returns, whether it is enabled and why, as JSON. With ?format=table it renders the
+// result with RenderTable instead, and ?show=enabled or ?show=disabled restricts the table to those collections.
+// current is typically the Current method of a CollectionFilterState; the endpoint responds 503 while it
+// returns nil.
+func SchemasHandler(current func() *FilterResult) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		result := current()
+		if result == nil {
+			http.Error(w, "collection filter has not been applied yet", http.StatusServiceUnavailable)
+			return
+		}
+		query := req.URL.Query()
+		if query.Get("format") == "table" {
+			opts := TableOptions{}
+			switch show := query.Get("show"); show {
+			case "":
+			case "enabled":
+				opts.Rows = TableEnabled
+			case "disabled":
+				opts.Rows = TableDisabled
+			default:
+				http.Error(w, fmt.Sprintf("unknown show %q: expected enabled or disabled", show), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte(RenderTable(result, opts)))
+			return
+		}
+		b, err := json.MarshalIndent(SchemasDebugFor(result), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestSchemasHandler(t *testing.T) {
+	in := collection.SchemasFor(testService, testPod, testSecret, testDeployment, testVirtualService, testAuthzPolicy)
+	required := collection.Names{testService.Name(), testPod.Name(), testSecret.Name(), testDeployment.Name(),
+		testVirtualService.Name()}
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, required,
+		WithExclusionsFrom(SourceDefault, DefaultExcludedResourceKinds()...),
+		WithExclusionsFrom(SourceMeshConfig, "networking.istio.io/*"),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := SchemasHandler(state.Current)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("json", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := serve("/debug/schemas")
+		g.Expect(rec.Code).To(Equal(http.StatusOK))
+		g.Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		// The fingerprint varies with the schemas, so it is not part of the golden output.
+		body := strings.Replace(rec.Body.String(), state.Current().Fingerprint, "<fingerprint>", 1)
+		testutil.CompareContent([]byte(body), "testdata/debug_schemas.golden", t)
+	})
+
+	t.Run("table", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := serve("/debug/schemas?format=table&show=disabled")
+		g.Expect(rec.Code).To(Equal(http.StatusOK))
+		g.Expect(rec.Body.String()).To(Equal(RenderTable(state.Current(), TableOptions{Rows: TableDisabled})))
+
+		rec = serve("/debug/schemas?format=table&show=bogus")
+		g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	t.Run("not applied", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := httptest.NewRecorder()
+		SchemasHandler(func() *FilterResult { return nil })(rec, httptest.NewRequest(http.MethodGet, "/debug/schemas", nil))
+		g.Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+}
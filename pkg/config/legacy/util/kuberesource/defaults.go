@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// ExclusionCategory groups default exclusions by why they are excluded.
+type ExclusionCategory string
+
+const (
+	// CategoryDiscoveryOnly is used for builtin kinds that are only consumed by service discovery, which
+	// watches them directly rather than through the config pipeline.
+	CategoryDiscoveryOnly ExclusionCategory = "DiscoveryOnly"
+
+	// CategoryHighChurn is used for kinds that change frequently enough that processing them through the
+	// config pipeline would be costly.
+	CategoryHighChurn ExclusionCategory = "HighChurn"
+
+	// CategoryLegacy is used for kinds that are only kept for compatibility with older releases.
+	CategoryLegacy ExclusionCategory = "Legacy"
+)
+
+// DefaultExclusion describes a kind that is excluded by default, and why.
+type DefaultExclusion struct {
+	Group     string            `json:"group"`
+	Kind      string            `json:"kind"`
+	Category  ExclusionCategory `json:"category"`
+	Rationale string            `json:"rationale"`
+}
+
+var defaultExclusions = []DefaultExclusion{
+	{
+		Kind:      KindService,
+		Category:  CategoryDiscoveryOnly,
+		Rationale: "watched directly by service discovery",
+	},
+	{
+		Kind:      KindNamespace,
+		Category:  CategoryDiscoveryOnly,
+		Rationale: "watched directly by service discovery for namespace labels",
+	},
+	{
+		Kind:      KindSecret,
+		Category:  CategoryDiscoveryOnly,
+		Rationale: "read directly by the credential controller; may be large and sensitive",
+	},
+	{
+		Kind:      KindPod,
+		Category:  CategoryHighChurn,
+		Rationale: "changes on every rollout and scale event; watched directly by service discovery",
+	},
+	{
+		Kind:      KindNode,
+		Category:  CategoryHighChurn,
+		Rationale: "status is updated frequently; watched directly by service discovery for locality",
+	},
+}
+
+// DefaultExclusions returns the kinds excluded by default, grouped by category.
+func DefaultExclusions() []DefaultExclusion {
+	return append([]DefaultExclusion{}, defaultExclusions...)
+}
+
+// IsDefaultExcluded returns true if res is one of the default exclusions.
+func IsDefaultExcluded(res resource.Schema) bool {
+	for _, e := range defaultExclusions {
+		if e.Group == res.Group() && e.Kind == res.Kind() {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultExclusionErrors returns an error for every collection in schemas that a default exclusion configured in o
+// excludes, that service discovery requires, and that the discovery override would not re-enable under o: such a
+// default fights the override on every startup. With service discovery disabled, default-excluded kinds staying
+// disabled is intended, so nothing is reported.
+func (o *filterOptions) defaultExclusionErrors(schemas collection.Schemas) []error {
+	if !o.features.ServiceDiscovery {
+		return nil
+	}
+	m := compileExclusions(o.excludedResourceKinds)
+	var errs []error
+	for _, s := range schemas.All() {
+		res := s.Resource()
+		if !IsDefaultExcluded(res) || !o.isDiscoveryKind(res) || !m.MatchesSchema(s) || !o.discoveryOverrideWithheld(res) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("default-excluded kind %s is required for service discovery, but "+
+			"WithDiscoveryOverrideOnly does not re-enable it: %s stays disabled and service discovery sees none of "+
+			"its objects", TypeKey(res.Group(), res.Kind()), s.Name()))
+	}
+	return errs
+}
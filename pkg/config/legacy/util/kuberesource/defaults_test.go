@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestDefaultExclusions(t *testing.T) {
+	for _, e := range DefaultExclusions() {
+		t.Run(TypeKey(e.Group, e.Kind), func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(e.Category).To(BeElementOf(CategoryDiscoveryOnly, CategoryHighChurn, CategoryLegacy))
+			g.Expect(e.Rationale).NotTo(BeEmpty())
+
+			matches := 0
+			for _, s := range schema.MustGet().KubeCollections().All() {
+				if s.Resource().Group() == e.Group && s.Resource().Kind() == e.Kind {
+					matches++
+					g.Expect(IsDefaultExcluded(s.Resource())).To(BeTrue())
+				}
+			}
+			g.Expect(matches).To(BeNumerically(">", 0))
+		})
+	}
+}
+
+func TestDefaultExcludedResourceKinds(t *testing.T) {
+	g := NewWithT(t)
+
+	kinds := make([]string, 0)
+	for _, e := range DefaultExclusions() {
+		kinds = append(kinds, e.Kind)
+	}
+	g.Expect(DefaultExcludedResourceKinds()).To(Equal(kinds))
+	g.Expect(DefaultExcludedResourceKinds()).To(ConsistOf(KindService, KindNamespace, KindSecret, KindPod, KindNode))
+}
+
+// Every default-excluded kind that service discovery requires must be re-enabled by the discovery override, so that
+// the defaults never fight the override.
+func TestDefaultExclusions_ConsistentWithDiscovery(t *testing.T) {
+	g := NewWithT(t)
+
+	in := schema.MustGet().KubeCollections()
+	opts := []FilterOption{
+		WithExcludedResourceKinds(DefaultExcludedResourceKinds()...),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+	}
+	g.Expect(ValidateFilterConfig(in, nil, opts...)).To(BeEmpty())
+
+	result, err := FilterCollections(in, nil, in.CollectionNames(), opts...)
+	g.Expect(err).To(BeNil())
+	for _, s := range in.All() {
+		if IsDefaultExcluded(s.Resource()) && IsRequiredForServiceDiscovery(s.Resource()) {
+			g.Expect(reasonOf(result, s.Name())).To(Equal(ReasonRequiredForServiceDiscovery), s.Name().String())
+		}
+	}
+}
+
+func TestDefaultExclusionErrors(t *testing.T) {
+	// Diverge the default table from the discovery table, by default-excluding EndpointSlice.
+	defer func(saved []DefaultExclusion) { defaultExclusions = saved }(defaultExclusions)
+	defaultExclusions = append(DefaultExclusions(), DefaultExclusion{
+		Group:     "discovery.k8s.io",
+		Kind:      KindEndpointSlice,
+		Category:  CategoryHighChurn,
+		Rationale: "test",
+	})
+
+	in := collection.SchemasFor(testService, testNamespace, testSecret, testPod, testNode, testEndpointSlice)
+	excluded := WithExcludedResourceKinds(DefaultExcludedResourceKinds()...)
+	discovery := WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true})
+	cases := []struct {
+		name     string
+		opts     []FilterOption
+		expected []string
+	}{
+		{
+			name: "override re-enables every kind",
+			opts: []FilterOption{excluded, discovery},
+		},
+		{
+			name: "discovery disabled",
+			opts: []FilterOption{excluded, WithDiscoveryOverrideOnly(KindService)},
+		},
+		{
+			name: "override withheld",
+			opts: []FilterOption{excluded, discovery,
+				WithDiscoveryOverrideOnly(KindService, KindNamespace, KindSecret, KindPod, KindNode)},
+			expected: []string{"default-excluded kind discovery.k8s.io/EndpointSlice is required for service " +
+				"discovery, but WithDiscoveryOverrideOnly does not re-enable it: " +
+				"k8s/discovery.k8s.io/v1/endpointslices stays disabled and service discovery sees none of its objects"},
+		},
+		{
+			name: "default not configured",
+			opts: []FilterOption{WithExcludedResourceKinds(KindService), discovery, WithDiscoveryOverrideOnly(KindService)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var msgs []string
+			for _, err := range ValidateFilterConfig(in, nil, c.opts...) {
+				msgs = append(msgs, err.Error())
+			}
+			g.Expect(msgs).To(Equal(c.expected))
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ConfigDelta describes a change to the configuration of a CollectionFilter.
+type ConfigDelta struct {
+	// AddedExclusions are appended to the exclusion entries.
+	AddedExclusions []string
+
+	// RemovedExclusions are removed from the exclusion entries. If an entry occurs more than once, the last
+	// occurrence is removed.
+	RemovedExclusions []string
+
+	// Availability holds resource types whose availability changed. It only has an effect if the filter has an
+	// availability probe.
+	Availability map[config.GroupVersionKind]bool
+
+	// RequiredCollections, if not nil, replaces the required collections. This changes which collections are
+	// upstream of the required ones, so it always causes a full Apply.
+	RequiredCollections collection.Names
+}
+
+// ApplyDelta applies delta to the filter configuration and returns the result of filtering the input of prev
+// with it. Only the schemas that delta can affect are evaluated again; decisions for every other schema are
+// carried over from prev, along with the per-collection state derived from them; see FilterResult. Deltas that can
+// affect any schema, and previous results computed with another configuration, fall back to a full Apply. Either
+// way, ApplyDelta fails with the errors Apply would fail with for the resulting configuration.
+//
+// On success the filter's configuration includes delta, so that further deltas can be applied to the returned
+// result. ApplyDelta must not be called concurrently with other methods of the filter.
+func (f *CollectionFilter) ApplyDelta(prev *FilterResult, delta ConfigDelta) (*FilterResult, error) {
+	return f.ApplyDeltaContext(context.Background(), prev, delta)
+}
+
+// ApplyDeltaContext is ApplyDelta with a context for the availability probe; see ApplyContext.
+func (f *CollectionFilter) ApplyDeltaContext(ctx context.Context, prev *FilterResult, delta ConfigDelta) (*FilterResult, error) {
+	if prev == nil || prev.Report == nil {
+		return nil, errors.New("ApplyDelta requires a previous result")
+	}
+
+	next := f.withDelta(prev, delta)
+	if next.err != nil {
+		return nil, next.err
+	}
+	in := prev.inputSchemas()
+	if delta.RequiredCollections != nil || f.opts.preferNewestVersion || prev.Fingerprint != f.fingerprint() {
+		result := next.apply(ctx, in)
+		if errs := next.configErrors(in, result.index); len(errs) > 0 {
+			return nil, errs[0]
+		}
+		if err := next.applyBudget(result); err != nil {
+			return nil, err
+		}
+		*f = *next
+		return result, nil
+	}
+
+	affected := affectedBy(prev.index, delta)
+	result := next.newResult(in, prev.index)
+	for gvk, available := range prev.availability {
+		if _, ok := delta.Availability[gvk]; !ok {
+			result.availability[gvk] = available
+		}
+	}
+	for gvk, err := range prev.undetermined {
+		if _, ok := delta.Availability[gvk]; !ok {
+			result.setUndetermined(gvk, err)
+		}
+	}
+	for _, w := range prev.Warnings {
+		if _, ok := affected[w.Collection]; isDecisionWarning(w.Code) && !ok {
+			result.Warnings = append(result.Warnings, w)
+		}
+	}
+
+	all := in.All()
+	decisions := make([]Decision, len(all))
+	for i, s := range all {
+		if _, ok := affected[s.Name()]; ok {
+			decisions[i] = next.evaluate(ctx, s, result)
+		} else {
+			decisions[i] = prev.Report.Entries[i].Decision
+		}
+	}
+
+	next.build(in, decisions, result)
+	next.runReasonHooks(result, affected)
+	if errs := next.configErrors(in, result.index); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if err := next.applyBudget(result); err != nil {
+		return nil, err
+	}
+	*f = *next
+	return result, nil
+}
+
+// withDelta returns a copy of the filter with delta applied to its configuration. Availability known to prev
+// is carried over, so that it is not probed again.
+func (f *CollectionFilter) withDelta(prev *FilterResult, delta ConfigDelta) *CollectionFilter {
+	opts := *f.opts
+	for _, entry := range delta.RemovedExclusions {
+		opts.removeExclusion(entry)
+	}
+	opts.addExclusions(SourceAPI, delta.AddedExclusions)
+
+	opts.knownAvailability = make(map[config.GroupVersionKind]bool)
+	for _, known := range []map[config.GroupVersionKind]bool{f.opts.knownAvailability, prev.availability, delta.Availability} {
+		for gvk, available := range known {
+			opts.knownAvailability[gvk] = available
+		}
+	}
+
+	requiredCols, aliased := f.requiredCols, f.aliased
+	upstream := f.upstream
+	if delta.RequiredCollections != nil {
+		requiredCols, aliased = resolveAliases(delta.RequiredCollections), delta.RequiredCollections.Clone()
+		upstream = f.providers.RequiredInputsFor(requiredCols)
+	}
+
+	exclusions, err := compileOptions(&opts)
+	return &CollectionFilter{
+		providers:    f.providers,
+		requiredCols: requiredCols,
+		aliased:      aliased,
+		opts:         &opts,
+		upstream:     upstream,
+		exclusions:   exclusions,
+		err:          err,
+	}
+}
+
+// affectedBy returns the collections of idx whose decision delta may change: those matched by an added or
+// removed exclusion entry, whether or not it is negated, and those whose availability changed.
+func affectedBy(idx *KindIndex, delta ConfigDelta) map[collection.Name]struct{} {
+	affected := make(map[collection.Name]struct{})
+	for _, entry := range append(append([]string{}, delta.AddedExclusions...), delta.RemovedExclusions...) {
+		m := compileExclusions([]string{strings.TrimPrefix(strings.TrimSpace(entry), negationPrefix)})
+		for _, s := range idx.candidates(m.exclusions[0]) {
+			if m.MatchesSchema(s) {
+				affected[s.Name()] = struct{}{}
+			}
+		}
+	}
+	if len(delta.Availability) == 0 {
+		return affected
+	}
+	for _, s := range idx.schemas.All() {
+		if _, ok := delta.Availability[s.Resource().GroupVersionKind()]; ok {
+			affected[s.Name()] = struct{}{}
+		}
+	}
+	return affected
+}
@@ -0,0 +1,195 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// expectSameResult asserts that a result computed by ApplyDelta matches one computed by a full Apply.
+func expectSameResult(g *WithT, actual, expected *FilterResult) {
+	g.Expect(actual.Report.Entries).To(Equal(expected.Report.Entries))
+	g.Expect(actual.Warnings).To(ConsistOf(expected.Warnings))
+	g.Expect(actual.Stats).To(Equal(expected.Stats))
+	g.Expect(actual.Fingerprint).To(Equal(expected.Fingerprint))
+	g.Expect(actual.SelectorHints).To(Equal(expected.SelectorHints))
+	g.Expect(actual.CollectionHints).To(Equal(expected.CollectionHints))
+	g.Expect(actual.availability).To(Equal(expected.availability))
+	g.Expect(actual.Schemas.CollectionNames()).To(Equal(expected.Schemas.CollectionNames()))
+	g.Expect(actual.Schemas.DisabledCollectionNames()).To(Equal(expected.Schemas.DisabledCollectionNames()))
+	for _, s := range actual.inputSchemas().All() {
+		if out := actual.Schemas.MustFind(s.Name().String()); !out.IsDisabled() {
+			g.Expect(out).To(BeIdenticalTo(s))
+		}
+	}
+}
+
+func TestApplyDelta_Randomized(t *testing.T) {
+	in := collection.SchemasFor(testService, testNamespace, testNode, testPod, testSecret, testEndpointSlice,
+		testDeployment, testKubeGateway, testVirtualService, testAuthzPolicy)
+	required := in.CollectionNames()[1:]
+	pool := []string{
+		KindPod, KindService, KindSecret, "core/*", "!core/Pod", "*Policy", "networking.istio.io/*",
+		"!networking.istio.io/v1alpha3/Gateway", "collection:k8s/apps/v1/deployments", "!Node", "EndpointSlice",
+	}
+	rng := rand.New(rand.NewSource(1))
+	pick := func(n int) []string {
+		var out []string
+		for i := 0; i < n; i++ {
+			out = append(out, pool[rng.Intn(len(pool))])
+		}
+		return out
+	}
+
+	for i := 0; i < 200; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			g := NewWithT(t)
+			base := pick(rng.Intn(5))
+			features := FeatureRequirements{ServiceDiscovery: rng.Intn(2) == 0}
+			opts := []FilterOption{
+				WithFeatureRequirements(features),
+				WithSelectorHint(KindSecret, SelectorHint{FieldSelector: "type=kubernetes.io/tls"}),
+			}
+			f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, required, append(opts, WithExcludedResourceKinds(base...))...)
+			prev, err := f.Apply(in)
+			g.Expect(err).To(BeNil())
+
+			delta := ConfigDelta{AddedExclusions: pick(rng.Intn(3))}
+			final := append([]string{}, base...)
+			if len(base) > 0 && rng.Intn(2) == 0 {
+				removed := base[rng.Intn(len(base))]
+				delta.RemovedExclusions = []string{removed}
+				for j := len(final) - 1; j >= 0; j-- {
+					if final[j] == removed {
+						final = append(final[:j], final[j+1:]...)
+						break
+					}
+				}
+			}
+			final = append(final, delta.AddedExclusions...)
+
+			actual, err := f.ApplyDelta(prev, delta)
+			g.Expect(err).To(BeNil())
+			expected, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, required, append(opts, WithExcludedResourceKinds(final...))...)
+			g.Expect(err).To(BeNil())
+			expectSameResult(g, actual, expected)
+
+			// The filter now holds the final configuration.
+			again, err := f.Apply(in)
+			g.Expect(err).To(BeNil())
+			expectSameResult(g, again, expected)
+		})
+	}
+}
+
+func TestApplyDelta_Availability(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testDeployment, testKubeGateway)
+	p := newFlakyProbe(0)
+	opts := []FilterOption{WithAvailabilityProbe(p.probe)}
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+	prev, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+	g.Expect(prev.EnabledCollectionNames()).To(HaveLen(3))
+
+	gvk := testKubeGateway.Resource().GroupVersionKind()
+	delta := ConfigDelta{Availability: map[config.GroupVersionKind]bool{gvk: false}}
+	actual, err := f.ApplyDelta(prev, delta)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(actual, testKubeGateway.Name())).To(Equal(ReasonResourceUnavailable))
+	// Availability of the other kinds was carried over rather than probed again.
+	g.Expect(p.calls).To(Equal(map[string]int{KindService: 1, KindDeployment: 1, "Gateway": 1}))
+
+	expected, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts, withKnownAvailability(delta.Availability))...)
+	g.Expect(err).To(BeNil())
+	expectSameResult(g, actual, expected)
+}
+
+func TestApplyDelta_ConfigErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	// An exclusion of a status writer is rejected by an incremental delta, as it is by Apply.
+	in := collection.SchemasFor(testService, testKubeGateway, testVirtualService)
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name()},
+		WithStatusWriters(testVirtualService.Name()))
+	prev, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+	fingerprint := f.fingerprint()
+	_, err = f.ApplyDelta(prev, ConfigDelta{AddedExclusions: []string{"VirtualService"}})
+	g.Expect(err).To(MatchError(ContainSubstring(
+		`status writer collection k8s/networking.istio.io/v1alpha3/virtualservices is excluded by "VirtualService"`)))
+	// A failed delta leaves the configuration unchanged.
+	g.Expect(f.fingerprint()).To(Equal(fingerprint))
+
+	// A conflicting entry is rejected under ConflictStrict.
+	in = collection.SchemasFor(testService, testPod)
+	f = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExclusionsFrom(SourceMeshConfig, KindPod), WithConflictPolicy(ConflictStrict))
+	prev, err = f.Apply(in)
+	g.Expect(err).To(BeNil())
+	_, err = f.ApplyDelta(prev, ConfigDelta{AddedExclusions: []string{"!Pod"}})
+	g.Expect(err).To(MatchError(ContainSubstring("conflicting exclusion entries")))
+	_, err = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExclusionsFrom(SourceMeshConfig, KindPod), WithExclusionsFrom(SourceAPI, "!Pod"),
+		WithConflictPolicy(ConflictStrict)).Apply(in)
+	g.Expect(err).To(MatchError(ContainSubstring("conflicting exclusion entries")))
+
+	// A previous result computed with another configuration falls back to a full Apply, which checks the
+	// namespace scope of collection hints.
+	in = collection.SchemasFor(testService, clusterScopedNode)
+	prev, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	f = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithCollectionHint(clusterScopedNode.Name(), CollectionHint{Namespaces: []string{"bookinfo"}}))
+	_, err = f.Apply(in)
+	g.Expect(err).To(MatchError(ContainSubstring("cannot scope cluster-scoped kind Node to namespaces")))
+	_, err = f.ApplyDelta(prev, ConfigDelta{AddedExclusions: []string{KindPod}})
+	g.Expect(err).To(MatchError(ContainSubstring("cannot scope cluster-scoped kind Node to namespaces")))
+}
+
+func TestApplyDelta_FullApply(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	var hooked []collection.Name
+	hook := WithReasonHook(ReasonNotUpstream, func(s collection.Schema, _ Decision) {
+		hooked = append(hooked, s.Name())
+	})
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), hook)
+	prev, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+	g.Expect(hooked).To(BeEmpty())
+
+	// Changing the required collections re-evaluates every schema.
+	actual, err := f.ApplyDelta(prev, ConfigDelta{RequiredCollections: collection.Names{testService.Name()}})
+	g.Expect(err).To(BeNil())
+	g.Expect(actual.EnabledCollectionNames()).To(Equal(collection.Names{testService.Name()}))
+	g.Expect(hooked).To(HaveLen(len(in.All()) - 1))
+
+	_, err = f.ApplyDelta(actual, ConfigDelta{AddedExclusions: []string{"/Pod"}})
+	g.Expect(err).NotTo(BeNil())
+	_, err = f.ApplyDelta(nil, ConfigDelta{})
+	g.Expect(err).To(MatchError("ApplyDelta requires a previous result"))
+}
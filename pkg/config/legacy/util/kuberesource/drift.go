@@ -0,0 +1,206 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"strings"
+)
+
+// Equal returns true if c and other have the same features and Kubernetes version gates, and exclude the same
+// resources, regardless of how the entries are written or ordered; see Covers.
+func (c ExclusionConfig) Equal(other ExclusionConfig) bool {
+	return c.Features == other.Features && kubeVersionGatesEqual(c.KubeVersionGates, other.KubeVersionGates) &&
+		c.Covers(other) && other.Covers(c)
+}
+
+func kubeVersionGatesEqual(a, b map[string]KubeVersionRange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for e, r := range a {
+		if o, ok := b[e]; !ok || o != r {
+			return false
+		}
+	}
+	return true
+}
+
+// Covers returns true if every resource excluded by the entries of other is also excluded by the entries of c,
+// taking globs, entries that subsume others and negations into account. Features and Kubernetes version gates
+// are not considered. The reasoning is conservative: Covers never reports coverage that does not hold, but may
+// fail to prove it when negated globs overlap in ways it cannot decide, or when a collection: entry would have to
+// be matched by a group/version/kind entry.
+func (c ExclusionConfig) Covers(other ExclusionConfig) bool {
+	ours := compileExclusions(c.ExcludedResourceKinds).exclusions
+	theirs := compileExclusions(other.ExcludedResourceKinds).exclusions
+	for j, b := range theirs {
+		if !b.Negated && !coveredEntry(ours, b, theirs[j+1:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// coveredEntry returns true if every resource for which b is the decisive entry, given that the entries in
+// laterTheirs follow it, is excluded by ours.
+func coveredEntry(ours []Exclusion, b Exclusion, laterTheirs []Exclusion) bool {
+	for i := len(ours) - 1; i >= 0; i-- {
+		a := ours[i]
+		if a.Negated || !exclusionCovers(a, b) {
+			continue
+		}
+		// a excludes everything b does, unless a later negation of ours re-includes part of it. That part is only
+		// a problem if b still excludes it, that is, if no later negation of theirs re-includes it too.
+		reincluded := false
+		for _, n := range ours[i+1:] {
+			if n.Negated && exclusionsMayOverlap(n, b) && !negatedLater(narrowest(n, b), laterTheirs) {
+				reincluded = true
+				break
+			}
+		}
+		if !reincluded {
+			return true
+		}
+	}
+	return false
+}
+
+// negatedLater returns true if one of the negations in entries re-includes everything n does.
+func negatedLater(n Exclusion, entries []Exclusion) bool {
+	for _, e := range entries {
+		if e.Negated && exclusionCovers(e, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// narrowest returns the intersection of what n and b match if it can be expressed as a single entry, because
+// for each segment one pattern covers the other and neither has equivalents, and n otherwise.
+func narrowest(n, b Exclusion) Exclusion {
+	if n.literal || b.literal || n.Collection != "" || b.Collection != "" ||
+		len(n.equivalents) > 0 || len(b.equivalents) > 0 {
+		return n
+	}
+	out := n
+	for _, seg := range []struct {
+		out   *string
+		other string
+	}{
+		{&out.Group, b.Group}, {&out.Version, b.Version}, {&out.Kind, b.Kind},
+	} {
+		switch {
+		case globCovers(*seg.out, seg.other):
+			*seg.out = seg.other
+		case !globCovers(seg.other, *seg.out):
+			return n
+		}
+	}
+	return out
+}
+
+// exclusionCovers returns true if every resource matched by b is matched by a, ignoring negation. Resources matched
+// through the moved kinds table count: every form of b must be covered by a form of a.
+func exclusionCovers(a, b Exclusion) bool {
+	for _, bf := range exclusionForms(b) {
+		covered := false
+		for _, af := range exclusionForms(a) {
+			if formCovers(af, bf) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// exclusionForms returns e and the exclusions it is equivalent to through the moved kinds table.
+func exclusionForms(e Exclusion) []Exclusion {
+	return append([]Exclusion{e}, e.equivalents...)
+}
+
+// formCovers is exclusionCovers for a single form of a and b, without their equivalents.
+func formCovers(a, b Exclusion) bool {
+	if a.literal || b.literal {
+		return a.literal && b.literal && a.Kind == b.Kind
+	}
+	if a.Collection != "" || b.Collection != "" {
+		return a.Collection == b.Collection
+	}
+	return globCovers(a.Group, b.Group) && globCovers(a.Version, b.Version) && globCovers(a.Kind, b.Kind)
+}
+
+// exclusionsMayOverlap returns false only if no resource can be matched by both a and b, ignoring negation.
+func exclusionsMayOverlap(a, b Exclusion) bool {
+	for _, af := range exclusionForms(a) {
+		for _, bf := range exclusionForms(b) {
+			if formsMayOverlap(af, bf) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formsMayOverlap is exclusionsMayOverlap for a single form of a and b, without their equivalents.
+func formsMayOverlap(a, b Exclusion) bool {
+	if a.literal || b.literal || a.Collection != "" || b.Collection != "" {
+		return !(a.Collection != "" && b.Collection != "" && a.Collection != b.Collection)
+	}
+	return globsMayOverlap(a.Group, b.Group) && globsMayOverlap(a.Version, b.Version) &&
+		globsMayOverlap(a.Kind, b.Kind)
+}
+
+// globCovers returns true if every string matched by the glob b is matched by the glob a. A '*' in b can only be
+// covered by a '*' in a, and a '?' in b by a '?' or '*' in a.
+func globCovers(a, b string) bool {
+	// covers[i][j] is true if a[i:] covers b[j:].
+	covers := make([][]bool, len(a)+1)
+	for i := range covers {
+		covers[i] = make([]bool, len(b)+1)
+	}
+	covers[len(a)][len(b)] = true
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b); j >= 0; j-- {
+			switch {
+			case a[i] == '*':
+				covers[i][j] = covers[i+1][j] || (j < len(b) && covers[i][j+1])
+			case j == len(b) || b[j] == '*':
+				covers[i][j] = false
+			case a[i] == '?':
+				covers[i][j] = covers[i+1][j+1]
+			default:
+				covers[i][j] = a[i] == b[j] && covers[i+1][j+1]
+			}
+		}
+	}
+	return covers[0][0]
+}
+
+// globsMayOverlap returns false only if no string is matched by both globs. Overlap between two patterns that
+// both contain wildcards is assumed.
+func globsMayOverlap(a, b string) bool {
+	switch {
+	case !strings.ContainsAny(a, "*?"):
+		return globMatch(b, a)
+	case !strings.ContainsAny(b, "*?"):
+		return globMatch(a, b)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestExclusionConfig_Covers(t *testing.T) {
+	cases := []struct {
+		name   string
+		ours   []string
+		theirs []string
+		covers bool
+	}{
+		{"identical", []string{"Pod"}, []string{"Pod"}, true},
+		{"empty", nil, []string{"Pod"}, false},
+		{"nothing to cover", []string{"Pod"}, nil, true},
+		{"kind subsumes group kind", []string{"Gateway"}, []string{"networking.istio.io/Gateway"}, true},
+		{"group kind does not subsume kind", []string{"networking.istio.io/Gateway"}, []string{"Gateway"}, false},
+		{"group subsumes kind", []string{"networking.istio.io/*"}, []string{"networking.istio.io/v1alpha3/Gateway"}, true},
+		{"glob subsumes kind", []string{"*/*/*Policy"}, []string{"security.istio.io/AuthorizationPolicy"}, true},
+		{"glob subsumes glob", []string{"*.istio.io/*"}, []string{"networking.istio.io/Virtual*"}, true},
+		{"narrower glob", []string{"networking.istio.io/Virtual*"}, []string{"networking.istio.io/*"}, false},
+		{"single character wildcard", []string{"networking.istio.io/v1alpha?/*"}, []string{"networking.istio.io/v1alpha3/Gateway"}, true},
+		{"star is not covered by question mark", []string{"networking.istio.io/v1alpha?/*"}, []string{"networking.istio.io/v1alpha*/Gateway"}, false},
+		{"core group alias", []string{"core/Pod"}, []string{"core/v1/Pod"}, true},
+		{"core group is not any group", []string{"core/Service"}, []string{"Service"}, false},
+		{"collection", []string{"collection:k8s/core/v1/pods"}, []string{"collection:k8s/core/v1/pods"}, true},
+		{"collection is not a kind", []string{"Pod"}, []string{"collection:k8s/core/v1/pods"}, false},
+		{"our negation re-includes", []string{"networking.istio.io/*", "!Gateway"}, []string{"networking.istio.io/Gateway"}, false},
+		{"our negation elsewhere", []string{"networking.istio.io/*", "!Gateway"}, []string{"networking.istio.io/VirtualService"}, true},
+		{"both negate", []string{"networking.istio.io/*", "!Gateway"}, []string{"networking.istio.io/*", "!networking.istio.io/Gateway"}, true},
+		{"their negation is narrower", []string{"networking.istio.io/*", "!Gateway"},
+			[]string{"networking.istio.io/*", "!networking.istio.io/v1beta1/Gateway"}, false},
+		{"both negate the same", []string{"networking.istio.io/*", "!Gateway"}, []string{"networking.istio.io/*", "!Gateway"}, true},
+		{"their negation is wider", []string{"networking.istio.io/*", "!networking.istio.io/v1alpha3/Gateway"},
+			[]string{"networking.istio.io/*", "!Gateway"}, true},
+		{"their negation is ignored", []string{"Pod"}, []string{"Pod", "!Pod", "Service"}, false},
+		{"re-excluded later", []string{"networking.istio.io/*", "!Gateway", "Gateway"}, []string{"Gateway"}, true},
+		{"glob does not cover moved kind", []string{"ext*/Ingress"}, []string{"extensions/Ingress"}, false},
+		{"moved kind does not cover glob", []string{"extensions/Ingress"}, []string{"ext*/Ingress"}, false},
+		{"moved kind covers its other end", []string{"extensions/Ingress"}, []string{"networking.k8s.io/Ingress"}, true},
+		{"moved kind needs both ends", []string{"ext*/Ingress", "networking.k8s.io/Ingress"}, []string{"extensions/Ingress"}, true},
+		{"our negation of the other end re-includes", []string{"extensions/Ingress", "!networking.k8s.io/Ingress"},
+			[]string{"extensions/Ingress"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ours := ExclusionConfig{ExcludedResourceKinds: c.ours}
+			theirs := ExclusionConfig{ExcludedResourceKinds: c.theirs}
+			g.Expect(ours.Covers(theirs)).To(Equal(c.covers))
+		})
+	}
+}
+
+func TestExclusionConfig_Equal(t *testing.T) {
+	g := NewWithT(t)
+
+	a := ExclusionConfig{
+		ExcludedResourceKinds: []string{"core/Service", "Pod", "Pod", "networking.istio.io/*"},
+		Features:              FeatureRequirements{ServiceDiscovery: true},
+	}
+	b := ExclusionConfig{
+		ExcludedResourceKinds: []string{"networking.istio.io/*/*", " */Pod", "core/*/Service"},
+		Features:              FeatureRequirements{ServiceDiscovery: true},
+	}
+	g.Expect(a.Equal(b)).To(BeTrue())
+	g.Expect(b.Equal(a)).To(BeTrue())
+
+	// A redundant entry does not make a difference.
+	b.ExcludedResourceKinds = append(b.ExcludedResourceKinds, "networking.istio.io/v1alpha3/Gateway")
+	g.Expect(a.Equal(b)).To(BeTrue())
+
+	b.Features = FeatureRequirements{}
+	g.Expect(a.Equal(b)).To(BeFalse())
+
+	b.Features = a.Features
+	b.ExcludedResourceKinds = append(b.ExcludedResourceKinds, "!networking.istio.io/Gateway")
+	g.Expect(a.Equal(b)).To(BeFalse())
+
+	// Only the entry without a wildcard also excludes Ingress at the other end of its move.
+	a = ExclusionConfig{ExcludedResourceKinds: []string{"ext*/Ingress"}}
+	b = ExclusionConfig{ExcludedResourceKinds: []string{"extensions/Ingress"}}
+	g.Expect(a.Equal(b)).To(BeFalse())
+}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// DuplicatePolicy controls how duplicate collections are handled when composing input schema sets.
+type DuplicatePolicy int
+
+const (
+	// DedupeIdentical drops duplicates that are identical to the first occurrence. Conflicting
+	// definitions are an error.
+	DedupeIdentical DuplicatePolicy = iota
+
+	// RejectDuplicates treats any duplicate collection as an error.
+	RejectDuplicates
+)
+
+// DedupRecord records a collection that appeared more than once in the composed input.
+type DedupRecord struct {
+	Collection  collection.Name `json:"collection"`
+	Occurrences int             `json:"occurrences"`
+}
+
+// ApplyComposed composes the given schema sets, in order, and applies the filter to the result. Collections
+// that appear in more than one set are handled according to the configured DuplicatePolicy, and any dropped
+// duplicates are recorded on the report.
+func (f *CollectionFilter) ApplyComposed(sets ...collection.Schemas) (*FilterResult, error) {
+	in, dedups, err := composeSchemas(f.opts.duplicatePolicy, sets)
+	if err != nil {
+		return nil, err
+	}
+	result, err := f.Apply(in)
+	if err != nil {
+		return nil, err
+	}
+	result.Report.Dedups = dedups
+	for _, d := range dedups {
+		f.opts.logger().Debugf("collection filter: dropped %d duplicates of %s", d.Occurrences-1, d.Collection)
+	}
+	return result, nil
+}
+
+func composeSchemas(policy DuplicatePolicy, sets []collection.Schemas) (collection.Schemas, []DedupRecord, error) {
+	b := collection.NewSchemasBuilder()
+	first := make(map[collection.Name]collection.Schema)
+	occurrences := make(map[collection.Name]int)
+	var order collection.Names
+	for _, set := range sets {
+		for _, s := range set.All() {
+			existing, ok := first[s.Name()]
+			if !ok {
+				first[s.Name()] = s
+				b.MustAdd(s)
+				continue
+			}
+			if policy == RejectDuplicates {
+				return collection.Schemas{}, nil, fmt.Errorf("duplicate collection %s in input schemas", s.Name())
+			}
+			if !existing.Equal(s) {
+				return collection.Schemas{}, nil, fmt.Errorf("conflicting definitions for collection %s in input schemas: %v and %v",
+					s.Name(), existing, s)
+			}
+			if occurrences[s.Name()] == 0 {
+				order = append(order, s.Name())
+				occurrences[s.Name()] = 1
+			}
+			occurrences[s.Name()]++
+		}
+	}
+
+	var dedups []DedupRecord
+	for _, n := range order {
+		dedups = append(dedups, DedupRecord{Collection: n, Occurrences: occurrences[n]})
+	}
+	return b.Build(), dedups, nil
+}
@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestApplyComposed_IdenticalDuplicates(t *testing.T) {
+	g := NewWithT(t)
+
+	a := collection.SchemasFor(testService, testPod)
+	b := collection.SchemasFor(testPod, testNode, testService)
+	c := collection.SchemasFor(testPod)
+	required := collection.Names{testService.Name(), testPod.Name(), testNode.Name()}
+
+	result, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, required).ApplyComposed(a, b, c)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Schemas.CollectionNames()).To(Equal(collection.Names{testNode.Name(), testPod.Name(), testService.Name()}))
+	g.Expect(result.Report.Entries).To(HaveLen(3))
+	g.Expect(result.Report.Dedups).To(Equal([]DedupRecord{
+		{Collection: testPod.Name(), Occurrences: 3},
+		{Collection: testService.Name(), Occurrences: 2},
+	}))
+}
+
+func TestApplyComposed_ConflictingDuplicates(t *testing.T) {
+	g := NewWithT(t)
+
+	conflicting := newNamedTestSchema(testPod.Name().String(), "", "v2", "Pod")
+	a := collection.SchemasFor(testService, testPod)
+	b := collection.SchemasFor(conflicting)
+
+	_, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil).ApplyComposed(a, b)
+	g.Expect(err).To(MatchError(ContainSubstring("conflicting definitions for collection k8s/core/v1/pods")))
+}
+
+func TestApplyComposed_RejectDuplicates(t *testing.T) {
+	g := NewWithT(t)
+
+	a := collection.SchemasFor(testService, testPod)
+	b := collection.SchemasFor(testPod)
+
+	_, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, WithDuplicatePolicy(RejectDuplicates)).ApplyComposed(a, b)
+	g.Expect(err).To(MatchError("duplicate collection k8s/core/v1/pods in input schemas"))
+
+	result, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, WithDuplicatePolicy(RejectDuplicates)).ApplyComposed(a)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Report.Dedups).To(BeEmpty())
+}
@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Indexer assigns each collection a stable small integer index. The collections it is created with are indexed
+// in name order; collections seen later are appended, in name order per batch, so an index never changes once
+// assigned. An Indexer is safe for concurrent use, and lookups do not lock.
+type Indexer struct {
+	mu sync.Mutex
+
+	// index holds the current map[collection.Name]int. A map is never modified once stored; extending the
+	// indexer stores a copy.
+	index atomic.Value
+}
+
+// NewIndexer returns an indexer for names.
+func NewIndexer(names collection.Names) *Indexer {
+	x := &Indexer{}
+	x.index.Store(map[collection.Name]int{})
+	x.extend(names)
+	return x
+}
+
+// Index returns the index of the named collection, if it has one.
+func (x *Indexer) Index(name collection.Name) (int, bool) {
+	i, ok := x.load()[name]
+	return i, ok
+}
+
+// Len returns the number of indexed collections.
+func (x *Indexer) Len() int {
+	return len(x.load())
+}
+
+func (x *Indexer) load() map[collection.Name]int {
+	return x.index.Load().(map[collection.Name]int)
+}
+
+// extend indexes the collections in names that have no index yet, and returns the resulting index.
+func (x *Indexer) extend(names collection.Names) map[collection.Name]int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	current := x.load()
+	var added collection.Names
+	for _, n := range names {
+		if _, ok := current[n]; !ok {
+			added = append(added, n)
+		}
+	}
+	if len(added) == 0 {
+		return current
+	}
+	added.Sort()
+	next := make(map[collection.Name]int, len(current)+len(added))
+	for n, i := range current {
+		next[n] = i
+	}
+	for _, n := range added {
+		if _, ok := next[n]; !ok {
+			next[n] = len(next)
+		}
+	}
+	x.index.Store(next)
+	return next
+}
+
+// EnabledSet is an immutable bitset of the collections enabled by a filter result, for consumers that check
+// whether a collection is enabled on every config event.
+type EnabledSet struct {
+	indexer *Indexer
+	index   map[collection.Name]int
+	bits    []uint64
+}
+
+// NewEnabledSet returns the enabled set of result, indexed by indexer. The collections of result that indexer has
+// no index for are indexed first. If indexer is nil, a new one is created for the collections of result. Sets
+// built from the results of successive updates should share an indexer, so that the indexes stay stable.
+func NewEnabledSet(result *FilterResult, indexer *Indexer) *EnabledSet {
+	names := result.Schemas.CollectionNames()
+	if indexer == nil {
+		indexer = NewIndexer(names)
+	}
+	s := &EnabledSet{indexer: indexer, index: indexer.extend(names)}
+	s.bits = make([]uint64, (len(s.index)+63)/64)
+	for _, n := range result.EnabledCollectionNames() {
+		i := s.index[n]
+		s.bits[i/64] |= 1 << (uint(i) % 64)
+	}
+	return s
+}
+
+// Contains returns true if the named collection is enabled.
+func (s *EnabledSet) Contains(name collection.Name) bool {
+	i, ok := s.index[name]
+	return ok && s.ContainsIndex(i)
+}
+
+// ContainsIndex returns true if the collection with index i in the indexer of s is enabled. Consumers that resolve
+// the index of a collection once through the shared Indexer avoid hashing the name on every check.
+func (s *EnabledSet) ContainsIndex(i int) bool {
+	return i >= 0 && i/64 < len(s.bits) && s.bits[i/64]&(1<<(uint(i)%64)) != 0
+}
+
+// Len returns the number of enabled collections.
+func (s *EnabledSet) Len() int {
+	n := 0
+	for _, w := range s.bits {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Indexer returns the indexer of s, to build the set of the next result with.
+func (s *EnabledSet) Indexer() *Indexer {
+	return s.indexer
+}
@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestEnabledSet(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindPod, KindDeployment))
+	g.Expect(err).To(BeNil())
+
+	set := NewEnabledSet(result, nil)
+	for _, s := range in.All() {
+		g.Expect(set.Contains(s.Name())).To(Equal(!result.Schemas.MustFind(s.Name().String()).IsDisabled()), s.Name().String())
+	}
+	g.Expect(set.Contains("k8s/example.com/v1/widgets")).To(BeFalse())
+	g.Expect(set.ContainsIndex(mustIndex(t, set.Indexer(), testService.Name()))).To(BeTrue())
+	g.Expect(set.ContainsIndex(mustIndex(t, set.Indexer(), testPod.Name()))).To(BeFalse())
+	g.Expect(set.ContainsIndex(-1)).To(BeFalse())
+	g.Expect(set.ContainsIndex(1000)).To(BeFalse())
+	g.Expect(set.Len()).To(Equal(len(result.EnabledCollectionNames())))
+
+	// Indexes follow the sorted names.
+	names := in.CollectionNames()
+	names.Sort()
+	for i, n := range names {
+		g.Expect(mustIndex(t, set.Indexer(), n)).To(Equal(i))
+	}
+}
+
+func TestEnabledSet_IndexStability(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testSecret)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	first := state.EnabledSet()
+	g.Expect(first.Len()).To(Equal(3))
+	indexes := make(map[collection.Name]int)
+	for _, n := range in.CollectionNames() {
+		indexes[n], _ = first.Indexer().Index(n)
+	}
+
+	_, err = state.Update(WithExcludedResourceKinds(KindPod))
+	g.Expect(err).To(BeNil())
+	second := state.EnabledSet()
+	g.Expect(second.Indexer()).To(BeIdenticalTo(first.Indexer()))
+	g.Expect(second.Contains(testPod.Name())).To(BeFalse())
+	g.Expect(first.Contains(testPod.Name())).To(BeTrue(), "earlier sets are immutable")
+
+	// A larger schema set extends the shared indexer without moving the existing indexes.
+	wider := collection.SchemasFor(testService, testPod, testSecret, testNamespace, testDeployment)
+	result, err := FilterCollections(wider, kuberesourcetest.ScriptedProviders{}, wider.CollectionNames())
+	g.Expect(err).To(BeNil())
+	third := NewEnabledSet(result, second.Indexer())
+	g.Expect(third.Len()).To(Equal(5))
+	for n, i := range indexes {
+		g.Expect(mustIndex(t, third.Indexer(), n)).To(Equal(i), n.String())
+	}
+	g.Expect(mustIndex(t, third.Indexer(), testDeployment.Name())).To(Equal(3))
+	g.Expect(mustIndex(t, third.Indexer(), testNamespace.Name())).To(Equal(4))
+	g.Expect(second.Contains(testNamespace.Name())).To(BeFalse())
+}
+
+func mustIndex(t *testing.T, x *Indexer, name collection.Name) int {
+	t.Helper()
+	i, ok := x.Index(name)
+	if !ok {
+		t.Fatalf("no index for %s", name)
+	}
+	return i
+}
@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// EndpointsMode selects which endpoints kinds the service discovery override protects.
+type EndpointsMode string
+
+const (
+	// EndpointsBoth protects both core Endpoints and EndpointSlice, for clusters in transition. It is the default.
+	EndpointsBoth EndpointsMode = "Both"
+
+	// EndpointSliceOnly protects EndpointSlice only, so that an exclusion of Endpoints is honored.
+	EndpointSliceOnly EndpointsMode = "EndpointSliceOnly"
+
+	// EndpointsOnly protects core Endpoints only, for clusters older than Kubernetes 1.21 and managed offerings
+	// without EndpointSlice. It is deprecated, and reported with a WarningDeprecatedEndpointsMode warning.
+	EndpointsOnly EndpointsMode = "EndpointsOnly"
+)
+
+// DiscoveryOptions configures how the service discovery override treats kinds whose use depends on the cluster.
+type DiscoveryOptions struct {
+	// EndpointsMode selects the endpoints kinds that are protected. If empty, EndpointsBoth is used.
+	EndpointsMode EndpointsMode `json:"endpointsMode,omitempty"`
+}
+
+// WithDiscoveryOptions sets the discovery options. They only have an effect with the ServiceDiscovery feature.
+func WithDiscoveryOptions(d DiscoveryOptions) FilterOption {
+	return func(o *filterOptions) {
+		o.discovery = d
+	}
+}
+
+// endpointsMode returns the endpoints mode in effect.
+func (o *filterOptions) endpointsMode() EndpointsMode {
+	if o.discovery.EndpointsMode == "" {
+		return EndpointsBoth
+	}
+	return o.discovery.EndpointsMode
+}
+
+// protects returns true if res is an endpoints kind that service discovery needs under m.
+func (m EndpointsMode) protects(res resource.Schema) bool {
+	switch TypeKey(res.Group(), res.Kind()) {
+	case TypeKey("", KindEndpoints):
+		return res.Version() == "v1" && m != EndpointSliceOnly
+	case TypeKey("discovery.k8s.io", KindEndpointSlice):
+		return m != EndpointsOnly
+	default:
+		return false
+	}
+}
+
+// protectedKinds returns the endpoints kinds protected under m.
+func (m EndpointsMode) protectedKinds() []string {
+	switch m {
+	case EndpointSliceOnly:
+		return []string{KindEndpointSlice}
+	case EndpointsOnly:
+		return []string{KindEndpoints}
+	default:
+		return []string{KindEndpoints, KindEndpointSlice}
+	}
+}
+
+// discoveryKinds returns the kinds service discovery requires: those of ServiceDiscoveryRequiredKinds, and the
+// endpoints kinds protected under the endpoints mode.
+func (o *filterOptions) discoveryKinds() map[string]struct{} {
+	kinds := make(map[string]struct{})
+	for _, gk := range ServiceDiscoveryRequiredKinds() {
+		kinds[gk.Kind] = struct{}{}
+	}
+	for _, k := range o.endpointsMode().protectedKinds() {
+		kinds[k] = struct{}{}
+	}
+	return kinds
+}
+
+// endpointsModeErrors returns an error if the endpoints mode is not one of the known modes.
+func (o *filterOptions) endpointsModeErrors() []error {
+	switch o.endpointsMode() {
+	case EndpointsBoth, EndpointSliceOnly, EndpointsOnly:
+		return nil
+	default:
+		return []error{fmt.Errorf("unknown endpoints mode %q", o.discovery.EndpointsMode)}
+	}
+}
+
+// endpointsModeWarnings returns a WarningDeprecatedEndpointsMode warning if service discovery is enabled in the
+// deprecated EndpointsOnly mode.
+func (o *filterOptions) endpointsModeWarnings() []FilterWarning {
+	if !o.features.ServiceDiscovery || o.endpointsMode() != EndpointsOnly {
+		return nil
+	}
+	return []FilterWarning{{
+		Code: WarningDeprecatedEndpointsMode,
+		Message: fmt.Sprintf("endpoints mode %s is deprecated; Endpoints is superseded by EndpointSlice, use %s or %s",
+			EndpointsOnly, EndpointSliceOnly, EndpointsBoth),
+	}}
+}
@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var testEndpoints = newTestSchema("", "v1", KindEndpoints)
+
+func TestEndpointsMode(t *testing.T) {
+	in := collection.SchemasFor(testService, testEndpoints, testEndpointSlice)
+	cases := []struct {
+		mode     EndpointsMode
+		enabled  []collection.Name
+		disabled []collection.Name
+	}{
+		{"", []collection.Name{testEndpoints.Name(), testEndpointSlice.Name()}, nil},
+		{EndpointsBoth, []collection.Name{testEndpoints.Name(), testEndpointSlice.Name()}, nil},
+		{EndpointSliceOnly, []collection.Name{testEndpointSlice.Name()}, []collection.Name{testEndpoints.Name()}},
+		{EndpointsOnly, []collection.Name{testEndpoints.Name()}, []collection.Name{testEndpointSlice.Name()}},
+	}
+	for _, c := range cases {
+		t.Run(string(c.mode), func(t *testing.T) {
+			g := NewWithT(t)
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+				WithExcludedResourceKinds(KindEndpoints, KindEndpointSlice),
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+				WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: c.mode}))
+			g.Expect(err).To(BeNil())
+
+			for _, n := range c.enabled {
+				g.Expect(reasonOf(result, n)).To(Equal(ReasonRequiredForServiceDiscovery), n.String())
+			}
+			// Exclusions of the kind that is not protected are honored.
+			for _, n := range c.disabled {
+				g.Expect(reasonOf(result, n)).To(Equal(ReasonExcludedKind), n.String())
+			}
+
+			expectedMode := c.mode
+			if expectedMode == "" {
+				expectedMode = EndpointsBoth
+			}
+			g.Expect(result.Report.EndpointsMode).To(Equal(expectedMode))
+
+			var codes []WarningCode
+			for _, w := range result.Warnings {
+				codes = append(codes, w.Code)
+			}
+			if c.mode == EndpointsOnly {
+				g.Expect(codes).To(ConsistOf(WarningDeprecatedEndpointsMode))
+			} else {
+				g.Expect(codes).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestEndpointsMode_WithoutDiscovery(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testEndpoints, testEndpointSlice)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindEndpoints), WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: EndpointsOnly}))
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testEndpoints.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(result.Report.EndpointsMode).To(BeEmpty())
+	g.Expect(result.Warnings).To(BeEmpty())
+}
+
+func TestEndpointsMode_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testEndpoints, testEndpointSlice)
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: "Endpointz"}))
+	g.Expect(err).To(MatchError(ContainSubstring(`unknown endpoints mode "Endpointz"`)))
+
+	// The discovery override can be limited to the protected endpoints kinds only.
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithDiscoveryOverrideOnly(KindService, KindEndpointSlice))
+	g.Expect(err).To(BeNil())
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: EndpointSliceOnly}),
+		WithDiscoveryOverrideOnly(KindEndpoints))
+	g.Expect(err).To(MatchError(ContainSubstring("discovery override kinds are not required for service discovery: Endpoints")))
+}
@@ -0,0 +1,458 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/util/istiomultierror"
+)
+
+const (
+	// MaxExclusionEntryLength is the maximum length of a single exclusion entry.
+	MaxExclusionEntryLength = 512
+
+	// MaxExclusionEntries is the maximum number of entries in an exclusion list.
+	MaxExclusionEntries = 1024
+
+	collectionPrefix = "collection:"
+	negationPrefix   = "!"
+	coreGroup        = "core"
+	anySegment       = "*"
+)
+
+// Exclusion is a parsed exclusion entry. The supported forms are:
+//
+//	Kind                   the kind in any group, e.g. Pod
+//	group/Kind             the kind in a single group, e.g. networking.istio.io/Gateway or core/Pod
+//	group/version/Kind     the kind at a single version, e.g. networking.istio.io/v1alpha3/Gateway
+//	collection:name        a single collection, e.g. collection:k8s/core/v1/pods
+//
+// Group, version and kind may contain the wildcards '*' (any sequence) and '?' (any single character), and an
+// entry prefixed with '!' re-includes what earlier entries excluded.
+type Exclusion struct {
+	// Entry is the entry as written.
+	Entry string `json:"entry"`
+
+	Negated bool `json:"negated,omitempty"`
+
+	// Collection is set for collection: entries, in which case the group, version and kind are unused.
+	Collection collection.Name `json:"collection,omitempty"`
+
+	// Group, Version and Kind are patterns. The core group is the empty string, and segments that were not
+	// specified are "*".
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+
+	// Source is where the entry was configured. It is only set for entries compiled from filter options.
+	Source ExclusionSource `json:"source,omitempty"`
+
+	// Equivalents lists the normalized patterns the entry also matches because its kind moved to or from
+	// another group or version; see MovedKinds.
+	Equivalents []string `json:"equivalents,omitempty"`
+
+	// literal is set for entries that could not be parsed, which are matched as exact kinds.
+	literal bool
+
+	// alias is the deprecated collection name a collection: entry was written with, if any.
+	alias collection.Name
+
+	// equivalents are the parsed forms of Equivalents.
+	equivalents []Exclusion
+}
+
+// String returns the normalized form of the exclusion.
+func (e Exclusion) String() string {
+	prefix := ""
+	if e.Negated {
+		prefix = negationPrefix
+	}
+	if e.Collection != "" {
+		return prefix + collectionPrefix + e.Collection.String()
+	}
+	group := e.Group
+	if group == "" {
+		group = coreGroup
+	}
+	return prefix + strings.Join([]string{group, e.Version, e.Kind}, "/")
+}
+
+func (e Exclusion) matches(name collection.Name, group, version, kind string) bool {
+	matched, _ := e.matchVia(name, group, version, kind)
+	return matched
+}
+
+// matchVia is like matches, but also returns the equivalent pattern the resource matched through, if it only
+// matched through one.
+func (e Exclusion) matchVia(name collection.Name, group, version, kind string) (bool, string) {
+	if e.Collection != "" {
+		return e.Collection == name, ""
+	}
+	if e.literal {
+		return e.Kind == kind, ""
+	}
+	if globMatch(e.Group, group) && globMatch(e.Version, version) && globMatch(e.Kind, kind) {
+		return true, ""
+	}
+	for _, eq := range e.equivalents {
+		if eq.matches(name, group, version, kind) {
+			return true, eq.String()
+		}
+	}
+	return false, ""
+}
+
+// ExclusionError describes an exclusion entry that could not be parsed.
+type ExclusionError struct {
+	// Index is the position of the entry in the exclusion list.
+	Index  int
+	Entry  string
+	Reason string
+
+	// Position is the position of the entry in the file it was loaded from, or nil if it is not known.
+	Position *Position
+}
+
+func (e *ExclusionError) Error() string {
+	if e.Position != nil {
+		return fmt.Sprintf("%s: invalid exclusion entry %d %q: %s", e.Position, e.Index, e.Entry, e.Reason)
+	}
+	return fmt.Sprintf("invalid exclusion entry %d %q: %s", e.Index, e.Entry, e.Reason)
+}
+
+// ParseExclusions parses a list of exclusion entries. All invalid entries are reported in the returned error.
+func ParseExclusions(entries []string) ([]Exclusion, error) {
+	return ParseExclusionsWithSchemas(entries, collection.SchemasFor())
+}
+
+// ParseExclusionsWithSchemas is like ParseExclusions, but uses known to suggest a correction for entries that
+// look like collection names written without the collection: prefix.
+func ParseExclusionsWithSchemas(entries []string, known collection.Schemas) ([]Exclusion, error) {
+	return ParseExclusionsAt(entries, ConfigPositions{}, known)
+}
+
+// ParseExclusionsAt is like ParseExclusionsWithSchemas, but reports the positions of the invalid entries given in
+// positions, for entries loaded from a file.
+func ParseExclusionsAt(entries []string, positions ConfigPositions, known collection.Schemas) ([]Exclusion, error) {
+	out, errs := parseExclusions(entries, positions, known)
+	if len(errs) > 0 {
+		return nil, multierror.Append(istiomultierror.New(), errs...).ErrorOrNil()
+	}
+	return out, nil
+}
+
+// parseExclusions returns the valid entries, and an error for each invalid one.
+func parseExclusions(entries []string, positions ConfigPositions, known collection.Schemas) ([]Exclusion, []error) {
+	if len(entries) > MaxExclusionEntries {
+		return nil, []error{fmt.Errorf("too many exclusion entries: %d (maximum %d)", len(entries), MaxExclusionEntries)}
+	}
+	var errs []error
+	out := make([]Exclusion, 0, len(entries))
+	for i, entry := range entries {
+		e, reason := parseExclusion(entry)
+		if reason != "" && looksLikeCollectionName(entry) {
+			reason = collectionNameReason(strings.TrimSpace(entry), known)
+		}
+		if reason != "" {
+			errs = append(errs, &ExclusionError{Index: i, Entry: truncateEntry(entry), Reason: reason,
+				Position: positions.entryPosition(entry)})
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, errs
+}
+
+// compileExclusions returns a matcher for entries. Entries that cannot be parsed are matched as exact kinds, so
+// that callers which cannot fail keep the behavior they had before exclusion entries were parsed; the errors for
+// them are reported by parseExclusions.
+func compileExclusions(entries []string) *ExclusionMatcher {
+	exclusions := make([]Exclusion, 0, len(entries))
+	for _, entry := range entries {
+		e, reason := parseExclusion(entry)
+		if reason != "" {
+			e = Exclusion{Entry: entry, Kind: entry, literal: true}
+		}
+		exclusions = append(exclusions, e)
+	}
+	return &ExclusionMatcher{exclusions: exclusions}
+}
+
+// parseExclusion parses a single entry, returning the reason it is invalid if it cannot be parsed.
+func parseExclusion(entry string) (Exclusion, string) {
+	if len(entry) > MaxExclusionEntryLength {
+		return Exclusion{}, fmt.Sprintf("entry is %d characters long (maximum %d)", len(entry), MaxExclusionEntryLength)
+	}
+	e := Exclusion{Entry: entry}
+	s := strings.TrimSpace(entry)
+	if strings.HasPrefix(s, negationPrefix) {
+		e.Negated = true
+		s = strings.TrimSpace(strings.TrimPrefix(s, negationPrefix))
+	}
+	if s == "" {
+		return Exclusion{}, "entry is empty"
+	}
+
+	if strings.HasPrefix(s, collectionPrefix) {
+		name := strings.TrimPrefix(s, collectionPrefix)
+		if !collection.IsValidName(name) {
+			return Exclusion{}, fmt.Sprintf("%q is not a valid collection name", name)
+		}
+		e.Collection = collection.NewName(name)
+		if current, ok := resolveAlias(e.Collection); ok {
+			e.Collection, e.alias = current, e.Collection
+		}
+		return e, ""
+	}
+
+	parts := strings.Split(s, "/")
+	for _, p := range parts {
+		if reason := validateSegment(p); reason != "" {
+			return Exclusion{}, reason
+		}
+	}
+	switch len(parts) {
+	case 1:
+		e.Group, e.Version, e.Kind = anySegment, anySegment, parts[0]
+	case 2:
+		group, kind, err := ParseTypeKey(s)
+		if err != nil {
+			return Exclusion{}, err.Error()
+		}
+		e.Group, e.Version, e.Kind = group, anySegment, kind
+	case 3:
+		e.Group, e.Version, e.Kind = normalizeGroup(parts[0]), parts[1], parts[2]
+	default:
+		return Exclusion{}, "expected Kind, group/Kind, group/version/Kind or collection:name"
+	}
+	e.Group, e.Version, e.Kind = compactGlob(e.Group), compactGlob(e.Version), compactGlob(e.Kind)
+	e.equivalents = movedEquivalents(e)
+	for _, eq := range e.equivalents {
+		e.Equivalents = append(e.Equivalents, eq.String())
+	}
+	return e, ""
+}
+
+// looksLikeCollectionName returns true if entry, which could not be parsed, has the shape of a collection name
+// such as k8s/core/v1/pods.
+func looksLikeCollectionName(entry string) bool {
+	if len(entry) > MaxExclusionEntryLength {
+		return false
+	}
+	s := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(entry), negationPrefix))
+	return strings.Count(s, "/") >= 2 && collection.IsValidName(s)
+}
+
+// collectionNameReason explains why entry, which looks like a collection name, is invalid. If known has a
+// collection of that name, the collection: form is suggested; otherwise, if the last segment is the plural of a
+// known kind, the group/version/Kind form of that kind is suggested.
+func collectionNameReason(entry string, known collection.Schemas) string {
+	prefix := ""
+	if strings.HasPrefix(entry, negationPrefix) {
+		prefix = negationPrefix
+		entry = strings.TrimSpace(strings.TrimPrefix(entry, negationPrefix))
+	}
+	const reason = "looks like a collection name; expected Kind, group/Kind, group/version/Kind or collection:name"
+	if _, ok := known.Find(entry); ok {
+		return fmt.Sprintf("%s; did you mean %s%s%s?", reason, prefix, collectionPrefix, entry)
+	}
+	plural := entry[strings.LastIndex(entry, "/")+1:]
+	for _, s := range known.All() {
+		if res := s.Resource(); strings.EqualFold(res.Plural(), plural) {
+			group := res.Group()
+			if group == "" {
+				group = coreGroup
+			}
+			return fmt.Sprintf("%s; did you mean %s%s/%s/%s?", reason, prefix, group, res.Version(), res.Kind())
+		}
+	}
+	return reason
+}
+
+func validateSegment(s string) string {
+	if s == "" {
+		return fmt.Sprintf("empty segment; use %q for the core group", coreGroup)
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '*', c == '?':
+		default:
+			return fmt.Sprintf("invalid character %q", c)
+		}
+	}
+	return ""
+}
+
+// truncateEntry shortens overly long entries so that they do not flood error messages.
+func truncateEntry(entry string) string {
+	const max = 64
+	if len(entry) <= max {
+		return entry
+	}
+	return entry[:max] + "..."
+}
+
+// ExclusionMatcher matches resources against a list of exclusions. Entries are evaluated in order and the last
+// matching entry decides, so a later negation re-includes what an earlier entry excluded. Each lookup is linear
+// in the number of entries.
+type ExclusionMatcher struct {
+	exclusions []Exclusion
+}
+
+// NewExclusionMatcher returns a matcher for the given exclusions.
+func NewExclusionMatcher(exclusions []Exclusion) *ExclusionMatcher {
+	return &ExclusionMatcher{exclusions: append([]Exclusion{}, exclusions...)}
+}
+
+// Matches returns true if the kind at the given group and version is excluded.
+func (m *ExclusionMatcher) Matches(group, version, kind string) bool {
+	return m.match("", group, version, kind)
+}
+
+// MatchesSchema returns true if the collection is excluded.
+func (m *ExclusionMatcher) MatchesSchema(s collection.Schema) bool {
+	res := s.Resource()
+	return m.match(s.Name(), res.Group(), res.Version(), res.Kind())
+}
+
+func (m *ExclusionMatcher) match(name collection.Name, group, version, kind string) bool {
+	if i := m.decisive(name, group, version, kind); i >= 0 {
+		return !m.exclusions[i].Negated
+	}
+	return false
+}
+
+// decisive returns the index of the entry that decides whether the resource is excluded, or -1 if no entry
+// matches it.
+func (m *ExclusionMatcher) decisive(name collection.Name, group, version, kind string) int {
+	if m == nil {
+		return -1
+	}
+	for i := len(m.exclusions) - 1; i >= 0; i-- {
+		if m.exclusions[i].matches(name, group, version, kind) {
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchStep records the evaluation of a single exclusion entry.
+type MatchStep struct {
+	Exclusion Exclusion `json:"exclusion"`
+	Matched   bool      `json:"matched"`
+
+	// Equivalent is the pattern the resource matched through, if it only matched the entry because its kind moved
+	// to or from another group or version; see MovedKinds.
+	Equivalent string `json:"equivalent,omitempty"`
+}
+
+// MatchTrace explains how a matcher decided whether a resource is excluded.
+type MatchTrace struct {
+	// Steps holds every entry, in the order they were configured.
+	Steps []MatchStep `json:"steps"`
+
+	// Decisive is the index in Steps of the last matching entry, which decided the outcome, or -1 if no entry
+	// matched.
+	Decisive int `json:"decisive"`
+
+	Excluded bool `json:"excluded"`
+
+	// Provenance is set by CollectionFilter.Explain when the decisive entry was not configured by the user.
+	Provenance *EffectiveExclusion `json:"provenance,omitempty"`
+}
+
+// DecisiveEntry returns the entry that decided the outcome, if any entry matched.
+func (t MatchTrace) DecisiveEntry() (Exclusion, bool) {
+	if t.Decisive < 0 {
+		return Exclusion{}, false
+	}
+	return t.Steps[t.Decisive].Exclusion, true
+}
+
+// Explain evaluates every entry against the kind in the given group. Since no version or collection is given,
+// entries restricted to a version or to a collection do not match; use ExplainSchema for those.
+func (m *ExclusionMatcher) Explain(group, kind string) MatchTrace {
+	return m.explain("", group, "", kind)
+}
+
+// ExplainSchema evaluates every entry against the collection.
+func (m *ExclusionMatcher) ExplainSchema(s collection.Schema) MatchTrace {
+	res := s.Resource()
+	return m.explain(s.Name(), res.Group(), res.Version(), res.Kind())
+}
+
+func (m *ExclusionMatcher) explain(name collection.Name, group, version, kind string) MatchTrace {
+	t := MatchTrace{Decisive: -1}
+	if m == nil {
+		return t
+	}
+	for i, e := range m.exclusions {
+		matched, via := e.matchVia(name, group, version, kind)
+		t.Steps = append(t.Steps, MatchStep{Exclusion: e, Matched: matched, Equivalent: via})
+		if matched {
+			t.Decisive = i
+		}
+	}
+	t.Excluded = t.Decisive >= 0 && !m.exclusions[t.Decisive].Negated
+	return t
+}
+
+// compactGlob collapses runs of '*', which match the same strings as a single '*'.
+func compactGlob(pattern string) string {
+	if !strings.Contains(pattern, "**") {
+		return pattern
+	}
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '*' && i > 0 && pattern[i-1] == '*' {
+			continue
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
+
+// globMatch returns true if s matches pattern, where '*' matches any sequence of bytes and '?' matches a single
+// byte. On a mismatch it only backtracks to the most recent '*', so it runs in O(len(pattern)*len(s)) time rather
+// than exponential time.
+func globMatch(pattern, s string) bool {
+	p, i := 0, 0
+	star, next := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, i
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case star >= 0:
+			next++
+			p, i = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
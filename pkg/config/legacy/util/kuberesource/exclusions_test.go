@@ -0,0 +1,248 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestParseExclusions(t *testing.T) {
+	cases := []struct {
+		entry    string
+		expected string
+		err      string
+	}{
+		{entry: "Pod", expected: "*/*/Pod"},
+		{entry: " Pod ", expected: "*/*/Pod"},
+		{entry: "core/Pod", expected: "core/*/Pod"},
+		{entry: "networking.istio.io/Gateway", expected: "networking.istio.io/*/Gateway"},
+		{entry: "networking.istio.io/v1alpha3/Gateway", expected: "networking.istio.io/v1alpha3/Gateway"},
+		{entry: "networking.k8s.io/*", expected: "networking.k8s.io/*/*"},
+		{entry: "*Policy", expected: "*/*/*Policy"},
+		{entry: "**Po***licy", expected: "*/*/*Po*licy"},
+		{entry: "!Secret", expected: "!*/*/Secret"},
+		{entry: "collection:k8s/core/v1/pods", expected: "collection:k8s/core/v1/pods"},
+		{entry: "", err: "entry is empty"},
+		{entry: "!", err: "entry is empty"},
+		{entry: "/Pod", err: `empty segment; use "core" for the core group`},
+		{entry: "core//Pod", err: "empty segment"},
+		{entry: "k8s/core/v1/pods", err: "looks like a collection name; expected Kind, group/Kind"},
+		{entry: "Pöd", err: `invalid character 'ö'`},
+		{entry: "Pod\x00", err: "invalid character"},
+		{entry: "collection:", err: "is not a valid collection name"},
+		{entry: strings.Repeat("a", MaxExclusionEntryLength+1), err: "characters long"},
+	}
+	for _, c := range cases {
+		t.Run(c.entry, func(t *testing.T) {
+			g := NewWithT(t)
+			exclusions, err := ParseExclusions([]string{c.entry})
+			if c.err != "" {
+				g.Expect(err).NotTo(BeNil())
+				g.Expect(err.Error()).To(ContainSubstring(c.err))
+				return
+			}
+			g.Expect(err).To(BeNil())
+			g.Expect(exclusions).To(HaveLen(1))
+			g.Expect(exclusions[0].Entry).To(Equal(c.entry))
+			g.Expect(exclusions[0].String()).To(Equal(c.expected))
+		})
+	}
+}
+
+func TestParseExclusions_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := ParseExclusions([]string{"Pod", "/Pod", "Service", "a/b/c/d"})
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring(`invalid exclusion entry 1 "/Pod"`))
+	g.Expect(err.Error()).To(ContainSubstring(`invalid exclusion entry 3 "a/b/c/d"`))
+
+	_, err = ParseExclusions(make([]string, MaxExclusionEntries+1))
+	g.Expect(err).To(MatchError("too many exclusion entries: 1025 (maximum 1024)"))
+
+	_, err = ParseExclusions([]string{strings.Repeat("x", 10000)})
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(len(err.Error())).To(BeNumerically("<", 200))
+}
+
+func TestParseExclusionsWithSchemas(t *testing.T) {
+	known := collection.SchemasFor(testPod, testDeployment)
+	cases := []struct {
+		entry string
+		err   string
+	}{
+		{entry: "k8s/core/v1/pods", err: "did you mean collection:k8s/core/v1/pods?"},
+		{entry: "!k8s/apps/v1/deployments", err: "did you mean !collection:k8s/apps/v1/deployments?"},
+		{entry: "istio/core/v1/pods", err: "did you mean core/v1/Pod?"},
+		{entry: "apps/v1/deployments_v2", err: "looks like a collection name"},
+		{entry: "istio/networking/v1alpha3/gateways", err: "looks like a collection name"},
+	}
+	for _, c := range cases {
+		t.Run(c.entry, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := ParseExclusionsWithSchemas([]string{c.entry}, known)
+			g.Expect(err).NotTo(BeNil())
+			g.Expect(err.Error()).To(ContainSubstring(c.err))
+			if !strings.Contains(c.err, "did you mean") {
+				g.Expect(err.Error()).NotTo(ContainSubstring("did you mean"))
+			}
+		})
+	}
+
+	// Entries that parse are unaffected, even if they name a known collection's plural.
+	exclusions, err := ParseExclusionsWithSchemas([]string{"core/v1/pods"}, known)
+	g := NewWithT(t)
+	g.Expect(err).To(BeNil())
+	g.Expect(exclusions[0].String()).To(Equal("core/v1/pods"))
+}
+
+func TestExclusionMatcher(t *testing.T) {
+	g := NewWithT(t)
+
+	exclusions, err := ParseExclusions([]string{
+		"networking.istio.io/*",
+		"!networking.istio.io/v1alpha3/Gateway",
+		"*Policy",
+		"core/Pod",
+		"Secret",
+		"!core/Secret",
+		"collection:k8s/apps/v1/deployments",
+	})
+	g.Expect(err).To(BeNil())
+	m := NewExclusionMatcher(exclusions)
+
+	g.Expect(m.Matches("networking.istio.io", "v1alpha3", "VirtualService")).To(BeTrue())
+	g.Expect(m.Matches("networking.istio.io", "v1alpha3", "Gateway")).To(BeFalse())
+	g.Expect(m.Matches("networking.istio.io", "v1beta1", "Gateway")).To(BeTrue())
+	g.Expect(m.Matches("security.istio.io", "v1beta1", "AuthorizationPolicy")).To(BeTrue())
+	g.Expect(m.Matches("", "v1", "Pod")).To(BeTrue())
+	g.Expect(m.Matches("metrics.k8s.io", "v1beta1", "Pod")).To(BeFalse())
+	g.Expect(m.Matches("", "v1", "Secret")).To(BeFalse())
+	g.Expect(m.Matches("example.com", "v1", "Secret")).To(BeTrue())
+	g.Expect(m.Matches("apps", "v1", "Deployment")).To(BeFalse())
+	g.Expect(m.MatchesSchema(testDeployment)).To(BeTrue())
+	g.Expect(m.MatchesSchema(testPod)).To(BeTrue())
+	g.Expect(m.MatchesSchema(testService)).To(BeFalse())
+
+	var nilMatcher *ExclusionMatcher
+	g.Expect(nilMatcher.Matches("", "v1", "Pod")).To(BeFalse())
+}
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "Pod", true},
+		{"P?d", "Pod", true},
+		{"P?d", "Pd", false},
+		{"*Policy", "AuthorizationPolicy", true},
+		{"*Policy", "PolicyFoo", false},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "aXbY", false},
+		{"*a*", "bab", true},
+	}
+	for _, c := range cases {
+		t.Run(c.pattern+"|"+c.s, func(t *testing.T) {
+			NewWithT(t).Expect(globMatch(c.pattern, c.s)).To(Equal(c.expected))
+		})
+	}
+}
+
+func TestGlobMatch_Pathological(t *testing.T) {
+	g := NewWithT(t)
+
+	// A naive recursive matcher is exponential in the number of stars for this input.
+	pattern := strings.Repeat("a*", MaxExclusionEntryLength/2-1) + "b"
+	exclusions, err := ParseExclusions([]string{pattern})
+	g.Expect(err).To(BeNil())
+	m := NewExclusionMatcher(exclusions)
+
+	start := time.Now()
+	g.Expect(m.Matches("", "v1", strings.Repeat("a", MaxExclusionEntryLength))).To(BeFalse())
+	g.Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+}
+
+func TestExclusionMatcher_Explain(t *testing.T) {
+	g := NewWithT(t)
+
+	exclusions, err := ParseExclusions([]string{"*Policy", "Secret", "!security.istio.io/AuthorizationPolicy", "networking.istio.io/v1alpha3/*"})
+	g.Expect(err).To(BeNil())
+	m := NewExclusionMatcher(exclusions)
+
+	trace := m.Explain("security.istio.io", "AuthorizationPolicy")
+	g.Expect(trace.Excluded).To(BeFalse())
+	g.Expect(trace.Decisive).To(Equal(2))
+	g.Expect(trace.Steps).To(HaveLen(4))
+	matched := make([]bool, 0, len(trace.Steps))
+	for _, s := range trace.Steps {
+		matched = append(matched, s.Matched)
+	}
+	g.Expect(matched).To(Equal([]bool{true, false, true, false}))
+	e, ok := trace.DecisiveEntry()
+	g.Expect(ok).To(BeTrue())
+	g.Expect(e.Entry).To(Equal("!security.istio.io/AuthorizationPolicy"))
+
+	trace = m.Explain("security.istio.io", "PeerAuthenticationPolicy")
+	g.Expect(trace.Excluded).To(BeTrue())
+	g.Expect(trace.Decisive).To(Equal(0))
+
+	// Without a version, version-specific entries do not match.
+	trace = m.Explain("networking.istio.io", "Gateway")
+	g.Expect(trace.Decisive).To(Equal(-1))
+	g.Expect(trace.Excluded).To(BeFalse())
+	_, ok = trace.DecisiveEntry()
+	g.Expect(ok).To(BeFalse())
+	g.Expect(m.ExplainSchema(testKubeGateway).Excluded).To(BeTrue())
+}
+
+func TestFilterReport_MatchedEntry(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testAuthzPolicy, testVirtualService, testSecret)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("*Policy", "!security.istio.io/AuthorizationPolicy", "networking.istio.io/*", KindService),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}))
+	g.Expect(err).To(BeNil())
+
+	entry := func(s collection.Schema) ReportEntry {
+		e, _ := result.Report.Entry(s.Name())
+		return e
+	}
+	g.Expect(entry(testAuthzPolicy).Decision).To(Equal(Decision{
+		Reason:       ReasonEnabled,
+		MatchedEntry: "!security.istio.io/AuthorizationPolicy",
+	}))
+	g.Expect(entry(testVirtualService).Decision).To(Equal(Decision{
+		Disabled:     true,
+		Reason:       ReasonExcludedKind,
+		MatchedEntry: "networking.istio.io/*",
+	}))
+	g.Expect(entry(testService).Decision).To(Equal(Decision{
+		Reason:       ReasonRequiredForServiceDiscovery,
+		MatchedEntry: KindService,
+	}))
+	g.Expect(entry(testSecret).Decision).To(Equal(Decision{Reason: ReasonEnabled}))
+}
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Finding is an excluded kind for which objects already exist in the cluster, which usually means the
+// exclusion is a mistake: those objects would silently stop being processed.
+type Finding struct {
+	GroupKind schema.GroupKind `json:"groupKind"`
+
+	// Count is the number of existing objects of the kind.
+	Count int `json:"count"`
+
+	// Entry is the exclusion entry that excludes the kind.
+	Entry string `json:"entry"`
+
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return f.Message
+}
+
+// CheckExclusionsAgainstExisting returns a Finding for every kind in existingKinds with objects that cfg
+// excludes, sorted by group and kind. existingKinds holds the number of existing objects per kind, typically
+// from an audit of the cluster; kinds with a zero count are ignored. Kinds kept enabled by cfg.Features are
+// not excluded. As in ExclusionMatcher.Explain, entries restricted to a version or collection do not exclude
+// a whole kind and are not reported.
+func CheckExclusionsAgainstExisting(cfg ExclusionConfig, existingKinds map[schema.GroupKind]int) []Finding {
+	required := make(map[string]struct{})
+	for _, gk := range FeatureRequiredKinds(cfg.Features) {
+		required[TypeKey(gk.Group, gk.Kind)] = struct{}{}
+	}
+
+	m := compileExclusions(cfg.ExcludedResourceKinds)
+	var findings []Finding
+	for gk, count := range existingKinds {
+		if count <= 0 {
+			continue
+		}
+		if _, ok := required[TypeKey(gk.Group, gk.Kind)]; ok {
+			continue
+		}
+		i := m.decisive("", gk.Group, "", gk.Kind)
+		if i < 0 || m.exclusions[i].Negated {
+			continue
+		}
+		entry := m.exclusions[i].Entry
+		findings = append(findings, Finding{
+			GroupKind: gk,
+			Count:     count,
+			Entry:     entry,
+			Message:   fmt.Sprintf("%s is excluded by %q, but %d %s in the cluster", DisplayGroupKind(gk.Group, gk.Kind), entry, count, objectsExist(count)),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i].GroupKind, findings[j].GroupKind
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Kind < b.Kind
+	})
+	return findings
+}
+
+func objectsExist(n int) string {
+	if n == 1 {
+		return "object already exists"
+	}
+	return "objects already exist"
+}
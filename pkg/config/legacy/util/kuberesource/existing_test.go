@@ -0,0 +1,70 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCheckExclusionsAgainstExisting(t *testing.T) {
+	g := NewWithT(t)
+
+	virtualServices := k8sschema.GroupKind{Group: "networking.istio.io", Kind: "VirtualService"}
+	gateways := k8sschema.GroupKind{Group: "networking.istio.io", Kind: "Gateway"}
+	sidecars := k8sschema.GroupKind{Group: "networking.istio.io", Kind: "Sidecar"}
+	policies := k8sschema.GroupKind{Group: "security.istio.io", Kind: "AuthorizationPolicy"}
+	services := k8sschema.GroupKind{Kind: KindService}
+	secrets := k8sschema.GroupKind{Kind: KindSecret}
+
+	cfg := ExclusionConfig{
+		ExcludedResourceKinds: []string{"networking.istio.io/*", "!Gateway", "security.istio.io/v1beta1/AuthorizationPolicy",
+			KindService, KindSecret},
+		Features: FeatureRequirements{ServiceDiscovery: true},
+	}
+	findings := CheckExclusionsAgainstExisting(cfg, map[k8sschema.GroupKind]int{
+		virtualServices: 12000,
+		sidecars:        1,
+		gateways:        3,
+		policies:        5,
+		services:        40,
+		secrets:         0,
+	})
+	g.Expect(findings).To(Equal([]Finding{
+		{
+			GroupKind: sidecars,
+			Count:     1,
+			Entry:     "networking.istio.io/*",
+			Message:   `networking.istio.io/Sidecar is excluded by "networking.istio.io/*", but 1 object already exists in the cluster`,
+		},
+		{
+			GroupKind: virtualServices,
+			Count:     12000,
+			Entry:     "networking.istio.io/*",
+			Message: `networking.istio.io/VirtualService is excluded by "networking.istio.io/*", but 12000 objects already ` +
+				`exist in the cluster`,
+		},
+	}))
+
+	// Without service discovery, the excluded Services are reported; Secrets still have no objects.
+	cfg.Features = FeatureRequirements{}
+	findings = CheckExclusionsAgainstExisting(cfg, map[k8sschema.GroupKind]int{services: 40, secrets: 0})
+	g.Expect(findings).To(HaveLen(1))
+	g.Expect(findings[0].Message).To(Equal(`core/Service is excluded by "Service", but 40 objects already exist in the cluster`))
+
+	g.Expect(CheckExclusionsAgainstExisting(cfg, nil)).To(BeEmpty())
+}
@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// FeatureRequirements describes the control plane features that need builtin kinds to be watched,
+// regardless of whether those kinds are excluded.
+type FeatureRequirements struct {
+	// ServiceDiscovery requires the kinds used by the Kubernetes service registry.
+	ServiceDiscovery bool `json:"serviceDiscovery,omitempty"`
+
+	// AmbientEnabled requires the kinds used by ambient mesh.
+	AmbientEnabled bool `json:"ambientEnabled,omitempty"`
+
+	// InjectionEnabled requires the kinds used by sidecar injection, which reads the injection template and
+	// mesh config from ConfigMaps.
+	InjectionEnabled bool `json:"injectionEnabled,omitempty"`
+}
+
+func (f FeatureRequirements) union(o FeatureRequirements) FeatureRequirements {
+	return FeatureRequirements{
+		ServiceDiscovery: f.ServiceDiscovery || o.ServiceDiscovery,
+		AmbientEnabled:   f.AmbientEnabled || o.AmbientEnabled,
+		InjectionEnabled: f.InjectionEnabled || o.InjectionEnabled,
+	}
+}
+
+// IsRequired returns true if res must remain enabled for any of the enabled features.
+func (f FeatureRequirements) IsRequired(res resource.Schema) bool {
+	_, ok := f.requiredReason(res)
+	return ok
+}
+
+// requiredReason returns the reason res is re-enabled by the first feature that requires it.
+func (f FeatureRequirements) requiredReason(res resource.Schema) (Reason, bool) {
+	if f.ServiceDiscovery && IsRequiredForServiceDiscovery(res) {
+		return ReasonRequiredForServiceDiscovery, true
+	}
+	if f.AmbientEnabled && IsRequiredForAmbient(res) {
+		return ReasonRequiredForAmbient, true
+	}
+	if f.InjectionEnabled && IsRequiredForInjection(res) {
+		return ReasonRequiredForInjection, true
+	}
+	return "", false
+}
+
+var (
+	ambientTypesMu sync.RWMutex
+
+	// ambientTypes are the kinds watched by ambient mesh.
+	ambientTypes = builtinAmbientTypes()
+)
+
+// builtinAmbientTypes returns the builtin kinds watched by ambient mesh. Unlike sidecar mode, ambient relies
+// on EndpointSlice and does not need Node.
+func builtinAmbientTypes() map[string]struct{} {
+	return map[string]struct{}{
+		TypeKey("", "Service"):                       {},
+		TypeKey("", "Namespace"):                     {},
+		TypeKey("", "Pod"):                           {},
+		TypeKey("", "Secret"):                        {},
+		TypeKey("discovery.k8s.io", "EndpointSlice"): {},
+	}
+}
+
+// RegisterAmbientType adds the given group/kind to the set of kinds required by ambient mesh. Registration fails
+// with ErrRegistriesFrozen once a filter has been applied.
+func RegisterAmbientType(group, kind string) error {
+	return register(func() {
+		ambientTypesMu.Lock()
+		defer ambientTypesMu.Unlock()
+		ambientTypes[TypeKey(group, kind)] = struct{}{}
+	})
+}
+
+// IsRequiredForAmbient returns true if res is watched by ambient mesh.
+func IsRequiredForAmbient(res resource.Schema) bool {
+	ambientTypesMu.RLock()
+	defer ambientTypesMu.RUnlock()
+	_, ok := ambientTypes[TypeKey(res.Group(), res.Kind())]
+	return ok
+}
+
+// injectionTypes are the builtin kinds read by sidecar injection.
+var injectionTypes = map[string]struct{}{
+	TypeKey("", KindConfigMap): {},
+}
+
+// IsRequiredForInjection returns true if res is read by sidecar injection.
+func IsRequiredForInjection(res resource.Schema) bool {
+	_, ok := injectionTypes[TypeKey(res.Group(), res.Kind())]
+	return ok
+}
+
+// ServiceDiscoveryRequiredKinds returns the kinds required by service discovery, sorted by group and kind.
+func ServiceDiscoveryRequiredKinds() []schema.GroupKind {
+	return sortedGroupKinds(knownTypeKeys())
+}
+
+// FeatureRequiredKinds returns the kinds that remain enabled for the features in f even if they are excluded,
+// sorted by group and kind.
+func FeatureRequiredKinds(f FeatureRequirements) []schema.GroupKind {
+	keys := make(map[string]struct{})
+	if f.ServiceDiscovery {
+		for k := range knownTypeKeys() {
+			keys[k] = struct{}{}
+		}
+	}
+	if f.AmbientEnabled {
+		ambientTypesMu.RLock()
+		for k := range ambientTypes {
+			keys[k] = struct{}{}
+		}
+		ambientTypesMu.RUnlock()
+	}
+	if f.InjectionEnabled {
+		for k := range injectionTypes {
+			keys[k] = struct{}{}
+		}
+	}
+	return sortedGroupKinds(keys)
+}
+
+func sortedGroupKinds(keys map[string]struct{}) []schema.GroupKind {
+	out := make([]schema.GroupKind, 0, len(keys))
+	for k := range keys {
+		group, kind, _ := ParseTypeKey(k)
+		out = append(out, schema.GroupKind{Group: group, Kind: kind})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Group != out[j].Group {
+			return out[i].Group < out[j].Group
+		}
+		return out[i].Kind < out[j].Kind
+	})
+	return out
+}
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// TestRequiredKindsDoc snapshots the kinds required by each feature, so that changes to them show up in review.
+func TestRequiredKindsDoc(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("| Feature | Group | Kind |\n|---|---|---|\n")
+	write := func(feature string, kinds []schema.GroupKind) {
+		for _, gk := range kinds {
+			group := gk.Group
+			if group == "" {
+				group = coreGroup
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", feature, group, gk.Kind)
+		}
+	}
+	write("ServiceDiscovery", ServiceDiscoveryRequiredKinds())
+	write("Ambient", FeatureRequiredKinds(FeatureRequirements{AmbientEnabled: true}))
+	write("Injection", FeatureRequiredKinds(FeatureRequirements{InjectionEnabled: true}))
+	testutil.CompareContent(b.Bytes(), "testdata/required_kinds.golden", t)
+}
+
+func TestFeatureRequiredKinds(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(FeatureRequiredKinds(FeatureRequirements{})).To(BeEmpty())
+	g.Expect(FeatureRequiredKinds(FeatureRequirements{ServiceDiscovery: true})).To(Equal(ServiceDiscoveryRequiredKinds()))
+
+	both := FeatureRequiredKinds(FeatureRequirements{ServiceDiscovery: true, AmbientEnabled: true})
+	g.Expect(both).To(Equal([]schema.GroupKind{
+		{Kind: KindNamespace},
+		{Kind: KindNode},
+		{Kind: KindPod},
+		{Kind: KindSecret},
+		{Kind: KindService},
+		{Group: "discovery.k8s.io", Kind: KindEndpointSlice},
+	}))
+
+	// Every listed kind is recognized as required by the feature.
+	for _, gk := range ServiceDiscoveryRequiredKinds() {
+		g.Expect(IsRequiredForServiceDiscovery(newTestSchema(gk.Group, "v1", gk.Kind).Resource())).To(BeTrue())
+	}
+}
+
+func TestInjectionRequiresConfigMap(t *testing.T) {
+	configMap := newTestSchema("", "v1", KindConfigMap)
+	in := collection.SchemasFor(configMap, testDeployment)
+
+	cases := []struct {
+		name     string
+		features FeatureRequirements
+		reason   Reason
+	}{
+		{name: "injection on", features: FeatureRequirements{InjectionEnabled: true}, reason: ReasonRequiredForInjection},
+		{name: "injection off", features: FeatureRequirements{ServiceDiscovery: true}, reason: ReasonExcludedKind},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+				WithExcludedResourceKinds(KindConfigMap), WithFeatureRequirements(c.features))
+			g.Expect(err).To(BeNil())
+			g.Expect(reasonOf(result, configMap.Name())).To(Equal(c.reason))
+		})
+	}
+}
@@ -0,0 +1,501 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/version"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/util/istiomultierror"
+)
+
+// CollectionFilter is a compiled filter configuration that can be applied to collection.Schemas.
+type CollectionFilter struct {
+	providers    InputProviders
+	requiredCols collection.Names
+	opts         *filterOptions
+
+	// upstream is the set of collections needed as inputs by requiredCols.
+	upstream map[collection.Name]struct{}
+
+	exclusions *ExclusionMatcher
+
+	// err records invalid exclusion entries, which Apply reports.
+	err error
+
+	// aliased is requiredCols as given, before aliases were resolved.
+	aliased collection.Names
+
+	// subset, if not nil, selects the schemas the filter applies to; see ApplyForSubset.
+	subset func(collection.Schema) bool
+}
+
+// NewCollectionFilter compiles a filter which disables collections not upstream of requiredCols, as well as
+// any collections excluded through opts.
+func NewCollectionFilter(providers InputProviders, requiredCols collection.Names, opts ...FilterOption) *CollectionFilter {
+	// Get upstream collections in terms of transformer configuration
+	// Required collections are specified in terms of transformer outputs, but we care here about the corresponding inputs
+	providers = orNoProviders(providers)
+	o := newFilterOptions(opts)
+	if o.onlyOutputs != nil {
+		providers, requiredCols = orNoProviders(o.onlyOutputsProviders), o.onlyOutputs
+	}
+	exclusions, err := compileOptions(o)
+	resolved := resolveAliases(requiredCols)
+	return &CollectionFilter{
+		providers:    providers,
+		requiredCols: resolved,
+		aliased:      requiredCols.Clone(),
+		opts:         o,
+		upstream:     providers.RequiredInputsFor(resolved),
+		exclusions:   exclusions,
+		err:          err,
+	}
+}
+
+// FilterCollections is a helper that compiles a CollectionFilter and applies it to in.
+func FilterCollections(in collection.Schemas, providers InputProviders, requiredCols collection.Names,
+	opts ...FilterOption) (*FilterResult, error) {
+	return NewCollectionFilter(providers, requiredCols, opts...).Apply(in)
+}
+
+// Apply filters in, returning the resulting schemas along with a report of every decision.
+//
+// Schemas that remain enabled are carried into the result as the same instances found in in, so consumers
+// may key caches by schema identity. Only schemas disabled by the filter are replaced, by disabled copies.
+// If no schema is disabled, in itself is returned as the result's Schemas.
+func (f *CollectionFilter) Apply(in collection.Schemas) (*FilterResult, error) {
+	return f.ApplyContext(context.Background(), in)
+}
+
+// ApplyContext is Apply with a context for the availability probe. Once ctx is done, the remaining probes are
+// skipped: the collections that were not probed yet are undetermined, and are decided according to the probe
+// failure policy with ReasonUndetermined. Filters without an availability probe do not use ctx.
+func (f *CollectionFilter) ApplyContext(ctx context.Context, in collection.Schemas) (*FilterResult, error) {
+	result, errs := f.applyLenient(ctx, in)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return result, nil
+}
+
+// applyLenient filters in even if the configuration is invalid, returning the errors Apply fails with, in the
+// order it checks for them, along with the result. It is the only implementation of filtering: Apply fails with
+// the first error, while the legacy DisableExcludedCollections functions, which cannot fail, log them and use the
+// result, so that the two cannot decide differently.
+func (f *CollectionFilter) applyLenient(ctx context.Context, in collection.Schemas) (*FilterResult, []error) {
+	result := f.apply(ctx, in)
+	errs := f.configErrors(in, result.index)
+	if err := f.applyBudget(result); err != nil {
+		errs = append(errs, err)
+	}
+	return result, errs
+}
+
+// configErrors returns the errors in the configuration of f that Apply fails with for in, other than the budget,
+// in the order Apply checks for them. idx is the kind index of in. ApplyDelta checks the same errors, so that a
+// delta cannot reach a configuration Apply rejects.
+func (f *CollectionFilter) configErrors(in collection.Schemas, idx *KindIndex) []error {
+	var errs []error
+	if f.err != nil {
+		errs = append(errs, f.err)
+	}
+	if swErrs := f.opts.statusWriterErrors(in); len(swErrs) > 0 {
+		errs = append(errs, multierror.Append(istiomultierror.New(), swErrs...).ErrorOrNil())
+	}
+	if nsErrs := f.opts.namespaceScopeErrors(in); len(nsErrs) > 0 {
+		errs = append(errs, multierror.Append(istiomultierror.New(), nsErrs...).ErrorOrNil())
+	}
+	if f.opts.conflictPolicy == ConflictStrict {
+		if cErrs := conflictErrors(f.opts.entryConflicts(idx)); len(cErrs) > 0 {
+			errs = append(errs, multierror.Append(istiomultierror.New(), cErrs...).ErrorOrNil())
+		}
+	}
+	return errs
+}
+
+// Decide evaluates the compiled configuration against a single schema, without building an output set, for
+// callers that enumerate schemas lazily. It makes the decision Apply makes for s, except for PreferNewestVersion,
+// which compares every version of a kind and so is only applied by Apply. Warnings are discarded, and with an
+// availability probe, availability is probed on every call.
+func (f *CollectionFilter) Decide(s collection.Schema) (Decision, error) {
+	if f.err != nil {
+		return Decision{}, f.err
+	}
+	freezeRegistries()
+	if full, expanded := ExpandCompact(s); expanded {
+		s = full
+	}
+	scratch := &FilterResult{availability: make(map[config.GroupVersionKind]bool)}
+	return f.evaluate(context.Background(), s, scratch), nil
+}
+
+func (f *CollectionFilter) apply(ctx context.Context, in collection.Schemas) *FilterResult {
+	freezeRegistries()
+	f.opts.warnLegacySemantics()
+	in = expandInput(in)
+	result := f.newResult(in, nil)
+
+	all := in.All()
+	decisions := make([]Decision, len(all))
+	for i, s := range all {
+		decisions[i] = f.evaluate(ctx, s, result)
+	}
+	if f.opts.preferNewestVersion {
+		supersedeOlderVersions(all, decisions)
+	}
+
+	f.build(in, decisions, result)
+	f.runReasonHooks(result, nil)
+	return result
+}
+
+// newResult returns a result for in with everything but the per-collection decisions filled in. idx is the kind
+// index of in, or nil to build it.
+func (f *CollectionFilter) newResult(in collection.Schemas, idx *KindIndex) *FilterResult {
+	snapshot := *f
+	if idx == nil {
+		idx = NewKindIndex(in)
+	}
+	warnings := validateExclusions(idx, f.providers, f.opts.excludedResourceKinds)
+	warnings = append(warnings, aliasWarnings(f.aliased, f.opts.excludedResourceKinds)...)
+	warnings = append(warnings, movedKindWarnings(idx, f.opts.excludedResourceKinds)...)
+	warnings = append(warnings, f.opts.endpointsModeWarnings()...)
+	warnings = append(warnings, f.opts.kubeVersionWarnings()...)
+	if f.opts.conflictPolicy == ConflictWarn {
+		warnings = append(warnings, conflictWarnings(f.opts.entryConflicts(idx))...)
+	}
+	return &FilterResult{
+		Report:           &FilterReport{},
+		Warnings:         warnings,
+		Fingerprint:      f.fingerprint(),
+		SchemaSet:        SchemaSetFingerprint(),
+		DefinitelyUnused: DefinitelyUnused(in, f.providers),
+		SelectorHints:    make(map[collection.Name]SelectorHint),
+		CollectionHints:  make(map[collection.Name]CollectionHint),
+		input:            in,
+		index:            idx,
+		availability:     make(map[config.GroupVersionKind]bool),
+		filter:           &snapshot,
+	}
+}
+
+// evaluate returns the decision for a single schema, recording availability and warnings in result.
+func (f *CollectionFilter) evaluate(ctx context.Context, s collection.Schema, result *FilterResult) Decision {
+	if f.passedThrough(s) {
+		return Decision{Disabled: s.IsDisabled(), Reason: ReasonPassedThrough}
+	}
+	if s.IsDisabled() && !f.opts.reenableInputDisabled {
+		return Decision{Disabled: true, Reason: ReasonAlreadyDisabled}
+	}
+	if !f.opts.inGroupScope(s) {
+		return Decision{Disabled: true, Reason: ReasonTrimmedByGroupScope}
+	}
+	d := f.applyAvailability(ctx, s, f.decide(s, result), result)
+	d = f.applyBaseline(s, d)
+	return f.applyLaziness(s.Resource().Kind(), d)
+}
+
+// build fills in the schemas, report and stats of result from the decisions made for each schema in in.
+func (f *CollectionFilter) build(in collection.Schemas, decisions []Decision, result *FilterResult) {
+	changed := false
+	resultBuilder := collection.NewSchemasBuilder()
+	for i, s := range in.All() {
+		d := decisions[i]
+		inputDisabled := s.IsDisabled()
+		switch {
+		case d.Reason == ReasonPassedThrough:
+			// Schemas outside the subset are added as they are.
+		case d.Disabled:
+			if !s.IsDisabled() {
+				s = s.Disable()
+				changed = true
+			}
+			if f.opts.compactDisabled {
+				var compacted bool
+				if s, compacted = compactDisabled(s); compacted {
+					changed = true
+				}
+			}
+		default:
+			if s.IsDisabled() {
+				s = enabledCopy(s)
+				changed = true
+			}
+			if hint, ok := f.opts.selectorHints[s.Resource().Kind()]; ok {
+				result.SelectorHints[s.Name()] = hint
+			}
+		}
+
+		result.Report.Entries = append(result.Report.Entries, ReportEntry{
+			Collection:    s.Name(),
+			Group:         s.Resource().Group(),
+			Version:       s.Resource().Version(),
+			Kind:          s.Resource().Kind(),
+			Decision:      d,
+			DiscoveryUse:  f.discoveryUse(s.Name(), d),
+			InputDisabled: inputDisabled,
+		})
+		_ = resultBuilder.Add(s)
+	}
+
+	if f.opts.features.ServiceDiscovery {
+		result.Report.EndpointsMode = f.opts.endpointsMode()
+	}
+	result.Schemas = resultBuilder.Build()
+	if !changed {
+		result.Schemas = in
+	}
+	if f.opts.compactDisabled && changed {
+		// Keeping the input would retain every full schema that compaction replaced.
+		result.input, result.inputCompacted = collection.Schemas{}, true
+		result.index = NewKindIndex(result.Schemas)
+	}
+	if f.opts.frozenResult {
+		result.Frozen = freeze(result.Schemas)
+	}
+	result.Stats = statsFor(result.Report, result.Schemas)
+	result.Warnings = append(result.Warnings, f.opts.namespaceWarnings(result.Report)...)
+	result.Warnings = append(result.Warnings, baselineWarnings(result.Report)...)
+	f.opts.positionWarnings(result.Warnings)
+	result.LazyCollections = result.lazyCollections()
+	f.applyCollectionHints(result)
+	f.applyNamespaceScope(result)
+	f.applyNamespaceExclusions(result)
+}
+
+// discoveryUse classifies the decision d for the named collection if it is kept for service discovery.
+func (f *CollectionFilter) discoveryUse(name collection.Name, d Decision) DiscoveryUse {
+	if d.Disabled || d.Reason != ReasonRequiredForServiceDiscovery {
+		return ""
+	}
+	if _, ok := f.upstream[name]; ok {
+		return KeptForDiscoveryAndPipeline
+	}
+	return KeptForDiscoveryOnly
+}
+
+// supersedeOlderVersions disables all but the newest enabled version of each group/kind.
+func supersedeOlderVersions(all []collection.Schema, decisions []Decision) {
+	newest := make(map[groupKind]int)
+	for i, s := range all {
+		if decisions[i].Disabled || decisions[i].Reason == ReasonPassedThrough {
+			continue
+		}
+		gk := groupKind{group: s.Resource().Group(), kind: s.Resource().Kind()}
+		j, ok := newest[gk]
+		if !ok || version.CompareKubeAwareVersionStrings(s.Resource().Version(), all[j].Resource().Version()) > 0 {
+			newest[gk] = i
+		}
+	}
+	for i, s := range all {
+		if decisions[i].Disabled || decisions[i].Reason == ReasonPassedThrough {
+			continue
+		}
+		if newest[groupKind{group: s.Resource().Group(), kind: s.Resource().Kind()}] != i {
+			decisions[i] = decisions[i].disabledFor(ReasonSupersededByNewerVersion)
+		}
+	}
+}
+
+// runReasonHooks invokes the registered reason hooks for the final decisions in result. If only is not nil,
+// hooks are only invoked for the collections in it.
+func (f *CollectionFilter) runReasonHooks(result *FilterResult, only map[collection.Name]struct{}) {
+	if len(f.opts.reasonHooks) == 0 {
+		return
+	}
+	for _, e := range result.Report.Entries {
+		if only != nil {
+			if _, ok := only[e.Collection]; !ok {
+				continue
+			}
+		}
+		s, _ := result.Schemas.Find(e.Collection.String())
+		for _, h := range f.opts.reasonHooks {
+			if h.reason == e.Reason {
+				h.fn(s, e.Decision)
+			}
+		}
+	}
+}
+
+// decide evaluates the compiled configuration against a single schema, recording warnings in result.
+func (f *CollectionFilter) decide(s collection.Schema, result *FilterResult) Decision {
+	var d Decision
+	if f.opts.legacy != nil {
+		d = f.legacyExclusionDecision(s, result)
+	} else {
+		d = f.exclusionDecision(s, result)
+	}
+
+	// Additionally, filter out any resources not upstream of required collections
+	res := s.Resource()
+	if _, ok := f.upstream[s.Name()]; !ok {
+		if reason, required := f.opts.requiredReason(res); required && f.opts.onlyOutputs != nil && !d.Disabled {
+			// OnlyForOutputs still watches what the enabled features need.
+			d.Reason = reason
+		} else if f.opts.isStatusWriter(s.Name()) && !d.Disabled {
+			d.Reason = ReasonKeptForStatus
+		} else {
+			d = d.disabledFor(ReasonNotUpstream)
+		}
+	}
+	return d
+}
+
+// exclusionDecision returns the decision for the exclusion entries and features, recording warnings in result.
+func (f *CollectionFilter) exclusionDecision(s collection.Schema, result *FilterResult) Decision {
+	d := Decision{Reason: ReasonEnabled}
+	res := s.Resource()
+	if i := f.exclusions.decisive(s.Name(), res.Group(), res.Version(), res.Kind()); i >= 0 {
+		e := f.exclusions.exclusions[i]
+		if !e.Negated {
+			// Found a matching exclude directive for this KubeResource. Disable the resource, unless it is
+			// needed for Service Discovery or another enabled feature.
+			if reason, ok := f.opts.requiredReason(res); ok {
+				d = Decision{Reason: reason}
+			} else {
+				d = d.disabledFor(ReasonExcludedKind)
+				if f.opts.discoveryOverrideWithheld(res) {
+					result.Warnings = append(result.Warnings, FilterWarning{
+						Code:       WarningDiscoveryImpact,
+						Entry:      e.Entry,
+						Collection: s.Name(),
+						Message: fmt.Sprintf("entry %s excludes %s, which is required for service discovery but not "+
+							"listed in the discovery override; service discovery may be incomplete", e.Entry, s.Name()),
+					})
+				}
+			}
+		}
+		d.MatchedEntry = e.Entry
+	}
+	return d
+}
+
+// compileOptions compiles the exclusion entries in o and validates the remaining options. Like invalid
+// exclusion entries, invalid options are reported by Apply.
+func compileOptions(o *filterOptions) (*ExclusionMatcher, error) {
+	exclusions := compileExclusions(o.excludedResourceKinds)
+	active := exclusions.exclusions[:0]
+	for i, e := range exclusions.exclusions {
+		e.Source = o.exclusionSources[i]
+		if !o.gatedOut(e.Entry) {
+			active = append(active, e)
+		}
+	}
+	exclusions.exclusions = active
+	errs := o.configErrors(collection.SchemasFor())
+	if len(errs) == 0 {
+		return exclusions, nil
+	}
+	return exclusions, multierror.Append(istiomultierror.New(), errs...).ErrorOrNil()
+}
+
+// fingerprint returns a stable hash of the normalized filter configuration and the builtin schema set. Exclusion
+// entries are hashed in order, since a later entry overrides an earlier one it contradicts.
+func (f *CollectionFilter) fingerprint() string {
+	required := f.requiredCols.Clone()
+	required.Sort()
+
+	hintKinds := make([]string, 0, len(f.opts.selectorHints))
+	for k := range f.opts.selectorHints {
+		hintKinds = append(hintKinds, k)
+	}
+	sort.Strings(hintKinds)
+
+	var groups []string
+	if f.opts.onlyGroups != nil {
+		groups = make([]string, 0, len(f.opts.onlyGroups))
+		for g := range f.opts.onlyGroups {
+			groups = append(groups, g)
+		}
+		sort.Strings(groups)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "schemaSet=%s\n", SchemaSetFingerprint())
+	fmt.Fprintf(h, "excluded=%s\n", strings.Join(f.opts.excludedResourceKinds, ","))
+	if len(f.opts.exclusionSources) > 0 {
+		fmt.Fprintf(h, "sources=%v\n", f.opts.exclusionSources)
+	}
+	fmt.Fprintf(h, "features=%+v\n", f.opts.features)
+	if f.opts.discoveryOverrideOnly != nil {
+		overrides := make([]string, 0, len(f.opts.discoveryOverrideOnly))
+		for k := range f.opts.discoveryOverrideOnly {
+			overrides = append(overrides, k)
+		}
+		sort.Strings(overrides)
+		fmt.Fprintf(h, "discoveryOverrideOnly=%s\n", strings.Join(overrides, ","))
+	}
+	if mode := f.opts.endpointsMode(); mode != EndpointsBoth {
+		fmt.Fprintf(h, "endpointsMode=%s\n", mode)
+	}
+	fmt.Fprintf(h, "required=%v\n", required)
+	if groups != nil {
+		fmt.Fprintf(h, "onlyGroups=%s\n", strings.Join(groups, ","))
+	}
+	for _, k := range hintKinds {
+		fmt.Fprintf(h, "hint=%s:%+v\n", k, f.opts.selectorHints[k])
+	}
+	if len(f.opts.lazyKinds) > 0 {
+		fmt.Fprintf(h, "lazy=%s\n", strings.Join(sortedKindSet(f.opts.lazyKinds), ","))
+		fmt.Fprintf(h, "referenced=%s\n", strings.Join(sortedKindSet(f.opts.referencedKinds), ","))
+	}
+	for _, n := range f.opts.collectionHintNames() {
+		fmt.Fprintf(h, "collectionHint=%s:%+v\n", n, f.opts.collectionHints[n])
+	}
+	if f.opts.namespaceScope != nil {
+		fmt.Fprintf(h, "namespaceScope=%v\n", f.opts.namespaceScope)
+	}
+	for _, ns := range f.opts.namespaceExclusionNamespaces() {
+		fmt.Fprintf(h, "namespaceExclusions=%s:%s\n", ns, strings.Join(f.opts.namespaceExclusions[ns], ","))
+	}
+	if len(f.opts.statusWriters) > 0 {
+		fmt.Fprintf(h, "statusWriters=%v\n", sortedCollectionNames(f.opts.statusWriters))
+	}
+	if f.opts.reviewedBaseline != nil {
+		fmt.Fprintf(h, "baseline=%v\n", sortedCollectionNames(f.opts.reviewedBaseline))
+	}
+	if f.opts.legacy != nil {
+		fmt.Fprintf(h, "legacy=%+v\n", *f.opts.legacy)
+	}
+	if f.opts.preferNewestVersion {
+		fmt.Fprintf(h, "preferNewestVersion=true\n")
+	}
+	if f.opts.reenableInputDisabled {
+		fmt.Fprintf(h, "reenableInputDisabled=true\n")
+	}
+	if f.opts.conflictPolicy != ConflictWarn {
+		fmt.Fprintf(h, "conflictPolicy=%d\n", f.opts.conflictPolicy)
+	}
+	if f.opts.onlyOutputs != nil {
+		fmt.Fprintf(h, "onlyOutputs=%v\n", f.opts.onlyOutputs)
+	}
+	if f.opts.enabledBudget != nil {
+		fmt.Fprintf(h, "budget=%d:%v\n", *f.opts.enabledBudget, f.opts.budgetPolicy)
+	}
+	for _, entry := range sortedGateEntries(f.opts.kubeVersionGates) {
+		fmt.Fprintf(h, "kubeVersionGate=%s:%+v:%t\n", entry, f.opts.kubeVersionGates[entry], f.opts.gatedOut(entry))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)[:8])
+}
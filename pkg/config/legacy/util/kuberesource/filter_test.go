@@ -0,0 +1,452 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestFilterCollections_Result(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	required := collection.Names{testService.Name(), testNode.Name(), testSecret.Name(), testDeployment.Name()}
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, required,
+		WithExcludedResourceKinds("Service", "Node", "Deployment"),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithSelectorHint("Secret", SelectorHint{FieldSelector: "type=kubernetes.io/tls"}),
+		WithSelectorHint("Pod", SelectorHint{LabelSelector: "app=foo"}))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(result.EnabledCollectionNames()).To(Equal(collection.Names{
+		testNode.Name(), testSecret.Name(), testService.Name(),
+	}))
+
+	expectedReasons := map[collection.Name]Reason{
+		testService.Name():       ReasonRequiredForServiceDiscovery,
+		testNode.Name():          ReasonRequiredForServiceDiscovery,
+		testSecret.Name():        ReasonEnabled,
+		testDeployment.Name():    ReasonExcludedKind,
+		testPod.Name():           ReasonNotUpstream,
+		testNamespace.Name():     ReasonNotUpstream,
+		testEndpointSlice.Name(): ReasonNotUpstream,
+	}
+	for name, reason := range expectedReasons {
+		r, ok := result.ReasonFor(name)
+		g.Expect(ok).To(BeTrue())
+		g.Expect(r).To(Equal(reason), string(name))
+	}
+	_, ok := result.ReasonFor("k8s/unknown/v1/foos")
+	g.Expect(ok).To(BeFalse())
+
+	hint, ok := result.SelectorHintFor(testSecret.Name())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hint.FieldSelector).To(Equal("type=kubernetes.io/tls"))
+	// Pod is disabled, so its hint is dropped.
+	_, ok = result.SelectorHintFor(testPod.Name())
+	g.Expect(ok).To(BeFalse())
+
+	g.Expect(result.Stats).To(Equal(FilterStats{
+		Total:    7,
+		Enabled:  3,
+		Disabled: 4,
+		ByReason: map[Reason]int{
+			ReasonRequiredForServiceDiscovery: 2,
+			ReasonEnabled:                     1,
+			ReasonExcludedKind:                1,
+			ReasonNotUpstream:                 3,
+		},
+		// The test schemas have no proto package, so they count as CRD kinds.
+		EnabledCRDKinds: 3,
+	}))
+	g.Expect(result.Fingerprint).NotTo(BeEmpty())
+}
+
+func TestFilterCollections_MatchesLegacy(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	excluded := []string{"Service", "Node", "Deployment"}
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(excluded...), WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}))
+	g.Expect(err).To(BeNil())
+
+	legacy := DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), excluded, true)
+	g.Expect(result.Schemas.Equal(legacy)).To(BeTrue())
+}
+
+func TestFilterCollections_Fingerprint(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	apply := func(opts ...FilterOption) string {
+		r, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+		g.Expect(err).To(BeNil())
+		return r.Fingerprint
+	}
+
+	g.Expect(apply(WithExcludedResourceKinds("Pod", "Node"))).To(Equal(apply(WithExcludedResourceKinds("Pod", "Node"))))
+	g.Expect(apply(WithExcludedResourceKinds("Pod"))).NotTo(Equal(apply(WithExcludedResourceKinds("Node"))))
+	g.Expect(apply()).NotTo(Equal(apply(WithFeatureRequirements(FeatureRequirements{AmbientEnabled: true}))))
+	g.Expect(apply()).NotTo(Equal(apply(PreferNewestVersion())))
+	g.Expect(apply()).NotTo(Equal(apply(WithReenableInputDisabled())))
+	g.Expect(apply()).NotTo(Equal(apply(WithConflictPolicy(ConflictStrict))))
+}
+
+func TestFilterCollections_FingerprintNegationOrder(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	excluded, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("!Secret", "Secret"))
+	g.Expect(err).To(BeNil())
+	included, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("Secret", "!Secret"))
+	g.Expect(err).To(BeNil())
+
+	// The last matching entry wins, so the two orders decide Secret differently and must not share a fingerprint.
+	g.Expect(excluded.Schemas.MustFind(testSecret.Name().String()).IsDisabled()).To(BeTrue())
+	g.Expect(included.Schemas.MustFind(testSecret.Name().String()).IsDisabled()).To(BeFalse())
+	g.Expect(excluded.Fingerprint).NotTo(Equal(included.Fingerprint))
+}
+
+func TestFilterResult_JSON(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("Deployment"),
+		WithSelectorHint("Secret", SelectorHint{FieldSelector: "type=kubernetes.io/tls"}))
+	g.Expect(err).To(BeNil())
+
+	b, err := json.Marshal(result)
+	g.Expect(err).To(BeNil())
+
+	var decoded FilterResult
+	g.Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+	g.Expect(decoded.Report).To(Equal(result.Report))
+	g.Expect(decoded.Stats).To(Equal(result.Stats))
+	g.Expect(decoded.Fingerprint).To(Equal(result.Fingerprint))
+	g.Expect(decoded.SelectorHints).To(Equal(result.SelectorHints))
+}
+
+func TestWithOnlyGroups(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testNamespace, testNode, testPod, testSecret, testEndpointSlice,
+		testDeployment, testKubeGateway, testVirtualService, testAuthzPolicy)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithOnlyGroups("networking.istio.io"), WithExcludedResourceKinds("VirtualService"))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(result.EnabledCollectionNames()).To(ConsistOf(
+		testService.Name(), testNamespace.Name(), testNode.Name(), testPod.Name(), testSecret.Name(),
+		testKubeGateway.Name()))
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonTrimmedByGroupScope))
+	g.Expect(reasonOf(result, testEndpointSlice.Name())).To(Equal(ReasonTrimmedByGroupScope))
+	g.Expect(reasonOf(result, testAuthzPolicy.Name())).To(Equal(ReasonTrimmedByGroupScope))
+	g.Expect(reasonOf(result, testVirtualService.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(result.Stats.ByReason[ReasonTrimmedByGroupScope]).To(Equal(3))
+
+	unscoped, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("VirtualService"))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Fingerprint).NotTo(Equal(unscoped.Fingerprint))
+}
+
+func TestWithOnlyGroups_KeepsDiscoveryBuiltins(t *testing.T) {
+	g := NewWithT(t)
+
+	in := schema.MustGet().KubeCollections()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithOnlyGroups("security.istio.io"))
+	g.Expect(err).To(BeNil())
+
+	discovery := 0
+	for _, s := range in.All() {
+		enabled := !result.Schemas.MustFind(s.Name().String()).IsDisabled()
+		switch {
+		case IsRequiredForServiceDiscovery(s.Resource()):
+			discovery++
+			g.Expect(enabled).To(BeTrue(), s.Name().String())
+		case s.Resource().Group() == "security.istio.io":
+			g.Expect(enabled).To(BeTrue(), s.Name().String())
+		default:
+			g.Expect(reasonOf(result, s.Name())).To(Equal(ReasonTrimmedByGroupScope), s.Name().String())
+		}
+	}
+	g.Expect(discovery).To(Equal(5))
+}
+
+func TestOnlyForOutputs(t *testing.T) {
+	g := NewWithT(t)
+
+	providers := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		testGateway.Name():    {testKubeGateway.Name()},
+		testMeshConfig.Name(): {testConfigMap.Name()},
+	}}
+	in := collection.SchemasFor(testService, testNamespace, testNode, testPod, testSecret, testEndpointSlice,
+		testDeployment, testConfigMap, testKubeGateway, testVirtualService)
+	outputs := collection.Names{testGateway.Name()}
+
+	// The required collections given to the filter are replaced by the outputs.
+	result, err := FilterCollections(in, providers, in.CollectionNames(), OnlyForOutputs(providers, outputs))
+	g.Expect(err).To(BeNil())
+	var inputs collection.Names
+	for n := range providers.RequiredInputsFor(outputs) {
+		inputs = append(inputs, n)
+	}
+	g.Expect(result.EnabledCollectionNames()).To(ConsistOf(inputs))
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonNotUpstream))
+	g.Expect(reasonOf(result, testConfigMap.Name())).To(Equal(ReasonNotUpstream))
+
+	// With service discovery, the discovery kinds are watched as well.
+	result, err = FilterCollections(in, providers, in.CollectionNames(), OnlyForOutputs(providers, outputs),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.EnabledCollectionNames()).To(ConsistOf(append(inputs, testService.Name(), testNamespace.Name(),
+		testNode.Name(), testPod.Name(), testSecret.Name(), testEndpointSlice.Name())))
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonRequiredForServiceDiscovery))
+	g.Expect(reasonOf(result, testEndpointSlice.Name())).To(Equal(ReasonRequiredForServiceDiscovery))
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonNotUpstream))
+	g.Expect(reasonOf(result, testVirtualService.Name())).To(Equal(ReasonNotUpstream))
+}
+
+func TestDecide_ReasonPrecedence(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testDeployment)
+	p := newFlakyProbe(0)
+	p.missing[KindDeployment] = true
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name()},
+		WithExcludedResourceKinds(KindDeployment), WithAvailabilityProbe(p.probe))
+	g.Expect(err).To(BeNil())
+
+	e, ok := result.Report.Entry(testDeployment.Name())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(e.Decision).To(Equal(Decision{
+		Disabled:         true,
+		Reason:           ReasonResourceUnavailable,
+		SecondaryReasons: []Reason{ReasonExcludedKind, ReasonNotUpstream},
+		MatchedEntry:     KindDeployment,
+	}))
+	g.Expect(result.Stats.ByReason).To(Equal(map[Reason]int{ReasonEnabled: 1, ReasonResourceUnavailable: 1}))
+
+	// Without the probe, explicit exclusion takes precedence over upstream pruning.
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name()},
+		WithExcludedResourceKinds(KindDeployment))
+	g.Expect(err).To(BeNil())
+	e, _ = result.Report.Entry(testDeployment.Name())
+	g.Expect(e.Decision).To(Equal(Decision{
+		Disabled:         true,
+		Reason:           ReasonExcludedKind,
+		SecondaryReasons: []Reason{ReasonNotUpstream},
+		MatchedEntry:     KindDeployment,
+	}))
+}
+
+func TestDecide_MatchesApply(t *testing.T) {
+	g := NewWithT(t)
+
+	in := schema.MustGet().KubeCollections()
+	p := newFlakyProbe(0)
+	p.missing["Gateway"] = true
+	for _, opts := range [][]FilterOption{
+		nil,
+		{WithExcludedResourceKinds(DefaultExcludedResourceKinds()...)},
+		{
+			WithExcludedResourceKinds(append(DefaultExcludedResourceKinds(), "networking.istio.io/*", "!VirtualService")...),
+			WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+			WithDiscoveryOverrideOnly(KindService),
+		},
+		{WithOnlyGroups("security.istio.io"), WithAvailabilityProbe(p.probe)},
+	} {
+		required := collection.Names{in.All()[0].Name(), in.All()[len(in.All())/2].Name()}
+		f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, required, opts...)
+		result, err := f.Apply(in)
+		g.Expect(err).To(BeNil())
+		for _, e := range result.Report.Entries {
+			d, err := f.Decide(in.MustFind(e.Collection.String()))
+			g.Expect(err).To(BeNil())
+			g.Expect(d).To(Equal(e.Decision), "collection %s", e.Collection)
+		}
+	}
+
+	_, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, WithExcludedResourceKinds("/Pod")).Decide(testPod)
+	g.Expect(err).NotTo(BeNil())
+}
+
+func TestDecision_DisabledFor(t *testing.T) {
+	g := NewWithT(t)
+
+	d := Decision{Reason: ReasonEnabled}.
+		disabledFor(ReasonTrimmedByGroupScope).
+		disabledFor(ReasonNotUpstream).
+		disabledFor(ReasonNotUpstream).
+		disabledFor(ReasonUndetermined).
+		disabledFor(ReasonExcludedKind).
+		disabledFor(ReasonResourceUnavailable)
+	g.Expect(d).To(Equal(Decision{
+		Disabled: true,
+		Reason:   ReasonResourceUnavailable,
+		SecondaryReasons: []Reason{
+			ReasonUndetermined, ReasonExcludedKind, ReasonNotUpstream, ReasonTrimmedByGroupScope,
+		},
+	}))
+}
+
+func TestPreferNewestVersion(t *testing.T) {
+	gwAlpha := newTestSchema("networking.istio.io", "v1alpha3", "Gateway")
+	gwBeta := newTestSchema("networking.istio.io", "v1beta1", "Gateway")
+	gwGA := newTestSchema("networking.istio.io", "v1", "Gateway")
+	peerAlpha1 := newTestSchema("security.istio.io", "v1alpha1", "PeerAuthentication")
+	peerAlpha2 := newTestSchema("security.istio.io", "v1alpha2", "PeerAuthentication")
+	in := collection.SchemasFor(gwAlpha, gwGA, gwBeta, peerAlpha2, peerAlpha1, testService)
+
+	cases := []struct {
+		name     string
+		excluded []string
+		enabled  []collection.Schema
+	}{
+		{
+			name:    "newest wins",
+			enabled: []collection.Schema{gwGA, peerAlpha2, testService},
+		},
+		{
+			name:     "excluding the newest lets the next newest win",
+			excluded: []string{"networking.istio.io/v1/Gateway"},
+			enabled:  []collection.Schema{gwBeta, peerAlpha2, testService},
+		},
+		{
+			name:     "excluding all but the oldest",
+			excluded: []string{"networking.istio.io/v1*/Gateway", "!networking.istio.io/v1alpha3/Gateway", "PeerAuthentication"},
+			enabled:  []collection.Schema{gwAlpha, testService},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+				PreferNewestVersion(), WithExcludedResourceKinds(c.excluded...))
+			g.Expect(err).To(BeNil())
+
+			expected := collection.SchemasFor(c.enabled...).CollectionNames()
+			g.Expect(result.EnabledCollectionNames()).To(Equal(expected))
+			for _, s := range in.All() {
+				if reasonOf(result, s.Name()) == ReasonSupersededByNewerVersion {
+					g.Expect(expected).NotTo(ContainElement(s.Name()))
+				}
+			}
+		})
+	}
+
+	g := NewWithT(t)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), PreferNewestVersion())
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, gwAlpha.Name())).To(Equal(ReasonSupersededByNewerVersion))
+	g.Expect(reasonOf(result, gwBeta.Name())).To(Equal(ReasonSupersededByNewerVersion))
+	g.Expect(reasonOf(result, peerAlpha1.Name())).To(Equal(ReasonSupersededByNewerVersion))
+
+	// Without the option, every version stays enabled.
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(result.EnabledCollectionNames()).To(HaveLen(len(in.All())))
+}
+
+func TestApply_InvalidExclusions(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod)
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithExcludedResourceKinds("Pod", "/Service"))
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring(`invalid exclusion entry 1 "/Service"`))
+
+	// The legacy entry point cannot fail, so it keeps matching valid entries.
+	out := DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), []string{"Pod", "/Service"}, false)
+	g.Expect(out.WithoutDisabledCollections().CollectionNames()).To(Equal(collection.Names{testService.Name()}))
+}
+
+func TestWithDiscoveryOverrideOnly(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testSecret, testDeployment)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithExcludedResourceKinds(KindService, KindSecret),
+		WithDiscoveryOverrideOnly(KindService))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonRequiredForServiceDiscovery))
+	g.Expect(reasonOf(result, testSecret.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(result.EnabledCollectionNames()).To(Equal(collection.Names{testDeployment.Name(), testService.Name()}))
+
+	g.Expect(result.Warnings).To(HaveLen(1))
+	g.Expect(result.Warnings[0].Code).To(Equal(WarningDiscoveryImpact))
+	g.Expect(result.Warnings[0].Entry).To(Equal(KindSecret))
+	g.Expect(result.Warnings[0].Collection).To(Equal(testSecret.Name()))
+
+	// Ambient still re-enables Secret, since the restriction only applies to the discovery override.
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true, AmbientEnabled: true}),
+		WithExcludedResourceKinds(KindService, KindSecret),
+		WithDiscoveryOverrideOnly(KindService))
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testSecret.Name())).To(Equal(ReasonRequiredForAmbient))
+	g.Expect(result.Warnings).To(BeEmpty())
+}
+
+func TestWithDiscoveryOverrideOnly_UnknownKind(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testDeployment)
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithDiscoveryOverrideOnly(KindService, KindDeployment))
+	g.Expect(err).To(MatchError("discovery override kinds are not required for service discovery: Deployment"))
+}
+
+func TestDiscoveryUse(t *testing.T) {
+	g := NewWithT(t)
+
+	providers := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		testGateway.Name(): {testKubeGateway.Name(), testService.Name()},
+	}}
+	in := collection.SchemasFor(testService, testNode, testKubeGateway, testDeployment)
+	result, err := FilterCollections(in, providers, in.CollectionNames(),
+		OnlyForOutputs(providers, collection.Names{testGateway.Name()}), WithExcludedResourceKinds("Service"),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}))
+	g.Expect(err).To(BeNil())
+
+	// Service is excluded, but re-enabled for discovery while the gateway output reads it too. Node is not an
+	// input of the output, and is only watched for discovery.
+	use := make(map[collection.Name]DiscoveryUse)
+	for _, e := range result.Report.Entries {
+		use[e.Collection] = e.DiscoveryUse
+	}
+	g.Expect(use).To(Equal(map[collection.Name]DiscoveryUse{
+		testService.Name():     KeptForDiscoveryAndPipeline,
+		testNode.Name():        KeptForDiscoveryOnly,
+		testKubeGateway.Name(): "",
+		testDeployment.Name():  "",
+	}))
+	g.Expect(result.Report.DiscoveryOnly()).To(Equal(collection.Names{testNode.Name()}))
+	g.Expect(StartupSummary(result)).To(ContainSubstring("Watched only for discovery: k8s/core/v1/nodes\n"))
+}
@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"errors"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ErrFrozenSchemas is returned when attempting to modify a FrozenSchemas.
+var ErrFrozenSchemas = errors.New("schemas are frozen; use Thaw to get a mutable copy")
+
+// FrozenSchemas is a read-only view of a filtered schema set, for consumers that hold it for the lifetime of the
+// process. Deriving a modified set requires an explicit Thaw.
+type FrozenSchemas struct {
+	schemas collection.Schemas
+}
+
+func freeze(s collection.Schemas) *FrozenSchemas {
+	return &FrozenSchemas{schemas: collection.BuildFrom(s)}
+}
+
+// All returns all schemas, in order. The returned slice is a copy.
+func (f *FrozenSchemas) All() []collection.Schema {
+	return f.schemas.All()
+}
+
+// Find looks up a schema by collection name.
+func (f *FrozenSchemas) Find(name string) (collection.Schema, bool) {
+	return f.schemas.Find(name)
+}
+
+// EnabledNames returns the sorted names of the enabled collections.
+func (f *FrozenSchemas) EnabledNames() collection.Names {
+	return f.schemas.WithoutDisabledCollections().CollectionNames()
+}
+
+// Add always fails with ErrFrozenSchemas.
+func (f *FrozenSchemas) Add(...collection.Schema) error {
+	return ErrFrozenSchemas
+}
+
+// Remove always fails with ErrFrozenSchemas.
+func (f *FrozenSchemas) Remove(...collection.Schema) error {
+	return ErrFrozenSchemas
+}
+
+// Thaw returns a mutable copy of the schemas.
+func (f *FrozenSchemas) Thaw() collection.Schemas {
+	return collection.BuildFrom(f.schemas)
+}
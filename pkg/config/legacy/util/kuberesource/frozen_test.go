@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestWithFrozenResult(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testSecret)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithExcludedResourceKinds(KindPod))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Frozen).To(BeNil())
+
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindPod), WithFrozenResult())
+	g.Expect(err).To(BeNil())
+	frozen := result.Frozen
+	g.Expect(frozen).NotTo(BeNil())
+
+	g.Expect(frozen.EnabledNames()).To(Equal(collection.Names{testSecret.Name(), testService.Name()}))
+	g.Expect(frozen.All()).To(HaveLen(3))
+	s, ok := frozen.Find(testService.Name().String())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(s).To(BeIdenticalTo(testService))
+
+	g.Expect(frozen.Add(testNode)).To(MatchError(ErrFrozenSchemas))
+	g.Expect(frozen.Remove(testService)).To(MatchError(ErrFrozenSchemas))
+
+	// Modifying the returned slice or a thawed copy does not affect the frozen view.
+	all := frozen.All()
+	all[0] = testNode
+	thawed := frozen.Thaw().Add(testNode).Remove(testService)
+	g.Expect(thawed.CollectionNames()).To(ConsistOf(testPod.Name(), testSecret.Name(), testNode.Name()))
+	g.Expect(frozen.All()[0]).To(BeIdenticalTo(testService))
+	g.Expect(frozen.EnabledNames()).To(Equal(collection.Names{testSecret.Name(), testService.Name()}))
+	_, ok = frozen.Find(testNode.Name().String())
+	g.Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"time"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// CollectionHint tunes the informer of a single collection. Every field is optional, and the informer default
+// is used for fields left at their zero value.
+type CollectionHint struct {
+	// ResyncPeriod is the informer resync period.
+	ResyncPeriod time.Duration `json:"resyncPeriod,omitempty"`
+
+	// PriorityClass orders informer startup and event processing, for example to sync Services before Nodes.
+	PriorityClass string `json:"priorityClass,omitempty"`
+
+	// PageSize is the number of objects requested per page when listing.
+	PageSize int64 `json:"pageSize,omitempty"`
+
+	// Namespaces, if not empty, are the only namespaces the informer lists and watches; see WithNamespaceScope.
+	// Only namespaced collections can be scoped.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ExcludedNamespaces are the namespaces the informer leaves out, in order; see WithNamespaceExclusions and
+	// NamespaceFieldSelector.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+}
+
+func (h CollectionHint) equal(o CollectionHint) bool {
+	return h.ResyncPeriod == o.ResyncPeriod && h.PriorityClass == o.PriorityClass && h.PageSize == o.PageSize &&
+		stringsEqual(h.Namespaces, o.Namespaces) && stringsEqual(h.ExcludedNamespaces, o.ExcludedNamespaces)
+}
+
+// WithCollectionHint attaches an informer hint to the named collection, replacing any hint previously given for
+// it. Like selector hints, collection hints are advisory: hints for collections that end up disabled, or that
+// are not in the filtered schemas, are reported as warnings. Negative resync periods or page sizes, and
+// namespaces for a cluster-scoped collection, cause Apply to fail.
+func WithCollectionHint(name collection.Name, hint CollectionHint) FilterOption {
+	return func(o *filterOptions) {
+		if o.collectionHints == nil {
+			o.collectionHints = make(map[collection.Name]CollectionHint)
+		}
+		o.collectionHints[name] = hint
+	}
+}
+
+// HintFor returns the informer hint for the named collection, if it is enabled and has one.
+func (r *FilterResult) HintFor(name collection.Name) (CollectionHint, bool) {
+	h, ok := r.CollectionHints[name]
+	return h, ok
+}
+
+// collectionHintErrors returns the hints with invalid values, in collection name order.
+func (o *filterOptions) collectionHintErrors() []error {
+	var errs []error
+	for _, name := range o.collectionHintNames() {
+		h := o.collectionHints[name]
+		if h.ResyncPeriod < 0 {
+			errs = append(errs, fmt.Errorf("collection hint for %s: negative resync period %v", name, h.ResyncPeriod))
+		}
+		if h.PageSize < 0 {
+			errs = append(errs, fmt.Errorf("collection hint for %s: negative page size %d", name, h.PageSize))
+		}
+	}
+	return errs
+}
+
+func (o *filterOptions) collectionHintNames() collection.Names {
+	names := make(collection.Names, 0, len(o.collectionHints))
+	for name := range o.collectionHints {
+		names = append(names, name)
+	}
+	names.Sort()
+	return names
+}
+
+// applyCollectionHints exposes the hints of enabled collections on result, and warns about the others.
+func (f *CollectionFilter) applyCollectionHints(result *FilterResult) {
+	for _, name := range f.opts.collectionHintNames() {
+		s, ok := result.Schemas.Find(name.String())
+		switch {
+		case !ok:
+			result.Warnings = append(result.Warnings, FilterWarning{
+				Code:       WarningIneffectiveHint,
+				Collection: name,
+				Message:    fmt.Sprintf("collection hint for %s has no effect: no such collection", name),
+			})
+		case s.IsDisabled():
+			result.Warnings = append(result.Warnings, FilterWarning{
+				Code:       WarningIneffectiveHint,
+				Collection: name,
+				Message:    fmt.Sprintf("collection hint for %s has no effect: the collection is disabled", name),
+			})
+		case f.passedThrough(s):
+			result.Warnings = append(result.Warnings, FilterWarning{
+				Code:       WarningIneffectiveHint,
+				Collection: name,
+				Message:    fmt.Sprintf("collection hint for %s has no effect: the collection is passed through", name),
+			})
+		default:
+			result.CollectionHints[name] = f.opts.collectionHints[name]
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+)
+
+func TestCollectionHints(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	nodeHint := CollectionHint{ResyncPeriod: time.Hour, PageSize: 500}
+	serviceHint := CollectionHint{PriorityClass: "high"}
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindSecret),
+		WithCollectionHint(testNode.Name(), CollectionHint{ResyncPeriod: time.Minute}),
+		WithCollectionHint(testNode.Name(), nodeHint),
+		WithCollectionHint(testService.Name(), serviceHint),
+		WithCollectionHint(testSecret.Name(), CollectionHint{PageSize: 10}),
+		WithCollectionHint("k8s/core/v1/widgets", CollectionHint{PageSize: 10}))
+	result, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+
+	hint, ok := result.HintFor(testNode.Name())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hint).To(Equal(nodeHint))
+	hint, ok = result.HintFor(testService.Name())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hint).To(Equal(serviceHint))
+	_, ok = result.HintFor(testPod.Name())
+	g.Expect(ok).To(BeFalse())
+
+	// Hints for the excluded Secret and the unknown collection are not exposed, and are warned about.
+	_, ok = result.HintFor(testSecret.Name())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(result.Warnings).To(Equal([]FilterWarning{
+		{
+			Code:       WarningIneffectiveHint,
+			Collection: "k8s/core/v1/secrets",
+			Message:    "collection hint for k8s/core/v1/secrets has no effect: the collection is disabled",
+		},
+		{
+			Code:       WarningIneffectiveHint,
+			Collection: "k8s/core/v1/widgets",
+			Message:    "collection hint for k8s/core/v1/widgets has no effect: no such collection",
+		},
+	}))
+
+	// Hints are part of the configuration.
+	other := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindSecret), WithCollectionHint(testNode.Name(), nodeHint))
+	g.Expect(other.fingerprint()).NotTo(Equal(f.fingerprint()))
+}
+
+func TestCollectionHints_Invalid(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithCollectionHint(testNode.Name(), CollectionHint{ResyncPeriod: -time.Second}))
+	g.Expect(err).To(MatchError("collection hint for k8s/core/v1/nodes: negative resync period -1s"))
+}
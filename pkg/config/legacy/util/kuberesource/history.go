@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"time"
+)
+
+// DefaultHistorySize is the number of updates a CollectionFilterState records by default.
+const DefaultHistorySize = 20
+
+// UpdateRecord describes a single successful update of a CollectionFilterState.
+type UpdateRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	Timestamp   time.Time `json:"timestamp"`
+
+	// Sequence is the sequence number the clock of the state assigned to the update; see WithClock.
+	Sequence uint64 `json:"sequence"`
+
+	// Diff is the SemanticDiff of the result against the previous one. It is empty for the initial configuration.
+	Diff []string `json:"diff,omitempty"`
+}
+
+// WithHistorySize sets the number of updates recorded by a CollectionFilterState, evicting the oldest once it is
+// full. The default is DefaultHistorySize, and a size of zero or less disables the history. It has no effect on a
+// single filter pass.
+func WithHistorySize(n int) FilterOption {
+	return func(o *filterOptions) {
+		o.historySize = &n
+	}
+}
+
+func (o *filterOptions) historyCapacity() int {
+	if o.historySize == nil {
+		return DefaultHistorySize
+	}
+	return *o.historySize
+}
+
+// updateHistory is a bounded ring buffer of update records.
+type updateHistory struct {
+	records []UpdateRecord
+	// next is the index the next record is written to, once the buffer is full.
+	next int
+}
+
+// add records r, keeping at most size records.
+func (h *updateHistory) add(r UpdateRecord, size int) {
+	if size <= 0 {
+		h.records, h.next = nil, 0
+		return
+	}
+	if size != cap(h.records) {
+		h.resize(size)
+	}
+	if len(h.records) < size {
+		h.records = append(h.records, r)
+		return
+	}
+	h.records[h.next] = r
+	h.next = (h.next + 1) % size
+}
+
+// resize changes the capacity of the buffer to size, keeping the newest records.
+func (h *updateHistory) resize(size int) {
+	all := h.list()
+	if len(all) > size {
+		all = all[len(all)-size:]
+	}
+	h.records = append(make([]UpdateRecord, 0, size), all...)
+	h.next = 0
+}
+
+// list returns the records, oldest first.
+func (h *updateHistory) list() []UpdateRecord {
+	out := make([]UpdateRecord, 0, len(h.records))
+	out = append(out, h.records[h.next:]...)
+	return append(out, h.records[:h.next]...)
+}
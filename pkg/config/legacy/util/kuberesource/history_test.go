@@ -0,0 +1,206 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// fakeClock only moves when it is advanced, and then runs the functions that became due.
+type fakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	seq    uint64
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	f  func()
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Sequence() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	return c.seq
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{at: c.t.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, t := range c.timers {
+			if t == timer {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Advance moves the clock forward by d, running the functions that become due in the order they are due, each
+// with the clock set to its due time.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.t.Add(d)
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+		if len(c.timers) == 0 || c.timers[0].at.After(end) {
+			break
+		}
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		if timer.at.After(c.t) {
+			c.t = timer.at
+		}
+		c.mu.Unlock()
+		timer.f()
+		c.mu.Lock()
+	}
+	c.t = end
+	c.mu.Unlock()
+}
+
+// pending returns the number of functions that are not due yet.
+func (c *fakeClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func TestCollectionFilterState_History(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testDeployment)
+	clock := &fakeClock{t: time.Unix(0, 0).UTC()}
+	opts := func(kinds ...string) []FilterOption {
+		return []FilterOption{WithHistorySize(3), WithExcludedResourceKinds(kinds...)}
+	}
+
+	// The clock is only given with the initial configuration, and kept by later updates.
+	clock.Advance(time.Second)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts(), WithClock(clock))...)
+	g.Expect(err).To(BeNil())
+	first := state.Current().Fingerprint
+
+	clock.Advance(time.Second)
+	_, err = state.Update(opts(KindPod)...)
+	g.Expect(err).To(BeNil())
+	clock.Advance(time.Second)
+	_, err = state.Update(opts(KindPod, KindDeployment)...)
+	g.Expect(err).To(BeNil())
+
+	history := state.History()
+	g.Expect(history).To(HaveLen(3))
+	g.Expect(history[0]).To(Equal(UpdateRecord{Fingerprint: first, Timestamp: time.Unix(1, 0).UTC(), Sequence: 1}))
+	g.Expect(history[1].Diff).To(Equal([]string{"k8s/core/v1/pods: enabled (Enabled) -> disabled (ExcludedKind)"}))
+	g.Expect(history[2].Diff).To(Equal([]string{"k8s/apps/v1/deployments: enabled (Enabled) -> disabled (ExcludedKind)"}))
+	g.Expect(history[2].Fingerprint).To(Equal(state.Current().Fingerprint))
+
+	// A failed update is not recorded, and the oldest record is evicted once the buffer is full.
+	_, err = state.Update(opts("/Pod")...)
+	g.Expect(err).NotTo(BeNil())
+	clock.Advance(time.Second)
+	_, err = state.Update(opts()...)
+	g.Expect(err).To(BeNil())
+
+	history = state.History()
+	g.Expect(history).To(HaveLen(3))
+	g.Expect(history[0].Timestamp).To(Equal(time.Unix(2, 0).UTC()))
+	g.Expect(history[2].Timestamp).To(Equal(time.Unix(4, 0).UTC()))
+	g.Expect(history[0].Sequence).To(Equal(uint64(2)))
+	g.Expect(history[2].Sequence).To(Equal(uint64(4)))
+	g.Expect(history[2].Fingerprint).To(Equal(first))
+	g.Expect(history[2].Diff).To(ConsistOf(
+		"k8s/apps/v1/deployments: disabled (ExcludedKind) -> enabled (Enabled)",
+		"k8s/core/v1/pods: disabled (ExcludedKind) -> enabled (Enabled)"))
+
+	// Shrinking the buffer keeps the newest records.
+	clock.Advance(time.Second)
+	_, err = state.Update(append(opts(), WithHistorySize(2))...)
+	g.Expect(err).To(BeNil())
+	history = state.History()
+	g.Expect(history).To(HaveLen(2))
+	g.Expect(history[0].Timestamp).To(Equal(time.Unix(4, 0).UTC()))
+	g.Expect(history[1].Timestamp).To(Equal(time.Unix(5, 0).UTC()))
+	g.Expect(history[1].Diff).To(BeEmpty())
+}
+
+func TestCollectionFilterState_Clock(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService)
+	clock := &fakeClock{t: time.Unix(1, 0).UTC()}
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithClock(clock))
+	g.Expect(err).To(BeNil())
+
+	// Updates are timestamped with the time of the clock, which only moves when it is advanced.
+	clock.Advance(99 * time.Second)
+	_, err = state.Update()
+	g.Expect(err).To(BeNil())
+
+	// A later clock replaces the earlier one.
+	other := &fakeClock{t: time.Unix(1001, 0).UTC(), seq: 10}
+	clock.Advance(time.Second)
+	_, err = state.Update(WithClock(other))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(state.History()).To(Equal([]UpdateRecord{
+		{Fingerprint: state.Current().Fingerprint, Timestamp: time.Unix(1, 0).UTC(), Sequence: 1},
+		{Fingerprint: state.Current().Fingerprint, Timestamp: time.Unix(100, 0).UTC(), Sequence: 2, Diff: []string{}},
+		{Fingerprint: state.Current().Fingerprint, Timestamp: time.Unix(1001, 0).UTC(), Sequence: 11, Diff: []string{}},
+	}))
+
+	rc := RealClock()
+	g.Expect(rc.Sequence()).To(Equal(uint64(1)))
+	g.Expect(rc.Sequence()).To(Equal(uint64(2)))
+}
+
+func TestCollectionFilterState_Snapshot(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithHistorySize(0))
+	g.Expect(err).To(BeNil())
+
+	snapshot := state.Snapshot()
+	g.Expect(snapshot.Current).To(BeIdenticalTo(state.Current()))
+	g.Expect(snapshot.History).To(BeEmpty())
+
+	_, err = json.Marshal(snapshot)
+	g.Expect(err).To(BeNil())
+}
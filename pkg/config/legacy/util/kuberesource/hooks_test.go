@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestWithReasonHook(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testNode, testDeployment)
+	required := collection.Names{testService.Name(), testPod.Name(), testNode.Name()}
+
+	var calls []string
+	hook := func(name string) func(collection.Schema, Decision) {
+		return func(s collection.Schema, d Decision) {
+			calls = append(calls, fmt.Sprintf("%s:%s:%s:%v", name, s.Resource().Kind(), d.Reason, s.IsDisabled()))
+		}
+	}
+
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, required,
+		WithExcludedResourceKinds(KindService, KindNode),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		// metrics want everything
+		WithReasonHook(ReasonEnabled, hook("metrics")),
+		WithReasonHook(ReasonRequiredForServiceDiscovery, hook("metrics")),
+		WithReasonHook(ReasonNotUpstream, hook("metrics")),
+		// events only want discovery-impacting decisions
+		WithReasonHook(ReasonRequiredForServiceDiscovery, hook("events")),
+		// logs want disabled collections, including reasons that never occur
+		WithReasonHook(ReasonNotUpstream, hook("logs")),
+		WithReasonHook(ReasonResourceUnavailable, hook("logs")))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(calls).To(Equal([]string{
+		"metrics:Service:RequiredForServiceDiscovery:false",
+		"events:Service:RequiredForServiceDiscovery:false",
+		"metrics:Pod:Enabled:false",
+		"metrics:Node:RequiredForServiceDiscovery:false",
+		"events:Node:RequiredForServiceDiscovery:false",
+		"metrics:Deployment:NotUpstreamOfRequired:true",
+		"logs:Deployment:NotUpstreamOfRequired:true",
+	}))
+}
@@ -0,0 +1,38 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WithReenableInputDisabled lets the filter enable collections that arrive disabled in its input, for example
+// from another component or a previous filter pass, if it would enable them had they arrived enabled. By default
+// they stay disabled with ReasonAlreadyDisabled, even if a feature requires them. Compact stand-ins created by
+// WithCompactDisabled are not considered disabled input, since the filter restores them itself.
+func WithReenableInputDisabled() FilterOption {
+	return func(o *filterOptions) {
+		o.reenableInputDisabled = true
+	}
+}
+
+// enabledCopy returns an enabled copy of the disabled schema s.
+func enabledCopy(s collection.Schema) collection.Schema {
+	return collection.Builder{
+		Name:         s.Name().String(),
+		VariableName: s.VariableName(),
+		Resource:     s.Resource(),
+	}.MustBuild()
+}
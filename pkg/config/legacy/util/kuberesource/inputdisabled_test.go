@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestInputDisabled(t *testing.T) {
+	// Service is required for service discovery and Deployment is not; both arrive disabled. Pod is excluded and
+	// re-enabled for service discovery, and ConfigMap is excluded.
+	in := collection.SchemasFor(testService.Disable(), testDeployment.Disable(), testPod, testConfigMap)
+	opts := []FilterOption{
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithExcludedResourceKinds(KindService, KindPod, "ConfigMap"),
+	}
+	cases := []struct {
+		name     string
+		reenable bool
+		reasons  map[collection.Name]Reason
+		enabled  collection.Names
+	}{
+		{
+			name: "kept disabled",
+			reasons: map[collection.Name]Reason{
+				testService.Name():    ReasonAlreadyDisabled,
+				testDeployment.Name(): ReasonAlreadyDisabled,
+				testPod.Name():        ReasonRequiredForServiceDiscovery,
+				testConfigMap.Name():  ReasonExcludedKind,
+			},
+			enabled: collection.Names{testPod.Name()},
+		},
+		{
+			name:     "re-enabled",
+			reenable: true,
+			reasons: map[collection.Name]Reason{
+				testService.Name():    ReasonRequiredForServiceDiscovery,
+				testDeployment.Name(): ReasonEnabled,
+				testPod.Name():        ReasonRequiredForServiceDiscovery,
+				testConfigMap.Name():  ReasonExcludedKind,
+			},
+			enabled: collection.Names{testDeployment.Name(), testPod.Name(), testService.Name()},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			caseOpts := opts
+			if c.reenable {
+				caseOpts = append(append([]FilterOption{}, opts...), WithReenableInputDisabled())
+			}
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), caseOpts...)
+			g.Expect(err).To(BeNil())
+			for name, reason := range c.reasons {
+				g.Expect(reasonOf(result, name)).To(Equal(reason), name.String())
+				e, _ := result.Report.Entry(name)
+				g.Expect(e.InputDisabled).To(Equal(name == testService.Name() || name == testDeployment.Name()), name.String())
+			}
+			g.Expect(result.EnabledCollectionNames()).To(Equal(c.enabled))
+			g.Expect(result.Stats.InputDisabled).To(Equal(2))
+			g.Expect(result.Stats.Enabled).To(Equal(len(c.enabled)))
+			g.Expect(result.Stats.Disabled).To(Equal(4 - len(c.enabled)))
+		})
+	}
+}
+
+func TestInputDisabled_Subset(t *testing.T) {
+	g := NewWithT(t)
+
+	// A schema disabled by a filter pass over a subset keeps its decision in a later pass over the whole set.
+	in := testSchemas()
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithExcludedResourceKinds(KindDeployment))
+	first, err := f.ApplyForSubset(in, func(s collection.Schema) bool {
+		return s.Resource().Kind() == KindDeployment
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(first, testDeployment.Name())).To(Equal(ReasonExcludedKind))
+
+	second, err := FilterCollections(first.Schemas, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(second, testDeployment.Name())).To(Equal(ReasonAlreadyDisabled))
+	g.Expect(second.EnabledCollectionNames()).NotTo(ContainElement(testDeployment.Name()))
+	g.Expect(second.Stats.ByReason[ReasonAlreadyDisabled]).To(Equal(1))
+}
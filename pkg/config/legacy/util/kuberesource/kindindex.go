@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// KindIndex maps each kind in a schema set to its collections, so that exclusion entries naming a kind are
+// resolved without scanning the set. It is built once per Apply and is read-only.
+type KindIndex struct {
+	schemas collection.Schemas
+	byKind  map[string][]collection.Schema
+}
+
+// NewKindIndex builds the kind index of schemas.
+func NewKindIndex(schemas collection.Schemas) *KindIndex {
+	idx := &KindIndex{
+		schemas: schemas,
+		byKind:  make(map[string][]collection.Schema),
+	}
+	for _, s := range schemas.All() {
+		kind := s.Resource().Kind()
+		idx.byKind[kind] = append(idx.byKind[kind], s)
+	}
+	return idx
+}
+
+// Lookup returns the collections of kind, in input order, in every group and at every version.
+func (idx *KindIndex) Lookup(kind string) []collection.Schema {
+	if idx == nil {
+		return nil
+	}
+	return idx.byKind[kind]
+}
+
+// Groups returns the sorted groups kind exists in. The core group is the empty string.
+func (idx *KindIndex) Groups(kind string) []string {
+	if idx == nil {
+		return nil
+	}
+	var groups []string
+	seen := make(map[string]struct{})
+	for _, s := range idx.byKind[kind] {
+		g := s.Resource().Group()
+		if _, ok := seen[g]; !ok {
+			seen[g] = struct{}{}
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Kinds returns the kinds in the index, in order.
+func (idx *KindIndex) Kinds() []string {
+	if idx == nil {
+		return nil
+	}
+	out := make([]string, 0, len(idx.byKind))
+	for k := range idx.byKind {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// candidates returns the collections e can match, in input order: those of its kind, the collection it names,
+// or every collection if its kind is a glob. Moved kinds keep their kind, so equivalents need no other lookup.
+func (idx *KindIndex) candidates(e Exclusion) []collection.Schema {
+	switch {
+	case e.Collection != "":
+		if s, ok := idx.schemas.Find(e.Collection.String()); ok {
+			return []collection.Schema{s}
+		}
+		return nil
+	case strings.ContainsAny(e.Kind, "*?"):
+		return idx.schemas.All()
+	default:
+		return idx.byKind[e.Kind]
+	}
+}
+
+// unmatchedReason explains why e, which matches no collection, is ineffective. If the kind of e is not a glob and
+// exists in groups that e does not match, those groups are suggested in the group/Kind form.
+func (idx *KindIndex) unmatchedReason(e Exclusion) string {
+	const reason = "matches no collection"
+	if e.literal || e.Collection != "" || strings.ContainsAny(e.Kind, "*?") {
+		return reason
+	}
+	var groups, suggestions []string
+	for _, g := range idx.Groups(e.Kind) {
+		if globMatch(e.Group, g) {
+			continue
+		}
+		display := g
+		if display == "" {
+			display = coreGroup
+		}
+		groups = append(groups, display)
+		suggestion := DisplayGroupKind(g, e.Kind)
+		if e.Negated {
+			suggestion = negationPrefix + suggestion
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	switch len(groups) {
+	case 0:
+		return reason
+	case 1:
+		return fmt.Sprintf("%s; kind %s exists in group %s; did you mean %s?", reason, e.Kind, groups[0], suggestions[0])
+	default:
+		return fmt.Sprintf("%s; kind %s exists in groups %s; did you mean %s?", reason, e.Kind,
+			strings.Join(groups, ", "), strings.Join(suggestions, " or "))
+	}
+}
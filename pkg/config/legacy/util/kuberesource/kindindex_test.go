@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var testGatewayAPIGateway = newTestSchema("gateway.networking.k8s.io", "v1alpha2", "Gateway")
+
+func TestKindIndex(t *testing.T) {
+	g := NewWithT(t)
+
+	idx := NewKindIndex(collection.SchemasFor(testGateway, testKubeGateway, testGatewayAPIGateway, testVirtualService,
+		testService))
+	g.Expect(idx.Groups("Gateway")).To(Equal([]string{"gateway.networking.k8s.io", "networking.istio.io"}))
+	g.Expect(idx.Groups("Service")).To(Equal([]string{""}))
+	g.Expect(idx.Groups("Widget")).To(BeEmpty())
+	g.Expect(idx.Kinds()).To(Equal([]string{"Gateway", "Service", "VirtualService"}))
+}
+
+func TestKindIndex_Lookup(t *testing.T) {
+	g := NewWithT(t)
+
+	gatewayV1 := newTestSchema("gateway.networking.k8s.io", "v1", "Gateway")
+	in := collection.SchemasFor(testGateway, testService, testGatewayAPIGateway, gatewayV1, testVirtualService)
+	idx := NewKindIndex(in)
+	g.Expect(idx.Lookup("Gateway")).To(Equal([]collection.Schema{testGateway, testGatewayAPIGateway, gatewayV1}))
+	g.Expect(idx.Lookup("Service")).To(Equal([]collection.Schema{testService}))
+	g.Expect(idx.Lookup("Widget")).To(BeEmpty())
+	g.Expect(idx.Groups("Gateway")).To(Equal([]string{"gateway.networking.k8s.io", "networking.istio.io"}))
+
+	cases := []struct {
+		entry string
+		want  []collection.Schema
+	}{
+		{"Gateway", []collection.Schema{testGateway, testGatewayAPIGateway, gatewayV1}},
+		{"networking.istio.io/Gateway", []collection.Schema{testGateway, testGatewayAPIGateway, gatewayV1}},
+		{"collection:" + gatewayV1.Name().String(), []collection.Schema{gatewayV1}},
+		{"collection:k8s/example.com/v1/widgets", nil},
+		{"networking.istio.io/*", in.All()},
+		{"Gate?ay", in.All()},
+	}
+	for _, c := range cases {
+		t.Run(c.entry, func(t *testing.T) {
+			g := NewWithT(t)
+			e, reason := parseExclusion(c.entry)
+			g.Expect(reason).To(BeEmpty())
+			g.Expect(idx.candidates(e)).To(Equal(c.want))
+		})
+	}
+
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("gateway.networking.k8s.io/Gateway"))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Index().Lookup("Gateway")).To(Equal(idx.Lookup("Gateway")))
+	g.Expect((&FilterResult{}).Index()).To(BeNil())
+}
+
+func TestUnmatchedEntrySuggestion(t *testing.T) {
+	in := collection.SchemasFor(testKubeGateway, testGatewayAPIGateway, testVirtualService, testService)
+	cases := []struct {
+		entry  string
+		reason string
+	}{
+		{
+			"networking.k8s.io/VirtualService",
+			"matches no collection; kind VirtualService exists in group networking.istio.io; " +
+				"did you mean networking.istio.io/VirtualService?",
+		},
+		{
+			"!networking.k8s.io/v1/VirtualService",
+			"matches no collection; kind VirtualService exists in group networking.istio.io; " +
+				"did you mean !networking.istio.io/VirtualService?",
+		},
+		{
+			"apps/Service",
+			"matches no collection; kind Service exists in group core; did you mean core/Service?",
+		},
+		{
+			"security.istio.io/Gateway",
+			"matches no collection; kind Gateway exists in groups gateway.networking.k8s.io, networking.istio.io; " +
+				"did you mean gateway.networking.k8s.io/Gateway or networking.istio.io/Gateway?",
+		},
+		{"Widget", "matches no collection"},
+		{"example.com/Virtual*", "matches no collection"},
+		// The group is right, so the version is what does not match.
+		{"networking.istio.io/v1/VirtualService", "matches no collection"},
+	}
+	for _, c := range cases {
+		t.Run(c.entry, func(t *testing.T) {
+			g := NewWithT(t)
+			errs := ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{}, WithExcludedResourceKinds(c.entry))
+			g.Expect(errs).To(HaveLen(1))
+			g.Expect(errs[0]).To(Equal(&ExclusionError{Index: 0, Entry: c.entry, Reason: c.reason}))
+		})
+	}
+}
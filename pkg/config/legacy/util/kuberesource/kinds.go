@@ -0,0 +1,62 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// Well-known builtin Kubernetes kinds.
+const (
+	KindConfigMap                    = "ConfigMap"
+	KindCustomResourceDefinition     = "CustomResourceDefinition"
+	KindDeployment                   = "Deployment"
+	KindEndpoints                    = "Endpoints"
+	KindEndpointSlice                = "EndpointSlice"
+	KindIngress                      = "Ingress"
+	KindMutatingWebhookConfiguration = "MutatingWebhookConfiguration"
+	KindNamespace                    = "Namespace"
+	KindNode                         = "Node"
+	KindPod                          = "Pod"
+	KindSecret                       = "Secret"
+	KindService                      = "Service"
+)
+
+// BuiltinKinds returns the sorted kinds of the builtin Kubernetes collections in the schema set.
+func BuiltinKinds() []string {
+	seen := make(map[string]struct{})
+	kinds := make([]string, 0)
+	for _, s := range schema.MustGet().KubeCollections().All() {
+		if !isBuiltin(s.Resource()) {
+			continue
+		}
+		if _, ok := seen[s.Resource().Kind()]; ok {
+			continue
+		}
+		seen[s.Resource().Kind()] = struct{}{}
+		kinds = append(kinds, s.Resource().Kind())
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// isBuiltin returns true if res is served by Kubernetes itself rather than by a CustomResourceDefinition.
+func isBuiltin(res resource.Schema) bool {
+	return strings.HasPrefix(res.ProtoPackage(), "k8s.io/")
+}
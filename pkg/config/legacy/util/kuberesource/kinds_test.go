@@ -0,0 +1,46 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/schema"
+)
+
+func TestKindConstants(t *testing.T) {
+	kinds := []string{
+		KindConfigMap, KindCustomResourceDefinition, KindDeployment, KindEndpoints, KindEndpointSlice, KindIngress,
+		KindMutatingWebhookConfiguration, KindNamespace, KindNode, KindPod, KindSecret, KindService,
+	}
+	for _, kind := range kinds {
+		t.Run(kind, func(t *testing.T) {
+			g := NewWithT(t)
+			matches := 0
+			for _, s := range schema.MustGet().KubeCollections().All() {
+				if s.Resource().Kind() == kind {
+					matches++
+					g.Expect(isBuiltin(s.Resource())).To(BeTrue())
+				}
+			}
+			g.Expect(matches).To(Equal(1))
+		})
+	}
+
+	g := NewWithT(t)
+	g.Expect(BuiltinKinds()).To(ConsistOf(kinds))
+}
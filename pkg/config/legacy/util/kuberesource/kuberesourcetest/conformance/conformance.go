@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformance checks that a composition of schemas and providers satisfies the invariants the collection
+// filter relies on. Distributions that register their own kinds, add schemas or swap provider sets should run it
+// against their composition. It is separate from kuberesourcetest, which the kuberesource tests import.
+package conformance
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Run checks schemas and providers, with a subtest for each group of invariants:
+//
+//	KnownTypes         every kind required by service discovery has a schema
+//	Providers          LintProviders finds nothing
+//	DefaultExclusions  the default exclusions resolve against schemas and do not fight service discovery
+//	DefaultApply       the filter with the default configuration succeeds and reports consistently
+//
+// Registrations, such as RegisterServiceDiscoveryType, must be made before calling Run, since it applies the
+// filter.
+func Run(t *testing.T, schemas collection.Schemas, providers transformer.Providers) {
+	t.Helper()
+	t.Run("KnownTypes", func(t *testing.T) {
+		kinds := make(map[string]struct{})
+		for _, s := range schemas.All() {
+			kinds[kuberesource.TypeKey(s.Resource().Group(), s.Resource().Kind())] = struct{}{}
+		}
+		for _, gk := range kuberesource.ServiceDiscoveryRequiredKinds() {
+			if _, ok := kinds[kuberesource.TypeKey(gk.Group, gk.Kind)]; !ok {
+				t.Errorf("kind %s is required for service discovery, but has no schema",
+					kuberesource.DisplayGroupKind(gk.Group, gk.Kind))
+			}
+		}
+	})
+	t.Run("Providers", func(t *testing.T) {
+		for _, f := range kuberesource.LintProviders(providers, schemas) {
+			t.Errorf("provider lint: %s", f)
+		}
+	})
+	t.Run("DefaultExclusions", func(t *testing.T) {
+		errs := kuberesource.ValidateFilterConfig(schemas, providers, defaultOptions()...)
+		for _, err := range errs {
+			t.Errorf("default configuration: %v", err)
+		}
+	})
+	t.Run("DefaultApply", func(t *testing.T) {
+		result, err := kuberesource.FilterCollections(schemas, providers, schemas.CollectionNames(), defaultOptions()...)
+		if err != nil {
+			t.Fatalf("filtering with the default configuration failed: %v", err)
+		}
+		checkResult(t, schemas, result)
+	})
+}
+
+// defaultOptions returns the configuration istiod runs with unless configured otherwise.
+func defaultOptions() []kuberesource.FilterOption {
+	return []kuberesource.FilterOption{
+		kuberesource.WithExclusionsFrom(kuberesource.SourceDefault, kuberesource.DefaultExcludedResourceKinds()...),
+		kuberesource.WithFeatureRequirements(kuberesource.FeatureRequirements{ServiceDiscovery: true}),
+	}
+}
+
+// checkResult checks that result, the filter result for in, reports a decision for every collection of in that
+// agrees with its filtered schemas, and keeps every collection required by service discovery enabled.
+func checkResult(t *testing.T, in collection.Schemas, result *kuberesource.FilterResult) {
+	t.Helper()
+	if result.Fingerprint == "" {
+		t.Errorf("result has no fingerprint")
+	}
+	if got, want := len(result.Report.Entries), len(in.All()); got != want {
+		t.Errorf("report has %d entries for %d collections", got, want)
+	}
+	for _, s := range in.All() {
+		e, ok := result.Report.Entry(s.Name())
+		if !ok {
+			t.Errorf("report has no entry for %s", s.Name())
+			continue
+		}
+		out, ok := result.Schemas.Find(s.Name().String())
+		if !ok {
+			t.Errorf("filtered schemas have no %s", s.Name())
+			continue
+		}
+		if out.IsDisabled() != e.Disabled {
+			t.Errorf("%s is reported as %s, but its filtered schema disagrees", s.Name(), e.Decision.String())
+		}
+		if kuberesource.IsRequiredForServiceDiscovery(s.Resource()) && e.Disabled {
+			t.Errorf("%s is required for service discovery, but is %s", s.Name(), e.Decision.String())
+		}
+	}
+	st := result.Stats
+	if st.Total != len(result.Report.Entries) || st.Enabled+st.Disabled != st.Total {
+		t.Errorf("stats do not add up: %d total, %d enabled, %d disabled", st.Total, st.Enabled, st.Disabled)
+	}
+}
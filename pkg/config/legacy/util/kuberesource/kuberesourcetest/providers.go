@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kuberesourcetest provides fakes for testing consumers of the kuberesource package.
+package kuberesourcetest
+
+import (
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ScriptedProviders is a transformer graph declared as a map from each output collection to the input collections
+// it is produced from. It implements kuberesource.InputProviders, with the same semantics as
+// transformer.Providers, without building transformers:
+//
+//	providers := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+//		gateways: {kubeGateways},
+//	}}
+type ScriptedProviders struct {
+	Inputs map[collection.Name]collection.Names
+}
+
+// RequiredInputsFor returns the inputs of outputs. Outputs without scripted inputs are their own inputs.
+func (p ScriptedProviders) RequiredInputsFor(outputs collection.Names) map[collection.Name]struct{} {
+	inputs := make(map[collection.Name]struct{})
+	for _, out := range outputs {
+		if len(p.Inputs[out]) == 0 {
+			inputs[out] = struct{}{}
+		}
+		for _, in := range p.Inputs[out] {
+			inputs[in] = struct{}{}
+		}
+	}
+	return inputs
+}
+
+// SynthesizedOutputs returns the scripted outputs that are not an input of any output, in name order.
+func (p ScriptedProviders) SynthesizedOutputs() collection.Names {
+	inputs := make(map[collection.Name]struct{})
+	for _, ins := range p.Inputs {
+		for _, in := range ins {
+			inputs[in] = struct{}{}
+		}
+	}
+	result := make(collection.Names, 0)
+	for out := range p.Inputs {
+		if _, ok := inputs[out]; !ok {
+			result = append(result, out)
+		}
+	}
+	result.Sort()
+	return result
+}
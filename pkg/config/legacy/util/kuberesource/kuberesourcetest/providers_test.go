@@ -0,0 +1,57 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesourcetest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/event"
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/legacy/testing/basicmeta"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// The scripted graph must behave like the transformer graph it describes.
+func TestScriptedProviders_MatchesTransformerProviders(t *testing.T) {
+	g := NewWithT(t)
+
+	handleFn := func(e event.Event, h event.Handler) {}
+	providers := transformer.Providers{
+		transformer.NewSimpleTransformerProvider(basicmeta.K8SCollection1, basicmeta.Collection2, handleFn),
+	}
+	scripted := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		basicmeta.Collection2.Name(): {basicmeta.K8SCollection1.Name()},
+	}}
+	var _ kuberesource.InputProviders = scripted
+
+	for _, outputs := range []collection.Names{
+		{basicmeta.Collection2.Name()},
+		{basicmeta.K8SCollection1.Name()},
+		{basicmeta.Collection2.Name(), basicmeta.K8SCollection1.Name()},
+		nil,
+	} {
+		g.Expect(scripted.RequiredInputsFor(outputs)).To(Equal(providers.RequiredInputsFor(outputs)))
+	}
+	g.Expect(scripted.SynthesizedOutputs()).To(Equal(providers.SynthesizedOutputs()))
+
+	in := collection.SchemasFor(basicmeta.K8SCollection1, basicmeta.Collection2)
+	required := collection.Names{basicmeta.Collection2.Name()}
+	g.Expect(kuberesource.DisableExcludedCollectionsFor(in, scripted, required, nil, false).WithoutDisabledCollections().CollectionNames()).
+		To(Equal(kuberesource.DisableExcludedCollections(in, providers, required, nil, false).WithoutDisabledCollections().CollectionNames()))
+}
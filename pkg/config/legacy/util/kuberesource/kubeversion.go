@@ -0,0 +1,204 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// KubeVersionRange is the range of Kubernetes versions an exclusion entry applies to. Either bound may be empty,
+// and both are inclusive at the precision they are written with, so that a maximum of 1.24 includes 1.24.3.
+// Versions are parsed as by Kubernetes: a leading v, and pre-release or build suffixes such as -eks-1, are
+// allowed.
+type KubeVersionRange struct {
+	MinKubeVersion string `json:"minKubeVersion,omitempty"`
+	MaxKubeVersion string `json:"maxKubeVersion,omitempty"`
+}
+
+func (r KubeVersionRange) String() string {
+	switch {
+	case r.MinKubeVersion == "":
+		return "up to " + r.MaxKubeVersion
+	case r.MaxKubeVersion == "":
+		return "from " + r.MinKubeVersion
+	default:
+		return fmt.Sprintf("from %s up to %s", r.MinKubeVersion, r.MaxKubeVersion)
+	}
+}
+
+// contains returns true if v is in the range. It must only be called on a range without errors.
+func (r KubeVersionRange) contains(v *version.Version) bool {
+	if r.MinKubeVersion != "" && compareAtPrecision(v, version.MustParseGeneric(r.MinKubeVersion)) < 0 {
+		return false
+	}
+	if r.MaxKubeVersion != "" && compareAtPrecision(v, version.MustParseGeneric(r.MaxKubeVersion)) > 0 {
+		return false
+	}
+	return true
+}
+
+// compareAtPrecision compares v to bound, ignoring the components of v that bound does not have.
+func compareAtPrecision(v, bound *version.Version) int {
+	vc, bc := v.Components(), bound.Components()
+	for i, b := range bc {
+		var c uint
+		if i < len(vc) {
+			c = vc[i]
+		}
+		switch {
+		case c < b:
+			return -1
+		case c > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+// errors returns the problems with the bounds of r, configured for entry.
+func (r KubeVersionRange) errors(entry string) []error {
+	var errs []error
+	bounds := []struct{ name, value string }{{"minKubeVersion", r.MinKubeVersion}, {"maxKubeVersion", r.MaxKubeVersion}}
+	for _, b := range bounds {
+		if b.value == "" {
+			continue
+		}
+		if _, err := version.ParseGeneric(b.value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q for exclusion entry %s", b.name, b.value, entry))
+		}
+	}
+	if len(errs) > 0 || r.MinKubeVersion == "" || r.MaxKubeVersion == "" {
+		return errs
+	}
+	if compareAtPrecision(version.MustParseGeneric(r.MinKubeVersion), version.MustParseGeneric(r.MaxKubeVersion)) > 0 {
+		errs = append(errs, fmt.Errorf("exclusion entry %s has minKubeVersion %s above maxKubeVersion %s", entry,
+			r.MinKubeVersion, r.MaxKubeVersion))
+	}
+	return errs
+}
+
+// WithKubeVersion sets the Kubernetes version of the cluster, as reported by its API server. Exclusion entries
+// gated with WithEntryKubeVersions to a range that does not contain it are ignored, and reported with a
+// WarningKubeVersionGate warning. Without a version, or if it cannot be parsed, every entry applies.
+func WithKubeVersion(v string) FilterOption {
+	return func(o *filterOptions) {
+		o.kubeVersion = v
+	}
+}
+
+// WithEntryKubeVersions gates the exclusion entry, as written, to the Kubernetes versions in r. A gate for an
+// entry that is not configured, or with a bound that cannot be parsed, causes Apply to fail. A later gate for the
+// same entry replaces an earlier one.
+func WithEntryKubeVersions(entry string, r KubeVersionRange) FilterOption {
+	return func(o *filterOptions) {
+		if o.kubeVersionGates == nil {
+			o.kubeVersionGates = make(map[string]KubeVersionRange)
+		}
+		o.kubeVersionGates[entry] = r
+	}
+}
+
+// copyKubeVersionGates returns a copy of gates, or nil if it is empty.
+func copyKubeVersionGates(gates map[string]KubeVersionRange) map[string]KubeVersionRange {
+	if len(gates) == 0 {
+		return nil
+	}
+	out := make(map[string]KubeVersionRange, len(gates))
+	for e, r := range gates {
+		out[e] = r
+	}
+	return out
+}
+
+// sortedGateEntries returns the entries of gates in order.
+func sortedGateEntries(gates map[string]KubeVersionRange) []string {
+	out := make([]string, 0, len(gates))
+	for e := range gates {
+		out = append(out, e)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// kubeVersionErrors returns an error for every gate with an invalid range, or for an entry that is not configured.
+func (o *filterOptions) kubeVersionErrors() []error {
+	configured := make(map[string]struct{}, len(o.excludedResourceKinds))
+	for _, e := range o.excludedResourceKinds {
+		configured[e] = struct{}{}
+	}
+	var errs []error
+	for _, entry := range sortedGateEntries(o.kubeVersionGates) {
+		pos := o.positions.gatePosition(entry)
+		if _, ok := configured[entry]; !ok {
+			errs = append(errs, atPosition(pos, fmt.Errorf("version gate for %s matches no exclusion entry", entry)))
+		}
+		for _, err := range o.kubeVersionGates[entry].errors(entry) {
+			errs = append(errs, atPosition(pos, err))
+		}
+	}
+	return errs
+}
+
+// clusterKubeVersion returns the parsed Kubernetes version, or nil if it is not set or cannot be parsed.
+func (o *filterOptions) clusterKubeVersion() *version.Version {
+	if o.kubeVersion == "" {
+		return nil
+	}
+	v, err := version.ParseGeneric(o.kubeVersion)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// gatedOut returns true if entry is ignored because the Kubernetes version is outside its gate.
+func (o *filterOptions) gatedOut(entry string) bool {
+	r, ok := o.kubeVersionGates[entry]
+	if !ok || len(r.errors(entry)) > 0 {
+		return false
+	}
+	v := o.clusterKubeVersion()
+	return v != nil && !r.contains(v)
+}
+
+// kubeVersionWarnings returns a WarningKubeVersionGate warning for every gated entry that is ignored, or a single
+// one if the Kubernetes version cannot be parsed and so every gated entry applies.
+func (o *filterOptions) kubeVersionWarnings() []FilterWarning {
+	if o.kubeVersion == "" || len(o.kubeVersionGates) == 0 {
+		return nil
+	}
+	if o.clusterKubeVersion() == nil {
+		return []FilterWarning{{
+			Code: WarningKubeVersionGate,
+			Message: fmt.Sprintf("Kubernetes version %q cannot be parsed; exclusion entries gated to a version "+
+				"range apply regardless", o.kubeVersion),
+		}}
+	}
+	var warnings []FilterWarning
+	for _, entry := range sortedGateEntries(o.kubeVersionGates) {
+		if o.gatedOut(entry) {
+			warnings = append(warnings, FilterWarning{
+				Code:  WarningKubeVersionGate,
+				Entry: entry,
+				Message: fmt.Sprintf("entry %s is ignored on Kubernetes %s: it applies %s", entry, o.kubeVersion,
+					o.kubeVersionGates[entry]),
+			})
+		}
+	}
+	return warnings
+}
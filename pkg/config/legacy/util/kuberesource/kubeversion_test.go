@@ -0,0 +1,177 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestKubeVersionGates(t *testing.T) {
+	in := collection.SchemasFor(testEndpoints, testEndpointSlice, testDeployment, testConfigMap)
+	gates := []FilterOption{
+		WithExcludedResourceKinds(KindEndpoints, KindDeployment, "ConfigMap"),
+		// Endpoints is only excluded where EndpointSlice is GA.
+		WithEntryKubeVersions(KindEndpoints, KubeVersionRange{MinKubeVersion: "1.21"}),
+		WithEntryKubeVersions(KindDeployment, KubeVersionRange{MinKubeVersion: "1.19", MaxKubeVersion: "1.24"}),
+	}
+	cases := []struct {
+		name     string
+		version  string
+		excluded []string
+		warnings []FilterWarning
+	}{
+		{
+			name:     "in range",
+			version:  "v1.22.3-eks-1",
+			excluded: []string{KindEndpoints, KindDeployment, "ConfigMap"},
+		},
+		{
+			name:     "maximum at its precision",
+			version:  "1.24.7",
+			excluded: []string{KindEndpoints, KindDeployment, "ConfigMap"},
+		},
+		{
+			name:     "below minimum",
+			version:  "1.20.4",
+			excluded: []string{KindDeployment, "ConfigMap"},
+			warnings: []FilterWarning{{
+				Code:    WarningKubeVersionGate,
+				Entry:   KindEndpoints,
+				Message: "entry Endpoints is ignored on Kubernetes 1.20.4: it applies from 1.21",
+			}},
+		},
+		{
+			name:     "below both minimums",
+			version:  "1.18",
+			excluded: []string{"ConfigMap"},
+			warnings: []FilterWarning{
+				{
+					Code:    WarningKubeVersionGate,
+					Entry:   KindDeployment,
+					Message: "entry Deployment is ignored on Kubernetes 1.18: it applies from 1.19 up to 1.24",
+				},
+				{
+					Code:    WarningKubeVersionGate,
+					Entry:   KindEndpoints,
+					Message: "entry Endpoints is ignored on Kubernetes 1.18: it applies from 1.21",
+				},
+			},
+		},
+		{
+			name:     "above maximum",
+			version:  "v1.25.0",
+			excluded: []string{KindEndpoints, "ConfigMap"},
+			warnings: []FilterWarning{{
+				Code:    WarningKubeVersionGate,
+				Entry:   KindDeployment,
+				Message: "entry Deployment is ignored on Kubernetes v1.25.0: it applies from 1.19 up to 1.24",
+			}},
+		},
+		{
+			name:     "unparsable version",
+			version:  "latest",
+			excluded: []string{KindEndpoints, KindDeployment, "ConfigMap"},
+			warnings: []FilterWarning{{
+				Code:    WarningKubeVersionGate,
+				Message: `Kubernetes version "latest" cannot be parsed; exclusion entries gated to a version range apply regardless`,
+			}},
+		},
+		{
+			name:     "absent version",
+			excluded: []string{KindEndpoints, KindDeployment, "ConfigMap"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			opts := append(append([]FilterOption{}, gates...), WithKubeVersion(c.version))
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+			g.Expect(err).To(BeNil())
+
+			var excluded []string
+			for _, e := range result.Report.Entries {
+				if e.Reason == ReasonExcludedKind {
+					excluded = append(excluded, e.Kind)
+				}
+			}
+			g.Expect(excluded).To(ConsistOf(c.excluded))
+			g.Expect(result.Warnings).To(Equal(c.warnings))
+		})
+	}
+}
+
+func TestKubeVersionGates_Fingerprint(t *testing.T) {
+	g := NewWithT(t)
+
+	gated := []FilterOption{
+		WithExcludedResourceKinds(KindEndpoints),
+		WithEntryKubeVersions(KindEndpoints, KubeVersionRange{MinKubeVersion: "1.21"}),
+	}
+	fingerprint := func(opts ...FilterOption) string {
+		f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, opts...)
+		return f.fingerprint()
+	}
+	ungated := fingerprint(WithExcludedResourceKinds(KindEndpoints))
+	g.Expect(fingerprint(gated...)).NotTo(Equal(ungated))
+	// Versions on the same side of the gate filter identically.
+	g.Expect(fingerprint(append(gated, WithKubeVersion("1.22"))...)).To(Equal(fingerprint(append(gated, WithKubeVersion("1.23"))...)))
+	g.Expect(fingerprint(append(gated, WithKubeVersion("1.20"))...)).NotTo(Equal(fingerprint(append(gated, WithKubeVersion("1.23"))...)))
+}
+
+func TestKubeVersionGates_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := FilterCollections(testSchemas(), kuberesourcetest.ScriptedProviders{}, nil,
+		WithExcludedResourceKinds(KindPod),
+		WithEntryKubeVersions(KindPod, KubeVersionRange{MinKubeVersion: "one"}),
+		WithEntryKubeVersions(KindNode, KubeVersionRange{MinKubeVersion: "1.25", MaxKubeVersion: "1.24"}))
+	g.Expect(err).To(MatchError(And(
+		ContainSubstring(`version gate for Node matches no exclusion entry`),
+		ContainSubstring(`exclusion entry Node has minKubeVersion 1.25 above maxKubeVersion 1.24`),
+		ContainSubstring(`invalid minKubeVersion "one" for exclusion entry Pod`))))
+}
+
+func TestKubeVersionGates_Render(t *testing.T) {
+	g := NewWithT(t)
+
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name()},
+		WithExcludedResourceKinds(KindEndpoints),
+		WithEntryKubeVersions(KindEndpoints, KubeVersionRange{MinKubeVersion: "1.21"}))
+	out, err := f.RenderConfig(ConfigFormatOperator)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(out)).To(ContainSubstring("kubeVersionGates:\n          Endpoints:\n            minKubeVersion: \"1.21\"\n"))
+
+	// Every entry is rendered, with its gate.
+	c, err := LoadConfig(ConfigFormatOperator, out)
+	g.Expect(err).To(BeNil())
+	g.Expect(c.ExcludedResourceKinds).To(Equal([]string{KindEndpoints}))
+	g.Expect(c.KubeVersionGates).To(Equal(map[string]KubeVersionRange{KindEndpoints: {MinKubeVersion: "1.21"}}))
+
+	_, err = f.RenderConfig(ConfigFormatFlags)
+	g.Expect(err).To(MatchError(ContainSubstring("uses Kubernetes version gates")))
+
+	// The cluster version is not part of the configuration, and would be lost by rendering.
+	f = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name()},
+		WithExcludedResourceKinds(KindEndpoints),
+		WithEntryKubeVersions(KindEndpoints, KubeVersionRange{MinKubeVersion: "1.21"}),
+		WithKubeVersion("1.20"))
+	_, err = f.RenderConfig(ConfigFormatOperator)
+	g.Expect(err).To(MatchError("filter configuration cannot be rendered: uses WithKubeVersion"))
+}
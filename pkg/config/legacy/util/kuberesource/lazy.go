@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WithLazyKinds marks kinds to be watched lazily: enabled collections of these kinds are decided with ReasonLazy
+// until the kind is referenced, see WithReferencedKinds and CollectionFilterState.Reference. Lazy collections
+// remain enabled in the result's schemas, so that the informer layer can register them, but should not be
+// started. Kinds required for service discovery can never be lazy, and cause Apply to fail.
+func WithLazyKinds(kinds ...string) FilterOption {
+	return func(o *filterOptions) {
+		if o.lazyKinds == nil {
+			o.lazyKinds = make(map[string]struct{})
+		}
+		for _, k := range kinds {
+			o.lazyKinds[k] = struct{}{}
+		}
+	}
+}
+
+// WithReferencedKinds marks lazy kinds as referenced, so that their collections are enabled as usual.
+func WithReferencedKinds(kinds ...string) FilterOption {
+	return func(o *filterOptions) {
+		if o.referencedKinds == nil {
+			o.referencedKinds = make(map[string]struct{})
+		}
+		for _, k := range kinds {
+			o.referencedKinds[k] = struct{}{}
+		}
+	}
+}
+
+// IsLazy returns true if the named collection is enabled, but is only to be watched once its kind is referenced.
+func (r *FilterResult) IsLazy(name collection.Name) bool {
+	i := sort.Search(len(r.LazyCollections), func(i int) bool { return r.LazyCollections[i] >= name })
+	return i < len(r.LazyCollections) && r.LazyCollections[i] == name
+}
+
+// isLazy returns true if collections of kind are not to be watched until the kind is referenced.
+func (o *filterOptions) isLazy(kind string) bool {
+	if _, ok := o.lazyKinds[kind]; !ok {
+		return false
+	}
+	_, referenced := o.referencedKinds[kind]
+	return !referenced
+}
+
+// applyLaziness defers the decision d to enable a collection of kind, if kind is lazy. Only collections enabled
+// for no more specific reason than ReasonEnabled are deferred.
+func (f *CollectionFilter) applyLaziness(kind string, d Decision) Decision {
+	if d.Disabled || d.Reason != ReasonEnabled || !f.opts.isLazy(kind) {
+		return d
+	}
+	return Decision{Reason: ReasonLazy, MatchedEntry: d.MatchedEntry}
+}
+
+// lazyErrors returns an error for the lazy kinds that are required for service discovery, including the endpoints
+// kinds protected under the endpoints mode.
+func (o *filterOptions) lazyErrors() []error {
+	required := o.discoveryKinds()
+	var invalid []string
+	for k := range o.lazyKinds {
+		if _, ok := required[k]; ok {
+			invalid = append(invalid, k)
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	sort.Strings(invalid)
+	return []error{fmt.Errorf("kinds required for service discovery cannot be lazy: %s", strings.Join(invalid, ", "))}
+}
+
+func sortedKindSet(kinds map[string]struct{}) []string {
+	out := make([]string, 0, len(kinds))
+	for k := range kinds {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// lazyCollections returns the lazy collections in r, in name order.
+func (r *FilterResult) lazyCollections() collection.Names {
+	var out collection.Names
+	for _, e := range r.Report.Entries {
+		if e.Reason == ReasonLazy {
+			out = append(out, e.Collection)
+		}
+	}
+	out.Sort()
+	return out
+}
+
+// Reference marks kinds as referenced, promoting their lazy collections, and recomputes the result with the
+// most recent configuration. Kinds stay referenced across later updates.
+func (s *CollectionFilterState) Reference(kinds ...string) (*FilterResult, error) {
+	return s.applyUpdate(nil, true, kinds)
+}
@@ -0,0 +1,98 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestLazyKinds(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas().Add(testKubeGateway)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithLazyKinds(KindDeployment, "Gateway"), WithExcludedResourceKinds("Gateway"))
+	g.Expect(err).To(BeNil())
+
+	// The lazy Deployment collection is registered but not started; an excluded lazy kind is simply disabled.
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonLazy))
+	g.Expect(result.Schemas.MustFind(testDeployment.Name().String()).IsDisabled()).To(BeFalse())
+	g.Expect(result.IsLazy(testDeployment.Name())).To(BeTrue())
+	g.Expect(reasonOf(result, testKubeGateway.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(result.IsLazy(testKubeGateway.Name())).To(BeFalse())
+	g.Expect(result.LazyCollections).To(Equal(collection.Names{testDeployment.Name()}))
+
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithLazyKinds(KindDeployment), WithReferencedKinds(KindDeployment))
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonEnabled))
+	g.Expect(result.LazyCollections).To(BeEmpty())
+
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithLazyKinds(KindDeployment, KindService, KindPod))
+	g.Expect(err).To(MatchError("kinds required for service discovery cannot be lazy: Pod, Service"))
+
+	// The endpoints kinds protected under the endpoints mode cannot be lazy either.
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithLazyKinds(KindDeployment, KindEndpointSlice))
+	g.Expect(err).To(MatchError("kinds required for service discovery cannot be lazy: EndpointSlice"))
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithLazyKinds(KindEndpointSlice), WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: EndpointsOnly}))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.IsLazy(testEndpointSlice.Name())).To(BeTrue())
+}
+
+func TestCollectionFilterState_LazyPromotion(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas().Add(testKubeGateway)
+	s, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithLazyKinds(KindDeployment))
+	g.Expect(err).To(BeNil())
+	g.Expect(s.Current().IsLazy(testDeployment.Name())).To(BeTrue())
+	ch := s.Notify()
+
+	// Referencing another kind changes nothing.
+	_, err = s.Reference("ConfigMap")
+	g.Expect(err).To(BeNil())
+	g.Consistently(ch).ShouldNot(Receive())
+
+	// Lazy to enabled: the collection is started.
+	result, err := s.Reference(KindDeployment)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonEnabled))
+	g.Expect(<-ch).To(Equal(FilterChange{Fingerprint: result.Fingerprint, Started: collection.Names{testDeployment.Name()}}))
+
+	// Enabled to disabled, and back: the kind stays referenced across updates.
+	result, err = s.Update(WithLazyKinds(KindDeployment), WithExcludedResourceKinds(KindDeployment))
+	g.Expect(err).To(BeNil())
+	g.Expect(<-ch).To(Equal(FilterChange{Fingerprint: result.Fingerprint, Stopped: collection.Names{testDeployment.Name()}}))
+	result, err = s.Update(WithLazyKinds(KindDeployment))
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonEnabled))
+	g.Expect(<-ch).To(Equal(FilterChange{Fingerprint: result.Fingerprint, Started: collection.Names{testDeployment.Name()}}))
+
+	// Enabled to lazy: a newly lazy kind is stopped until it is referenced.
+	result, err = s.Update(WithLazyKinds(KindDeployment, "Gateway"))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.IsLazy(testKubeGateway.Name())).To(BeTrue())
+	g.Expect(<-ch).To(Equal(FilterChange{Fingerprint: result.Fingerprint, Stopped: collection.Names{testKubeGateway.Name()}}))
+	g.Expect(s.Changed()).To(Equal(collection.Names{testKubeGateway.Name()}))
+}
@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sync"
+
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// legacySemantics are the behaviors of the filter before exclusion entries were parsed that WithLegacySemantics
+// restores. Every divergence from the current semantics is decided by legacyExclusionDecision, except that
+// exactKindEntries also skips the validation of entries in configErrors.
+type legacySemantics struct {
+	// exactKindEntries matches exclusion entries as exact kind names, in any group and at any version. Entries
+	// with a group, a glob, a negation or a collection: prefix match no kind, and are not reported as invalid.
+	exactKindEntries bool
+
+	// unpinnedDiscoveryKinds re-enables the builtin kinds required for service discovery at every version, and
+	// no other kind: kinds registered with RegisterServiceDiscoveryType and the endpoints kinds are not
+	// re-enabled.
+	unpinnedDiscoveryKinds bool
+
+	// serviceDiscoveryOnly re-enables excluded kinds for service discovery only, ignoring the other features.
+	serviceDiscoveryOnly bool
+}
+
+// WithLegacySemantics restores the decisions the filter made before exclusion entries were parsed, for one
+// release, so that operators can validate an upgrade. Exclusion entries are matched as exact kind names and are
+// never reported as invalid, and only service discovery re-enables excluded kinds, namely the builtin core
+// Service, Namespace, Node, Pod and Secret kinds at every version. Other options apply as usual. A deprecation
+// warning is logged the first time a filter with legacy semantics is applied.
+//
+// Deprecated: WithLegacySemantics will be removed in the next release.
+func WithLegacySemantics() FilterOption {
+	return func(o *filterOptions) {
+		o.legacy = &legacySemantics{
+			exactKindEntries:       true,
+			unpinnedDiscoveryKinds: true,
+			serviceDiscoveryOnly:   true,
+		}
+	}
+}
+
+// legacySemanticsOnce logs the deprecation of WithLegacySemantics once.
+var legacySemanticsOnce sync.Once
+
+func (o *filterOptions) warnLegacySemantics() {
+	if o.legacy == nil {
+		return
+	}
+	legacySemanticsOnce.Do(func() {
+		o.logger().Warnf("collection filter: WithLegacySemantics is deprecated and will be removed in the next " +
+			"release; validate the current filter decisions and remove it")
+	})
+}
+
+// legacyExclusionDecision returns the decision for the exclusion entries and features under the legacy semantics.
+// The decision for collections that are not upstream of the required collections is unchanged, and made by the
+// caller.
+func (f *CollectionFilter) legacyExclusionDecision(s collection.Schema, result *FilterResult) Decision {
+	if !f.opts.legacy.exactKindEntries {
+		return f.exclusionDecision(s, result)
+	}
+	res := s.Resource()
+	d := Decision{Reason: ReasonEnabled}
+	for _, e := range f.exclusions.exclusions {
+		if e.Entry == res.Kind() {
+			d.MatchedEntry = e.Entry
+		}
+	}
+	if d.MatchedEntry == "" {
+		return d
+	}
+	if reason, ok := f.opts.legacyRequiredReason(res); ok {
+		d.Reason = reason
+		return d
+	}
+	return d.disabledFor(ReasonExcludedKind)
+}
+
+// legacyRequiredReason is requiredReason under the legacy semantics.
+func (o *filterOptions) legacyRequiredReason(res resource.Schema) (Reason, bool) {
+	discovery := o.isDiscoveryKind(res) && !o.discoveryOverrideWithheld(res)
+	if o.legacy.unpinnedDiscoveryKinds {
+		_, discovery = builtinServiceDiscoveryTypes()[TypeKey(res.Group(), res.Kind())]
+		if discovery && o.discoveryOverrideOnly != nil {
+			_, discovery = o.discoveryOverrideOnly[res.Kind()]
+		}
+	}
+	if o.features.ServiceDiscovery && discovery {
+		return ReasonRequiredForServiceDiscovery, true
+	}
+	if o.legacy.serviceDiscoveryOnly {
+		return "", false
+	}
+	others := o.features
+	others.ServiceDiscovery = false
+	return others.requiredReason(res)
+}
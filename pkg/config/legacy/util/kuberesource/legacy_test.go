@@ -0,0 +1,141 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// preFilterDisabled reimplements the decisions made before exclusion entries were parsed: entries are exact kind
+// names, only the core Service, Namespace, Node, Pod and Secret kinds are re-enabled for service discovery, and
+// collections that are not upstream of the required collections are disabled regardless.
+func preFilterDisabled(in collection.Schemas, providers kuberesourcetest.ScriptedProviders, required collection.Names,
+	excluded []string, discovery bool) map[collection.Name]bool {
+	upstream := providers.RequiredInputsFor(required)
+	out := make(map[collection.Name]bool)
+	for _, s := range in.All() {
+		res := s.Resource()
+		disabled := false
+		for _, e := range excluded {
+			if e == res.Kind() {
+				disabled = true
+			}
+		}
+		if disabled && discovery && res.Group() == "" {
+			switch res.Kind() {
+			case "Service", "Namespace", "Node", "Pod", "Secret":
+				disabled = false
+			}
+		}
+		if _, ok := upstream[s.Name()]; !ok {
+			disabled = true
+		}
+		out[s.Name()] = disabled
+	}
+	return out
+}
+
+func TestWithLegacySemantics(t *testing.T) {
+	in := collection.SchemasFor(testService, testNamespace, testNode, testPod, testSecret, testEndpointSlice,
+		testEndpoints, testDeployment, testConfigMap, testKubeGateway, testVirtualService, testAuthzPolicy)
+	exclusions := map[string][]string{
+		"none":       nil,
+		"defaults":   DefaultExcludedResourceKinds(),
+		"kinds":      {"Pod", "Service", "Deployment", "EndpointSlice", "Endpoints"},
+		"group":      {"apps/Deployment", "core/v1/ConfigMap"},
+		"negation":   {"networking.istio.io/*", "!Gateway"},
+		"collection": {"collection:k8s/core/v1/services", "collection:k8s/core/v1/configmaps"},
+		"everything": {"*"},
+	}
+	graphs := map[string]struct {
+		providers kuberesourcetest.ScriptedProviders
+		required  collection.Names
+	}{
+		"identity": {kuberesourcetest.ScriptedProviders{}, in.CollectionNames()},
+		"subset":   {kuberesourcetest.ScriptedProviders{}, collection.Names{testPod.Name(), testVirtualService.Name()}},
+		"transformed": {
+			kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+				testGateway.Name():    {testKubeGateway.Name(), testService.Name()},
+				testMeshConfig.Name(): {testConfigMap.Name()},
+			}},
+			collection.Names{testGateway.Name(), testMeshConfig.Name(), testAuthzPolicy.Name()},
+		},
+	}
+
+	var names []string
+	for exName := range exclusions {
+		for graphName := range graphs {
+			for _, discovery := range []bool{false, true} {
+				names = append(names, fmt.Sprintf("%s/%s/discovery=%v", exName, graphName, discovery))
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var diff strings.Builder
+	for _, name := range names {
+		parts := strings.Split(name, "/")
+		excluded, graph, discovery := exclusions[parts[0]], graphs[parts[1]], parts[2] == "discovery=true"
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			opts := []FilterOption{
+				WithExcludedResourceKinds(excluded...),
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: discovery}),
+			}
+			current, err := FilterCollections(in, graph.providers, graph.required, opts...)
+			g.Expect(err).To(BeNil())
+			legacy, err := FilterCollections(in, graph.providers, graph.required, append(opts, WithLegacySemantics())...)
+			g.Expect(err).To(BeNil())
+
+			want := preFilterDisabled(in, graph.providers, graph.required, excluded, discovery)
+			for _, s := range in.All() {
+				g.Expect(legacy.Schemas.MustFind(s.Name().String()).IsDisabled()).To(Equal(want[s.Name()]), s.Name().String())
+			}
+			g.Expect(legacy.Fingerprint).NotTo(Equal(current.Fingerprint))
+
+			if changes := current.Report.SemanticDiff(legacy.Report); len(changes) > 0 {
+				fmt.Fprintf(&diff, "%s\n", name)
+				for _, c := range changes {
+					fmt.Fprintf(&diff, "  %s\n", c)
+				}
+			}
+		})
+	}
+	testutil.CompareContent([]byte(diff.String()), "testdata/legacy_semantics_diff.golden", t)
+}
+
+func TestWithLegacySemantics_SkipsEntryValidation(t *testing.T) {
+	g := NewWithT(t)
+	in := collection.SchemasFor(testService, testPod)
+	required := in.CollectionNames()
+
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, required, WithExcludedResourceKinds("Po d"))
+	g.Expect(err).NotTo(BeNil())
+
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, required,
+		WithExcludedResourceKinds("Po d", "Pod"), WithLegacySemantics())
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Schemas.MustFind(testPod.Name().String()).IsDisabled()).To(BeTrue())
+	g.Expect(result.Schemas.MustFind(testService.Name().String()).IsDisabled()).To(BeFalse())
+}
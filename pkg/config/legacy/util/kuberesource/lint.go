@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// LintCode identifies the class of a LintFinding.
+type LintCode string
+
+const (
+	// LintDanglingInput is reported for an input collection that has no schema.
+	LintDanglingInput LintCode = "DanglingInput"
+
+	// LintNoOutputs is reported for a provider that declares no output collections.
+	LintNoOutputs LintCode = "NoOutputs"
+
+	// LintDuplicateEdge is reported for an input to output edge already declared by an earlier provider.
+	LintDuplicateEdge LintCode = "DuplicateEdge"
+
+	// LintSelfLoop is reported for a collection that is both an input and an output of the same provider. A
+	// provider that only forwards a single collection to itself, as the direct transforms do for the Kubernetes
+	// collections the pipeline consumes unchanged, is a pass-through and is not reported.
+	LintSelfLoop LintCode = "SelfLoop"
+)
+
+// LintFinding describes a problem in a transformer provider declaration.
+type LintFinding struct {
+	Code LintCode `json:"code"`
+
+	// Provider is the position of the provider in the linted providers, which have no other identity, and
+	// ProviderInputs and ProviderOutputs are the collections it declares.
+	Provider        int              `json:"provider"`
+	ProviderInputs  collection.Names `json:"providerInputs"`
+	ProviderOutputs collection.Names `json:"providerOutputs"`
+
+	// Collections are the offending collections: the dangling input, the input and output of a duplicate edge,
+	// or the collection of a self loop. They are empty for LintNoOutputs.
+	Collections collection.Names `json:"collections,omitempty"`
+
+	// Duplicates is the position of the earlier provider that declares the same edge. It is only meaningful for
+	// LintDuplicateEdge.
+	Duplicates int `json:"duplicates"`
+}
+
+func (f LintFinding) String() string {
+	p := fmt.Sprintf("provider %d (%v -> %v)", f.Provider, f.ProviderInputs, f.ProviderOutputs)
+	switch f.Code {
+	case LintDanglingInput:
+		return fmt.Sprintf("%s: %s: input %s has no schema", f.Code, p, f.Collections[0])
+	case LintNoOutputs:
+		return fmt.Sprintf("%s: %s: declares no outputs", f.Code, p)
+	case LintDuplicateEdge:
+		return fmt.Sprintf("%s: %s: edge %s -> %s is already declared by provider %d",
+			f.Code, p, f.Collections[0], f.Collections[1], f.Duplicates)
+	case LintSelfLoop:
+		return fmt.Sprintf("%s: %s: %s is both an input and an output", f.Code, p, f.Collections[0])
+	default:
+		return fmt.Sprintf("%s: %s: %v", f.Code, p, f.Collections)
+	}
+}
+
+// LintProviders checks the declarations of providers against schemas, and returns the findings in provider order,
+// and for each provider in the order of the LintCode constants and collection name. It is meant for the tests of
+// schema and transformer packages, so that mistakes in provider wiring fail the build rather than leave a
+// collection silently empty; the findings do not depend on any filter configuration.
+func LintProviders(providers transformer.Providers, schemas collection.Schemas) []LintFinding {
+	type edge struct{ in, out collection.Name }
+	declared := make(map[edge]int)
+
+	var out []LintFinding
+	for i := range providers {
+		p := &providers[i]
+		inputs, outputs := p.Inputs().CollectionNames(), p.Outputs().CollectionNames()
+		inputs.Sort()
+		outputs.Sort()
+		finding := func(code LintCode, names ...collection.Name) LintFinding {
+			return LintFinding{
+				Code:            code,
+				Provider:        i,
+				ProviderInputs:  inputs,
+				ProviderOutputs: outputs,
+				Collections:     names,
+			}
+		}
+
+		for _, in := range inputs {
+			if _, ok := schemas.Find(in.String()); !ok {
+				out = append(out, finding(LintDanglingInput, in))
+			}
+		}
+		if len(outputs) == 0 {
+			out = append(out, finding(LintNoOutputs))
+		}
+		for _, in := range inputs {
+			for _, o := range outputs {
+				e := edge{in: in, out: o}
+				if first, ok := declared[e]; ok {
+					f := finding(LintDuplicateEdge, in, o)
+					f.Duplicates = first
+					out = append(out, f)
+					continue
+				}
+				declared[e] = i
+			}
+		}
+		passThrough := len(inputs) == 1 && len(outputs) == 1 && inputs[0] == outputs[0]
+		for _, in := range inputs {
+			if _, ok := p.Outputs().Find(in.String()); ok && !passThrough {
+				out = append(out, finding(LintSelfLoop, in))
+			}
+		}
+	}
+	return out
+}
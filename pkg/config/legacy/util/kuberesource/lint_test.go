@@ -0,0 +1,133 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/legacy/processor/transforms"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func newTestProvider(inputs, outputs []collection.Schema) transformer.Provider {
+	return transformer.NewProvider(collection.SchemasFor(inputs...), collection.SchemasFor(outputs...), nil)
+}
+
+func TestLintProviders(t *testing.T) {
+	schemas := collection.SchemasFor(testKubeGateway, testGateway, testService, testMeshConfig)
+	cases := []struct {
+		name      string
+		providers transformer.Providers
+		expected  []LintFinding
+	}{
+		{
+			name: "clean",
+			providers: transformer.Providers{
+				newTestProvider([]collection.Schema{testKubeGateway}, []collection.Schema{testGateway}),
+				newTestProvider([]collection.Schema{testService}, []collection.Schema{testMeshConfig}),
+				newTestProvider([]collection.Schema{testService}, []collection.Schema{testService}),
+			},
+		},
+		{
+			name: "dangling input",
+			providers: transformer.Providers{
+				newTestProvider([]collection.Schema{testKubeGateway, testPod}, []collection.Schema{testGateway}),
+			},
+			expected: []LintFinding{{
+				Code:            LintDanglingInput,
+				ProviderInputs:  collection.Names{testPod.Name(), testKubeGateway.Name()},
+				ProviderOutputs: collection.Names{testGateway.Name()},
+				Collections:     collection.Names{testPod.Name()},
+			}},
+		},
+		{
+			name: "no outputs",
+			providers: transformer.Providers{
+				newTestProvider([]collection.Schema{testKubeGateway}, []collection.Schema{testGateway}),
+				newTestProvider([]collection.Schema{testService}, nil),
+			},
+			expected: []LintFinding{{
+				Code:            LintNoOutputs,
+				Provider:        1,
+				ProviderInputs:  collection.Names{testService.Name()},
+				ProviderOutputs: collection.Names{},
+			}},
+		},
+		{
+			name: "duplicate edge",
+			providers: transformer.Providers{
+				newTestProvider([]collection.Schema{testKubeGateway}, []collection.Schema{testGateway}),
+				newTestProvider([]collection.Schema{testService}, []collection.Schema{testMeshConfig}),
+				newTestProvider([]collection.Schema{testKubeGateway, testService}, []collection.Schema{testGateway}),
+			},
+			expected: []LintFinding{{
+				Code:            LintDuplicateEdge,
+				Provider:        2,
+				ProviderInputs:  collection.Names{testService.Name(), testKubeGateway.Name()},
+				ProviderOutputs: collection.Names{testGateway.Name()},
+				Collections:     collection.Names{testKubeGateway.Name(), testGateway.Name()},
+				Duplicates:      0,
+			}},
+		},
+		{
+			name: "self loop",
+			providers: transformer.Providers{
+				newTestProvider([]collection.Schema{testService}, []collection.Schema{testService, testMeshConfig}),
+			},
+			expected: []LintFinding{{
+				Code:            LintSelfLoop,
+				ProviderInputs:  collection.Names{testService.Name()},
+				ProviderOutputs: collection.Names{testMeshConfig.Name(), testService.Name()},
+				Collections:     collection.Names{testService.Name()},
+			}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(LintProviders(c.providers, schemas)).To(Equal(c.expected))
+		})
+	}
+}
+
+func TestLintFinding_String(t *testing.T) {
+	g := NewWithT(t)
+
+	f := LintFinding{
+		Code:            LintDuplicateEdge,
+		Provider:        2,
+		ProviderInputs:  collection.Names{testKubeGateway.Name()},
+		ProviderOutputs: collection.Names{testGateway.Name()},
+		Collections:     collection.Names{testKubeGateway.Name(), testGateway.Name()},
+	}
+	g.Expect(f.String()).To(Equal("DuplicateEdge: provider 2 ([k8s/networking.istio.io/v1alpha3/gatewaies] -> " +
+		"[istio/networking/v1alpha3/gateways]): edge k8s/networking.istio.io/v1alpha3/gatewaies -> " +
+		"istio/networking/v1alpha3/gateways is already declared by provider 0"))
+}
+
+// The providers the pipeline is built with must lint clean against the full schema set, so that a regression in
+// provider wiring fails here.
+func TestLintProviders_Real(t *testing.T) {
+	g := NewWithT(t)
+
+	m := schema.MustGet()
+	for _, f := range LintProviders(transforms.Providers(m), m.AllCollections()) {
+		g.Expect(f).To(BeNil(), f.String())
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config/analysis/scope"
+)
+
+// Logger is what the filter logs to: the startup summary and errors of DisableExcludedCollectionsFor, the
+// warnings and update lines of a CollectionFilterState, dropped duplicates and deprecation notices.
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+}
+
+// WithLogger sets the logger of the filter, so that embedders can route its output through their own logging. The
+// logger given with the initial configuration of a CollectionFilterState is kept by later updates that give none.
+// The default logs to the processing scope.
+func WithLogger(l Logger) FilterOption {
+	return func(o *filterOptions) {
+		o.log = l
+	}
+}
+
+// logger returns the configured logger, or the processing scope.
+func (o *filterOptions) logger() Logger {
+	if o.log != nil {
+		return o.log
+	}
+	return scopeLogger{}
+}
+
+// scopeLogger logs to the processing scope.
+type scopeLogger struct{}
+
+func (scopeLogger) Infof(format string, args ...interface{}) {
+	scope.Processing.Infof(scopeArgs(format, args)...)
+}
+
+func (scopeLogger) Warnf(format string, args ...interface{}) {
+	scope.Processing.Warnf(scopeArgs(format, args)...)
+}
+
+func (scopeLogger) Debugf(format string, args ...interface{}) {
+	scope.Processing.Debugf(scopeArgs(format, args)...)
+}
+
+// scopeArgs returns format and args as the arguments of the formatting methods of a scope, which take the format
+// as their first argument.
+func scopeArgs(format string, args []interface{}) []interface{} {
+	return append([]interface{}{format}, args...)
+}
@@ -0,0 +1,109 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// capturingLogger records every line logged to it, prefixed by its level.
+type capturingLogger struct {
+	lines []string
+}
+
+func (l *capturingLogger) Infof(format string, args ...interface{}) {
+	l.lines = append(l.lines, "info: "+fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Warnf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "warn: "+fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Debugf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "debug: "+fmt.Sprintf(format, args...))
+}
+
+// warningOptions configure a filter whose results have two warnings.
+func warningOptions() []FilterOption {
+	return []FilterOption{
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: EndpointsOnly}),
+		WithDiscoveryOverrideOnly(KindService, KindEndpoints),
+		WithExcludedResourceKinds(KindNode),
+	}
+}
+
+func TestWithLogger_DisableExcludedCollectionsFor(t *testing.T) {
+	g := NewWithT(t)
+	in := testSchemas()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), warningOptions()...)
+	g.Expect(err).To(BeNil())
+
+	l := &capturingLogger{}
+	DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), nil, false,
+		append(warningOptions(), WithLogger(l))...)
+	for _, w := range result.Warnings {
+		g.Expect(l.lines).To(ContainElement("warn: " + w.Message))
+	}
+}
+
+func TestWithLogger_State(t *testing.T) {
+	g := NewWithT(t)
+	in := testSchemas()
+	l := &capturingLogger{}
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(warningOptions(), WithLogger(l))...)
+	g.Expect(err).To(BeNil())
+	g.Expect(l.lines).To(HaveLen(2))
+	for _, w := range state.ActiveWarnings() {
+		g.Expect(l.lines).To(ContainElement("warn: " + w.Message))
+	}
+
+	// The logger given initially is kept, and logs the update line.
+	l.lines = nil
+	result, err := state.Update(append(warningOptions(), WithExcludedResourceKinds(KindDeployment))...)
+	g.Expect(err).To(BeNil())
+	g.Expect(l.lines).To(ContainElement(fmt.Sprintf("info: collection filter: updated to configuration %s, changed %s",
+		result.Fingerprint, testDeployment.Name())))
+
+	// A logger given by an update replaces it.
+	next := &capturingLogger{}
+	l.lines = nil
+	result, err = state.Update(append(warningOptions(), WithExcludedResourceKinds(KindDeployment), WithLogger(next))...)
+	g.Expect(err).To(BeNil())
+	g.Expect(l.lines).To(BeEmpty())
+	g.Expect(next.lines).To(Equal([]string{
+		fmt.Sprintf("debug: collection filter: updated to configuration %s, nothing changed", result.Fingerprint),
+	}))
+}
+
+func TestWithLogger_Dedups(t *testing.T) {
+	g := NewWithT(t)
+	l := &capturingLogger{}
+	a := collection.SchemasFor(testService, testPod)
+	b := collection.SchemasFor(testPod, testService)
+	_, err := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, a.CollectionNames(), WithLogger(l)).ApplyComposed(a, b)
+	g.Expect(err).To(BeNil())
+	g.Expect(l.lines).To(Equal([]string{
+		fmt.Sprintf("debug: collection filter: dropped 1 duplicates of %s", testPod.Name()),
+		fmt.Sprintf("debug: collection filter: dropped 1 duplicates of %s", testService.Name()),
+	}))
+}
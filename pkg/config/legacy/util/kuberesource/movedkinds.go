@@ -0,0 +1,119 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KindLocation is where a kind is served. The core group is the empty string. Version is only set for kinds that
+// moved between versions of the same group.
+type KindLocation struct {
+	Group   string `json:"group"`
+	Version string `json:"version,omitempty"`
+}
+
+// MovedKind is a kind that moved from one API group, or group version, to another between Kubernetes versions.
+type MovedKind struct {
+	Kind string       `json:"kind"`
+	From KindLocation `json:"from"`
+	To   KindLocation `json:"to"`
+}
+
+// movedKinds is the equivalence table for exclusion entries: an entry that names a kind at one end of a move
+// also matches the kind at the other end, so that a single entry covers the kind wherever the schema set places
+// it. Entries are only resolved through the table if their group and kind are given without wildcards.
+var movedKinds = []MovedKind{
+	{Kind: "CronJob", From: KindLocation{Group: "batch", Version: "v1beta1"},
+		To: KindLocation{Group: "batch", Version: "v1"}},
+	{Kind: "DaemonSet", From: KindLocation{Group: "extensions"}, To: KindLocation{Group: "apps"}},
+	{Kind: KindDeployment, From: KindLocation{Group: "extensions"}, To: KindLocation{Group: "apps"}},
+	{Kind: KindEndpointSlice, From: KindLocation{Group: "discovery.k8s.io", Version: "v1beta1"},
+		To: KindLocation{Group: "discovery.k8s.io", Version: "v1"}},
+	{Kind: "Event", From: KindLocation{Group: ""}, To: KindLocation{Group: "events.k8s.io"}},
+	{Kind: "HorizontalPodAutoscaler", From: KindLocation{Group: "autoscaling", Version: "v2beta2"},
+		To: KindLocation{Group: "autoscaling", Version: "v2"}},
+	{Kind: KindIngress, From: KindLocation{Group: "extensions"}, To: KindLocation{Group: "networking.k8s.io"}},
+	{Kind: "NetworkPolicy", From: KindLocation{Group: "extensions"}, To: KindLocation{Group: "networking.k8s.io"}},
+	{Kind: "PodDisruptionBudget", From: KindLocation{Group: "policy", Version: "v1beta1"},
+		To: KindLocation{Group: "policy", Version: "v1"}},
+	{Kind: "ReplicaSet", From: KindLocation{Group: "extensions"}, To: KindLocation{Group: "apps"}},
+}
+
+// MovedKinds returns the kinds whose exclusion entries are resolved through the moved kinds table.
+func MovedKinds() []MovedKind {
+	return append([]MovedKind{}, movedKinds...)
+}
+
+// holds returns true if an entry for group and version names the kind at l. An entry for any version of a group
+// names the kind at a location without a version only, since the entry already covers every version of it.
+func (l KindLocation) holds(group, version string) bool {
+	return group == l.Group && (l.Version == "" || version == l.Version)
+}
+
+// pattern returns an exclusion matching kind at l.
+func (l KindLocation) pattern(kind string) Exclusion {
+	version := l.Version
+	if version == "" {
+		version = anySegment
+	}
+	return Exclusion{Group: l.Group, Version: version, Kind: kind}
+}
+
+// movedEquivalents returns the exclusions e is equivalent to through the moved kinds table.
+func movedEquivalents(e Exclusion) []Exclusion {
+	if e.Collection != "" || e.literal || strings.ContainsAny(e.Group+e.Kind, "*?") {
+		return nil
+	}
+	var out []Exclusion
+	for _, m := range movedKinds {
+		if m.Kind != e.Kind {
+			continue
+		}
+		switch {
+		case m.From.holds(e.Group, e.Version):
+			out = append(out, m.To.pattern(m.Kind))
+		case m.To.holds(e.Group, e.Version):
+			out = append(out, m.From.pattern(m.Kind))
+		}
+	}
+	return out
+}
+
+// movedKindWarnings returns a WarningMovedKind warning for every collection of idx that an entry matches only
+// through the moved kinds table.
+func movedKindWarnings(idx *KindIndex, entries []string) []FilterWarning {
+	var warnings []FilterWarning
+	for _, entry := range entries {
+		e, reason := parseExclusion(entry)
+		if reason != "" || len(e.equivalents) == 0 {
+			continue
+		}
+		for _, s := range idx.candidates(e) {
+			res := s.Resource()
+			if _, via := e.matchVia(s.Name(), res.Group(), res.Version(), res.Kind()); via != "" {
+				warnings = append(warnings, FilterWarning{
+					Code:       WarningMovedKind,
+					Entry:      entry,
+					Collection: s.Name(),
+					Message: fmt.Sprintf("entry %s matches %s through the moved kinds table, as %s", entry, s.Name(),
+						via),
+				})
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestMovedKinds_Table(t *testing.T) {
+	g := NewWithT(t)
+
+	seen := make(map[string]struct{})
+	for _, m := range MovedKinds() {
+		g.Expect(m.From).NotTo(Equal(m.To), "kind %s does not move", m.Kind)
+		g.Expect(m.From.Version == "").To(Equal(m.To.Version == ""), "kind %s moves a version only on one side", m.Kind)
+		if m.From.Group == m.To.Group {
+			g.Expect(m.From.Version).NotTo(BeEmpty(), "kind %s moves within group %s without a version", m.Kind, m.From.Group)
+		}
+		for _, l := range []KindLocation{m.From, m.To} {
+			key := DisplayGroupKind(l.Group, m.Kind) + "/" + l.Version
+			_, dup := seen[key]
+			g.Expect(dup).To(BeFalse(), "location %s is listed more than once", key)
+			seen[key] = struct{}{}
+		}
+	}
+}
+
+func TestParseExclusions_MovedKinds(t *testing.T) {
+	cases := []struct {
+		entry       string
+		equivalents []string
+	}{
+		{entry: "extensions/Ingress", equivalents: []string{"networking.k8s.io/*/Ingress"}},
+		{entry: "networking.k8s.io/Ingress", equivalents: []string{"extensions/*/Ingress"}},
+		{entry: "extensions/v1beta1/Ingress", equivalents: []string{"networking.k8s.io/*/Ingress"}},
+		{entry: "!apps/Deployment", equivalents: []string{"extensions/*/Deployment"}},
+		{entry: "core/Event", equivalents: []string{"events.k8s.io/*/Event"}},
+		{entry: "batch/v1beta1/CronJob", equivalents: []string{"batch/v1/CronJob"}},
+		{entry: "batch/v1/CronJob", equivalents: []string{"batch/v1beta1/CronJob"}},
+		// An entry for every version of the group already covers both ends of the move.
+		{entry: "batch/CronJob"},
+		// Entries with wildcards in the group or kind, or without a group, are not resolved through the table.
+		{entry: "Ingress"},
+		{entry: "*/Ingress"},
+		{entry: "extensions/Ingr*"},
+		{entry: "extensions/Gateway"},
+		{entry: "collection:k8s/extensions/v1beta1/ingresses"},
+	}
+	for _, c := range cases {
+		t.Run(c.entry, func(t *testing.T) {
+			g := NewWithT(t)
+			exclusions, err := ParseExclusions([]string{c.entry})
+			g.Expect(err).To(BeNil())
+			g.Expect(exclusions[0].Equivalents).To(Equal(c.equivalents))
+		})
+	}
+}
+
+func TestFilterCollections_MovedKinds(t *testing.T) {
+	g := NewWithT(t)
+
+	ingress := newTestSchema("networking.k8s.io", "v1", KindIngress)
+	cronJob := newTestSchema("batch", "v1", "CronJob")
+	event := newTestSchema("", "v1", "Event")
+	in := collection.SchemasFor(ingress, cronJob, event, testService)
+
+	opts := []FilterOption{WithExcludedResourceKinds("extensions/Ingress", "batch/v1beta1/CronJob", "events.k8s.io/Event",
+		"!core/Event")}
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, ingress.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(reasonOf(result, cronJob.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(reasonOf(result, event.Name())).To(Equal(ReasonEnabled))
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonEnabled))
+	entry, _ := result.Report.Entry(ingress.Name())
+	g.Expect(entry.MatchedEntry).To(Equal("extensions/Ingress"))
+
+	g.Expect(result.Warnings).To(Equal([]FilterWarning{
+		{
+			Code:       WarningMovedKind,
+			Entry:      "extensions/Ingress",
+			Collection: ingress.Name(),
+			Message: "entry extensions/Ingress matches " + ingress.Name().String() + " through the moved kinds " +
+				"table, as networking.k8s.io/*/Ingress",
+		},
+		{
+			Code:       WarningMovedKind,
+			Entry:      "batch/v1beta1/CronJob",
+			Collection: cronJob.Name(),
+			Message:    "entry batch/v1beta1/CronJob matches k8s/batch/v1/cronjobs through the moved kinds table, as batch/v1/CronJob",
+		},
+		// The entry is reported even though a later entry re-includes the collection.
+		{
+			Code:       WarningMovedKind,
+			Entry:      "events.k8s.io/Event",
+			Collection: event.Name(),
+			Message:    "entry events.k8s.io/Event matches k8s/core/v1/events through the moved kinds table, as core/*/Event",
+		},
+	}))
+
+	// The mapping is shown with the effective exclusions, and the trace notes the pattern the entry matched as.
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+	effective := f.EffectiveExclusions()
+	g.Expect(effective[0].Pattern).To(Equal("extensions/*/Ingress"))
+	g.Expect(effective[0].Equivalents).To(Equal([]string{"networking.k8s.io/*/Ingress"}))
+
+	trace := f.Explain(ingress)
+	g.Expect(trace.Excluded).To(BeTrue())
+	g.Expect(trace.Steps[0].Matched).To(BeTrue())
+	g.Expect(trace.Steps[0].Equivalent).To(Equal("networking.k8s.io/*/Ingress"))
+	trace = f.Explain(event)
+	g.Expect(trace.Excluded).To(BeFalse())
+	g.Expect(trace.Steps[2].Equivalent).To(Equal("core/*/Event"))
+	g.Expect(trace.Steps[3].Equivalent).To(BeEmpty())
+}
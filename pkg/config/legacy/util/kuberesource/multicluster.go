@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Severity ranks an Inconsistency or a FilterWarning.
+type Severity string
+
+const (
+	// SeverityWarning is used for inconsistencies that only affect config processing.
+	SeverityWarning Severity = "Warning"
+
+	// SeverityError is used for inconsistencies in kinds required for service discovery, which make the
+	// aggregated service registry differ between clusters.
+	SeverityError Severity = "Error"
+)
+
+// Inconsistency is a collection that per-cluster filters decide differently, although it feeds outputs shared
+// by every cluster.
+type Inconsistency struct {
+	Collection collection.Name `json:"collection"`
+
+	// Outputs are the provider outputs the collection feeds, in name order.
+	Outputs collection.Names `json:"outputs"`
+
+	// Enabled and Disabled are the clusters in which the collection is enabled and disabled, sorted.
+	Enabled  []cluster.ID `json:"enabled"`
+	Disabled []cluster.ID `json:"disabled"`
+
+	Severity Severity `json:"severity"`
+}
+
+func (i Inconsistency) String() string {
+	return fmt.Sprintf("%s: collection %s, feeding %v, is enabled in clusters %v but disabled in clusters %v",
+		i.Severity, i.Collection, i.Outputs, i.Enabled, i.Disabled)
+}
+
+// CrossClusterConsistencyCheck compares the results of per-cluster filters that share providers, and returns an
+// Inconsistency for every collection that is enabled in some clusters and disabled in others while feeding a
+// provider output, since the aggregated output then depends on which cluster it is computed from. Collections
+// that feed no output, or that only some clusters have, are not reported. Inconsistencies are sorted by
+// collection name.
+func CrossClusterConsistencyCheck(results map[cluster.ID]*FilterResult, providers InputProviders) []Inconsistency {
+	providers = orNoProviders(providers)
+	clusters := make([]cluster.ID, 0, len(results))
+	for id := range results {
+		clusters = append(clusters, id)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i] < clusters[j] })
+
+	// Every collection a filter decided on is a candidate output, along with the synthesized outputs.
+	outputs := providers.SynthesizedOutputs()
+	for _, id := range clusters {
+		outputs = append(outputs, results[id].Schemas.CollectionNames()...)
+	}
+
+	var out []Inconsistency
+	for in, fed := range feeds(outputs, providers) {
+		i := Inconsistency{Collection: in, Severity: SeverityWarning}
+		shared := true
+		for _, id := range clusters {
+			s, ok := results[id].Schemas.Find(in.String())
+			if !ok {
+				shared = false
+				break
+			}
+			if s.IsDisabled() {
+				i.Disabled = append(i.Disabled, id)
+			} else {
+				i.Enabled = append(i.Enabled, id)
+			}
+			if IsRequiredForServiceDiscovery(s.Resource()) {
+				i.Severity = SeverityError
+			}
+		}
+		if !shared || len(i.Enabled) == 0 || len(i.Disabled) == 0 {
+			continue
+		}
+		for o := range fed {
+			i.Outputs = append(i.Outputs, o)
+		}
+		i.Outputs.Sort()
+		out = append(out, i)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Collection < out[b].Collection })
+	return out
+}
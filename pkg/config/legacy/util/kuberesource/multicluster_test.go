@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestCrossClusterConsistencyCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testKubeGateway, testGateway, testConfigMap, testMeshConfig, testDeployment)
+	providers := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		testGateway.Name():    {testKubeGateway.Name(), testService.Name()},
+		testMeshConfig.Name(): {testConfigMap.Name()},
+	}}
+	apply := func(excluded ...string) *FilterResult {
+		result, err := FilterCollections(in, providers, in.CollectionNames(), WithExcludedResourceKinds(excluded...))
+		g.Expect(err).To(BeNil())
+		return result
+	}
+
+	// Both clusters agree on the shared inputs; Deployment feeds no output, so diverging on it is harmless.
+	results := map[cluster.ID]*FilterResult{
+		"east": apply(KindDeployment),
+		"west": apply(),
+	}
+	g.Expect(CrossClusterConsistencyCheck(results, providers)).To(BeEmpty())
+
+	// Diverging on the shared inputs of the gateways output.
+	results["west"] = apply(KindService)
+	results["north"] = apply(KindService, "networking.istio.io/Gateway")
+	inconsistencies := CrossClusterConsistencyCheck(results, providers)
+	g.Expect(inconsistencies).To(Equal([]Inconsistency{
+		{
+			Collection: testService.Name(),
+			Outputs:    collection.Names{testGateway.Name()},
+			Enabled:    []cluster.ID{"east"},
+			Disabled:   []cluster.ID{"north", "west"},
+			Severity:   SeverityError,
+		},
+		{
+			Collection: testKubeGateway.Name(),
+			Outputs:    collection.Names{testGateway.Name()},
+			Enabled:    []cluster.ID{"east", "west"},
+			Disabled:   []cluster.ID{"north"},
+			Severity:   SeverityWarning,
+		},
+	}))
+	g.Expect(inconsistencies[0].String()).To(Equal("Error: collection k8s/core/v1/services, feeding " +
+		"[istio/networking/v1alpha3/gateways], is enabled in clusters [east] but disabled in clusters [north west]"))
+
+	g.Expect(CrossClusterConsistencyCheck(nil, providers)).To(BeEmpty())
+}
@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WithNamespaceExclusions excludes the kinds matched by entries, in the syntax described on Exclusion, in
+// namespace only, for example to ignore Secrets in kube-system while watching them everywhere else. Unlike
+// cluster-wide exclusions, they do not disable any collection: the namespace is added to the ExcludedNamespaces
+// of the collection hints of the enabled, namespaced collections they match, which the informer layer turns into
+// a field selector with NamespaceFieldSelector. Cluster-scoped kinds are never matched. Entries are merged with
+// any previously given for namespace, and entries that cannot be parsed cause Apply to fail.
+func WithNamespaceExclusions(namespace string, entries ...string) FilterOption {
+	return func(o *filterOptions) {
+		if o.namespaceExclusions == nil {
+			o.namespaceExclusions = make(map[string][]string)
+		}
+		o.namespaceExclusions[namespace] = append(append([]string{}, o.namespaceExclusions[namespace]...), entries...)
+	}
+}
+
+// NamespaceFieldSelector returns the field selector that leaves out the objects in the excluded namespaces of h,
+// such as metadata.namespace!=kube-system, or "" if there are none.
+func (h CollectionHint) NamespaceFieldSelector() string {
+	terms := make([]string, 0, len(h.ExcludedNamespaces))
+	for _, ns := range h.ExcludedNamespaces {
+		terms = append(terms, "metadata.namespace!="+ns)
+	}
+	return strings.Join(terms, ",")
+}
+
+// namespaceExclusionNamespaces returns the namespaces given to WithNamespaceExclusions, in order.
+func (o *filterOptions) namespaceExclusionNamespaces() []string {
+	out := make([]string, 0, len(o.namespaceExclusions))
+	for ns := range o.namespaceExclusions {
+		out = append(out, ns)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// namespaceExclusionErrors returns an error for every namespace exclusion without a namespace, and for every
+// entry of one that cannot be parsed, with corrections suggested from known.
+func (o *filterOptions) namespaceExclusionErrors(known collection.Schemas) []error {
+	var errs []error
+	for _, ns := range o.namespaceExclusionNamespaces() {
+		if ns == "" {
+			errs = append(errs, fmt.Errorf("namespace exclusion without a namespace"))
+			continue
+		}
+		_, parseErrs := parseExclusions(o.namespaceExclusions[ns], o.positions, known)
+		for _, err := range parseErrs {
+			errs = append(errs, fmt.Errorf("namespace exclusion for %s: %v", ns, err))
+		}
+	}
+	return errs
+}
+
+// applyNamespaceExclusions adds the namespaces whose exclusions match them to the collection hints of the
+// enabled, namespaced collections of result.
+func (f *CollectionFilter) applyNamespaceExclusions(result *FilterResult) {
+	if len(f.opts.namespaceExclusions) == 0 {
+		return
+	}
+	namespaces := f.opts.namespaceExclusionNamespaces()
+	matchers := make([]*ExclusionMatcher, 0, len(namespaces))
+	for _, ns := range namespaces {
+		matchers = append(matchers, compileExclusions(f.opts.namespaceExclusions[ns]))
+	}
+	for _, s := range result.Schemas.All() {
+		if s.IsDisabled() || s.Resource().IsClusterScoped() || f.passedThrough(s) {
+			continue
+		}
+		var excluded []string
+		for i, m := range matchers {
+			if m.MatchesSchema(s) {
+				excluded = append(excluded, namespaces[i])
+			}
+		}
+		if len(excluded) == 0 {
+			continue
+		}
+		h := result.CollectionHints[s.Name()]
+		h.ExcludedNamespaces = excluded
+		result.CollectionHints[s.Name()] = h
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestWithNamespaceExclusions(t *testing.T) {
+	g := NewWithT(t)
+	in := namespaceScopeInput()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithNamespaceExclusions("kube-system", KindPod, KindService, "!core/Service", KindNode),
+		WithNamespaceExclusions("istio-system", KindPod),
+		WithNamespaceExclusions("legacy", "networking.istio.io/*"),
+		WithCollectionHint(testPod.Name(), CollectionHint{PageSize: 500}))
+	g.Expect(err).To(BeNil())
+
+	// The collections stay enabled: only the namespaces are left out.
+	g.Expect(result.Schemas.DisabledCollectionNames()).To(BeEmpty())
+	hint, ok := result.HintFor(testPod.Name())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hint).To(Equal(CollectionHint{PageSize: 500, ExcludedNamespaces: []string{"istio-system", "kube-system"}}))
+	g.Expect(hint.NamespaceFieldSelector()).To(Equal("metadata.namespace!=istio-system,metadata.namespace!=kube-system"))
+	hint, _ = result.HintFor(testVirtualService.Name())
+	g.Expect(hint.ExcludedNamespaces).To(Equal([]string{"legacy"}))
+
+	// The negated entry re-includes Service, and the cluster-scoped Node is never matched.
+	for _, n := range []collection.Name{testService.Name(), clusterScopedNode.Name(), testEndpoints.Name()} {
+		_, ok := result.HintFor(n)
+		g.Expect(ok).To(BeFalse(), n.String())
+	}
+	g.Expect(CollectionHint{}.NamespaceFieldSelector()).To(BeEmpty())
+}
+
+func TestWithNamespaceExclusions_Errors(t *testing.T) {
+	g := NewWithT(t)
+	in := namespaceScopeInput()
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithNamespaceExclusions("kube-system", "a/b/c/d"))
+	g.Expect(err).To(MatchError(ContainSubstring(`namespace exclusion for kube-system: invalid exclusion entry 0 "a/b/c/d"`)))
+
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithNamespaceExclusions("", KindPod))
+	g.Expect(err).To(MatchError(ContainSubstring("namespace exclusion without a namespace")))
+}
+
+func TestWithNamespaceExclusions_Update(t *testing.T) {
+	g := NewWithT(t)
+	in := namespaceScopeInput()
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithNamespaceExclusions("kube-system", KindSecret))
+	g.Expect(err).To(BeNil())
+	initial := state.Current().Fingerprint
+
+	// Excluding Pod in a namespace changes its hint, but no decision.
+	result, err := state.Update(WithNamespaceExclusions("kube-system", KindPod))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Fingerprint).NotTo(Equal(initial))
+	g.Expect(state.Changed()).To(Equal(collection.Names{testPod.Name()}))
+	g.Expect(reasonOf(result, testPod.Name())).To(Equal(ReasonEnabled))
+}
@@ -0,0 +1,70 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NamespaceDependent is a feature that reads the metadata of namespaces, and breaks if they are not watched.
+type NamespaceDependent struct {
+	Feature string `json:"feature"`
+
+	// Metadata is the namespace metadata the feature reads.
+	Metadata string `json:"metadata"`
+}
+
+var namespaceDependents = []NamespaceDependent{
+	{Feature: "revision selection", Metadata: "istio.io/rev label"},
+	{Feature: "discovery selectors", Metadata: "labels matched by meshConfig.discoverySelectors"},
+	{Feature: "sidecar injection", Metadata: "istio-injection label"},
+	{Feature: "ambient enrollment", Metadata: "istio.io/dataplane-mode label"},
+	{Feature: "network topology", Metadata: "topology.istio.io/network label"},
+}
+
+// NamespaceDependents returns the features known to depend on namespace metadata.
+func NamespaceDependents() []NamespaceDependent {
+	return append([]NamespaceDependent{}, namespaceDependents...)
+}
+
+// namespaceWarnings returns a WarningNamespaceDisabled warning, of SeverityError, if service discovery is enabled
+// but the report disables the core Namespace collection for an exclusion, which the discovery override did not
+// undo.
+func (o *filterOptions) namespaceWarnings(report *FilterReport) []FilterWarning {
+	if !o.features.ServiceDiscovery {
+		return nil
+	}
+	var out []FilterWarning
+	for _, e := range report.Entries {
+		if !e.Disabled || !isExcludedKind(e.Decision) || e.Group != "" || e.Kind != KindNamespace ||
+			!isServiceDiscoveryType(e.Group, e.Version, e.Kind) {
+			continue
+		}
+		dependents := make([]string, 0, len(namespaceDependents))
+		for _, d := range namespaceDependents {
+			dependents = append(dependents, fmt.Sprintf("%s (%s)", d.Feature, d.Metadata))
+		}
+		out = append(out, FilterWarning{
+			Code:       WarningNamespaceDisabled,
+			Severity:   SeverityError,
+			Entry:      e.MatchedEntry,
+			Collection: e.Collection,
+			Message: fmt.Sprintf("%s is disabled while service discovery is enabled; features that read namespace "+
+				"metadata will not work: %s", e.Collection, strings.Join(dependents, ", ")),
+		})
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+)
+
+func TestNamespaceDependents(t *testing.T) {
+	g := NewWithT(t)
+
+	features := make(map[string]struct{})
+	for _, d := range NamespaceDependents() {
+		g.Expect(d.Feature).NotTo(BeEmpty())
+		g.Expect(d.Metadata).NotTo(BeEmpty())
+		g.Expect(features).NotTo(HaveKey(d.Feature))
+		features[d.Feature] = struct{}{}
+	}
+	g.Expect(features).To(HaveKey("revision selection"))
+	g.Expect(features).To(HaveKey("discovery selectors"))
+}
+
+func TestNamespaceWarnings(t *testing.T) {
+	in := testSchemas()
+	discovery := WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true})
+	cases := []struct {
+		name     string
+		opts     []FilterOption
+		expected bool
+	}{
+		{
+			name: "overridden",
+			opts: []FilterOption{WithExcludedResourceKinds(KindNamespace), discovery},
+		},
+		{
+			name: "discovery disabled",
+			opts: []FilterOption{WithExcludedResourceKinds(KindNamespace)},
+		},
+		{
+			name:     "override withheld",
+			opts:     []FilterOption{WithExcludedResourceKinds(KindNamespace), discovery, WithDiscoveryOverrideOnly(KindService)},
+			expected: true,
+		},
+		{
+			name: "other kind withheld",
+			opts: []FilterOption{WithExcludedResourceKinds(KindPod), discovery, WithDiscoveryOverrideOnly(KindService)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), c.opts...)
+			g.Expect(err).To(BeNil())
+
+			var warnings []FilterWarning
+			for _, w := range result.Warnings {
+				if w.Code == WarningNamespaceDisabled {
+					warnings = append(warnings, w)
+				}
+			}
+			if !c.expected {
+				g.Expect(warnings).To(BeEmpty())
+				return
+			}
+			g.Expect(warnings).To(HaveLen(1))
+			g.Expect(warnings[0].Severity).To(Equal(SeverityError))
+			g.Expect(warnings[0].Entry).To(Equal(KindNamespace))
+			g.Expect(warnings[0].Collection).To(Equal(testNamespace.Name()))
+			g.Expect(warnings[0].Message).To(Equal("k8s/core/v1/namespaces is disabled while service discovery is " +
+				"enabled; features that read namespace metadata will not work: revision selection (istio.io/rev label), " +
+				"discovery selectors (labels matched by meshConfig.discoverySelectors), sidecar injection " +
+				"(istio-injection label), ambient enrollment (istio.io/dataplane-mode label), network topology " +
+				"(topology.istio.io/network label)"))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WithNamespaceScope scopes the informers of the enabled, namespaced kinds required for service discovery, such as
+// Pod, Service and Endpoints, to namespaces, for example those selected by meshConfig.discoverySelectors. The
+// namespaces are attached to the collection hints of those collections, which the informer layer reads through
+// HintFor, unless a collection hint already gives namespaces of its own. Cluster-scoped kinds are never scoped. An
+// empty list, the default, watches all namespaces.
+func WithNamespaceScope(namespaces []string) FilterOption {
+	return func(o *filterOptions) {
+		o.namespaceScope = sortedNamespaces(namespaces)
+	}
+}
+
+// sortedNamespaces returns the distinct namespaces in order, or nil if there are none.
+func sortedNamespaces(namespaces []string) []string {
+	if len(namespaces) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(namespaces))
+	out := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if _, ok := seen[ns]; !ok {
+			seen[ns] = struct{}{}
+			out = append(out, ns)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// namespaceScopeErrors returns an error for every collection hint that scopes a cluster-scoped collection of in to
+// namespaces.
+func (o *filterOptions) namespaceScopeErrors(in collection.Schemas) []error {
+	var errs []error
+	for _, name := range o.collectionHintNames() {
+		if len(o.collectionHints[name].Namespaces) == 0 {
+			continue
+		}
+		if s, ok := in.Find(name.String()); ok && s.Resource().IsClusterScoped() {
+			errs = append(errs, fmt.Errorf("collection hint for %s: cannot scope cluster-scoped kind %s to namespaces",
+				name, s.Resource().Kind()))
+		}
+	}
+	return errs
+}
+
+// applyNamespaceScope attaches the namespace scope to the collection hints of the enabled, namespaced discovery
+// collections of result.
+func (f *CollectionFilter) applyNamespaceScope(result *FilterResult) {
+	if f.opts.namespaceScope == nil {
+		return
+	}
+	for _, s := range result.Schemas.All() {
+		res := s.Resource()
+		if s.IsDisabled() || res.IsClusterScoped() || !f.opts.isDiscoveryKind(res) || f.passedThrough(s) {
+			continue
+		}
+		h := result.CollectionHints[s.Name()]
+		if len(h.Namespaces) == 0 {
+			h.Namespaces = append([]string{}, f.opts.namespaceScope...)
+		}
+		result.CollectionHints[s.Name()] = h
+	}
+}
+
+// hintChanges returns the collections whose collection hint differs between prev and next, in name order.
+func hintChanges(prev, next *FilterResult) collection.Names {
+	var out collection.Names
+	for name, h := range next.CollectionHints {
+		if old, ok := prev.CollectionHints[name]; !ok || !old.equal(h) {
+			out = append(out, name)
+		}
+	}
+	for name := range prev.CollectionHints {
+		if _, ok := next.CollectionHints[name]; !ok {
+			out = append(out, name)
+		}
+	}
+	out.Sort()
+	return out
+}
+
+// mergeNames returns the distinct names of a and b, in name order.
+func mergeNames(a, b collection.Names) collection.Names {
+	set := make(map[collection.Name]struct{}, len(a)+len(b))
+	for _, n := range append(append(collection.Names{}, a...), b...) {
+		set[n] = struct{}{}
+	}
+	return sortedCollectionNames(set)
+}
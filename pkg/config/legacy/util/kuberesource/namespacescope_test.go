@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// newClusterScopedTestSchema is newTestSchema for a cluster-scoped kind.
+func newClusterScopedTestSchema(group, version, kind string) collection.Schema {
+	s := newTestSchema(group, version, kind)
+	return collection.Builder{
+		Name:         s.Name().String(),
+		VariableName: kind,
+		Resource: resource.Builder{
+			ClusterScoped: true,
+			Group:         group,
+			Version:       version,
+			Kind:          kind,
+			Plural:        s.Resource().Plural(),
+			Proto:         "google.protobuf.Empty",
+			ProtoPackage:  "github.com/gogo/protobuf/types",
+		}.BuildNoValidate(),
+	}.MustBuild()
+}
+
+var (
+	clusterScopedNode      = newClusterScopedTestSchema("", "v1", "Node")
+	clusterScopedNamespace = newClusterScopedTestSchema("", "v1", "Namespace")
+)
+
+func namespaceScopeInput() collection.Schemas {
+	return collection.SchemasFor(testService, testPod, testEndpoints, testEndpointSlice, clusterScopedNode,
+		clusterScopedNamespace, testVirtualService)
+}
+
+func TestWithNamespaceScope(t *testing.T) {
+	in := namespaceScopeInput()
+	discovery := WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true})
+
+	t.Run("scoped", func(t *testing.T) {
+		g := NewWithT(t)
+		result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), discovery,
+			WithNamespaceScope([]string{"istio-system", "bookinfo", "istio-system"}),
+			WithCollectionHint(testPod.Name(), CollectionHint{PageSize: 500}),
+			WithCollectionHint(testEndpoints.Name(), CollectionHint{Namespaces: []string{"legacy"}}))
+		g.Expect(err).To(BeNil())
+
+		scope := []string{"bookinfo", "istio-system"}
+		hint, ok := result.HintFor(testService.Name())
+		g.Expect(ok).To(BeTrue())
+		g.Expect(hint.Namespaces).To(Equal(scope))
+		hint, _ = result.HintFor(testEndpointSlice.Name())
+		g.Expect(hint.Namespaces).To(Equal(scope))
+		// The scope is merged into an existing hint, but does not replace namespaces it gives.
+		hint, _ = result.HintFor(testPod.Name())
+		g.Expect(hint).To(Equal(CollectionHint{PageSize: 500, Namespaces: scope}))
+		hint, _ = result.HintFor(testEndpoints.Name())
+		g.Expect(hint.Namespaces).To(Equal([]string{"legacy"}))
+
+		for _, n := range []collection.Name{clusterScopedNode.Name(), clusterScopedNamespace.Name(), testVirtualService.Name()} {
+			_, ok := result.HintFor(n)
+			g.Expect(ok).To(BeFalse(), n.String())
+		}
+	})
+
+	t.Run("all namespaces", func(t *testing.T) {
+		g := NewWithT(t)
+		scoped, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), discovery,
+			WithNamespaceScope(nil))
+		g.Expect(err).To(BeNil())
+		g.Expect(scoped.CollectionHints).To(BeEmpty())
+
+		unscoped, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), discovery)
+		g.Expect(err).To(BeNil())
+		g.Expect(scoped.Fingerprint).To(Equal(unscoped.Fingerprint))
+	})
+
+	t.Run("disabled collections are not scoped", func(t *testing.T) {
+		g := NewWithT(t)
+		result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+			WithExcludedResourceKinds(KindPod), WithNamespaceScope([]string{"bookinfo"}))
+		g.Expect(err).To(BeNil())
+		_, ok := result.HintFor(testPod.Name())
+		g.Expect(ok).To(BeFalse())
+		_, ok = result.HintFor(testService.Name())
+		g.Expect(ok).To(BeTrue())
+	})
+}
+
+func TestWithNamespaceScope_ClusterScoped(t *testing.T) {
+	g := NewWithT(t)
+	in := namespaceScopeInput()
+	opts := []FilterOption{
+		WithNamespaceScope([]string{"bookinfo"}),
+		WithCollectionHint(clusterScopedNode.Name(), CollectionHint{ResyncPeriod: time.Minute, Namespaces: []string{"bookinfo"}}),
+	}
+
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+	g.Expect(err).To(MatchError(ContainSubstring("cannot scope cluster-scoped kind Node to namespaces")))
+
+	errs := ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{}, opts...)
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0]).To(MatchError(ContainSubstring(clusterScopedNode.Name().String())))
+}
+
+func TestWithNamespaceScope_Update(t *testing.T) {
+	g := NewWithT(t)
+	in := namespaceScopeInput()
+	discovery := WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true})
+
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), discovery,
+		WithNamespaceScope([]string{"bookinfo"}))
+	g.Expect(err).To(BeNil())
+
+	// The namespace list changes, but no decision does: the scoped collections are reported as changed.
+	result, err := state.Update(discovery, WithNamespaceScope([]string{"bookinfo", "reviews"}))
+	g.Expect(err).To(BeNil())
+	g.Expect(state.Changed()).To(Equal(collection.Names{testEndpoints.Name(), testPod.Name(), testService.Name(),
+		testEndpointSlice.Name()}))
+	hint, _ := result.HintFor(testService.Name())
+	g.Expect(hint.Namespaces).To(Equal([]string{"bookinfo", "reviews"}))
+
+	// The same list again changes nothing.
+	_, err = state.Update(discovery, WithNamespaceScope([]string{"reviews", "bookinfo"}))
+	g.Expect(err).To(BeNil())
+	g.Expect(state.Changed()).To(BeEmpty())
+
+	// Widening to all namespaces drops the scope from every hint.
+	result, err = state.Update(discovery)
+	g.Expect(err).To(BeNil())
+	g.Expect(state.Changed()).To(HaveLen(4))
+	g.Expect(result.CollectionHints).To(BeEmpty())
+}
@@ -0,0 +1,99 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WouldChangeAnything evaluates appending a single exclusion entry to the configuration that produced current,
+// and returns whether it would change the set of enabled collections, along with an explanation. The entry is
+// decided with the same matcher, precedence, negation and feature overrides as the filter. Availability is taken
+// from current, so nothing is probed; collections that are unavailable or out of the group scope stay disabled.
+func WouldChangeAnything(current *FilterResult, entry string) (bool, string) {
+	if current == nil || current.filter == nil || current.Report == nil {
+		return false, "the result was not produced by a collection filter"
+	}
+	in := current.inputSchemas()
+	if _, errs := parseExclusions([]string{entry}, ConfigPositions{}, in); len(errs) > 0 {
+		return false, errs[0].Error()
+	}
+	next := current.filter.withDelta(current, ConfigDelta{AddedExclusions: []string{entry}})
+
+	affected := affectedBy(current.index, ConfigDelta{AddedExclusions: []string{entry}})
+	if len(affected) == 0 {
+		e, _ := parseExclusion(entry)
+		return false, fmt.Sprintf("entry %s %s", entry, current.Index().unmatchedReason(e))
+	}
+
+	scratch := &FilterResult{}
+	var enabled, disabled collection.Names
+	var unchanged []string
+	for i, s := range in.All() {
+		if _, ok := affected[s.Name()]; !ok {
+			continue
+		}
+		was := current.Report.Entries[i].Decision
+		d := was
+		if next.opts.inGroupScope(s) {
+			d = keepUnavailable(next.decide(s, scratch), was)
+		}
+		switch {
+		case d.Disabled == was.Disabled:
+			unchanged = append(unchanged, fmt.Sprintf("%s stays %s", s.Name(), &d))
+		case d.Disabled:
+			disabled = append(disabled, s.Name())
+		default:
+			enabled = append(enabled, s.Name())
+		}
+	}
+
+	if len(enabled) == 0 && len(disabled) == 0 {
+		return false, fmt.Sprintf("entry %s would not change the enabled set: %s", entry, strings.Join(unchanged, ", "))
+	}
+	var changes []string
+	if len(disabled) > 0 {
+		changes = append(changes, fmt.Sprintf("disable %s", joinNames(disabled)))
+	}
+	if len(enabled) > 0 {
+		changes = append(changes, fmt.Sprintf("enable %s", joinNames(enabled)))
+	}
+	return true, fmt.Sprintf("entry %s would %s", entry, strings.Join(changes, " and "))
+}
+
+// keepUnavailable returns d, disabled for the availability reasons was is disabled for, since adding an
+// exclusion entry cannot make a resource available.
+func keepUnavailable(d, was Decision) Decision {
+	if !was.Disabled {
+		return d
+	}
+	for _, r := range append([]Reason{was.Reason}, was.SecondaryReasons...) {
+		if r == ReasonResourceUnavailable || r == ReasonUndetermined {
+			d = d.disabledFor(r)
+		}
+	}
+	return d
+}
+
+func joinNames(names collection.Names) string {
+	s := make([]string, 0, len(names))
+	for _, n := range names {
+		s = append(s, n.String())
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,121 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestWouldChangeAnything(t *testing.T) {
+	in := collection.SchemasFor(testService, testPod, testDeployment, testKubeGateway, testVirtualService)
+	apply := func(t *testing.T, opts ...FilterOption) *FilterResult {
+		result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+		NewWithT(t).Expect(err).To(BeNil())
+		return result
+	}
+
+	cases := []struct {
+		name    string
+		opts    []FilterOption
+		entry   string
+		changes bool
+		explain string
+	}{
+		{
+			name:    "already excluded by default",
+			opts:    []FilterOption{WithExcludedResourceKinds(DefaultExcludedResourceKinds()...)},
+			entry:   "core/Pod",
+			explain: "entry core/Pod would not change the enabled set: k8s/core/v1/pods stays disabled (ExcludedKind)",
+		},
+		{
+			name: "protected by service discovery",
+			opts: []FilterOption{
+				WithExcludedResourceKinds(DefaultExcludedResourceKinds()...),
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+			},
+			entry: "Service",
+			explain: "entry Service would not change the enabled set: " +
+				"k8s/core/v1/services stays enabled (RequiredForServiceDiscovery)",
+		},
+		{
+			name: "discovery override withheld",
+			opts: []FilterOption{
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+				WithDiscoveryOverrideOnly(KindPod),
+			},
+			entry:   "Service",
+			changes: true,
+			explain: "entry Service would disable k8s/core/v1/services",
+		},
+		{
+			name:    "impactful",
+			opts:    []FilterOption{WithExcludedResourceKinds("networking.istio.io/*")},
+			entry:   "apps/Deployment",
+			changes: true,
+			explain: "entry apps/Deployment would disable k8s/apps/v1/deployments",
+		},
+		{
+			name:    "negation",
+			opts:    []FilterOption{WithExcludedResourceKinds("networking.istio.io/*")},
+			entry:   "!VirtualService",
+			changes: true,
+			explain: "entry !VirtualService would enable k8s/networking.istio.io/v1alpha3/virtualservices",
+		},
+		{
+			name:    "negation of an enabled kind",
+			entry:   "!Deployment",
+			explain: "entry !Deployment would not change the enabled set: k8s/apps/v1/deployments stays enabled (Enabled)",
+		},
+		{
+			name:    "no match",
+			entry:   "Widget",
+			explain: "entry Widget matches no collection",
+		},
+		{
+			name:    "invalid",
+			entry:   "/Service",
+			explain: `invalid exclusion entry 0 "/Service": empty segment; use "core" for the core group`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			current := apply(t, c.opts...)
+			changes, explain := WouldChangeAnything(current, c.entry)
+			g.Expect(explain).To(Equal(c.explain))
+			g.Expect(changes).To(Equal(c.changes))
+
+			// The answer agrees with applying the entry.
+			if c.name == "invalid" {
+				return
+			}
+			next := apply(t, append(c.opts, WithExcludedResourceKinds(c.entry))...)
+			if c.changes {
+				g.Expect(next.EnabledCollectionNames()).NotTo(Equal(current.EnabledCollectionNames()))
+			} else {
+				g.Expect(next.EnabledCollectionNames()).To(Equal(current.EnabledCollectionNames()))
+			}
+		})
+	}
+
+	changes, explain := WouldChangeAnything(&FilterResult{}, "Pod")
+	NewWithT(t).Expect(changes).To(BeFalse())
+	NewWithT(t).Expect(explain).To(Equal("the result was not produced by a collection filter"))
+}
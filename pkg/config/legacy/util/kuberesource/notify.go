@@ -0,0 +1,203 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"time"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// FilterChange describes an update of a CollectionFilterState that changed the set of enabled collections.
+type FilterChange struct {
+	// Fingerprint is the fingerprint of the new result.
+	Fingerprint string `json:"fingerprint"`
+
+	// Started and Stopped are the collections that became enabled and disabled, in name order. A lazy collection
+	// is started once it is promoted, and stopped if it becomes lazy again or is disabled.
+	Started collection.Names `json:"started,omitempty"`
+	Stopped collection.Names `json:"stopped,omitempty"`
+}
+
+// Notify returns a channel that receives a FilterChange for every update that changes the set of started
+// collections, which are the enabled collections that are not lazy. Sends never block Update: if the consumer has
+// not received the previous change yet, it is replaced by a single change that combines both, so the consumer
+// always eventually receives the most recent state. Changes are delivered in update order, and may be held back
+// and combined; see WithNotifyWindow. The channel is closed by Close.
+func (s *CollectionFilterState) Notify() <-chan FilterChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan FilterChange, 1)
+	if s.closed {
+		close(ch)
+		return ch
+	}
+	s.subscribers = append(s.subscribers, ch)
+	return ch
+}
+
+// WithNotifyWindow makes a CollectionFilterState hold a change back for window before it is delivered to the
+// channels returned by Notify, combining it with every change made in the meantime, so that a burst of updates is
+// notified once. The window starts with the first change since the last delivery, and is measured by the clock of
+// the state; see WithClock. By default, changes are delivered as soon as they are made. It has no effect on a
+// single filter pass.
+func WithNotifyWindow(window time.Duration) FilterOption {
+	return func(o *filterOptions) {
+		o.notifyWindow = window
+	}
+}
+
+// Close closes every channel returned by Notify, dropping any change held back by the notification window, and
+// stops re-probing availability. Updates are still applied after Close, but no longer notified.
+func (s *CollectionFilterState) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	subscribers := s.subscribers
+	s.subscribers = nil
+	if s.stopPending != nil {
+		s.stopPending()
+	}
+	s.pending, s.stopPending = nil, nil
+	s.reprobes.cancel()
+	// Wait for changes that are being sent, as notify does, so that no channel is closed while it is sent to.
+	s.notifyMu.Lock()
+	s.mu.Unlock()
+	defer s.notifyMu.Unlock()
+	for _, ch := range subscribers {
+		close(ch)
+	}
+}
+
+// notify returns a function that sends the change from prev to next to every current subscriber, or nil if there
+// is nothing to send yet. It must be called with the lock held. With a positive window, the change is held back
+// until flush delivers it.
+func (s *CollectionFilterState) notify(prev, next *FilterResult, window time.Duration) func() {
+	if len(s.subscribers) == 0 {
+		return nil
+	}
+	change := FilterChange{
+		Fingerprint: next.Fingerprint,
+		Started:     startedDifference(next, prev),
+		Stopped:     startedDifference(prev, next),
+	}
+	if len(change.Started) == 0 && len(change.Stopped) == 0 {
+		return nil
+	}
+	if s.pending != nil {
+		change = coalesce(*s.pending, change)
+		if window <= 0 {
+			s.stopPending()
+			s.pending, s.stopPending = nil, nil
+		}
+	}
+	if window > 0 {
+		if s.pending == nil {
+			s.stopPending = s.clock.AfterFunc(window, s.flush)
+		}
+		s.pending = &change
+		return nil
+	}
+	return s.send(change)
+}
+
+// flush delivers the change held back by the notification window, if any.
+func (s *CollectionFilterState) flush() {
+	s.mu.Lock()
+	if s.pending == nil {
+		s.mu.Unlock()
+		return
+	}
+	send := s.send(*s.pending)
+	s.pending, s.stopPending = nil, nil
+	s.mu.Unlock()
+	if send != nil {
+		send()
+	}
+}
+
+// send returns a function that sends change to every current subscriber, or nil if change is empty, as changes
+// that were combined can be. It must be called with the lock held. Unless it returns nil, it acquires notifyMu,
+// which the returned function releases, so that changes are sent after the lock is released,
+// but still one at a time and in order. Being the only sender, it always finds a slot free once the pending change
+// has been taken.
+func (s *CollectionFilterState) send(change FilterChange) func() {
+	if len(change.Started) == 0 && len(change.Stopped) == 0 {
+		return nil
+	}
+	subscribers := append([]chan FilterChange{}, s.subscribers...)
+	s.notifyMu.Lock()
+	return func() {
+		defer s.notifyMu.Unlock()
+		for _, ch := range subscribers {
+			c := change
+			select {
+			case pending := <-ch:
+				c = coalesce(pending, change)
+			default:
+			}
+			ch <- c
+		}
+	}
+}
+
+// startedDifference returns the collections started in a but not in b, in name order.
+func startedDifference(a, b *FilterResult) collection.Names {
+	var out collection.Names
+	for _, n := range a.Schemas.WithoutDisabledCollections().CollectionNames() {
+		if a.IsLazy(n) {
+			continue
+		}
+		if s, ok := b.Schemas.Find(n.String()); !ok || s.IsDisabled() || b.IsLazy(n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// coalesce combines the change first with the change second that follows it.
+func coalesce(first, second FilterChange) FilterChange {
+	return FilterChange{
+		Fingerprint: second.Fingerprint,
+		Started:     union(subtract(first.Started, second.Stopped), subtract(second.Started, first.Stopped)),
+		Stopped:     union(subtract(first.Stopped, second.Started), subtract(second.Stopped, first.Started)),
+	}
+}
+
+func subtract(a, b collection.Names) collection.Names {
+	remove := make(map[collection.Name]struct{}, len(b))
+	for _, n := range b {
+		remove[n] = struct{}{}
+	}
+	var out collection.Names
+	for _, n := range a {
+		if _, ok := remove[n]; !ok {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func union(a, b collection.Names) collection.Names {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	out := append(append(collection.Names{}, a...), b...)
+	out.Sort()
+	return out
+}
@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestCollectionFilterState_Notify(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testDeployment)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	ch := state.Notify()
+
+	// An update that does not change the enabled set is not notified.
+	_, err = state.Update(WithSelectorHint(KindPod, SelectorHint{}))
+	g.Expect(err).To(BeNil())
+	g.Expect(ch).NotTo(Receive())
+
+	result, err := state.Update(WithExcludedResourceKinds(KindPod))
+	g.Expect(err).To(BeNil())
+	g.Expect(ch).To(Receive(Equal(FilterChange{
+		Fingerprint: result.Fingerprint,
+		Stopped:     collection.Names{testPod.Name()},
+	})))
+
+	result, err = state.Update()
+	g.Expect(err).To(BeNil())
+	g.Expect(ch).To(Receive(Equal(FilterChange{
+		Fingerprint: result.Fingerprint,
+		Started:     collection.Names{testPod.Name()},
+	})))
+}
+
+func TestCollectionFilterState_NotifyCoalesces(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testDeployment)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindService))
+	g.Expect(err).To(BeNil())
+	ch := state.Notify()
+
+	// The consumer does not receive until all updates are done, so they are combined into the net change.
+	for _, kinds := range [][]string{
+		{KindPod},
+		{KindPod, KindDeployment},
+		{KindDeployment},
+	} {
+		_, err := state.Update(WithExcludedResourceKinds(kinds...))
+		g.Expect(err).To(BeNil())
+	}
+	g.Expect(ch).To(Receive(Equal(FilterChange{
+		Fingerprint: state.Current().Fingerprint,
+		Started:     collection.Names{testService.Name()},
+		Stopped:     collection.Names{testDeployment.Name()},
+	})))
+	g.Expect(ch).NotTo(Receive())
+
+	// A change that is undone before it is received leaves only the latest fingerprint.
+	_, err = state.Update(WithExcludedResourceKinds(KindDeployment, KindPod))
+	g.Expect(err).To(BeNil())
+	_, err = state.Update(WithExcludedResourceKinds(KindDeployment))
+	g.Expect(err).To(BeNil())
+	g.Expect(ch).To(Receive(Equal(FilterChange{Fingerprint: state.Current().Fingerprint})))
+}
+
+func TestCollectionFilterState_NotifyWindow(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testDeployment)
+	clock := &fakeClock{t: time.Unix(0, 0).UTC()}
+	opts := func(kinds ...string) []FilterOption {
+		return []FilterOption{WithNotifyWindow(10 * time.Second), WithExcludedResourceKinds(kinds...)}
+	}
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts(), WithClock(clock))...)
+	g.Expect(err).To(BeNil())
+	ch := state.Notify()
+
+	// Changes within the window of the first one are delivered together once it has elapsed.
+	_, err = state.Update(opts(KindPod)...)
+	g.Expect(err).To(BeNil())
+	clock.Advance(5 * time.Second)
+	_, err = state.Update(opts(KindPod, KindDeployment)...)
+	g.Expect(err).To(BeNil())
+	clock.Advance(5*time.Second - time.Nanosecond)
+	g.Expect(ch).NotTo(Receive())
+	clock.Advance(time.Nanosecond)
+	g.Expect(ch).To(Receive(Equal(FilterChange{
+		Fingerprint: state.Current().Fingerprint,
+		Stopped:     collection.Names{testDeployment.Name(), testPod.Name()},
+	})))
+	g.Expect(clock.pending()).To(Equal(0))
+
+	// A change that is undone within the window is not delivered.
+	_, err = state.Update(opts(KindPod)...)
+	g.Expect(err).To(BeNil())
+	_, err = state.Update(opts(KindPod, KindDeployment)...)
+	g.Expect(err).To(BeNil())
+	clock.Advance(10 * time.Second)
+	g.Expect(ch).NotTo(Receive())
+
+	// Without a window, the change held back is delivered at once, along with the new one.
+	_, err = state.Update(opts(KindPod)...)
+	g.Expect(err).To(BeNil())
+	_, err = state.Update()
+	g.Expect(err).To(BeNil())
+	g.Expect(ch).To(Receive(Equal(FilterChange{
+		Fingerprint: state.Current().Fingerprint,
+		Started:     collection.Names{testDeployment.Name(), testPod.Name()},
+	})))
+	g.Expect(clock.pending()).To(Equal(0))
+
+	// Close drops the change held back.
+	_, err = state.Update(opts(KindPod)...)
+	g.Expect(err).To(BeNil())
+	state.Close()
+	g.Expect(clock.pending()).To(Equal(0))
+	g.Expect(ch).To(BeClosed())
+}
+
+func TestCollectionFilterState_NotifyOrdering(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	ch := state.Notify()
+
+	received := make(chan []FilterChange)
+	go func() {
+		var changes []FilterChange
+		for c := range ch {
+			changes = append(changes, c)
+		}
+		received <- changes
+	}()
+
+	var fingerprints []string
+	for i := 0; i < 100; i++ {
+		var opts []FilterOption
+		if i%2 == 0 {
+			opts = append(opts, WithExcludedResourceKinds(KindPod))
+		}
+		result, err := state.Update(opts...)
+		g.Expect(err).To(BeNil())
+		fingerprints = append(fingerprints, result.Fingerprint)
+	}
+	state.Close()
+
+	changes := <-received
+	g.Expect(changes).NotTo(BeEmpty())
+	// Changes arrive in update order, so replaying them reaches the final state.
+	g.Expect(changes[len(changes)-1].Fingerprint).To(Equal(fingerprints[len(fingerprints)-1]))
+	enabled := map[collection.Name]bool{testService.Name(): true, testPod.Name(): true}
+	for _, c := range changes {
+		for _, n := range c.Started {
+			g.Expect(enabled[n]).To(BeFalse())
+			enabled[n] = true
+		}
+		for _, n := range c.Stopped {
+			g.Expect(enabled[n]).To(BeTrue())
+			enabled[n] = false
+		}
+	}
+	g.Expect(enabled).To(Equal(map[collection.Name]bool{testService.Name(): true, testPod.Name(): true}))
+
+	g.Expect(state.Notify()).To(BeClosed())
+}
@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DecodeOperatorExclusions decodes excluded resource kinds as passed through the IstioOperator spec. raw may be
+// a []string, a []interface{} of strings, or a single comma separated string, which may itself be a YAML flow
+// list such as "[Pod, Node]". Entries are trimmed of whitespace and quotes, and empty entries are dropped.
+func DecodeOperatorExclusions(raw interface{}) ([]string, error) {
+	var entries []string
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		entries = v
+	case []interface{}:
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("excluded resource kind at index %d: expected string, got %T", i, e)
+			}
+			entries = append(entries, s)
+		}
+	case string:
+		s := strings.TrimSpace(v)
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+		}
+		entries = strings.Split(s, ",")
+	default:
+		return nil, fmt.Errorf("excluded resource kinds: expected []string, []interface{} or string, got %T", raw)
+	}
+
+	result := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e = normalizeOperatorEntry(e); e != "" {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func normalizeOperatorEntry(e string) string {
+	e = strings.TrimSpace(e)
+	for len(e) >= 2 && (e[0] == '"' || e[0] == '\'') && e[len(e)-1] == e[0] {
+		e = strings.TrimSpace(e[1 : len(e)-1])
+	}
+	return strings.Trim(e, `"'`)
+}
@@ -0,0 +1,52 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDecodeOperatorExclusions(t *testing.T) {
+	cases := []struct {
+		name     string
+		raw      interface{}
+		expected []string
+		err      string
+	}{
+		{name: "nil"},
+		{name: "string slice", raw: []string{"Pod", " Node ", `"Secret"`, ""}, expected: []string{"Pod", "Node", "Secret"}},
+		{name: "interface slice", raw: []interface{}{"Pod", "'Node'"}, expected: []string{"Pod", "Node"}},
+		{name: "interface slice with non-string", raw: []interface{}{"Pod", 3}, err: "excluded resource kind at index 1: expected string, got int"},
+		{name: "comma separated", raw: "Pod, Node,,Secret", expected: []string{"Pod", "Node", "Secret"}},
+		{name: "flow list", raw: `["Pod", 'Node', Secret]`, expected: []string{"Pod", "Node", "Secret"}},
+		{name: "quoted string", raw: `"Pod,Node"`, expected: []string{"Pod", "Node"}},
+		{name: "empty string", raw: "", expected: []string{}},
+		{name: "map", raw: map[string]interface{}{"Pod": true}, err: "expected []string, []interface{} or string, got map[string]interface {}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, err := DecodeOperatorExclusions(c.raw)
+			if c.err != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(c.err)))
+				return
+			}
+			g.Expect(err).To(BeNil())
+			g.Expect(got).To(Equal(c.expected))
+		})
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// FilterOption configures optional behavior of the collection filter.
+type FilterOption func(o *filterOptions)
+
+// filterOptions holds the settings accumulated from a list of FilterOption.
+type filterOptions struct {
+	excludedResourceKinds []string
+	features              FeatureRequirements
+	selectorHints         map[string]SelectorHint
+	collectionHints       map[collection.Name]CollectionHint
+	duplicatePolicy       DuplicatePolicy
+	availability          *availabilityCheck
+	reasonHooks           []reasonHook
+	compactDisabled       bool
+
+	// exclusionSources holds the source of each entry in excludedResourceKinds.
+	exclusionSources []ExclusionSource
+
+	preferNewestVersion bool
+	frozenResult        bool
+
+	// discoveryOverrideOnly, if not nil, limits the kinds the service discovery override re-enables.
+	discoveryOverrideOnly map[string]struct{}
+
+	// onlyGroups, if not nil, limits filtering to schemas in these groups and the discovery builtins.
+	onlyGroups map[string]struct{}
+
+	// knownAvailability is availability determined by a previous result, which is not probed again.
+	knownAvailability map[config.GroupVersionKind]bool
+
+	// historySize configures the update history of a CollectionFilterState, and clock its clock.
+	historySize *int
+	clock       Clock
+
+	// notifyWindow and reprobe configure the notifications and availability re-probes of a CollectionFilterState.
+	notifyWindow time.Duration
+	reprobe      reprobeBackoff
+
+	// deferredProbes are the resource types whose availability is not probed, along with the error of their last
+	// probe; see WithReprobeBackoff.
+	deferredProbes map[config.GroupVersionKind]error
+
+	// warningSink logs the warnings of a CollectionFilterState.
+	warningSink func(FilterWarning)
+
+	// log is the logger given to WithLogger, or nil for the processing scope.
+	log Logger
+
+	// positions are the positions of the entries given to WithConfigPositions.
+	positions ConfigPositions
+
+	// kubeVersion is the Kubernetes version of the cluster, and kubeVersionGates the versions each gated exclusion
+	// entry applies to.
+	kubeVersion      string
+	kubeVersionGates map[string]KubeVersionRange
+
+	// reenableInputDisabled lets the filter enable collections that arrive disabled.
+	reenableInputDisabled bool
+
+	// namespaceScope are the namespaces given to WithNamespaceScope, in order, or nil for all namespaces.
+	namespaceScope []string
+
+	// namespaceExclusions are the exclusion entries given to WithNamespaceExclusions, keyed by namespace.
+	namespaceExclusions map[string][]string
+
+	// reviewedBaseline, if not nil, are the collections given to WithDenyNewCollections.
+	reviewedBaseline map[collection.Name]struct{}
+
+	// legacy, if not nil, restores the behaviors of the filter before exclusion entries were parsed.
+	legacy *legacySemantics
+
+	// enabledBudget, if not nil, caps the number of enabled CRD-backed collections.
+	enabledBudget *int
+	budgetPolicy  BudgetPolicy
+
+	// lazyKinds are watched once referenced, unless they are in referencedKinds.
+	lazyKinds       map[string]struct{}
+	referencedKinds map[string]struct{}
+
+	// onlyOutputs, if not nil, replaces the required collections, with onlyOutputsProviders resolving their inputs.
+	onlyOutputs          collection.Names
+	onlyOutputsProviders InputProviders
+
+	// statusWriters are kept enabled although they are not upstream of the required collections.
+	statusWriters map[collection.Name]struct{}
+
+	// conflictPolicy decides whether conflicting entries from different sources are a warning or an error.
+	conflictPolicy ConflictPolicy
+
+	// discovery configures the service discovery override.
+	discovery DiscoveryOptions
+}
+
+func newFilterOptions(opts []FilterOption) *filterOptions {
+	o := &filterOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFeatureRequirements sets the features whose required builtin kinds must remain enabled
+// even if they are excluded. Flags are merged with any previously set features.
+func WithFeatureRequirements(f FeatureRequirements) FilterOption {
+	return func(o *filterOptions) {
+		o.features = o.features.union(f)
+	}
+}
+
+// WithDiscoveryOverrideOnly limits the service discovery override to the given kinds. Other kinds required for
+// service discovery stay disabled when they are excluded, and a WarningDiscoveryImpact warning is reported for
+// each of them. Every kind must be required for service discovery, or Apply fails. Kinds are merged with any
+// previously set.
+func WithDiscoveryOverrideOnly(kinds ...string) FilterOption {
+	return func(o *filterOptions) {
+		if o.discoveryOverrideOnly == nil {
+			o.discoveryOverrideOnly = make(map[string]struct{})
+		}
+		for _, k := range kinds {
+			o.discoveryOverrideOnly[k] = struct{}{}
+		}
+	}
+}
+
+// requiredReason returns the reason res is re-enabled by the first enabled feature that requires it, taking
+// WithDiscoveryOverrideOnly and the endpoints mode into account.
+func (o *filterOptions) requiredReason(res resource.Schema) (Reason, bool) {
+	if o.features.ServiceDiscovery && o.isDiscoveryKind(res) && !o.discoveryOverrideWithheld(res) {
+		return ReasonRequiredForServiceDiscovery, true
+	}
+	others := o.features
+	others.ServiceDiscovery = false
+	return others.requiredReason(res)
+}
+
+// isDiscoveryKind returns true if res is required for service discovery, or is an endpoints kind protected under
+// the endpoints mode.
+func (o *filterOptions) isDiscoveryKind(res resource.Schema) bool {
+	return IsRequiredForServiceDiscovery(res) || o.endpointsMode().protects(res)
+}
+
+// discoveryOverrideWithheld returns true if res is required for service discovery, which is enabled, but
+// WithDiscoveryOverrideOnly does not allow the override to re-enable it.
+func (o *filterOptions) discoveryOverrideWithheld(res resource.Schema) bool {
+	if o.discoveryOverrideOnly == nil || !o.features.ServiceDiscovery || !o.isDiscoveryKind(res) {
+		return false
+	}
+	_, ok := o.discoveryOverrideOnly[res.Kind()]
+	return !ok
+}
+
+// configErrors returns every problem with the options that can be found without looking at the schemas being
+// filtered. known is only used to suggest corrections.
+func (o *filterOptions) configErrors(known collection.Schemas) []error {
+	var errs []error
+	if o.legacy == nil || !o.legacy.exactKindEntries {
+		_, errs = parseExclusions(o.excludedResourceKinds, o.positions, known)
+	}
+	errs = append(errs, o.collectionHintErrors()...)
+	errs = append(errs, o.budgetErrors()...)
+	errs = append(errs, o.lazyErrors()...)
+	errs = append(errs, o.statusWriterErrors(known)...)
+	errs = append(errs, o.namespaceScopeErrors(known)...)
+	errs = append(errs, o.namespaceExclusionErrors(known)...)
+	errs = append(errs, o.endpointsModeErrors()...)
+	errs = append(errs, o.selectorHintErrors()...)
+	errs = append(errs, o.kubeVersionErrors()...)
+	if o.discoveryOverrideOnly == nil {
+		return errs
+	}
+	required := o.discoveryKinds()
+	var unknown []string
+	for k := range o.discoveryOverrideOnly {
+		if _, ok := required[k]; !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		errs = append(errs, fmt.Errorf("discovery override kinds are not required for service discovery: %s",
+			strings.Join(unknown, ", ")))
+	}
+	return errs
+}
+
+// WithExcludedResourceKinds adds exclusion entries, in the syntax described on Exclusion. Excluded kinds are
+// disabled unless they are required by one of the enabled features. Entries that cannot be parsed cause Apply
+// to fail.
+func WithExcludedResourceKinds(kinds ...string) FilterOption {
+	return func(o *filterOptions) {
+		o.addExclusions(SourceAPI, kinds)
+	}
+}
+
+// WithSelectorHint attaches a selector hint to enabled collections of the given kind. Hints are advisory
+// and are exposed on the FilterResult for the informer layer to consume.
+func WithSelectorHint(kind string, hint SelectorHint) FilterOption {
+	return func(o *filterOptions) {
+		if o.selectorHints == nil {
+			o.selectorHints = make(map[string]SelectorHint)
+		}
+		o.selectorHints[kind] = hint
+	}
+}
+
+// OnlyForOutputs focuses the filter on producing the given provider outputs, for example to debug a single
+// pipeline. The outputs replace the required collections given to the filter, and every collection that is not
+// one of their transitive inputs is disabled with ReasonNotUpstream, unless an enabled feature such as service
+// discovery requires it. The result is the minimal set of collections to watch.
+func OnlyForOutputs(providers InputProviders, outputs collection.Names) FilterOption {
+	return func(o *filterOptions) {
+		o.onlyOutputs = outputs.Clone()
+		o.onlyOutputsProviders = providers
+	}
+}
+
+// WithOnlyGroups scopes the filter to schemas in the given API groups, plus the builtin kinds required for
+// service discovery. Every other schema is disabled up front with ReasonTrimmedByGroupScope, without being
+// evaluated further. The core group may be given as "core" or "". Groups are merged with any previously set.
+func WithOnlyGroups(groups ...string) FilterOption {
+	return func(o *filterOptions) {
+		if o.onlyGroups == nil {
+			o.onlyGroups = make(map[string]struct{})
+		}
+		for _, g := range groups {
+			o.onlyGroups[normalizeGroup(g)] = struct{}{}
+		}
+	}
+}
+
+// inGroupScope returns true if s survives the WithOnlyGroups trim.
+func (o *filterOptions) inGroupScope(s collection.Schema) bool {
+	if o.onlyGroups == nil {
+		return true
+	}
+	if _, ok := o.onlyGroups[s.Resource().Group()]; ok {
+		return true
+	}
+	return IsRequiredForServiceDiscovery(s.Resource())
+}
+
+// PreferNewestVersion keeps only the newest enabled version of each group/kind, in Kubernetes version order
+// (v1 > v1beta1 > v1alpha1), and disables the others with ReasonSupersededByNewerVersion. Versions disabled for
+// any other reason do not take part, so excluding the newest version lets the next newest win.
+func PreferNewestVersion() FilterOption {
+	return func(o *filterOptions) {
+		o.preferNewestVersion = true
+	}
+}
+
+// WithFrozenResult sets FilterResult.Frozen to a read-only view of the filtered schemas.
+func WithFrozenResult() FilterOption {
+	return func(o *filterOptions) {
+		o.frozenResult = true
+	}
+}
+
+// WithDuplicatePolicy sets how duplicate collections are handled by ApplyComposed. The default is DedupeIdentical.
+func WithDuplicatePolicy(p DuplicatePolicy) FilterOption {
+	return func(o *filterOptions) {
+		o.duplicatePolicy = p
+	}
+}
+
+// WithAvailabilityProbe enables checking that enabled collections are served by the API server. Collections
+// that are not served are disabled.
+func WithAvailabilityProbe(probe AvailabilityProbe) FilterOption {
+	if probe == nil {
+		return WithContextAvailabilityProbe(nil)
+	}
+	return WithContextAvailabilityProbe(func(_ context.Context, gvk config.GroupVersionKind) (bool, error) {
+		return probe(gvk)
+	})
+}
+
+// WithContextAvailabilityProbe is WithAvailabilityProbe for a probe that honors the context given to
+// ApplyContext, so that a slow API server cannot block the filter past its deadline.
+func WithContextAvailabilityProbe(probe ContextAvailabilityProbe) FilterOption {
+	return func(o *filterOptions) {
+		o.availabilityCheck().probe = probe
+	}
+}
+
+// WithAvailabilityRetry probes up to attempts times while the probe returns errors for which isRetryable
+// returns true. A nil isRetryable retries all errors. Collections whose availability remains undetermined are
+// reported with ReasonUndetermined.
+func WithAvailabilityRetry(attempts int, isRetryable func(error) bool) FilterOption {
+	return func(o *filterOptions) {
+		a := o.availabilityCheck()
+		a.attempts = attempts
+		a.isRetryable = isRetryable
+	}
+}
+
+// WithProbeFailurePolicy sets whether collections with undetermined availability are kept enabled (FailOpen,
+// the default) or disabled (FailClosed).
+func WithProbeFailurePolicy(p ProbeFailurePolicy) FilterOption {
+	return func(o *filterOptions) {
+		o.availabilityCheck().policy = p
+	}
+}
+
+// withKnownAvailability seeds the filter with availability determined by a previous result.
+func withKnownAvailability(known map[config.GroupVersionKind]bool) FilterOption {
+	return func(o *filterOptions) {
+		o.knownAvailability = known
+	}
+}
+
+func (o *filterOptions) availabilityCheck() *availabilityCheck {
+	if o.availability == nil {
+		o.availability = &availabilityCheck{attempts: 1}
+	}
+	return o.availability
+}
+
+// reasonHook is a callback registered for a single Reason.
+type reasonHook struct {
+	reason Reason
+	fn     func(collection.Schema, Decision)
+}
+
+// WithReasonHook registers fn to be called for every collection whose final decision has the given reason.
+// Multiple hooks may be registered, including for the same reason. Hooks are invoked once filtering is
+// complete, in schema order and, for each schema, in registration order.
+func WithReasonHook(reason Reason, fn func(collection.Schema, Decision)) FilterOption {
+	return func(o *filterOptions) {
+		o.reasonHooks = append(o.reasonHooks, reasonHook{reason: reason, fn: fn})
+	}
+}
+
+// WithCompactDisabled replaces disabled collections in the result with lightweight stand-ins that retain only
+// the collection name and resource type. Only collections that can be restored from the global schema set are
+// compacted; if such a collection is passed through the filter again, it is restored and may be re-enabled.
+func WithCompactDisabled() FilterOption {
+	return func(o *filterOptions) {
+		o.compactDisabled = true
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"context"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ApplyForSubset is Apply restricted to the schemas of in for which subset returns true, for migrations that
+// govern part of a schema set with new rules and leave the rest to the legacy call. Schemas outside the subset are
+// passed through as they are, enabled or disabled, and reported with ReasonPassedThrough: they get no selector or
+// collection hints, are not compacted and never supersede nor are superseded by other versions. The result has
+// every schema of in exactly once. Pass-through schemas still count toward the budget set by WithEnabledBudget,
+// since they are watched all the same.
+func (f *CollectionFilter) ApplyForSubset(in collection.Schemas, subset func(collection.Schema) bool) (*FilterResult, error) {
+	g := *f
+	g.subset = subset
+	return g.ApplyContext(context.Background(), in)
+}
+
+// passedThrough returns true if s is outside the subset the filter applies to.
+func (f *CollectionFilter) passedThrough(s collection.Schema) bool {
+	return f.subset != nil && !f.subset(s)
+}
@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestApplyForSubset(t *testing.T) {
+	g := NewWithT(t)
+
+	disabledVS := newTestSchema("networking.istio.io", "v1beta1", "VirtualService").Disable()
+	in := collection.SchemasFor(testService, testPod, testDeployment, testVirtualService, disabledVS, testAuthzPolicy)
+	istioGroups := func(s collection.Schema) bool {
+		return s.Resource().Group() == "networking.istio.io" || s.Resource().Group() == "security.istio.io"
+	}
+
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindPod, "security.istio.io/*"), WithSelectorHint(KindService, SelectorHint{LabelSelector: "a=b"}))
+	result, err := f.ApplyForSubset(in, istioGroups)
+	g.Expect(err).To(BeNil())
+
+	// Every schema is in the result once, in input order.
+	g.Expect(result.Schemas.CollectionNames()).To(Equal(in.CollectionNames()))
+
+	// The Istio groups are filtered.
+	g.Expect(reasonOf(result, testVirtualService.Name())).To(Equal(ReasonEnabled))
+	g.Expect(reasonOf(result, testAuthzPolicy.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(result.Schemas.MustFind(testAuthzPolicy.Name().String()).IsDisabled()).To(BeTrue())
+
+	// The rest is passed through as it is, although Pod is excluded.
+	for _, s := range []collection.Schema{testService, testPod, testDeployment} {
+		g.Expect(reasonOf(result, s.Name())).To(Equal(ReasonPassedThrough), s.Name().String())
+		g.Expect(result.Schemas.MustFind(s.Name().String()) == s).To(BeTrue(), s.Name().String())
+	}
+	g.Expect(result.SelectorHints).To(BeEmpty())
+
+	// A schema disabled before filtering is passed through disabled, unless it is in the subset.
+	result, err = f.ApplyForSubset(in, func(s collection.Schema) bool { return !istioGroups(s) })
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Schemas.CollectionNames()).To(Equal(in.CollectionNames()))
+	g.Expect(result.Schemas.MustFind(disabledVS.Name().String()) == disabledVS).To(BeTrue())
+	e, _ := result.Report.Entry(disabledVS.Name())
+	g.Expect(e.Decision).To(Equal(Decision{Disabled: true, Reason: ReasonPassedThrough}))
+	g.Expect(reasonOf(result, testPod.Name())).To(Equal(ReasonExcludedKind))
+	g.Expect(result.SelectorHints).To(HaveKey(testService.Name()))
+
+	// The filter itself still applies to every schema.
+	result, err = f.Apply(in)
+	g.Expect(err).To(BeNil())
+	for _, e := range result.Report.Entries {
+		g.Expect(e.Reason).NotTo(Equal(ReasonPassedThrough), e.Collection.String())
+	}
+}
+
+func TestApplyForSubset_PreferNewestVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	v1beta1 := newTestSchema("networking.istio.io", "v1beta1", "VirtualService")
+	in := collection.SchemasFor(testVirtualService, v1beta1)
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), PreferNewestVersion())
+
+	// The newer version outside the subset does not supersede the older one inside it.
+	result, err := f.ApplyForSubset(in, func(s collection.Schema) bool { return s.Resource().Version() == "v1alpha3" })
+	g.Expect(err).To(BeNil())
+	g.Expect(reasonOf(result, testVirtualService.Name())).To(Equal(ReasonEnabled))
+	g.Expect(reasonOf(result, v1beta1.Name())).To(Equal(ReasonPassedThrough))
+}
@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// Feature is a high level pilot feature that consumes a known set of collections.
+type Feature string
+
+const (
+	FeatureTrafficManagement Feature = "TrafficManagement"
+	FeatureSecurity          Feature = "Security"
+	FeatureSidecarInjection  Feature = "SidecarInjection"
+	FeatureGatewayAPI        Feature = "GatewayAPI"
+	FeatureMulticluster      Feature = "Multicluster"
+	FeatureTelemetry         Feature = "Telemetry"
+)
+
+// FeatureSet is a list of enabled pilot features.
+type FeatureSet []Feature
+
+// featureCollections maps each feature to the output collections it consumes.
+var featureCollections = map[Feature]collection.Names{
+	FeatureTrafficManagement: {
+		collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+		collections.IstioNetworkingV1Alpha3Envoyfilters.Name(),
+		collections.IstioNetworkingV1Alpha3Gateways.Name(),
+		collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+		collections.IstioNetworkingV1Alpha3Sidecars.Name(),
+		collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		collections.IstioNetworkingV1Alpha3Workloadentries.Name(),
+		collections.IstioNetworkingV1Alpha3Workloadgroups.Name(),
+		collections.IstioNetworkingV1Beta1Proxyconfigs.Name(),
+		collections.IstioExtensionsV1Alpha1Wasmplugins.Name(),
+	},
+	FeatureSecurity: {
+		collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+		collections.IstioSecurityV1Beta1Peerauthentications.Name(),
+		collections.IstioSecurityV1Beta1Requestauthentications.Name(),
+	},
+	FeatureSidecarInjection: {
+		collections.IstioMeshV1Alpha1MeshConfig.Name(),
+		collections.K8SAdmissionregistrationK8SIoV1Mutatingwebhookconfigurations.Name(),
+		collections.K8SCoreV1Configmaps.Name(),
+		collections.K8SCoreV1Namespaces.Name(),
+		collections.K8SCoreV1Pods.Name(),
+	},
+	FeatureGatewayAPI: {
+		collections.K8SGatewayApiV1Alpha2Gatewayclasses.Name(),
+		collections.K8SGatewayApiV1Alpha2Gateways.Name(),
+		collections.K8SGatewayApiV1Alpha2Httproutes.Name(),
+		collections.K8SGatewayApiV1Alpha2Referencepolicies.Name(),
+		collections.K8SGatewayApiV1Alpha2Tcproutes.Name(),
+		collections.K8SGatewayApiV1Alpha2Tlsroutes.Name(),
+	},
+	FeatureMulticluster: {
+		collections.IstioMeshV1Alpha1MeshNetworks.Name(),
+		collections.K8SCoreV1Secrets.Name(),
+	},
+	FeatureTelemetry: {
+		collections.IstioTelemetryV1Alpha1Telemetries.Name(),
+	},
+}
+
+// RequiredCollectionsFor returns the sorted, de-duplicated output collections needed by the given features,
+// suitable for use as the requiredCols of a CollectionFilter.
+func RequiredCollectionsFor(features FeatureSet) (collection.Names, error) {
+	seen := make(map[collection.Name]struct{})
+	result := make(collection.Names, 0)
+	for _, f := range features {
+		names, ok := featureCollections[f]
+		if !ok {
+			return nil, fmt.Errorf("unknown feature %q", f)
+		}
+		for _, n := range names {
+			if _, ok := seen[n]; ok {
+				continue
+			}
+			seen[n] = struct{}{}
+			result = append(result, n)
+		}
+	}
+	result.Sort()
+	return result, nil
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/processor/transforms"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func TestRequiredCollectionsFor(t *testing.T) {
+	g := NewWithT(t)
+
+	names, err := RequiredCollectionsFor(FeatureSet{FeatureTelemetry, FeatureMulticluster, FeatureTelemetry})
+	g.Expect(err).To(BeNil())
+	g.Expect(names).To(Equal(collection.Names{
+		collections.IstioMeshV1Alpha1MeshNetworks.Name(),
+		collections.IstioTelemetryV1Alpha1Telemetries.Name(),
+		collections.K8SCoreV1Secrets.Name(),
+	}))
+
+	names, err = RequiredCollectionsFor(nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(names).To(BeEmpty())
+
+	_, err = RequiredCollectionsFor(FeatureSet{FeatureTelemetry, "Bogus"})
+	g.Expect(err).To(MatchError(`unknown feature "Bogus"`))
+}
+
+// Every collection in the feature table must be produced by a transformer, or be a Kubernetes collection
+// that is consumed directly, so that RequiredInputsFor can resolve it.
+func TestRequiredCollectionsFor_MatchesProviders(t *testing.T) {
+	m := schema.MustGet()
+	providers := transforms.Providers(m)
+	outputs := make(map[collection.Name]struct{})
+	for _, n := range providers.Outputs() {
+		outputs[n] = struct{}{}
+	}
+
+	for feature := range featureCollections {
+		t.Run(string(feature), func(t *testing.T) {
+			g := NewWithT(t)
+			names, err := RequiredCollectionsFor(FeatureSet{feature})
+			g.Expect(err).To(BeNil())
+			g.Expect(names).NotTo(BeEmpty())
+			for _, n := range names {
+				if _, ok := outputs[n]; ok {
+					continue
+				}
+				_, found := m.KubeCollections().Find(n.String())
+				g.Expect(found).To(BeTrue(), "%s is neither a provider output nor a Kubernetes collection", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// PlannedChange is a collection whose state a proposed configuration would flip.
+type PlannedChange struct {
+	Collection collection.Name `json:"collection"`
+
+	// From is the current decision, or nil if the current result has no such collection, and To the proposed
+	// decision, or nil if the proposed result has no such collection.
+	From *Decision `json:"from,omitempty"`
+	To   *Decision `json:"to,omitempty"`
+}
+
+func (c PlannedChange) String() string {
+	return decisionChange{collection: c.Collection, from: c.From, to: c.To}.String()
+}
+
+// Plan describes what a proposed filter configuration would change, compared to the current result.
+type Plan struct {
+	// Enabled and Disabled are the collections the proposed configuration would enable and disable, in name order.
+	// Collections whose reason changes, but whose state does not, are not listed.
+	Enabled  []PlannedChange `json:"enabled,omitempty"`
+	Disabled []PlannedChange `json:"disabled,omitempty"`
+
+	// AddedWarnings and RemovedWarnings are the warnings that would appear and disappear, in code and message order.
+	AddedWarnings   []FilterWarning `json:"addedWarnings,omitempty"`
+	RemovedWarnings []FilterWarning `json:"removedWarnings,omitempty"`
+
+	CurrentFingerprint  string `json:"currentFingerprint"`
+	ProposedFingerprint string `json:"proposedFingerprint"`
+}
+
+// Empty returns true if the plan changes neither collections nor warnings.
+func (p Plan) Empty() bool {
+	return len(p.Enabled)+len(p.Disabled)+len(p.AddedWarnings)+len(p.RemovedWarnings) == 0
+}
+
+// PlanChanges applies proposed to schemas and compares the outcome with current, for reviewing a configuration
+// change before it is rolled out. current only needs its report, warnings and fingerprint, so it can be decoded
+// from the JSON served by the debug endpoint; nothing is read from a cluster, and availability is not probed.
+func PlanChanges(current *FilterResult, proposed FilterConfig, schemas collection.Schemas, providers InputProviders) (Plan, error) {
+	result, err := proposed.Apply(schemas, providers)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{
+		CurrentFingerprint:  current.Fingerprint,
+		ProposedFingerprint: result.Fingerprint,
+	}
+	for _, c := range current.Report.decisionChanges(result.Report) {
+		switch {
+		case c.to != nil && !c.to.Disabled && (c.from == nil || c.from.Disabled):
+			plan.Enabled = append(plan.Enabled, PlannedChange{Collection: c.collection, From: c.from, To: c.to})
+		case c.from != nil && !c.from.Disabled && (c.to == nil || c.to.Disabled):
+			plan.Disabled = append(plan.Disabled, PlannedChange{Collection: c.collection, From: c.from, To: c.to})
+		}
+	}
+	plan.AddedWarnings = warningDifference(result.Warnings, current.Warnings)
+	plan.RemovedWarnings = warningDifference(current.Warnings, result.Warnings)
+	return plan, nil
+}
+
+// warningDifference returns the warnings in a that are not in b, sorted by code and message.
+func warningDifference(a, b []FilterWarning) []FilterWarning {
+	remove := make(map[FilterWarning]struct{}, len(b))
+	for _, w := range b {
+		remove[w] = struct{}{}
+	}
+	var out []FilterWarning
+	for _, w := range a {
+		if _, ok := remove[w]; !ok {
+			out = append(out, w)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Code != out[j].Code {
+			return out[i].Code < out[j].Code
+		}
+		return out[i].Message < out[j].Message
+	})
+	return out
+}
+
+// String renders the plan for display, for example as the plan output of a GitOps pipeline.
+func (p Plan) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Fingerprint: %s -> %s\n", p.CurrentFingerprint, p.ProposedFingerprint)
+	if p.Empty() {
+		sb.WriteString("No changes.\n")
+		return sb.String()
+	}
+	if len(p.Enabled) > 0 {
+		sb.WriteString("Collections to enable:\n")
+		for _, c := range p.Enabled {
+			fmt.Fprintf(&sb, "  + %s\n", c)
+		}
+	}
+	if len(p.Disabled) > 0 {
+		sb.WriteString("Collections to disable:\n")
+		for _, c := range p.Disabled {
+			fmt.Fprintf(&sb, "  - %s\n", c)
+		}
+	}
+	if len(p.AddedWarnings) > 0 {
+		sb.WriteString("New warnings:\n")
+		for _, w := range p.AddedWarnings {
+			fmt.Fprintf(&sb, "  + %s\n", w)
+		}
+	}
+	if len(p.RemovedWarnings) > 0 {
+		sb.WriteString("Resolved warnings:\n")
+		for _, w := range p.RemovedWarnings {
+			fmt.Fprintf(&sb, "  - %s\n", w)
+		}
+	}
+	return sb.String()
+}
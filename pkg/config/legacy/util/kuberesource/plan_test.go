@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestPlanChanges(t *testing.T) {
+	in := collection.SchemasFor(testService, testNamespace, testPod, testDeployment, testVirtualService, testAuthzPolicy)
+	currentConfig := FilterConfig{
+		ExcludedResourceKinds: []string{KindPod, KindDeployment, "collection:k8s/service_apis/v1alpha1/httproutes"},
+		Features:              FeatureRequirements{ServiceDiscovery: true},
+	}
+	cases := []struct {
+		name     string
+		proposed FilterConfig
+		golden   string
+	}{
+		{
+			name: "additive",
+			proposed: FilterConfig{
+				ExcludedResourceKinds: []string{KindPod},
+				Features:              FeatureRequirements{ServiceDiscovery: true},
+			},
+			golden: "testdata/plan_additive.golden",
+		},
+		{
+			name: "destructive",
+			proposed: FilterConfig{
+				ExcludedResourceKinds: []string{KindPod, KindDeployment, KindNamespace, "security.istio.io/*",
+					"collection:k8s/service_apis/v1alpha1/httproutes", "collection:k8s/service_apis/v1alpha1/gateways"},
+				Features:  FeatureRequirements{ServiceDiscovery: true},
+				Selectors: map[string]SelectorHint{"Widget": {LabelSelector: "app=foo"}},
+			},
+			golden: "testdata/plan_destructive.golden",
+		},
+		{
+			name:     "no-op",
+			proposed: currentConfig,
+			golden:   "testdata/plan_noop.golden",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// The current result is decoded from JSON, as served by the debug endpoint.
+			running, err := currentConfig.Apply(in, kuberesourcetest.ScriptedProviders{})
+			g.Expect(err).To(BeNil())
+			data, err := json.Marshal(running)
+			g.Expect(err).To(BeNil())
+			current := &FilterResult{}
+			g.Expect(json.Unmarshal(data, current)).To(Succeed())
+
+			plan, err := PlanChanges(current, c.proposed, in, kuberesourcetest.ScriptedProviders{})
+			g.Expect(err).To(BeNil())
+			g.Expect(plan.CurrentFingerprint).To(Equal(running.Fingerprint))
+			g.Expect(plan.Empty()).To(Equal(c.name == "no-op"))
+			if plan.Empty() {
+				g.Expect(plan.ProposedFingerprint).To(Equal(plan.CurrentFingerprint))
+			} else {
+				g.Expect(plan.ProposedFingerprint).NotTo(Equal(plan.CurrentFingerprint))
+			}
+
+			// The fingerprints vary with the builtin schema set, so they are not part of the golden output.
+			plan.CurrentFingerprint, plan.ProposedFingerprint = "current", "proposed"
+			out, err := json.MarshalIndent(plan, "", "  ")
+			g.Expect(err).To(BeNil())
+			testutil.CompareContent([]byte(plan.String()+"\n"+string(out)+"\n"), c.golden, t)
+		})
+	}
+}
+
+func TestPlanChanges_InvalidConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	current, err := FilterConfig{}.Apply(in, kuberesourcetest.ScriptedProviders{})
+	g.Expect(err).To(BeNil())
+	_, err = PlanChanges(current, FilterConfig{ExcludedResourceKinds: []string{"/Pod"}}, in, kuberesourcetest.ScriptedProviders{})
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid exclusion entry 0 "/Pod"`)))
+}
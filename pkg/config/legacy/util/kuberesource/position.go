@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Position is the location of an entry in the file a filter configuration was loaded from.
+type Position struct {
+	// File is the name of the file, or empty if the configuration was loaded from data without one.
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// String renders p as file.yaml:42:7, or 42:7 without a file name.
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// ConfigPositions are the positions of the entries of a filter configuration loaded from a file. Errors and
+// warnings about an entry with a position report it; entries without one, such as those configured
+// programmatically, are identified by their text only.
+type ConfigPositions struct {
+	// ExcludedResourceKinds are the positions of the exclusion entries, keyed by entry as written. An entry
+	// listed more than once has the position of its first occurrence.
+	ExcludedResourceKinds map[string]Position
+
+	// KubeVersionGates are the positions of the Kubernetes version gates, keyed by exclusion entry.
+	KubeVersionGates map[string]Position
+}
+
+// WithConfigPositions sets the positions of the entries of a filter configuration loaded from a file; see
+// LoadConfigFile. Positions are not part of the configuration, and do not change the fingerprint.
+func WithConfigPositions(p ConfigPositions) FilterOption {
+	return func(o *filterOptions) {
+		if o.positions.ExcludedResourceKinds == nil {
+			o.positions.ExcludedResourceKinds = make(map[string]Position)
+		}
+		if o.positions.KubeVersionGates == nil {
+			o.positions.KubeVersionGates = make(map[string]Position)
+		}
+		for entry, pos := range p.ExcludedResourceKinds {
+			o.positions.ExcludedResourceKinds[entry] = pos
+		}
+		for entry, pos := range p.KubeVersionGates {
+			o.positions.KubeVersionGates[entry] = pos
+		}
+	}
+}
+
+// entryPosition returns the position of the exclusion entry, or nil if it is not known.
+func (p ConfigPositions) entryPosition(entry string) *Position {
+	if pos, ok := p.ExcludedResourceKinds[entry]; ok {
+		return &pos
+	}
+	return nil
+}
+
+// gatePosition returns the position of the version gate of the exclusion entry, falling back to the position of
+// the entry itself, or nil if neither is known.
+func (p ConfigPositions) gatePosition(entry string) *Position {
+	if pos, ok := p.KubeVersionGates[entry]; ok {
+		return &pos
+	}
+	return p.entryPosition(entry)
+}
+
+// atPosition returns err prefixed with pos, or err if pos is nil.
+func atPosition(pos *Position, err error) error {
+	if pos == nil {
+		return err
+	}
+	return fmt.Errorf("%s: %w", pos, err)
+}
+
+// positionWarnings prefixes the message of every warning about an exclusion entry with a known position with that
+// position, and records it on the warning. Warnings that already have a position are left alone.
+func (o *filterOptions) positionWarnings(warnings []FilterWarning) {
+	for i, w := range warnings {
+		if w.Entry == "" || w.Position != "" {
+			continue
+		}
+		if pos := o.positions.entryPosition(w.Entry); pos != nil {
+			warnings[i].Position = pos.String()
+			warnings[i].Message = fmt.Sprintf("%s: %s", pos, w.Message)
+		}
+	}
+}
+
+// LoadConfigFile loads a filter configuration in the given format from the file at path, like LoadConfig. For the
+// Helm and operator formats, errors and warnings about its exclusion entries and version gates report their
+// position in the file, as path:line:column; the positions are returned on the configuration.
+func LoadConfigFile(format ConfigFormat, path string) (FilterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FilterConfig{}, err
+	}
+	return loadConfigAt(format, path, data)
+}
+
+// configPositions returns the positions of the exclusion entries and version gates of the filter configuration
+// in data, in the given format. Formats other than Helm and operator values yield no positions, and so do
+// documents that cannot be parsed.
+func configPositions(format ConfigFormat, file string, data []byte) ConfigPositions {
+	var path []string
+	switch format {
+	case ConfigFormatHelm:
+		path = []string{"pilot", "collectionFilter"}
+	case ConfigFormatOperator:
+		path = []string{"spec", "values", "pilot", "collectionFilter"}
+	default:
+		return ConfigPositions{}
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return ConfigPositions{}
+	}
+	node := doc.Content[0]
+	for _, key := range path {
+		if node = mappingValue(node, key); node == nil {
+			return ConfigPositions{}
+		}
+	}
+
+	var out ConfigPositions
+	at := func(n *yamlv3.Node) Position {
+		return Position{File: file, Line: n.Line, Column: n.Column}
+	}
+	record := func(entry string, pos Position) {
+		if out.ExcludedResourceKinds == nil {
+			out.ExcludedResourceKinds = make(map[string]Position)
+		}
+		if _, ok := out.ExcludedResourceKinds[entry]; !ok {
+			out.ExcludedResourceKinds[entry] = pos
+		}
+	}
+	if kinds := mappingValue(node, "excludedResourceKinds"); kinds != nil {
+		switch kinds.Kind {
+		case yamlv3.SequenceNode:
+			for _, item := range kinds.Content {
+				record(strings.TrimSpace(item.Value), at(item))
+			}
+		case yamlv3.ScalarNode:
+			// Entries given as a single comma separated string all have the position of the string.
+			entries, _ := DecodeOperatorExclusions(kinds.Value)
+			for _, entry := range entries {
+				record(entry, at(kinds))
+			}
+		}
+	}
+	if gates := mappingValue(node, "kubeVersionGates"); gates != nil && gates.Kind == yamlv3.MappingNode {
+		out.KubeVersionGates = make(map[string]Position)
+		for i := 0; i+1 < len(gates.Content); i += 2 {
+			out.KubeVersionGates[gates.Content[i].Value] = at(gates.Content[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+const positionsFile = "testdata/positions.yaml"
+
+func TestLoadConfigFile_Positions(t *testing.T) {
+	g := NewWithT(t)
+	c, err := LoadConfigFile(ConfigFormatHelm, positionsFile)
+	g.Expect(err).To(BeNil())
+	g.Expect(c.Positions).To(Equal(ConfigPositions{
+		ExcludedResourceKinds: map[string]Position{
+			"Foo":                         {File: positionsFile, Line: 4, Column: 7},
+			"networking.istio.io/Service": {File: positionsFile, Line: 5, Column: 7},
+			"Endpoints":                   {File: positionsFile, Line: 6, Column: 7},
+			"!core/Secret":                {File: positionsFile, Line: 7, Column: 7},
+		},
+		KubeVersionGates: map[string]Position{
+			"Endpoints": {File: positionsFile, Line: 9, Column: 7},
+		},
+	}))
+
+	// The file has an unknown kind, a kind in the wrong group and a version gate that cannot be parsed; the negated
+	// entry conflicts with a default exclusion.
+	in := collection.SchemasFor(testService, testDeployment, testEndpoints, testSecret)
+	errs := ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{},
+		append(c.Options(), WithExclusionsFrom(SourceDefault, KindSecret))...)
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	g.Expect(messages).To(Equal([]string{
+		`testdata/positions.yaml:9:7: invalid minKubeVersion "bogus" for exclusion entry Endpoints`,
+		`testdata/positions.yaml:4:7: invalid exclusion entry 1 "Foo": matches no collection`,
+		`testdata/positions.yaml:5:7: invalid exclusion entry 2 "networking.istio.io/Service": matches no collection; ` +
+			`kind Service exists in group core; did you mean core/Service?`,
+		`testdata/positions.yaml:7:7: conflicting exclusion entries: entry Secret from Default excludes ` +
+			`[k8s/core/v1/secrets], which entry !core/Secret from API re-includes; !core/Secret from API wins`,
+	}))
+	var exclusionErr *ExclusionError
+	g.Expect(errs[1]).To(BeAssignableToTypeOf(exclusionErr))
+	g.Expect(errs[1].(*ExclusionError).Position).To(Equal(&Position{File: positionsFile, Line: 4, Column: 7}))
+}
+
+func TestConfigPositions_Warnings(t *testing.T) {
+	g := NewWithT(t)
+	c, err := LoadConfigFile(ConfigFormatHelm, positionsFile)
+	g.Expect(err).To(BeNil())
+	in := collection.SchemasFor(testService, testDeployment, testEndpoints, testSecret)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithConfigPositions(c.Positions), WithExclusionsFrom(SourceDefault, KindSecret),
+		WithExcludedResourceKinds("!core/Secret", KindPod))
+	g.Expect(err).To(BeNil())
+	var conflict FilterWarning
+	for _, w := range result.Warnings {
+		if w.Code == WarningConflictingEntries {
+			conflict = w
+		}
+	}
+	g.Expect(conflict.Position).To(Equal("testdata/positions.yaml:7:7"))
+	g.Expect(conflict.Message).To(HavePrefix("testdata/positions.yaml:7:7: entry Secret from Default"))
+
+	// Programmatic entries without a position are identified by their text only.
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithConfigPositions(c.Positions), WithExcludedResourceKinds("a/b/c/d"))
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid exclusion entry 0 "a/b/c/d"`)))
+	g.Expect(err.Error()).NotTo(ContainSubstring(positionsFile))
+}
+
+func TestPosition_String(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(Position{File: "file.yaml", Line: 42, Column: 7}.String()).To(Equal("file.yaml:42:7"))
+	g.Expect(Position{Line: 42, Column: 7}.String()).To(Equal("42:7"))
+}
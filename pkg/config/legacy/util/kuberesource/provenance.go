@@ -0,0 +1,160 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ExclusionSource identifies where an exclusion entry was configured.
+type ExclusionSource string
+
+const (
+	SourceDefault    ExclusionSource = "Default"
+	SourceProfile    ExclusionSource = "Profile"
+	SourceMeshConfig ExclusionSource = "MeshConfig"
+	SourceFile       ExclusionSource = "File"
+	SourceEnv        ExclusionSource = "Env"
+	SourceFlag       ExclusionSource = "Flag"
+
+	// SourceAPI is used for entries passed to WithExcludedResourceKinds.
+	SourceAPI ExclusionSource = "API"
+)
+
+// sourcePrecedence orders the sources from lowest to highest precedence. Unknown sources rank with SourceAPI.
+var sourcePrecedence = map[ExclusionSource]int{
+	SourceDefault:    0,
+	SourceProfile:    1,
+	SourceMeshConfig: 2,
+	SourceFile:       3,
+	SourceEnv:        4,
+	SourceFlag:       5,
+	SourceAPI:        6,
+}
+
+func (s ExclusionSource) precedence() int {
+	if p, ok := sourcePrecedence[s]; ok {
+		return p
+	}
+	return sourcePrecedence[SourceAPI]
+}
+
+// IsUser returns false for the sources that are not configured by the user directly: the defaults and profiles.
+func (s ExclusionSource) IsUser() bool {
+	return s != SourceDefault && s != SourceProfile
+}
+
+// WithExclusionsFrom adds exclusion entries configured in source. Entries are ordered by source precedence
+// (defaults, profiles, mesh config, files, environment, flags and then WithExcludedResourceKinds) and, within a
+// source, in the order they were added. Since the last matching entry decides, an entry from a higher precedence
+// source overrides entries from lower precedence sources, for example a negation given as a flag re-includes a
+// kind excluded by mesh config.
+func WithExclusionsFrom(source ExclusionSource, entries ...string) FilterOption {
+	return func(o *filterOptions) {
+		o.addExclusions(source, entries)
+	}
+}
+
+// addExclusions inserts entries after every entry of the same or lower precedence.
+func (o *filterOptions) addExclusions(source ExclusionSource, entries []string) {
+	i := len(o.exclusionSources)
+	for i > 0 && o.exclusionSources[i-1].precedence() > source.precedence() {
+		i--
+	}
+	kinds := make([]string, 0, len(o.excludedResourceKinds)+len(entries))
+	kinds = append(append(append(kinds, o.excludedResourceKinds[:i]...), entries...), o.excludedResourceKinds[i:]...)
+	sources := make([]ExclusionSource, 0, len(kinds))
+	sources = append(sources, o.exclusionSources[:i]...)
+	for range entries {
+		sources = append(sources, source)
+	}
+	o.excludedResourceKinds, o.exclusionSources = kinds, append(sources, o.exclusionSources[i:]...)
+}
+
+// removeExclusion removes the last occurrence of entry, whatever its source.
+func (o *filterOptions) removeExclusion(entry string) {
+	for i := len(o.excludedResourceKinds) - 1; i >= 0; i-- {
+		if o.excludedResourceKinds[i] == entry {
+			o.excludedResourceKinds = append(append([]string{}, o.excludedResourceKinds[:i]...), o.excludedResourceKinds[i+1:]...)
+			o.exclusionSources = append(append([]ExclusionSource{}, o.exclusionSources[:i]...), o.exclusionSources[i+1:]...)
+			return
+		}
+	}
+}
+
+// EffectiveExclusion is a normalized exclusion pattern, along with every source that configured it.
+type EffectiveExclusion struct {
+	// Pattern is the normalized form of the entries, as returned by Exclusion.String.
+	Pattern string `json:"pattern"`
+
+	// Sources lists every source that configured the pattern, in precedence order.
+	Sources []ExclusionSource `json:"sources"`
+
+	// Authoritative is the highest precedence source that configured the pattern, and Entry is the pattern as
+	// written there. This is the entry that matches last, and so decides.
+	Authoritative ExclusionSource `json:"authoritative"`
+	Entry         string          `json:"entry"`
+
+	// Equivalents lists the patterns the pattern also matches through the moved kinds table; see MovedKinds.
+	Equivalents []string `json:"equivalents,omitempty"`
+}
+
+// EffectiveExclusions returns the exclusion patterns the filter applies, in evaluation order of their
+// authoritative entries. Entries that cannot be parsed are listed as written.
+func (f *CollectionFilter) EffectiveExclusions() []EffectiveExclusion {
+	var out []EffectiveExclusion
+	index := make(map[string]int)
+	for _, e := range f.exclusions.exclusions {
+		pattern := e.String()
+		if e.literal {
+			pattern = e.Entry
+		}
+		i, ok := index[pattern]
+		if !ok {
+			index[pattern] = len(out)
+			out = append(out, EffectiveExclusion{Pattern: pattern, Equivalents: e.Equivalents})
+			i = len(out) - 1
+		}
+		ee := &out[i]
+		if n := len(ee.Sources); n == 0 || ee.Sources[n-1] != e.Source {
+			ee.Sources = append(ee.Sources, e.Source)
+		}
+		ee.Authoritative, ee.Entry = e.Source, e.Entry
+	}
+	return out
+}
+
+// Explain evaluates the filter's exclusion entries against s. If the decisive entry was not configured by the
+// user directly, the trace also describes every source of its pattern, to explain exclusions that remain after
+// the user removed their own entry.
+func (f *CollectionFilter) Explain(s collection.Schema) MatchTrace {
+	t := f.exclusions.ExplainSchema(s)
+	e, ok := t.DecisiveEntry()
+	if !ok || e.Source.IsUser() {
+		return t
+	}
+	pattern := e.String()
+	if e.literal {
+		pattern = e.Entry
+	}
+	for _, ee := range f.EffectiveExclusions() {
+		if ee.Pattern == pattern {
+			ee := ee
+			t.Provenance = &ee
+			break
+		}
+	}
+	return t
+}
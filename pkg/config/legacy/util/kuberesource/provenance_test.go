@@ -0,0 +1,78 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+)
+
+func TestEffectiveExclusions_Provenance(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	// Options are given out of precedence order; entries are ordered by source regardless.
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExclusionsFrom(SourceFlag, "!Pod"),
+		WithExclusionsFrom(SourceMeshConfig, "*/*/Pod"),
+		WithExclusionsFrom(SourceDefault, KindPod, "Node"))
+	result, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Schemas.MustFind(testPod.Name().String()).IsDisabled()).To(BeFalse())
+	g.Expect(result.Schemas.MustFind(testNode.Name().String()).IsDisabled()).To(BeTrue())
+
+	g.Expect(f.EffectiveExclusions()).To(Equal([]EffectiveExclusion{
+		{Pattern: "*/*/Pod", Sources: []ExclusionSource{SourceDefault, SourceMeshConfig}, Authoritative: SourceMeshConfig, Entry: "*/*/Pod"},
+		{Pattern: "*/*/Node", Sources: []ExclusionSource{SourceDefault}, Authoritative: SourceDefault, Entry: "Node"},
+		{Pattern: "!*/*/Pod", Sources: []ExclusionSource{SourceFlag}, Authoritative: SourceFlag, Entry: "!Pod"},
+	}))
+
+	// The flag decides for Pod, so no provenance is needed to explain it.
+	trace := f.Explain(testPod)
+	e, ok := trace.DecisiveEntry()
+	g.Expect(ok).To(BeTrue())
+	g.Expect(e.Source).To(Equal(SourceFlag))
+	g.Expect(trace.Excluded).To(BeFalse())
+	g.Expect(trace.Provenance).To(BeNil())
+
+	// Node is only excluded by the defaults.
+	trace = f.Explain(testNode)
+	g.Expect(trace.Excluded).To(BeTrue())
+	g.Expect(trace.Provenance).To(Equal(&EffectiveExclusion{
+		Pattern: "*/*/Node", Sources: []ExclusionSource{SourceDefault}, Authoritative: SourceDefault, Entry: "Node",
+	}))
+
+	g.Expect(f.Explain(testService).Decisive).To(Equal(-1))
+}
+
+func TestEffectiveExclusions_Delta(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("Secret"),
+		WithExclusionsFrom(SourceDefault, "Pod"))
+	prev, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+
+	next := f.withDelta(prev, ConfigDelta{RemovedExclusions: []string{"Secret"}, AddedExclusions: []string{"!Pod"}})
+	g.Expect(next.opts.excludedResourceKinds).To(Equal([]string{"Pod", "!Pod"}))
+	g.Expect(next.opts.exclusionSources).To(Equal([]ExclusionSource{SourceDefault, SourceAPI}))
+	// The original filter is unchanged.
+	g.Expect(f.opts.excludedResourceKinds).To(Equal([]string{"Pod", "Secret"}))
+}
@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// PruneProviders returns the providers that read no collection result disables, in order. Providers wired to a
+// disabled input would process a collection nobody watches; pruning them is what keeps AssertNoDisabledInputs
+// from failing on the normal path. Inputs that are not in result are not disabled by it.
+func PruneProviders(providers transformer.Providers, result *FilterResult) transformer.Providers {
+	disabled := disabledCollections(result)
+	out := make(transformer.Providers, 0, len(providers))
+	for _, p := range providers {
+		if len(disabledInputs(p, disabled)) == 0 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// AssertNoDisabledInputs checks the contract that disabled collections produce no provider work: it returns an
+// error naming every provider that reads a collection result disables, or nil if there is none. It is meant for
+// integration tests, and for startup checks behind a debug flag, after the providers have been passed through
+// PruneProviders. Providers are named by their position and their outputs, since they have no name of their own.
+func AssertNoDisabledInputs(providers transformer.Providers, result *FilterResult) error {
+	disabled := disabledCollections(result)
+	var violations []string
+	for i, p := range providers {
+		if inputs := disabledInputs(p, disabled); len(inputs) > 0 {
+			violations = append(violations, fmt.Sprintf("provider %d (%s) reads disabled %s", i,
+				joinNames(p.Outputs().CollectionNames()), joinNames(inputs)))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d providers are wired to disabled inputs that were not pruned: %s", len(violations),
+		strings.Join(violations, "; "))
+}
+
+// disabledCollections returns the collections result disables.
+func disabledCollections(result *FilterResult) map[collection.Name]struct{} {
+	out := make(map[collection.Name]struct{})
+	if result == nil {
+		return out
+	}
+	for _, n := range result.Schemas.DisabledCollectionNames() {
+		out[n] = struct{}{}
+	}
+	return out
+}
+
+// disabledInputs returns the inputs of p that are in disabled, in name order.
+func disabledInputs(p transformer.Provider, disabled map[collection.Name]struct{}) collection.Names {
+	var out collection.Names
+	for _, n := range p.Inputs().CollectionNames() {
+		if _, ok := disabled[n]; ok {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/event"
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestAssertNoDisabledInputs(t *testing.T) {
+	g := NewWithT(t)
+
+	noop := func(event.Event, event.Handler) {}
+	gateways := transformer.NewSimpleTransformerProvider(testKubeGateway, testGateway, noop)
+	services := transformer.NewSimpleTransformerProvider(testService, testVirtualService, noop)
+	meshConfig := transformer.NewSimpleTransformerProvider(testConfigMap, testMeshConfig, noop)
+	providers := transformer.Providers{gateways, services, meshConfig}
+
+	in := collection.SchemasFor(testKubeGateway, testService, testConfigMap)
+	result, err := FilterCollections(in, providers, collection.Names{testGateway.Name(), testVirtualService.Name(),
+		testMeshConfig.Name()}, WithExcludedResourceKinds(KindService, KindConfigMap))
+	g.Expect(err).To(BeNil())
+
+	// Wiring the unpruned providers is the inconsistent state the assertion catches.
+	err = AssertNoDisabledInputs(providers, result)
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("2 providers are wired to disabled inputs"))
+	g.Expect(err.Error()).To(ContainSubstring("provider 1 (" + testVirtualService.Name().String() + ") reads disabled " +
+		testService.Name().String()))
+	g.Expect(err.Error()).To(ContainSubstring("provider 2 (" + testMeshConfig.Name().String() + ") reads disabled " +
+		testConfigMap.Name().String()))
+	g.Expect(err.Error()).NotTo(ContainSubstring("provider 0"))
+
+	pruned := PruneProviders(providers, result)
+	g.Expect(pruned).To(HaveLen(1))
+	g.Expect(pruned[0].Outputs().CollectionNames()).To(Equal(collection.Names{testGateway.Name()}))
+	g.Expect(AssertNoDisabledInputs(pruned, result)).To(BeNil())
+}
+
+func TestAssertNoDisabledInputs_NormalPath(t *testing.T) {
+	g := NewWithT(t)
+
+	noop := func(event.Event, event.Handler) {}
+	providers := transformer.Providers{
+		transformer.NewSimpleTransformerProvider(testKubeGateway, testGateway, noop),
+		transformer.NewSimpleTransformerProvider(testService, testVirtualService, noop),
+	}
+	in := collection.SchemasFor(testKubeGateway, testService, testPod)
+	required := collection.Names{testGateway.Name(), testVirtualService.Name()}
+	for _, excluded := range [][]string{nil, {KindService}, {KindService, "Gateway"}, {"*"}} {
+		result, err := FilterCollections(in, providers, required, WithExcludedResourceKinds(excluded...))
+		g.Expect(err).To(BeNil())
+		g.Expect(AssertNoDisabledInputs(PruneProviders(providers, result), result)).To(BeNil(), "%v", excluded)
+	}
+	g.Expect(AssertNoDisabledInputs(providers, nil)).To(BeNil())
+}
@@ -0,0 +1,43 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// InputProviders is the view of the transformer graph the filter needs. It is implemented by
+// transformer.Providers; tests can implement it without building transformers, see
+// kuberesourcetest.ScriptedProviders.
+type InputProviders interface {
+	// RequiredInputsFor returns the input collections needed to produce outputs. Outputs that no transformer
+	// produces are their own inputs.
+	RequiredInputsFor(outputs collection.Names) map[collection.Name]struct{}
+
+	// SynthesizedOutputs returns the outputs that are never inputs, which are not read from Kubernetes.
+	SynthesizedOutputs() collection.Names
+}
+
+var _ InputProviders = transformer.Providers{}
+
+// orNoProviders returns providers, or an empty transformer graph if providers is nil, as callers could pass a nil
+// transformer.Providers before InputProviders was introduced.
+func orNoProviders(providers InputProviders) InputProviders {
+	if providers == nil {
+		return transformer.Providers{}
+	}
+	return providers
+}
@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// watchVerbs are the verbs needed to run an informer on a resource.
+var watchVerbs = []string{"get", "list", "watch"}
+
+// WatchRBACRules are the RBAC rules needed to watch a set of collections, split by resource scope.
+type WatchRBACRules struct {
+	// ClusterScoped are the rules for cluster scoped resources, which can only be granted by a ClusterRole.
+	ClusterScoped []rbacv1.PolicyRule `json:"clusterScoped,omitempty"`
+
+	// Namespaced are the rules for namespaced resources, which may also be granted per namespace by a Role.
+	Namespaced []rbacv1.PolicyRule `json:"namespaced,omitempty"`
+}
+
+// All returns the rules for both cluster scoped and namespaced resources, for rendering a single ClusterRole.
+func (r WatchRBACRules) All() []rbacv1.PolicyRule {
+	return mergeRules(append(append([]rbacv1.PolicyRule{}, r.ClusterScoped...), r.Namespaced...))
+}
+
+// RBACRulesFor returns the rules needed to watch the enabled collections in schemas. There is one rule per API
+// group, with the resources sorted and deduplicated, and the rules sorted by group.
+func RBACRulesFor(schemas collection.Schemas) WatchRBACRules {
+	var clusterScoped, namespaced []rbacv1.PolicyRule
+	for _, s := range schemas.WithoutDisabledCollections().All() {
+		res := s.Resource()
+		rule := rbacv1.PolicyRule{APIGroups: []string{res.Group()}, Resources: []string{res.Plural()}}
+		if res.IsClusterScoped() {
+			clusterScoped = append(clusterScoped, rule)
+		} else {
+			namespaced = append(namespaced, rule)
+		}
+	}
+	return WatchRBACRules{
+		ClusterScoped: mergeRules(clusterScoped),
+		Namespaced:    mergeRules(namespaced),
+	}
+}
+
+// mergeRules merges single group rules into one rule per group, with sorted resources and the watch verbs.
+func mergeRules(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	byGroup := make(map[string]map[string]struct{})
+	for _, r := range rules {
+		for _, g := range r.APIGroups {
+			if byGroup[g] == nil {
+				byGroup[g] = make(map[string]struct{})
+			}
+			for _, res := range r.Resources {
+				byGroup[g][res] = struct{}{}
+			}
+		}
+	}
+
+	groups := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	var out []rbacv1.PolicyRule
+	for _, g := range groups {
+		resources := make([]string, 0, len(byGroup[g]))
+		for res := range byGroup[g] {
+			resources = append(resources, res)
+		}
+		sort.Strings(resources)
+		out = append(out, rbacv1.PolicyRule{
+			APIGroups: []string{g},
+			Resources: resources,
+			Verbs:     append([]string{}, watchVerbs...),
+		})
+	}
+	return out
+}
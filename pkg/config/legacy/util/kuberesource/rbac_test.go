@@ -0,0 +1,46 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+)
+
+func TestRBACRulesFor(t *testing.T) {
+	g := NewWithT(t)
+
+	in := schema.MustGet().KubeCollections()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds("networking.istio.io/*", "!networking.istio.io/Gateway", KindPod))
+	g.Expect(err).To(BeNil())
+
+	rules := RBACRulesFor(result.Schemas)
+	for _, r := range append(rules.ClusterScoped, rules.Namespaced...) {
+		g.Expect(r.APIGroups).To(HaveLen(1))
+		g.Expect(r.Verbs).To(Equal([]string{"get", "list", "watch"}))
+	}
+	g.Expect(rules.All()).To(HaveLen(len(mergeRules(append(rules.ClusterScoped, rules.Namespaced...)))))
+
+	out, err := yaml.Marshal(rules)
+	g.Expect(err).To(BeNil())
+	testutil.CompareContent(out, "testdata/rbac_rules.golden", t)
+}
@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WatchExclusions reconfigures the state whenever a dynamic configuration source changes its exclusion entries,
+// so that istiod does not need to be restarted. It registers a handler with register, typically the
+// AddMeshHandler method of a mesh.Watcher, that updates the state with opts followed by the entries returned by
+// current, configured in source. Since an update replaces the whole configuration, opts must be the complete
+// configuration besides those entries. An update that fails is logged, and the state keeps its current result.
+// The handler is called once before WatchExclusions returns, to apply the entries current returns at that time.
+func (s *CollectionFilterState) WatchExclusions(register func(handler func()), source ExclusionSource,
+	current func() []string, opts ...FilterOption) {
+	handler := func() {
+		entries := current()
+		if _, err := s.Update(append(append([]FilterOption{}, opts...), WithExclusionsFrom(source, entries...))...); err != nil {
+			s.mu.RLock()
+			logger := s.logger
+			s.mu.RUnlock()
+			logger.Warnf("collection filter: keeping the current configuration, exclusion entries from %s were "+
+				"rejected: %v", source, err)
+		}
+	}
+	register(handler)
+	handler()
+}
+
+// WatchHandlers react to the changes of the enabled collections of a CollectionFilterState; see
+// RunWatchHandlers. Every handler is optional.
+type WatchHandlers struct {
+	// Start starts the informer of a collection that was started, and Stop stops the informer of a collection
+	// that was stopped.
+	Start func(s collection.Schema)
+	Stop  func(s collection.Schema)
+
+	// Rebuild is called after the informers of a change have been started and stopped, with the current result,
+	// to recompute what depends on the enabled collections, such as the transformer pipeline with PruneProviders.
+	Rebuild func(result *FilterResult)
+}
+
+// RunWatchHandlers calls h for every change of the started collections of the state, stopping the informers of
+// the collections that were stopped before starting those of the collections that were started, until stop is
+// closed or the state is closed. Changes that arrive while h is running are combined, as described on Notify. It
+// blocks, and is typically run in its own goroutine.
+func (s *CollectionFilterState) RunWatchHandlers(stop <-chan struct{}, h WatchHandlers) {
+	changes := s.Notify()
+	for {
+		select {
+		case <-stop:
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			current := s.Current()
+			if h.Stop != nil {
+				for _, n := range change.Stopped {
+					if schema, ok := current.Schemas.Find(n.String()); ok {
+						h.Stop(schema)
+					}
+				}
+			}
+			if h.Start != nil {
+				for _, n := range change.Started {
+					if schema, ok := current.Schemas.Find(n.String()); ok {
+						h.Start(schema)
+					}
+				}
+			}
+			if h.Rebuild != nil {
+				h.Rebuild(current)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// fakeMeshWatcher holds exclusion entries and the handlers registered for their changes, like a mesh.Watcher.
+type fakeMeshWatcher struct {
+	entries  []string
+	handlers []func()
+}
+
+func (w *fakeMeshWatcher) AddMeshHandler(h func()) {
+	w.handlers = append(w.handlers, h)
+}
+
+func (w *fakeMeshWatcher) set(entries ...string) {
+	w.entries = entries
+	for _, h := range w.handlers {
+		h()
+	}
+}
+
+func TestWatchExclusions(t *testing.T) {
+	g := NewWithT(t)
+	in := collection.SchemasFor(testService, testPod, testDeployment)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+
+	w := &fakeMeshWatcher{entries: []string{KindPod}}
+	state.WatchExclusions(w.AddMeshHandler, SourceMeshConfig, func() []string { return w.entries },
+		WithExcludedResourceKinds(KindService))
+	g.Expect(enabledNames(state.Current().Schemas)).To(Equal([]string{"k8s/apps/v1/deployments"}))
+
+	w.set(KindDeployment)
+	g.Expect(enabledNames(state.Current().Schemas)).To(Equal([]string{"k8s/core/v1/pods"}))
+
+	// Invalid entries are rejected, and the current result is kept.
+	l := &capturingLogger{}
+	_, err = state.Update(WithLogger(l), WithExcludedResourceKinds(KindService),
+		WithExclusionsFrom(SourceMeshConfig, KindDeployment))
+	g.Expect(err).To(BeNil())
+	current := state.Current()
+	w.set("a/b/c/d")
+	g.Expect(state.Current()).To(BeIdenticalTo(current))
+	g.Expect(l.lines).To(ContainElement(ContainSubstring("exclusion entries from MeshConfig were rejected")))
+}
+
+func TestRunWatchHandlers(t *testing.T) {
+	g := NewWithT(t)
+	in := collection.SchemasFor(testService, testPod, testDeployment)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+
+	var (
+		mu       sync.Mutex
+		events   []string
+		rebuilds = make(chan *FilterResult, 10)
+	)
+	record := func(event string) func(s collection.Schema) {
+		return func(s collection.Schema) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event+" "+s.Resource().Kind())
+		}
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		state.RunWatchHandlers(stop, WatchHandlers{
+			Start:   record("start"),
+			Stop:    record("stop"),
+			Rebuild: func(result *FilterResult) { rebuilds <- result },
+		})
+		close(done)
+	}()
+
+	// Wait for the handlers to subscribe before updating.
+	g.Eventually(func() int {
+		state.mu.RLock()
+		defer state.mu.RUnlock()
+		return len(state.subscribers)
+	}).Should(Equal(1))
+
+	result, err := state.Update(WithExcludedResourceKinds(KindPod, KindDeployment))
+	g.Expect(err).To(BeNil())
+	g.Eventually(rebuilds).Should(Receive(BeIdenticalTo(result)))
+
+	result, err = state.Update(WithExcludedResourceKinds(KindService))
+	g.Expect(err).To(BeNil())
+	g.Eventually(rebuilds).Should(Receive(BeIdenticalTo(result)))
+
+	mu.Lock()
+	g.Expect(events).To(Equal([]string{
+		"stop Deployment", "stop Pod",
+		"stop Service", "start Deployment", "start Pod",
+	}))
+	mu.Unlock()
+
+	close(stop)
+	g.Eventually(done).Should(BeClosed())
+}
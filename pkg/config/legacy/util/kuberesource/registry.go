@@ -0,0 +1,88 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrRegistriesFrozen is returned by the registration functions once a filter has been applied, unless late
+// registration was allowed with AllowLateRegistration.
+var ErrRegistriesFrozen = errors.New("kind registries are frozen: registration must happen before the first filter is applied")
+
+var (
+	// registriesMu serializes registrations with freezing, so that a registration either completes before the
+	// registries are frozen or fails.
+	registriesMu sync.Mutex
+
+	// registriesFrozen is set, under registriesMu, by the first Apply, and read without the lock on the fast path.
+	registriesFrozen int32
+
+	lateRegistrationAllowed bool
+)
+
+// freezeRegistries freezes the kind registries, so that every filter in the process sees the same kinds.
+func freezeRegistries() {
+	if atomic.LoadInt32(&registriesFrozen) == 1 {
+		return
+	}
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+	atomic.StoreInt32(&registriesFrozen, 1)
+}
+
+// register runs fn, which modifies a kind registry, unless the registries are frozen.
+func register(fn func()) error {
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+	if atomic.LoadInt32(&registriesFrozen) == 1 && !lateRegistrationAllowed {
+		return ErrRegistriesFrozen
+	}
+	fn()
+	return nil
+}
+
+// AllowLateRegistration lets the registration functions modify the kind registries after a filter has been
+// applied. It is intended for tests; in production, two components of a process could otherwise filter with
+// different kinds.
+func AllowLateRegistration() {
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+	lateRegistrationAllowed = true
+}
+
+// ResetRegistriesForTest restores the kind registries to their builtin contents, unfreezes them and revokes
+// AllowLateRegistration. It must only be used by tests.
+func ResetRegistriesForTest() {
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+
+	knownTypesMu.Lock()
+	knownTypes = builtinServiceDiscoveryTypes()
+	knownTypesMu.Unlock()
+
+	ambientTypesMu.Lock()
+	ambientTypes = builtinAmbientTypes()
+	ambientTypesMu.Unlock()
+
+	selectableFieldsMu.Lock()
+	selectableFields = builtinSelectableFields()
+	selectableFieldsMu.Unlock()
+
+	atomic.StoreInt32(&registriesFrozen, 0)
+	lateRegistrationAllowed = false
+}
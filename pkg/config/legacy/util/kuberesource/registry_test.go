@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestRegistriesFreeze(t *testing.T) {
+	g := NewWithT(t)
+	ResetRegistriesForTest()
+	t.Cleanup(ResetRegistriesForTest)
+
+	// Registration succeeds until the first filter is applied.
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Widget")).To(Succeed())
+	g.Expect(RegisterAmbientType("example.com", "Widget")).To(Succeed())
+
+	in := collection.SchemasFor(testService, testDeployment)
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Gadget")).To(MatchError(ErrRegistriesFrozen))
+	g.Expect(RegisterAmbientType("example.com", "Gadget")).To(MatchError(ErrRegistriesFrozen))
+	g.Expect(IsRequiredForServiceDiscovery(newTestSchema("example.com", "v1", "Gadget").Resource())).To(BeFalse())
+	g.Expect(IsRequiredForAmbient(newTestSchema("example.com", "v1", "Gadget").Resource())).To(BeFalse())
+
+	// DisableExcludedCollections freezes the registries as well.
+	ResetRegistriesForTest()
+	DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), nil, true)
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Gadget")).To(MatchError(ErrRegistriesFrozen))
+
+	// The explicit unlock allows late registration.
+	AllowLateRegistration()
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Gadget")).To(Succeed())
+	g.Expect(IsRequiredForServiceDiscovery(newTestSchema("example.com", "v1", "Gadget").Resource())).To(BeTrue())
+
+	// Resetting restores the builtin kinds and revokes the unlock.
+	ResetRegistriesForTest()
+	g.Expect(IsRequiredForServiceDiscovery(newTestSchema("example.com", "v1", "Gadget").Resource())).To(BeFalse())
+	g.Expect(IsRequiredForServiceDiscovery(newTestSchema("example.com", "v1", "Widget").Resource())).To(BeFalse())
+	g.Expect(IsRequiredForServiceDiscovery(testService.Resource())).To(BeTrue())
+	freezeRegistries()
+	g.Expect(RegisterAmbientType("example.com", "Gadget")).To(MatchError(ErrRegistriesFrozen))
+}
+
+func TestRegistriesFreeze_Concurrent(t *testing.T) {
+	g := NewWithT(t)
+	ResetRegistriesForTest()
+	t.Cleanup(ResetRegistriesForTest)
+
+	in := collection.SchemasFor(testService, testDeployment)
+	kinds := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+	errs := make([]error, len(kinds))
+	var wg sync.WaitGroup
+	for i, kind := range kinds {
+		i, kind := i, kind
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs[i] = RegisterServiceDiscoveryType("example.com", kind)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+		}()
+	}
+	wg.Wait()
+
+	// Every registration either failed or is visible.
+	for i, kind := range kinds {
+		registered := IsRequiredForServiceDiscovery(newTestSchema("example.com", "v1", kind).Resource())
+		g.Expect(registered).To(Equal(errs[i] == nil), kind)
+	}
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Z")).To(MatchError(ErrRegistriesFrozen))
+}
@@ -0,0 +1,322 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/util/istiomultierror"
+)
+
+// ConfigFormat is a format a filter configuration can be rendered in and loaded from.
+type ConfigFormat string
+
+const (
+	// ConfigFormatFlags renders the configuration as command line flags, one per line.
+	ConfigFormatFlags ConfigFormat = "flags"
+
+	// ConfigFormatHelm renders the configuration as a Helm values snippet.
+	ConfigFormatHelm ConfigFormat = "helm"
+
+	// ConfigFormatOperator renders the configuration as an IstioOperator fragment.
+	ConfigFormatOperator ConfigFormat = "operator"
+)
+
+const (
+	flagRequiredCollections   = "requiredCollections"
+	flagExcludedResourceKinds = "excludedResourceKinds"
+	flagServiceDiscovery      = "serviceDiscovery"
+	flagAmbientEnabled        = "ambientEnabled"
+	flagInjectionEnabled      = "injectionEnabled"
+)
+
+// helmValues is the layout of the filter configuration in Helm values, which the operator format nests under
+// spec.values.
+type helmValues struct {
+	Pilot struct {
+		CollectionFilter FilterConfig `json:"collectionFilter"`
+	} `json:"pilot"`
+}
+
+// operatorFragment is the layout of the filter configuration in an IstioOperator. Excluded resource kinds are
+// kept raw, since the operator accepts the forms handled by DecodeOperatorExclusions.
+type operatorFragment struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Values struct {
+			Pilot struct {
+				CollectionFilter struct {
+					RequiredCollections   collection.Names        `json:"requiredCollections,omitempty"`
+					ExcludedResourceKinds interface{}             `json:"excludedResourceKinds,omitempty"`
+					Features              FeatureRequirements     `json:"features"`
+					Selectors             map[string]SelectorHint `json:"selectors,omitempty"`
+
+					KubeVersionGates map[string]KubeVersionRange `json:"kubeVersionGates,omitempty"`
+				} `json:"collectionFilter"`
+			} `json:"pilot"`
+		} `json:"values"`
+	} `json:"spec"`
+}
+
+// Config returns the declarative configuration of f. Only the required collections, exclusion entries, features,
+// selector hints and Kubernetes version gates can be expressed declaratively, so an error is returned if f uses
+// collection hints, lazy kinds, WithOnlyGroups, WithDiscoveryOverrideOnly, OnlyForOutputs, WithStatusWriters,
+// WithNamespaceScope, WithNamespaceExclusions, WithDenyNewCollections, WithLegacySemantics, WithKubeVersion,
+// PreferNewestVersion, WithReenableInputDisabled, WithEnabledBudget, WithBudgetPolicy, exclusion entries from a
+// source other than SourceAPI, or a conflict policy or an endpoints mode other than the default. Runtime options
+// such as availability probing are not part of the configuration.
+func (f *CollectionFilter) Config() (FilterConfig, error) {
+	if f.err != nil {
+		return FilterConfig{}, f.err
+	}
+	var unsupported []string
+	if len(f.opts.collectionHints) > 0 {
+		unsupported = append(unsupported, "collection hints")
+	}
+	if len(f.opts.lazyKinds) > 0 {
+		unsupported = append(unsupported, "lazy kinds")
+	}
+	if f.opts.onlyGroups != nil {
+		unsupported = append(unsupported, "WithOnlyGroups")
+	}
+	if f.opts.discoveryOverrideOnly != nil {
+		unsupported = append(unsupported, "WithDiscoveryOverrideOnly")
+	}
+	if f.opts.onlyOutputs != nil {
+		unsupported = append(unsupported, "OnlyForOutputs")
+	}
+	if len(f.opts.statusWriters) > 0 {
+		unsupported = append(unsupported, "WithStatusWriters")
+	}
+	if f.opts.namespaceScope != nil {
+		unsupported = append(unsupported, "WithNamespaceScope")
+	}
+	if len(f.opts.namespaceExclusions) > 0 {
+		unsupported = append(unsupported, "WithNamespaceExclusions")
+	}
+	if f.opts.reviewedBaseline != nil {
+		unsupported = append(unsupported, "WithDenyNewCollections")
+	}
+	if f.opts.legacy != nil {
+		unsupported = append(unsupported, "WithLegacySemantics")
+	}
+	if f.opts.kubeVersion != "" {
+		unsupported = append(unsupported, "WithKubeVersion")
+	}
+	if f.opts.preferNewestVersion {
+		unsupported = append(unsupported, "PreferNewestVersion")
+	}
+	if f.opts.reenableInputDisabled {
+		unsupported = append(unsupported, "WithReenableInputDisabled")
+	}
+	if f.opts.conflictPolicy != ConflictWarn {
+		unsupported = append(unsupported, "WithConflictPolicy")
+	}
+	if f.opts.enabledBudget != nil {
+		unsupported = append(unsupported, "WithEnabledBudget")
+	}
+	if f.opts.budgetPolicy != BudgetWarn {
+		unsupported = append(unsupported, "WithBudgetPolicy")
+	}
+	for _, source := range f.opts.exclusionSources {
+		if source != SourceAPI {
+			unsupported = append(unsupported, "WithExclusionsFrom")
+			break
+		}
+	}
+	if f.opts.endpointsMode() != EndpointsBoth {
+		unsupported = append(unsupported, "WithDiscoveryOptions")
+	}
+	if len(unsupported) > 0 {
+		return FilterConfig{}, fmt.Errorf("filter configuration cannot be rendered: uses %s", strings.Join(unsupported, ", "))
+	}
+
+	required := f.requiredCols.Clone()
+	required.Sort()
+	var selectors map[string]SelectorHint
+	if len(f.opts.selectorHints) > 0 {
+		selectors = make(map[string]SelectorHint, len(f.opts.selectorHints))
+		for k, h := range f.opts.selectorHints {
+			selectors[k] = h
+		}
+	}
+	return FilterConfig{
+		RequiredCollections:   required,
+		ExcludedResourceKinds: append([]string{}, f.opts.excludedResourceKinds...),
+		Features:              f.opts.features,
+		Selectors:             selectors,
+		KubeVersionGates:      copyKubeVersionGates(f.opts.kubeVersionGates),
+	}, nil
+}
+
+// RenderConfig renders the configuration of f in the given format. All formats are generated from Config, and
+// LoadConfig loads each of them back. Selectors and Kubernetes version gates cannot be rendered as flags.
+func (f *CollectionFilter) RenderConfig(format ConfigFormat) ([]byte, error) {
+	c, err := f.Config()
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case ConfigFormatFlags:
+		if len(c.Selectors) > 0 {
+			return nil, fmt.Errorf("filter configuration cannot be rendered as %s: uses selectors", format)
+		}
+		if len(c.KubeVersionGates) > 0 {
+			return nil, fmt.Errorf("filter configuration cannot be rendered as %s: uses Kubernetes version gates", format)
+		}
+		return renderFlags(c), nil
+	case ConfigFormatHelm:
+		var v helmValues
+		v.Pilot.CollectionFilter = c
+		return yaml.Marshal(v)
+	case ConfigFormatOperator:
+		var op operatorFragment
+		op.APIVersion = "install.istio.io/v1alpha1"
+		op.Kind = "IstioOperator"
+		cf := &op.Spec.Values.Pilot.CollectionFilter
+		cf.RequiredCollections = c.RequiredCollections
+		if len(c.ExcludedResourceKinds) > 0 {
+			cf.ExcludedResourceKinds = c.ExcludedResourceKinds
+		}
+		cf.Features = c.Features
+		cf.Selectors = c.Selectors
+		cf.KubeVersionGates = c.KubeVersionGates
+		return yaml.Marshal(op)
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+}
+
+func renderFlags(c FilterConfig) []byte {
+	var b bytes.Buffer
+	if len(c.RequiredCollections) > 0 {
+		names := make([]string, 0, len(c.RequiredCollections))
+		for _, n := range c.RequiredCollections {
+			names = append(names, n.String())
+		}
+		fmt.Fprintf(&b, "--%s=%s\n", flagRequiredCollections, strings.Join(names, ","))
+	}
+	if len(c.ExcludedResourceKinds) > 0 {
+		fmt.Fprintf(&b, "--%s=%s\n", flagExcludedResourceKinds, strings.Join(c.ExcludedResourceKinds, ","))
+	}
+	if c.Features.ServiceDiscovery {
+		fmt.Fprintf(&b, "--%s\n", flagServiceDiscovery)
+	}
+	if c.Features.AmbientEnabled {
+		fmt.Fprintf(&b, "--%s\n", flagAmbientEnabled)
+	}
+	if c.Features.InjectionEnabled {
+		fmt.Fprintf(&b, "--%s\n", flagInjectionEnabled)
+	}
+	return b.Bytes()
+}
+
+// LoadConfig loads a filter configuration in the given format, as rendered by RenderConfig. Selectors are
+// validated against the builtin kinds and the Kubernetes selector syntax; errors for them carry the line of the
+// offending entry. The positions of the exclusion entries and version gates are returned on the configuration,
+// without a file name; see LoadConfigFile.
+func LoadConfig(format ConfigFormat, data []byte) (FilterConfig, error) {
+	return loadConfigAt(format, "", data)
+}
+
+// loadConfigAt is LoadConfig for data read from file, which may be empty if data has no file.
+func loadConfigAt(format ConfigFormat, file string, data []byte) (FilterConfig, error) {
+	c, err := loadConfig(format, data)
+	if err != nil {
+		return FilterConfig{}, err
+	}
+	for _, n := range c.RequiredCollections {
+		if !collection.IsValidName(n.String()) {
+			return FilterConfig{}, fmt.Errorf("invalid required collection name %q", n)
+		}
+	}
+	var lines map[string]int
+	switch format {
+	case ConfigFormatHelm:
+		lines = selectorLines(data, "pilot", "collectionFilter", "selectors")
+	case ConfigFormatOperator:
+		lines = selectorLines(data, "spec", "values", "pilot", "collectionFilter", "selectors")
+	}
+	if errs := validateSelectors(c.Selectors, lines); len(errs) > 0 {
+		return FilterConfig{}, multierror.Append(istiomultierror.New(), errs...).ErrorOrNil()
+	}
+	c.Positions = configPositions(format, file, data)
+	return c, nil
+}
+
+func loadConfig(format ConfigFormat, data []byte) (FilterConfig, error) {
+	switch format {
+	case ConfigFormatFlags:
+		return loadFlags(data)
+	case ConfigFormatHelm:
+		var v helmValues
+		if err := yaml.UnmarshalStrict(data, &v); err != nil {
+			return FilterConfig{}, fmt.Errorf("invalid Helm values: %v", err)
+		}
+		return v.Pilot.CollectionFilter, nil
+	case ConfigFormatOperator:
+		var op operatorFragment
+		if err := yaml.UnmarshalStrict(data, &op); err != nil {
+			return FilterConfig{}, fmt.Errorf("invalid IstioOperator fragment: %v", err)
+		}
+		cf := op.Spec.Values.Pilot.CollectionFilter
+		kinds, err := DecodeOperatorExclusions(cf.ExcludedResourceKinds)
+		if err != nil {
+			return FilterConfig{}, err
+		}
+		return FilterConfig{
+			RequiredCollections:   cf.RequiredCollections,
+			ExcludedResourceKinds: kinds,
+			Features:              cf.Features,
+			Selectors:             cf.Selectors,
+			KubeVersionGates:      cf.KubeVersionGates,
+		}, nil
+	default:
+		return FilterConfig{}, fmt.Errorf("unknown config format %q", format)
+	}
+}
+
+func loadFlags(data []byte) (FilterConfig, error) {
+	var (
+		c        FilterConfig
+		required []string
+	)
+	fs := pflag.NewFlagSet("collection filter", pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringSliceVar(&required, flagRequiredCollections, nil, "Output collections that must be produced.")
+	fs.StringSliceVar(&c.ExcludedResourceKinds, flagExcludedResourceKinds, nil, "Exclusion entries.")
+	fs.BoolVar(&c.Features.ServiceDiscovery, flagServiceDiscovery, false, "Keep the kinds required for service discovery.")
+	fs.BoolVar(&c.Features.AmbientEnabled, flagAmbientEnabled, false, "Keep the kinds required for ambient mesh.")
+	fs.BoolVar(&c.Features.InjectionEnabled, flagInjectionEnabled, false, "Keep the kinds required for sidecar injection.")
+	if err := fs.Parse(strings.Fields(string(data))); err != nil {
+		return FilterConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return FilterConfig{}, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	for _, n := range required {
+		c.RequiredCollections = append(c.RequiredCollections, collection.Name(n))
+	}
+	return c, nil
+}
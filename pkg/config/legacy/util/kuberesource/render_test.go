@@ -0,0 +1,261 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestRenderConfig_RoundTrip(t *testing.T) {
+	filters := map[string]*CollectionFilter{
+		"empty": NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil),
+		"full": NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name(), testDeployment.Name()},
+			WithExcludedResourceKinds("Pod", "core/v1/Secret", "!core/Pod", "collection:k8s/apps/v1/deployments", "*.istio.io/*"),
+			WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true, AmbientEnabled: true, InjectionEnabled: true})),
+	}
+	for name, f := range filters {
+		for _, format := range []ConfigFormat{ConfigFormatFlags, ConfigFormatHelm, ConfigFormatOperator} {
+			t.Run(name+"/"+string(format), func(t *testing.T) {
+				g := NewWithT(t)
+				out, err := f.RenderConfig(format)
+				g.Expect(err).To(BeNil())
+
+				c, err := LoadConfig(format, out)
+				g.Expect(err).To(BeNil(), string(out))
+				expected, _ := f.Config()
+				g.Expect(c.ExcludedResourceKinds).To(ConsistOf(expected.ExcludedResourceKinds))
+
+				loaded := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, c.RequiredCollections, c.Options()...)
+				g.Expect(loaded.fingerprint()).To(Equal(f.fingerprint()), string(out))
+			})
+		}
+	}
+}
+
+// TestRenderConfig_FingerprintPreserved checks that options which are part of the fingerprint are either rendered
+// or rejected, so that loading a rendered configuration never gives a filter that decides differently.
+func TestRenderConfig_FingerprintPreserved(t *testing.T) {
+	required := collection.Names{testService.Name()}
+	cases := map[string]struct {
+		opts    []FilterOption
+		renders bool
+	}{
+		"budget":        {opts: []FilterOption{WithEnabledBudget(3)}},
+		"strict budget": {opts: []FilterOption{WithEnabledBudget(3), WithBudgetPolicy(BudgetStrict)}},
+		"mesh config":   {opts: []FilterOption{WithExclusionsFrom(SourceMeshConfig, "Pod")}},
+		"api source":    {opts: []FilterOption{WithExclusionsFrom(SourceAPI, "Pod")}, renders: true},
+	}
+	for name, tc := range cases {
+		for _, format := range []ConfigFormat{ConfigFormatFlags, ConfigFormatHelm} {
+			t.Run(name+"/"+string(format), func(t *testing.T) {
+				g := NewWithT(t)
+				f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, required, tc.opts...)
+				out, err := f.RenderConfig(format)
+				if !tc.renders {
+					g.Expect(err).To(MatchError(ContainSubstring("filter configuration cannot be rendered")))
+					return
+				}
+				g.Expect(err).To(BeNil())
+				c, err := LoadConfig(format, out)
+				g.Expect(err).To(BeNil(), string(out))
+				loaded := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, c.RequiredCollections, c.Options()...)
+				g.Expect(loaded.fingerprint()).To(Equal(f.fingerprint()), string(out))
+			})
+		}
+	}
+}
+
+func TestRenderConfig_Formats(t *testing.T) {
+	g := NewWithT(t)
+
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, collection.Names{testService.Name()},
+		WithExcludedResourceKinds("Pod", "!core/Pod"), WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}))
+
+	out, err := f.RenderConfig(ConfigFormatFlags)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(out)).To(Equal("--requiredCollections=k8s/core/v1/services\n" +
+		"--excludedResourceKinds=Pod,!core/Pod\n" +
+		"--serviceDiscovery\n"))
+
+	out, err = f.RenderConfig(ConfigFormatHelm)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(out)).To(Equal(`pilot:
+  collectionFilter:
+    excludedResourceKinds:
+    - Pod
+    - '!core/Pod'
+    features:
+      serviceDiscovery: true
+    requiredCollections:
+    - k8s/core/v1/services
+`))
+
+	_, err = f.RenderConfig("toml")
+	g.Expect(err).To(MatchError(`unknown config format "toml"`))
+}
+
+func TestRenderConfig_Unsupported(t *testing.T) {
+	g := NewWithT(t)
+
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, WithOnlyGroups("apps"), WithDiscoveryOverrideOnly(KindService))
+	_, err := f.RenderConfig(ConfigFormatHelm)
+	g.Expect(err).To(MatchError("filter configuration cannot be rendered: uses WithOnlyGroups, WithDiscoveryOverrideOnly"))
+
+	f = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, WithLegacySemantics(), PreferNewestVersion(),
+		WithReenableInputDisabled(), WithConflictPolicy(ConflictStrict))
+	_, err = f.Config()
+	g.Expect(err).To(MatchError("filter configuration cannot be rendered: " +
+		"uses WithLegacySemantics, PreferNewestVersion, WithReenableInputDisabled, WithConflictPolicy"))
+
+	f = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, WithEnabledBudget(3), WithBudgetPolicy(BudgetStrict),
+		WithExclusionsFrom(SourceMeshConfig, "Pod"), WithExclusionsFrom(SourceAPI, "Secret"))
+	_, err = f.Config()
+	g.Expect(err).To(MatchError("filter configuration cannot be rendered: " +
+		"uses WithEnabledBudget, WithBudgetPolicy, WithExclusionsFrom"))
+
+	f = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, nil, WithExcludedResourceKinds("/Pod"))
+	_, err = f.RenderConfig(ConfigFormatFlags)
+	g.Expect(err).NotTo(BeNil())
+}
+
+func TestLoadConfig_Operator(t *testing.T) {
+	g := NewWithT(t)
+
+	c, err := LoadConfig(ConfigFormatOperator, []byte(`apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+spec:
+  values:
+    pilot:
+      collectionFilter:
+        excludedResourceKinds: "Pod, Node"
+        features:
+          ambientEnabled: true
+`))
+	g.Expect(err).To(BeNil())
+	g.Expect(c).To(Equal(FilterConfig{
+		ExcludedResourceKinds: []string{"Pod", "Node"},
+		Features:              FeatureRequirements{AmbientEnabled: true},
+		// Entries given as a single string have the position of the string.
+		Positions: ConfigPositions{ExcludedResourceKinds: map[string]Position{
+			"Pod":  {Line: 7, Column: 32},
+			"Node": {Line: 7, Column: 32},
+		}},
+	}))
+
+	_, err = LoadConfig(ConfigFormatFlags, []byte("--requiredCollections=k8s//pods"))
+	g.Expect(err).To(MatchError(`invalid required collection name "k8s//pods"`))
+	_, err = LoadConfig(ConfigFormatFlags, []byte("--bogus"))
+	g.Expect(err).NotTo(BeNil())
+}
+
+func TestRenderConfig_Selectors(t *testing.T) {
+	g := NewWithT(t)
+
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, collection.Names{testSecret.Name()},
+		WithSelectorHint(KindSecret, SelectorHint{FieldSelector: "type=kubernetes.io/tls"}),
+		WithSelectorHint(KindPod, SelectorHint{LabelSelector: "app in (foo, bar)"}))
+	for _, format := range []ConfigFormat{ConfigFormatHelm, ConfigFormatOperator} {
+		out, err := f.RenderConfig(format)
+		g.Expect(err).To(BeNil())
+		c, err := LoadConfig(format, out)
+		g.Expect(err).To(BeNil(), string(out))
+		g.Expect(c.Selectors).To(Equal(map[string]SelectorHint{
+			KindSecret: {FieldSelector: "type=kubernetes.io/tls"},
+			KindPod:    {LabelSelector: "app in (foo, bar)"},
+		}))
+		loaded := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, c.RequiredCollections, c.Options()...)
+		g.Expect(loaded.fingerprint()).To(Equal(f.fingerprint()), string(out))
+	}
+
+	_, err := f.RenderConfig(ConfigFormatFlags)
+	g.Expect(err).To(MatchError("filter configuration cannot be rendered as flags: uses selectors"))
+}
+
+func TestLoadConfig_Selectors(t *testing.T) {
+	cases := []struct {
+		name      string
+		selectors string
+		errors    []string
+	}{
+		{
+			name: "valid",
+			selectors: `
+      Secret:
+        fieldSelector: type=kubernetes.io/tls
+      Pod:
+        labelSelector: app=foo
+        fieldSelector: spec.nodeName=node-1
+`,
+		},
+		{
+			name: "invalid syntax",
+			selectors: `
+      Secret:
+        fieldSelector: type
+      Pod:
+        labelSelector: "app in (foo"
+      Service: {}
+`,
+			errors: []string{
+				`line 7: invalid selector for kind Pod: label selector "app in (foo": `,
+				`line 5: invalid selector for kind Secret: field selector "type": `,
+				`line 9: invalid selector for kind Service: no label or field selector given`,
+			},
+		},
+		{
+			name: "invalid field",
+			selectors: `
+      Secret:
+        fieldSelector: spec.nodeName=node-1
+`,
+			errors: []string{
+				`line 5: invalid selector for kind Secret: field selector term "spec.nodeName=node-1": field ` +
+					`spec.nodeName is not supported for kind Secret; supported fields: metadata.name, metadata.namespace, type`,
+			},
+		},
+		{
+			name: "disallowed kind",
+			selectors: `
+      Secret:
+        fieldSelector: type=kubernetes.io/tls
+      VirtualService:
+        labelSelector: app=foo
+`,
+			errors: []string{
+				`line 7: invalid selector for kind VirtualService: selectors are only supported for builtin kinds`,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			data := "pilot:\n  collectionFilter:\n    features: {}\n    selectors:" + c.selectors
+			_, err := LoadConfig(ConfigFormatHelm, []byte(data))
+			if len(c.errors) == 0 {
+				g.Expect(err).To(BeNil())
+				return
+			}
+			g.Expect(err).NotTo(BeNil())
+			for _, msg := range c.errors {
+				g.Expect(err.Error()).To(ContainSubstring(msg))
+			}
+		})
+	}
+}
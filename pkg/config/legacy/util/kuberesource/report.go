@@ -0,0 +1,343 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Reason explains the filter decision for a collection.
+type Reason string
+
+const (
+	// ReasonEnabled is used for collections that are upstream of the required collections and not excluded.
+	ReasonEnabled Reason = "Enabled"
+
+	// ReasonExcludedKind is used for collections whose kind matches an exclusion entry.
+	ReasonExcludedKind Reason = "ExcludedKind"
+
+	// ReasonNotUpstream is used for collections that are not needed as inputs by the required collections.
+	ReasonNotUpstream Reason = "NotUpstreamOfRequired"
+
+	// ReasonRequiredForServiceDiscovery is used for excluded collections that were re-enabled because
+	// service discovery needs them.
+	ReasonRequiredForServiceDiscovery Reason = "RequiredForServiceDiscovery"
+
+	// ReasonRequiredForAmbient is used for excluded collections that were re-enabled because ambient
+	// mesh needs them.
+	ReasonRequiredForAmbient Reason = "RequiredForAmbient"
+
+	// ReasonRequiredForInjection is used for excluded collections that were re-enabled because sidecar
+	// injection needs them.
+	ReasonRequiredForInjection Reason = "RequiredForInjection"
+
+	// ReasonResourceUnavailable is used for collections whose resource type is not served by the API server,
+	// for example because the CRD is not installed.
+	ReasonResourceUnavailable Reason = "ResourceUnavailable"
+
+	// ReasonTrimmedByGroupScope is used for collections outside the groups given to WithOnlyGroups.
+	ReasonTrimmedByGroupScope Reason = "TrimmedByGroupScope"
+
+	// ReasonSupersededByNewerVersion is used for collections disabled by PreferNewestVersion because a newer
+	// version of the same group/kind is enabled.
+	ReasonSupersededByNewerVersion Reason = "SupersededByNewerVersion"
+
+	// ReasonUndetermined is used for collections whose availability could not be determined. They are kept
+	// enabled unless the probe failure policy is FailClosed.
+	ReasonUndetermined Reason = "Undetermined"
+
+	// ReasonLazy is used for enabled collections of a kind given to WithLazyKinds that has not been referenced
+	// yet. They are registered, but not watched.
+	ReasonLazy Reason = "Lazy"
+
+	// ReasonKeptForStatus is used for collections given to WithStatusWriters that are not upstream of the required
+	// collections, and are kept enabled because istiod writes status to them.
+	ReasonKeptForStatus Reason = "KeptForStatus"
+
+	// ReasonPassedThrough is used for collections outside the subset given to ApplyForSubset, which are passed
+	// through enabled or disabled as they were.
+	ReasonPassedThrough Reason = "PassedThrough"
+
+	// ReasonAlreadyDisabled is used for collections that arrived disabled in the input, and that the filter does
+	// not enable again unless WithReenableInputDisabled is given.
+	ReasonAlreadyDisabled Reason = "AlreadyDisabled"
+
+	// ReasonNotInBaseline is used for collections that would be enabled, but are not in the baseline given to
+	// WithDenyNewCollections.
+	ReasonNotInBaseline Reason = "NotInBaseline"
+)
+
+// reasonPrecedence orders the reasons a collection can be disabled for, from highest to lowest. When more than
+// one applies, the highest is the primary reason of the decision and the others are kept as secondary reasons:
+//
+//	ResourceUnavailable > Undetermined > ExcludedKind > NotUpstreamOfRequired > TrimmedByGroupScope >
+//	SupersededByNewerVersion > NotInBaseline
+//
+// That is, a resource that cannot be watched at all is reported as such before any configuration that would
+// have disabled it, and explicit operator configuration is reported before derived pruning.
+var reasonPrecedence = map[Reason]int{
+	ReasonResourceUnavailable:      0,
+	ReasonUndetermined:             1,
+	ReasonExcludedKind:             2,
+	ReasonNotUpstream:              3,
+	ReasonTrimmedByGroupScope:      4,
+	ReasonSupersededByNewerVersion: 5,
+	ReasonNotInBaseline:            6,
+}
+
+// Decision is the outcome of the filter for a single collection.
+type Decision struct {
+	Disabled bool `json:"disabled"`
+
+	// Reason is the primary reason for the decision.
+	Reason Reason `json:"reason"`
+
+	// SecondaryReasons are the other reasons a disabled collection would have been disabled for, in order of
+	// precedence. They are informational; comparisons between decisions only consider the primary reason.
+	SecondaryReasons []Reason `json:"secondaryReasons,omitempty"`
+
+	// MatchedEntry is the exclusion entry that decided whether the collection is excluded, if any matched. For a
+	// negated entry, the collection was re-included by it.
+	MatchedEntry string `json:"matchedEntry,omitempty"`
+}
+
+// disabledFor returns d with reason added to the reasons it is disabled for.
+func (d Decision) disabledFor(reason Reason) Decision {
+	reasons := []Reason{reason}
+	if d.Disabled {
+		reasons = append(append(reasons, d.Reason), d.SecondaryReasons...)
+	}
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return reasonPrecedence[reasons[i]] < reasonPrecedence[reasons[j]]
+	})
+	out := Decision{Disabled: true, Reason: reasons[0], MatchedEntry: d.MatchedEntry}
+	for _, r := range reasons[1:] {
+		if r != out.Reason && (len(out.SecondaryReasons) == 0 || out.SecondaryReasons[len(out.SecondaryReasons)-1] != r) {
+			out.SecondaryReasons = append(out.SecondaryReasons, r)
+		}
+	}
+	return out
+}
+
+// sameAs returns true if both decisions agree on whether the collection is disabled and on the primary reason.
+func (d Decision) sameAs(o Decision) bool {
+	return d.Disabled == o.Disabled && d.Reason == o.Reason
+}
+
+// ReportEntry records the decision for a single collection.
+type ReportEntry struct {
+	Collection collection.Name `json:"collection"`
+	Group      string          `json:"group"`
+	Version    string          `json:"version"`
+	Kind       string          `json:"kind"`
+	Decision
+
+	// DiscoveryUse tells, for collections kept for service discovery, whether the pipeline consumes them too.
+	DiscoveryUse DiscoveryUse `json:"discoveryUse,omitempty"`
+
+	// InputDisabled is set for collections that arrived disabled in the input, whatever the decision.
+	InputDisabled bool `json:"inputDisabled,omitempty"`
+}
+
+// DiscoveryUse classifies a collection kept enabled with ReasonRequiredForServiceDiscovery.
+type DiscoveryUse string
+
+const (
+	// KeptForDiscoveryOnly is used for collections that are watched only for service discovery: no required
+	// collection needs them as an input.
+	KeptForDiscoveryOnly DiscoveryUse = "KeptForDiscoveryOnly"
+
+	// KeptForDiscoveryAndPipeline is used for collections that service discovery needs and that are also
+	// upstream of the required collections.
+	KeptForDiscoveryAndPipeline DiscoveryUse = "KeptForDiscoveryAndPipeline"
+)
+
+// FilterReport records the decision made for every collection passed through the filter, in input order.
+type FilterReport struct {
+	Entries []ReportEntry `json:"entries"`
+
+	// Dedups lists identical duplicate collections that were dropped while composing the input.
+	Dedups []DedupRecord `json:"dedups,omitempty"`
+
+	// EndpointsMode is the endpoints mode the service discovery override used, if service discovery is enabled.
+	EndpointsMode EndpointsMode `json:"endpointsMode,omitempty"`
+}
+
+// Entry returns the report entry for the named collection.
+func (r *FilterReport) Entry(name collection.Name) (ReportEntry, bool) {
+	if r == nil {
+		return ReportEntry{}, false
+	}
+	for _, e := range r.Entries {
+		if e.Collection == name {
+			return e, true
+		}
+	}
+	return ReportEntry{}, false
+}
+
+// DiscoveryOnly returns the names of the collections kept enabled only for service discovery, in name order.
+func (r *FilterReport) DiscoveryOnly() collection.Names {
+	out := make(collection.Names, 0)
+	if r == nil {
+		return out
+	}
+	for _, e := range r.Entries {
+		if e.DiscoveryUse == KeptForDiscoveryOnly {
+			out = append(out, e.Collection)
+		}
+	}
+	out.Sort()
+	return out
+}
+
+// Equal returns true if both reports make the same decision for the same set of collections. Entry order, the
+// resource metadata recorded with each entry and dedup records are ignored.
+func (r *FilterReport) Equal(other *FilterReport) bool {
+	return len(r.decisionChanges(other)) == 0
+}
+
+// SemanticDiff describes, in collection name order, every collection whose decision differs between r and other,
+// including collections only present in one of them. Like Equal, it ignores ordering and informational fields.
+func (r *FilterReport) SemanticDiff(other *FilterReport) []string {
+	changes := r.decisionChanges(other)
+	out := make([]string, 0, len(changes))
+	for _, c := range changes {
+		out = append(out, c.String())
+	}
+	return out
+}
+
+// changedCollections returns the names of the collections whose decision differs between r and other.
+func (r *FilterReport) changedCollections(other *FilterReport) collection.Names {
+	changes := r.decisionChanges(other)
+	out := make(collection.Names, 0, len(changes))
+	for _, c := range changes {
+		out = append(out, c.collection)
+	}
+	return out
+}
+
+// decisionChange is a difference in the decision for a single collection. A nil decision means the collection is
+// absent from that report.
+type decisionChange struct {
+	collection collection.Name
+	from, to   *Decision
+}
+
+func (c decisionChange) String() string {
+	switch {
+	case c.from == nil:
+		return fmt.Sprintf("%s: added as %s", c.collection, c.to)
+	case c.to == nil:
+		return fmt.Sprintf("%s: removed, was %s", c.collection, c.from)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", c.collection, c.from, c.to)
+	}
+}
+
+func (d *Decision) String() string {
+	state := "enabled"
+	if d.Disabled {
+		state = "disabled"
+	}
+	return fmt.Sprintf("%s (%s)", state, d.Reason)
+}
+
+func (r *FilterReport) decisionChanges(other *FilterReport) []decisionChange {
+	from := r.decisions()
+	to := other.decisions()
+
+	var changes []decisionChange
+	for name, d := range from {
+		d := d
+		o, ok := to[name]
+		switch {
+		case !ok:
+			changes = append(changes, decisionChange{collection: name, from: &d})
+		case !o.sameAs(d):
+			o := o
+			changes = append(changes, decisionChange{collection: name, from: &d, to: &o})
+		}
+	}
+	for name, d := range to {
+		d := d
+		if _, ok := from[name]; !ok {
+			changes = append(changes, decisionChange{collection: name, to: &d})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].collection < changes[j].collection
+	})
+	return changes
+}
+
+func (r *FilterReport) decisions() map[collection.Name]Decision {
+	out := make(map[collection.Name]Decision)
+	if r == nil {
+		return out
+	}
+	for _, e := range r.Entries {
+		out[e.Collection] = e.Decision
+	}
+	return out
+}
+
+// FilterStats summarizes a FilterReport.
+type FilterStats struct {
+	Total    int            `json:"total"`
+	Enabled  int            `json:"enabled"`
+	Disabled int            `json:"disabled"`
+	ByReason map[Reason]int `json:"byReason"`
+
+	// EnabledBuiltinKinds and EnabledCRDKinds count the kinds of the enabled collections, as SchemaSetStats does.
+	EnabledBuiltinKinds int `json:"enabledBuiltinKinds"`
+	EnabledCRDKinds     int `json:"enabledCRDKinds"`
+
+	// InputDisabled counts the collections that arrived disabled in the input, including any the filter enabled
+	// again. Those it kept disabled are also counted in Disabled.
+	InputDisabled int `json:"inputDisabled,omitempty"`
+}
+
+// statsFor summarizes r, whose filtered set is schemas.
+func statsFor(r *FilterReport, schemas collection.Schemas) FilterStats {
+	c := newSchemaCounter()
+	for _, s := range schemas.All() {
+		if !s.IsDisabled() {
+			c.add(s.Resource())
+		}
+	}
+	st := FilterStats{
+		ByReason:            make(map[Reason]int),
+		EnabledBuiltinKinds: c.out.BuiltinKinds,
+		EnabledCRDKinds:     c.out.CRDKinds,
+	}
+	for _, e := range r.Entries {
+		st.Total++
+		if e.InputDisabled {
+			st.InputDisabled++
+		}
+		if e.Disabled {
+			st.Disabled++
+		} else {
+			st.Enabled++
+		}
+		st.ByReason[e.Reason]++
+	}
+	return st
+}
@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestFilterReport_SemanticDiff(t *testing.T) {
+	enabled := func(name collection.Name) ReportEntry {
+		return ReportEntry{Collection: name, Kind: "Foo", Decision: Decision{Reason: ReasonEnabled}}
+	}
+	excluded := func(name collection.Name) ReportEntry {
+		return ReportEntry{Collection: name, Kind: "Foo", Decision: Decision{Disabled: true, Reason: ReasonExcludedKind}}
+	}
+
+	cases := []struct {
+		name     string
+		a, b     *FilterReport
+		expected []string
+	}{
+		{
+			name:     "identical",
+			a:        &FilterReport{Entries: []ReportEntry{enabled("a"), excluded("b")}},
+			b:        &FilterReport{Entries: []ReportEntry{enabled("a"), excluded("b")}},
+			expected: []string{},
+		},
+		{
+			name:     "order only",
+			a:        &FilterReport{Entries: []ReportEntry{enabled("a"), excluded("b")}},
+			b:        &FilterReport{Entries: []ReportEntry{excluded("b"), enabled("a")}},
+			expected: []string{},
+		},
+		{
+			name: "provenance only",
+			a:    &FilterReport{Entries: []ReportEntry{enabled("a")}},
+			b: &FilterReport{
+				Entries: []ReportEntry{{Collection: "a", Group: "foo.io", Version: "v2", Kind: "Bar", Decision: Decision{Reason: ReasonEnabled}}},
+				Dedups:  []DedupRecord{{Collection: "a", Occurrences: 2}},
+			},
+			expected: []string{},
+		},
+		{
+			name: "secondary reasons only",
+			a:    &FilterReport{Entries: []ReportEntry{excluded("a")}},
+			b: &FilterReport{Entries: []ReportEntry{{
+				Collection: "a",
+				Decision:   Decision{Disabled: true, Reason: ReasonExcludedKind, SecondaryReasons: []Reason{ReasonNotUpstream}},
+			}}},
+			expected: []string{},
+		},
+		{
+			name: "decisions",
+			a:    &FilterReport{Entries: []ReportEntry{enabled("a"), enabled("b"), enabled("c")}},
+			b: &FilterReport{Entries: []ReportEntry{
+				excluded("b"),
+				{Collection: "c", Decision: Decision{Reason: ReasonRequiredForServiceDiscovery}},
+				enabled("d"),
+			}},
+			expected: []string{
+				"a: removed, was enabled (Enabled)",
+				"b: enabled (Enabled) -> disabled (ExcludedKind)",
+				"c: enabled (Enabled) -> enabled (RequiredForServiceDiscovery)",
+				"d: added as enabled (Enabled)",
+			},
+		},
+		{
+			name:     "nil",
+			a:        nil,
+			b:        &FilterReport{Entries: []ReportEntry{excluded("a")}},
+			expected: []string{"a: added as disabled (ExcludedKind)"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(c.a.SemanticDiff(c.b)).To(Equal(c.expected))
+			g.Expect(c.a.Equal(c.b)).To(Equal(len(c.expected) == 0))
+			g.Expect(c.b.Equal(c.a)).To(Equal(len(c.expected) == 0))
+		})
+	}
+}
+
+func TestCollectionFilterState_Changed(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(state.Changed()).To(BeEmpty())
+
+	_, err = state.Update(WithExcludedResourceKinds(KindNode, KindPod))
+	g.Expect(err).To(BeNil())
+	g.Expect(state.Changed()).To(Equal(collection.Names{testNode.Name(), testPod.Name()}))
+
+	_, err = state.Update(WithExcludedResourceKinds(KindPod, KindNode))
+	g.Expect(err).To(BeNil())
+	g.Expect(state.Changed()).To(BeEmpty())
+}
@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"time"
+
+	"istio.io/istio/pkg/config"
+)
+
+// reprobeBackoff configures when a CollectionFilterState probes undetermined resource types again.
+type reprobeBackoff struct {
+	initial time.Duration
+	max     time.Duration
+}
+
+// WithReprobeBackoff makes a CollectionFilterState probe the resource types whose availability could not be
+// determined again on its own: initial after the probe failed, and then after twice the previous delay on every
+// further failure, up to max. Until a re-probe is due, updates keep the types undetermined with the error of their
+// last probe, rather than probing them. A max of zero or less does not cap the delay. By default, undetermined
+// types are probed again on every update, and only then. The times are those of the clock of the state; see
+// WithClock. It has no effect on a single filter pass.
+func WithReprobeBackoff(initial, max time.Duration) FilterOption {
+	return func(o *filterOptions) {
+		o.reprobe = reprobeBackoff{initial: initial, max: max}
+	}
+}
+
+// withDeferredProbes makes the filter use the given errors for the availability of the given resource types,
+// instead of probing them.
+func withDeferredProbes(deferred map[config.GroupVersionKind]error) FilterOption {
+	return func(o *filterOptions) {
+		o.deferredProbes = deferred
+	}
+}
+
+// next returns the delay after a probe that failed, given the delay after the previous failure, or zero.
+func (b reprobeBackoff) next(prev time.Duration) time.Duration {
+	d := b.initial
+	if prev > 0 {
+		d = 2 * prev
+	}
+	if b.max > 0 && d > b.max {
+		d = b.max
+	}
+	return d
+}
+
+// reprobeEntry is the backoff of a resource type whose availability is undetermined.
+type reprobeEntry struct {
+	err   error
+	delay time.Duration
+	due   time.Time
+}
+
+// reprobeSchedule tracks the undetermined resource types of a CollectionFilterState, and the re-probe scheduled
+// for them.
+type reprobeSchedule struct {
+	entries map[config.GroupVersionKind]reprobeEntry
+	stop    func() bool
+}
+
+// deferred returns the resource types whose re-probe is not due at now, with the error of their last probe.
+func (r *reprobeSchedule) deferred(now time.Time) map[config.GroupVersionKind]error {
+	out := make(map[config.GroupVersionKind]error)
+	for gvk, e := range r.entries {
+		if e.due.After(now) {
+			out[gvk] = e.err
+		}
+	}
+	return out
+}
+
+// record replaces the entries with the resource types result left undetermined at now, deferred being those that
+// were not probed, and returns the delay until the next re-probe is due. It returns false if none is.
+func (r *reprobeSchedule) record(result *FilterResult, deferred map[config.GroupVersionKind]error, now time.Time,
+	b reprobeBackoff) (time.Duration, bool) {
+	r.cancel()
+	if b.initial <= 0 || len(result.undetermined) == 0 {
+		r.entries = nil
+		return 0, false
+	}
+	entries := make(map[config.GroupVersionKind]reprobeEntry, len(result.undetermined))
+	var next time.Time
+	for gvk, err := range result.undetermined {
+		e, ok := r.entries[gvk]
+		if _, unprobed := deferred[gvk]; !ok || !unprobed {
+			e.delay = b.next(e.delay)
+			e.err, e.due = err, now.Add(e.delay)
+		}
+		entries[gvk] = e
+		if next.IsZero() || e.due.Before(next) {
+			next = e.due
+		}
+	}
+	r.entries = entries
+	return next.Sub(now), true
+}
+
+// cancel stops the scheduled re-probe, if any.
+func (r *reprobeSchedule) cancel() {
+	if r.stop != nil {
+		r.stop()
+		r.stop = nil
+	}
+}
+
+// reprobeNow updates the state with its current configuration, probing the undetermined resource types whose
+// re-probe is due.
+func (s *CollectionFilterState) reprobeNow() {
+	if _, err := s.applyUpdate(nil, true, nil); err != nil {
+		s.mu.RLock()
+		logger := s.logger
+		s.mu.RUnlock()
+		logger.Warnf("collection filter: re-probing availability failed: %v", err)
+	}
+}
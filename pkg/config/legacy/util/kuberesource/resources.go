@@ -15,101 +15,156 @@
 package kuberesource
 
 import (
-	"fmt"
+	"context"
+	"sync"
 
 	"istio.io/istio/pkg/config/legacy/processing/transformer"
-	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/resource"
 )
 
-// DisableExcludedCollections is a helper that filters collection.Schemas to disable some resources
+// DisableExcludedCollections is DisableExcludedCollectionsFor with a concrete transformer graph, kept for
+// compatibility.
+func DisableExcludedCollections(in collection.Schemas, providers transformer.Providers,
+	requiredCols collection.Names, excludedResourceKinds []string, enableServiceDiscovery bool, opts ...FilterOption) collection.Schemas {
+	return DisableExcludedCollectionsFor(in, providers, requiredCols, excludedResourceKinds, enableServiceDiscovery, opts...)
+}
+
+// DisableExcludedCollectionsFor is a helper that filters collection.Schemas to disable some resources
 // The first filter behaves in the same way as existing logic:
 // - Builtin types are excluded by default.
 // - If ServiceDiscovery is enabled, any built-in type should be re-added.
-// In addition, any resources not needed as inputs by the specified collections are disabled
-func DisableExcludedCollections(in collection.Schemas, providers transformer.Providers,
-	requiredCols collection.Names, excludedResourceKinds []string, enableServiceDiscovery bool) collection.Schemas {
-	// Get upstream collections in terms of transformer configuration
-	// Required collections are specified in terms of transformer outputs, but we care here about the corresponding inputs
-	upstreamCols := providers.RequiredInputsFor(requiredCols)
-
-	resultBuilder := collection.NewSchemasBuilder()
-	for _, s := range in.All() {
-		disabled := false
-		if isKindExcluded(excludedResourceKinds, s.Resource().Kind()) {
-			// Found a matching exclude directive for this KubeResource. Disable the resource.
-			disabled = true
-
-			// Check and see if this is needed for Service Discovery. If needed, we will need to re-enable.
-			if enableServiceDiscovery {
-				if IsRequiredForServiceDiscovery(s.Resource()) {
-					// This is needed for service discovery. Re-enable.
-					disabled = false
-				}
-			}
-		}
-
-		// Additionally, filter out any resources not upstream of required collections
-		if _, ok := upstreamCols[s.Name()]; !ok {
-			disabled = true
-		}
-
-		if disabled {
-			s = s.Disable()
-		}
-
-		_ = resultBuilder.Add(s)
+// In addition, any resources not needed as inputs by the specified collections are disabled.
+// Additional feature requirements, such as ambient mesh, can be supplied through opts.
+// Enabled schemas are returned as the same instances found in in; see CollectionFilter.Apply.
+// It filters exactly as FilterCollections does with the equivalent options, but since it cannot fail, the errors
+// FilterCollections would return are logged and the schemas are filtered regardless.
+func DisableExcludedCollectionsFor(in collection.Schemas, providers InputProviders,
+	requiredCols collection.Names, excludedResourceKinds []string, enableServiceDiscovery bool, opts ...FilterOption) collection.Schemas {
+	f := NewCollectionFilter(providers, requiredCols, legacyOptions(excludedResourceKinds, enableServiceDiscovery, opts)...)
+	result, errs := f.applyLenient(context.Background(), in)
+	log := f.opts.logger()
+	for _, err := range errs {
+		log.Warnf("collection filter: %v", err)
 	}
+	for _, w := range result.Warnings {
+		log.Warnf("%s", w.Message)
+	}
+	startupSummaryOnce.Do(func() {
+		log.Infof("%s", StartupSummary(result))
+	})
+	return result.Schemas
+}
 
-	return resultBuilder.Build()
+// legacyOptions returns the options equivalent to the positional arguments of DisableExcludedCollectionsFor,
+// followed by opts.
+func legacyOptions(excludedResourceKinds []string, enableServiceDiscovery bool, opts []FilterOption) []FilterOption {
+	return append([]FilterOption{
+		WithExcludedResourceKinds(excludedResourceKinds...),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: enableServiceDiscovery}),
+	}, opts...)
 }
 
-// DefaultExcludedResourceKinds returns the default list of resource kinds to exclude.
+// startupSummaryOnce logs the StartupSummary of the first filter applied at startup only.
+var startupSummaryOnce sync.Once
+
+// DefaultExcludedResourceKinds returns the default list of resource kinds to exclude. See DefaultExclusions for
+// why each kind is excluded.
 func DefaultExcludedResourceKinds() []string {
-	resources := make([]string, 0)
-	for _, r := range schema.MustGet().KubeCollections().All() {
-		if IsDefaultExcluded(r.Resource()) {
-			resources = append(resources, r.Resource().Kind())
-		}
+	resources := make([]string, 0, len(defaultExclusions))
+	for _, e := range defaultExclusions {
+		resources = append(resources, e.Kind)
 	}
 	return resources
 }
 
-func isKindExcluded(excludedResourceKinds []string, kind string) bool {
-	for _, excludedKind := range excludedResourceKinds {
-		if kind == excludedKind {
-			return true
-		}
+// the following code minimally duplicates logic from galley/pkg/config/source/kube/rt/known.go
+// without propagating the many dependencies it comes with.
+
+var (
+	knownTypesMu sync.RWMutex
+
+	// knownTypes maps each kind required for service discovery to the versions it is required at.
+	knownTypes = builtinServiceDiscoveryTypes()
+)
+
+// builtinServiceDiscoveryTypes returns the builtin kinds required for service discovery. The core kinds are pinned
+// to v1, so that a kind of the same name served by an aggregated API at another version is not mistaken for them.
+func builtinServiceDiscoveryTypes() map[string]versionSet {
+	return map[string]versionSet{
+		TypeKey("", KindService):   newVersionSet("v1"),
+		TypeKey("", KindNamespace): newVersionSet("v1"),
+		TypeKey("", KindNode):      newVersionSet("v1"),
+		TypeKey("", KindPod):       newVersionSet("v1"),
+		TypeKey("", KindSecret):    newVersionSet("v1"),
 	}
+}
 
-	return false
+// versionSet is the set of versions a type is pinned to. An empty set matches every version.
+type versionSet map[string]struct{}
+
+func newVersionSet(versions ...string) versionSet {
+	v := make(versionSet, len(versions))
+	for _, version := range versions {
+		v[version] = struct{}{}
+	}
+	return v
 }
 
-// the following code minimally duplicates logic from galley/pkg/config/source/kube/rt/known.go
-// without propagating the many dependencies it comes with.
+func (v versionSet) matches(version string) bool {
+	if len(v) == 0 {
+		return true
+	}
+	_, ok := v[version]
+	return ok
+}
 
-var knownTypes = map[string]struct{}{
-	asTypesKey("", "Service"):   struct{}{},
-	asTypesKey("", "Namespace"): struct{}{},
-	asTypesKey("", "Node"):      struct{}{},
-	asTypesKey("", "Pod"):       struct{}{},
-	asTypesKey("", "Secret"):    struct{}{},
+// RegisterServiceDiscoveryType adds the given group/kind to the set of kinds required by service discovery. If
+// versions are given, the kind is only required at those versions, in addition to any it was pinned to before;
+// otherwise, or if the kind was already registered without versions, it is required at every version.
+// Registration fails with ErrRegistriesFrozen once a filter has been applied.
+func RegisterServiceDiscoveryType(group, kind string, versions ...string) error {
+	return register(func() {
+		knownTypesMu.Lock()
+		defer knownTypesMu.Unlock()
+		key := TypeKey(group, kind)
+		existing, ok := knownTypes[key]
+		switch {
+		case len(versions) == 0:
+			knownTypes[key] = newVersionSet()
+		case !ok:
+			knownTypes[key] = newVersionSet(versions...)
+		case len(existing) > 0:
+			for _, v := range versions {
+				existing[v] = struct{}{}
+			}
+		}
+	})
 }
 
-func asTypesKey(group, kind string) string {
-	if group == "" {
-		return kind
+// knownTypeKeys returns the group/kind keys of the kinds required by service discovery.
+func knownTypeKeys() map[string]struct{} {
+	knownTypesMu.RLock()
+	defer knownTypesMu.RUnlock()
+	keys := make(map[string]struct{}, len(knownTypes))
+	for k := range knownTypes {
+		keys[k] = struct{}{}
 	}
-	return fmt.Sprintf("%s/%s", group, kind)
+	return keys
 }
 
+// IsRequiredForServiceDiscovery returns true if res is watched by service discovery, at its version if the kind
+// is pinned to specific versions. The builtin kinds are Service, Namespace, Node, Pod and Secret; service
+// registries, such as MCS or external registries, add their own with RegisterServiceDiscoveryType, and a filter
+// can narrow the set with WithDiscoveryOverrideOnly. The endpoints kinds are not part of the set: which of
+// Endpoints and EndpointSlice service discovery needs depends on the EndpointsMode; see WithDiscoveryOptions.
 func IsRequiredForServiceDiscovery(res resource.Schema) bool {
-	key := asTypesKey(res.Group(), res.Kind())
-	_, ok := knownTypes[key]
-	return ok
+	return isServiceDiscoveryType(res.Group(), res.Version(), res.Kind())
 }
 
-func IsDefaultExcluded(res resource.Schema) bool {
-	return IsRequiredForServiceDiscovery(res)
+func isServiceDiscoveryType(group, version, kind string) bool {
+	knownTypesMu.RLock()
+	defer knownTypesMu.RUnlock()
+	versions, ok := knownTypes[TypeKey(group, kind)]
+	return ok && versions.matches(version)
 }
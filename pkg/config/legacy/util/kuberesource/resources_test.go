@@ -0,0 +1,349 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// newTestSchema builds a collection schema for the given group/version/kind, named like the
+// generated Kubernetes collections (e.g. k8s/core/v1/services).
+func newTestSchema(group, version, kind string) collection.Schema {
+	plural := testPlural(kind)
+	g := group
+	if g == "" {
+		g = "core"
+	}
+	return newNamedTestSchema("k8s/"+g+"/"+version+"/"+plural, group, version, kind)
+}
+
+// newNamedTestSchema builds a collection schema with an explicit collection name.
+func newNamedTestSchema(name, group, version, kind string) collection.Schema {
+	plural := testPlural(kind)
+	return collection.Builder{
+		Name:         name,
+		VariableName: kind,
+		Resource: resource.Builder{
+			Group:        group,
+			Version:      version,
+			Kind:         kind,
+			Plural:       plural,
+			Proto:        "google.protobuf.Empty",
+			ProtoPackage: "github.com/gogo/protobuf/types",
+		}.BuildNoValidate(),
+	}.MustBuild()
+}
+
+func testPlural(kind string) string {
+	k := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(k, "s"):
+		return k
+	case strings.HasSuffix(k, "y"):
+		return strings.TrimSuffix(k, "y") + "ies"
+	default:
+		return k + "s"
+	}
+}
+
+var (
+	testService       = newTestSchema("", "v1", "Service")
+	testNamespace     = newTestSchema("", "v1", "Namespace")
+	testNode          = newTestSchema("", "v1", "Node")
+	testPod           = newTestSchema("", "v1", "Pod")
+	testSecret        = newTestSchema("", "v1", "Secret")
+	testEndpointSlice = newTestSchema("discovery.k8s.io", "v1", "EndpointSlice")
+	testDeployment    = newTestSchema("apps", "v1", "Deployment")
+)
+
+func testSchemas() collection.Schemas {
+	return collection.SchemasFor(testService, testNamespace, testNode, testPod, testSecret, testEndpointSlice, testDeployment)
+}
+
+func enabledNames(s collection.Schemas) []string {
+	var out []string
+	for _, n := range s.WithoutDisabledCollections().CollectionNames() {
+		out = append(out, n.String())
+	}
+	return out
+}
+
+func TestDisableExcludedCollections_FeatureRequirements(t *testing.T) {
+	in := testSchemas()
+	excluded := []string{"Service", "Namespace", "Node", "Pod", "Secret", "EndpointSlice"}
+
+	cases := []struct {
+		name     string
+		sd       bool
+		opts     []FilterOption
+		expected []string
+	}{
+		{
+			name:     "none",
+			expected: []string{"k8s/apps/v1/deployments"},
+		},
+		{
+			name: "sidecar only",
+			sd:   true,
+			expected: []string{
+				"k8s/apps/v1/deployments", "k8s/core/v1/namespaces", "k8s/core/v1/nodes", "k8s/core/v1/pods",
+				"k8s/core/v1/secrets", "k8s/core/v1/services", "k8s/discovery.k8s.io/v1/endpointslices",
+			},
+		},
+		{
+			name: "sidecar only with Endpoints",
+			sd:   true,
+			opts: []FilterOption{WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: EndpointsOnly})},
+			expected: []string{
+				"k8s/apps/v1/deployments", "k8s/core/v1/namespaces", "k8s/core/v1/nodes",
+				"k8s/core/v1/pods", "k8s/core/v1/secrets", "k8s/core/v1/services",
+			},
+		},
+		{
+			name: "ambient only",
+			opts: []FilterOption{WithFeatureRequirements(FeatureRequirements{AmbientEnabled: true})},
+			expected: []string{
+				"k8s/apps/v1/deployments", "k8s/core/v1/namespaces", "k8s/core/v1/pods",
+				"k8s/core/v1/secrets", "k8s/core/v1/services", "k8s/discovery.k8s.io/v1/endpointslices",
+			},
+		},
+		{
+			name: "both",
+			sd:   true,
+			opts: []FilterOption{WithFeatureRequirements(FeatureRequirements{AmbientEnabled: true})},
+			expected: []string{
+				"k8s/apps/v1/deployments", "k8s/core/v1/namespaces", "k8s/core/v1/nodes", "k8s/core/v1/pods",
+				"k8s/core/v1/secrets", "k8s/core/v1/services", "k8s/discovery.k8s.io/v1/endpointslices",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			out := DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), excluded, c.sd, c.opts...)
+			g.Expect(enabledNames(out)).To(Equal(c.expected))
+		})
+	}
+}
+
+func TestDisableExcludedCollections_Patterns(t *testing.T) {
+	in := collection.SchemasFor(testService, testPod, testSecret, testEndpointSlice, testDeployment, testAuthzPolicy)
+
+	cases := []struct {
+		name     string
+		excluded []string
+		sd       bool
+		expected []string
+	}{
+		{
+			name:     "group",
+			excluded: []string{"discovery.k8s.io/*"},
+			expected: []string{
+				"k8s/apps/v1/deployments", "k8s/core/v1/pods", "k8s/core/v1/secrets", "k8s/core/v1/services",
+				"k8s/security.istio.io/v1beta1/authorizationpolicies",
+			},
+		},
+		{
+			name:     "kind glob",
+			excluded: []string{"*Policy", "apps/Deployment"},
+			expected: []string{
+				"k8s/core/v1/pods", "k8s/core/v1/secrets", "k8s/core/v1/services", "k8s/discovery.k8s.io/v1/endpointslices",
+			},
+		},
+		{
+			// Kinds required for service discovery are re-enabled whatever pattern excluded them.
+			name:     "core group with service discovery",
+			excluded: []string{"core/*", "discovery.k8s.io/*"},
+			sd:       true,
+			expected: []string{
+				"k8s/apps/v1/deployments", "k8s/core/v1/pods", "k8s/core/v1/secrets", "k8s/core/v1/services",
+				"k8s/discovery.k8s.io/v1/endpointslices", "k8s/security.istio.io/v1beta1/authorizationpolicies",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			out := DisableExcludedCollections(in, nil, in.CollectionNames(), c.excluded, c.sd)
+			g.Expect(enabledNames(out)).To(Equal(c.expected))
+		})
+	}
+}
+
+func TestRegisterAmbientType(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(IsRequiredForAmbient(testDeployment.Resource())).To(BeFalse())
+
+	AllowLateRegistration()
+	t.Cleanup(ResetRegistriesForTest)
+	g.Expect(RegisterAmbientType("apps", "Deployment")).To(Succeed())
+
+	g.Expect(IsRequiredForAmbient(testDeployment.Resource())).To(BeTrue())
+	g.Expect(FeatureRequirements{AmbientEnabled: true}.IsRequired(testDeployment.Resource())).To(BeTrue())
+	g.Expect(FeatureRequirements{ServiceDiscovery: true}.IsRequired(testDeployment.Resource())).To(BeFalse())
+	g.Expect(FeatureRequiredKinds(FeatureRequirements{AmbientEnabled: true})).
+		To(ContainElement(schema.GroupKind{Group: "apps", Kind: KindDeployment}))
+}
+
+// reasonOf returns the reason recorded for name, or an empty reason if it is not in the result.
+func reasonOf(r *FilterResult, name collection.Name) Reason {
+	reason, _ := r.ReasonFor(name)
+	return reason
+}
+
+func TestIsRequiredForServiceDiscovery_Versions(t *testing.T) {
+	g := NewWithT(t)
+
+	// The core kinds are pinned to v1.
+	g.Expect(IsRequiredForServiceDiscovery(testService.Resource())).To(BeTrue())
+	g.Expect(IsRequiredForServiceDiscovery(newTestSchema("", "v2beta1", KindService).Resource())).To(BeFalse())
+	g.Expect(IsRequiredForServiceDiscovery(newTestSchema("example.com", "v1", KindService).Resource())).To(BeFalse())
+
+	AllowLateRegistration()
+	t.Cleanup(ResetRegistriesForTest)
+
+	widget := func(version string) bool {
+		return IsRequiredForServiceDiscovery(newTestSchema("example.com", version, "Widget").Resource())
+	}
+	gadget := func(version string) bool {
+		return IsRequiredForServiceDiscovery(newTestSchema("example.com", version, "Gadget").Resource())
+	}
+
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Widget")).To(Succeed())
+	g.Expect(widget("v1")).To(BeTrue())
+	g.Expect(widget("v1alpha1")).To(BeTrue())
+	// Pinning versions onto an unpinned kind does not narrow it.
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Widget", "v1")).To(Succeed())
+	g.Expect(widget("v1alpha1")).To(BeTrue())
+
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Gadget", "v1")).To(Succeed())
+	g.Expect(gadget("v1")).To(BeTrue())
+	g.Expect(gadget("v2")).To(BeFalse())
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Gadget", "v2")).To(Succeed())
+	g.Expect(gadget("v2")).To(BeTrue())
+	g.Expect(gadget("v3")).To(BeFalse())
+	g.Expect(RegisterServiceDiscoveryType("example.com", "Gadget")).To(Succeed())
+	g.Expect(gadget("v3")).To(BeTrue())
+
+	g.Expect(ServiceDiscoveryRequiredKinds()).To(ContainElements(
+		schema.GroupKind{Group: "example.com", Kind: "Gadget"},
+		schema.GroupKind{Group: "example.com", Kind: "Widget"}))
+}
+
+func TestDisableExcludedCollections_PinnedVersions(t *testing.T) {
+	g := NewWithT(t)
+
+	aggregated := newNamedTestSchema("k8s/core/v2beta1/services", "", "v2beta1", KindService)
+	in := collection.SchemasFor(testService, aggregated)
+	out := DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), []string{KindService}, true)
+	g.Expect(enabledNames(out)).To(Equal([]string{testService.Name().String()}))
+}
+
+func TestOutputHasNoLeadingSlash(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testNamespace, testNode, testPod, testSecret)
+	cfg := FilterConfig{ExcludedResourceKinds: []string{KindService, "core/Pod", "core/v1/Node"}}
+	var outputs []string
+
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), cfg.Options()...)
+	result, err := f.Apply(in)
+	g.Expect(err).To(BeNil())
+	for _, c := range ReportToConditions(result.Report, result.Warnings) {
+		outputs = append(outputs, c.Message)
+	}
+	for _, w := range result.Warnings {
+		outputs = append(outputs, w.String())
+	}
+	for _, e := range f.EffectiveExclusions() {
+		outputs = append(outputs, e.Pattern)
+	}
+
+	upgraded := collection.SchemasFor(testService, testNamespace, testNode, newTestSchema("", "v2", KindPod), testSecret)
+	diff, err := DiffAcrossSchemaSets(in, upgraded, FilterConfig{})
+	g.Expect(err).To(BeNil())
+	g.Expect(diff.VersionChanged).NotTo(BeEmpty())
+	outputs = append(outputs, diff.String())
+
+	for _, finding := range CheckExclusionsAgainstExisting(ExclusionConfig{ExcludedResourceKinds: cfg.ExcludedResourceKinds},
+		map[schema.GroupKind]int{{Kind: KindService}: 1}) {
+		outputs = append(outputs, finding.Message)
+	}
+
+	g.Expect(outputs).NotTo(BeEmpty())
+	for _, out := range outputs {
+		for _, field := range strings.FieldsFunc(out, func(r rune) bool { return strings.ContainsRune(" \n\t,:\"'(", r) }) {
+			g.Expect(field).NotTo(HavePrefix("/"), out)
+		}
+	}
+}
+
+// TestDisableExcludedCollections_Parity runs the legacy entry point and FilterCollections with the equivalent
+// options across exclusion lists, discovery settings and provider graphs, and expects the same decisions.
+func TestDisableExcludedCollections_Parity(t *testing.T) {
+	in := collection.SchemasFor(testService, testNamespace, testNode, testPod, testSecret, testEndpointSlice,
+		testDeployment, testConfigMap, testKubeGateway, testVirtualService, testAuthzPolicy)
+	exclusions := map[string][]string{
+		"none":       nil,
+		"defaults":   DefaultExcludedResourceKinds(),
+		"kinds":      {"Pod", "Service", "Deployment"},
+		"negation":   {"networking.istio.io/*", "!Gateway"},
+		"collection": {"collection:k8s/core/v1/services", "collection:k8s/core/v1/configmaps"},
+		"everything": {"*"},
+	}
+	graphs := map[string]struct {
+		providers kuberesourcetest.ScriptedProviders
+		required  collection.Names
+	}{
+		"identity": {kuberesourcetest.ScriptedProviders{}, in.CollectionNames()},
+		"subset":   {kuberesourcetest.ScriptedProviders{}, collection.Names{testPod.Name(), testVirtualService.Name()}},
+		"transformed": {
+			kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+				testGateway.Name():    {testKubeGateway.Name(), testService.Name()},
+				testMeshConfig.Name(): {testConfigMap.Name()},
+			}},
+			collection.Names{testGateway.Name(), testMeshConfig.Name(), testAuthzPolicy.Name()},
+		},
+	}
+	for exName, excluded := range exclusions {
+		for graphName, graph := range graphs {
+			for _, discovery := range []bool{false, true} {
+				name := fmt.Sprintf("%s/%s/discovery=%v", exName, graphName, discovery)
+				t.Run(name, func(t *testing.T) {
+					g := NewWithT(t)
+					legacy := DisableExcludedCollectionsFor(in, graph.providers, graph.required, excluded, discovery)
+					result, err := FilterCollections(in, graph.providers, graph.required,
+						WithExcludedResourceKinds(excluded...),
+						WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: discovery}))
+					g.Expect(err).To(BeNil())
+					g.Expect(legacy.Equal(result.Schemas)).To(BeTrue())
+					for _, s := range in.All() {
+						g.Expect(legacy.MustFind(s.Name().String()).IsDisabled()).
+							To(Equal(result.Schemas.MustFind(s.Name().String()).IsDisabled()), s.Name().String())
+					}
+				})
+			}
+		}
+	}
+}
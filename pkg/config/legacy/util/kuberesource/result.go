@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// SelectorHint narrows the objects an informer needs to watch for a collection.
+type SelectorHint struct {
+	LabelSelector string `json:"labelSelector,omitempty"`
+	FieldSelector string `json:"fieldSelector,omitempty"`
+}
+
+// FilterResult is the outcome of applying a CollectionFilter.
+//
+// Per-collection state other than the decision, such as selector and collection hints, lazy collections and the
+// discovery use, lives on the result, keyed by collection name, and is derived from the decision and the
+// configuration alone. Every result owns its state: ApplyDelta carries the decisions of untouched collections
+// forward, so their state carries forward with them, and recomputes it for touched collections; ApplyAll computes
+// it independently for every set.
+type FilterResult struct {
+	// Schemas is the filtered set, with excluded collections disabled. It is not serialized; the
+	// report carries the same information in a JSON friendly form.
+	Schemas collection.Schemas `json:"-"`
+
+	// Frozen is a read-only view of Schemas, set if the filter was configured WithFrozenResult. Consumers that
+	// keep the filtered set for the lifetime of the process should hold this instead of Schemas.
+	Frozen *FrozenSchemas `json:"-"`
+
+	Report      *FilterReport   `json:"report"`
+	Warnings    []FilterWarning `json:"warnings,omitempty"`
+	Stats       FilterStats     `json:"stats"`
+	Fingerprint string          `json:"fingerprint"`
+
+	// SchemaSet is the SchemaSetFingerprint of the binary that computed the result.
+	SchemaSet string `json:"schemaSet"`
+
+	// DefinitelyUnused are the input collections that no configuration would enable; see DefinitelyUnused.
+	DefinitelyUnused collection.Names `json:"definitelyUnused,omitempty"`
+
+	// LazyCollections are the enabled collections decided with ReasonLazy, in name order. The informer layer
+	// should register, but not start them.
+	LazyCollections collection.Names `json:"lazyCollections,omitempty"`
+
+	// SelectorHints are the selector hints of enabled collections, keyed by collection name.
+	SelectorHints map[collection.Name]SelectorHint `json:"selectorHints,omitempty"`
+
+	// CollectionHints are the informer hints of enabled collections, keyed by collection name.
+	CollectionHints map[collection.Name]CollectionHint `json:"collectionHints,omitempty"`
+
+	// input is the schema set the result was computed from, unless inputCompacted is set; see inputSchemas.
+	input          collection.Schemas
+	inputCompacted bool
+
+	// index is the kind index of input, or of Schemas if inputCompacted is set.
+	index *KindIndex
+
+	// availability holds the probed availability of resource types whose availability was determined, and
+	// undetermined the error of the last probe of those whose availability could not be determined.
+	availability map[config.GroupVersionKind]bool
+	undetermined map[config.GroupVersionKind]error
+
+	// filter is a snapshot of the filter that computed the result, for evaluating candidate configuration changes.
+	filter *CollectionFilter
+}
+
+// EnabledCollectionNames returns the sorted names of the enabled collections.
+func (r *FilterResult) EnabledCollectionNames() collection.Names {
+	return r.Schemas.WithoutDisabledCollections().CollectionNames()
+}
+
+// ReasonFor returns the reason for the decision made for the named collection.
+func (r *FilterResult) ReasonFor(name collection.Name) (Reason, bool) {
+	e, ok := r.Report.Entry(name)
+	return e.Reason, ok
+}
+
+// SelectorHintFor returns the selector hint for the named collection, if it is enabled and has one.
+func (r *FilterResult) SelectorHintFor(name collection.Name) (SelectorHint, bool) {
+	h, ok := r.SelectorHints[name]
+	return h, ok
+}
+
+// Index returns the kind index of the schemas the result was computed from, or nil if the result was not produced
+// by a collection filter.
+func (r *FilterResult) Index() *KindIndex {
+	if r == nil {
+		return nil
+	}
+	return r.index
+}
@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestDisabledSurvivesRebuild(t *testing.T) {
+	in := testSchemas()
+	filtered := DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		[]string{"Node", "Deployment", "EndpointSlice"}, false)
+	expected := collection.Names{testNode.Name(), testEndpointSlice.Name(), testDeployment.Name()}
+
+	rebuilds := map[string]func(collection.Schemas) collection.Schemas{
+		"BuildFrom": collection.BuildFrom,
+		"fresh builder": func(s collection.Schemas) collection.Schemas {
+			b := collection.NewSchemasBuilder()
+			for _, schema := range s.All() {
+				b.MustAdd(schema)
+			}
+			return b.Build()
+		},
+		"SchemasFor": func(s collection.Schemas) collection.Schemas {
+			return collection.SchemasFor(s.All()...)
+		},
+		"Add": func(s collection.Schemas) collection.Schemas {
+			return collection.SchemasFor().Add(s.All()...)
+		},
+		"Remove": func(s collection.Schemas) collection.Schemas {
+			return s.Remove()
+		},
+		"Intersect": func(s collection.Schemas) collection.Schemas {
+			return s.Intersect(in)
+		},
+		"refilter": func(s collection.Schemas) collection.Schemas {
+			return DisableExcludedCollectionsFor(s, kuberesourcetest.ScriptedProviders{}, s.CollectionNames(), nil, false)
+		},
+	}
+	for name, rebuild := range rebuilds {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			out := filtered
+			for i := 0; i < 3; i++ {
+				out = rebuild(out)
+				g.Expect(out.DisabledCollectionNames()).To(Equal(expected))
+				g.Expect(out.Equal(filtered)).To(BeTrue())
+			}
+		})
+	}
+}
+
+func TestApply_PreservesIdentity(t *testing.T) {
+	g := NewWithT(t)
+
+	alreadyDisabled := newTestSchema("example.com", "v1", "Widget").Disable()
+	in := collection.SchemasFor(testService, testPod, testNode, alreadyDisabled)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithExcludedResourceKinds(KindNode, "Widget"))
+	g.Expect(err).To(BeNil())
+
+	out := result.Schemas
+	g.Expect(out.MustFind(testService.Name().String())).To(BeIdenticalTo(testService))
+	g.Expect(out.MustFind(testPod.Name().String())).To(BeIdenticalTo(testPod))
+
+	node := out.MustFind(testNode.Name().String())
+	g.Expect(node).NotTo(BeIdenticalTo(testNode))
+	g.Expect(node.IsDisabled()).To(BeTrue())
+	g.Expect(testNode.IsDisabled()).To(BeFalse())
+
+	// Disabling an already disabled schema does not copy it again.
+	g.Expect(out.MustFind(alreadyDisabled.Name().String())).To(BeIdenticalTo(alreadyDisabled))
+}
+
+func TestApply_UnchangedInputReturned(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Schemas).To(Equal(in))
+	for i, s := range result.Schemas.All() {
+		g.Expect(s).To(BeIdenticalTo(in.All()[i]))
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"sync"
+
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var (
+	builtinSchemaSetOnce        sync.Once
+	builtinSchemaSetFingerprint string
+)
+
+// SchemaSetFingerprint identifies the schema set built into this binary, as a hash over the name and resource
+// type of every collection in schema.MustGet(). Two builds with different schema sets can watch different
+// collections under the same filter configuration, so it is part of the filter fingerprint.
+func SchemaSetFingerprint() string {
+	builtinSchemaSetOnce.Do(func() {
+		builtinSchemaSetFingerprint = schemaSetFingerprint(schema.MustGet().AllCollections())
+	})
+	return builtinSchemaSetFingerprint
+}
+
+// schemaSetFingerprint returns a stable hash over the collection names and resource types of schemas,
+// independent of their order.
+func schemaSetFingerprint(schemas collection.Schemas) string {
+	all := schemas.All()
+	lines := make([]string, 0, len(all))
+	for _, s := range all {
+		lines = append(lines, fmt.Sprintf("%s=%s", s.Name(), s.Resource().GroupVersionKind()))
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, l := range lines {
+		fmt.Fprintln(h, l)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)[:8])
+}
@@ -0,0 +1,42 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestSchemaSetFingerprint(t *testing.T) {
+	g := NewWithT(t)
+
+	base := schemaSetFingerprint(collection.SchemasFor(testService, testPod, testDeployment))
+	g.Expect(schemaSetFingerprint(collection.SchemasFor(testDeployment, testService, testPod))).To(Equal(base))
+	g.Expect(schemaSetFingerprint(collection.SchemasFor(testService, testPod, testDeployment, testSecret))).NotTo(Equal(base))
+	g.Expect(schemaSetFingerprint(collection.SchemasFor(testService, testPod,
+		newTestSchema("apps", "v1beta1", "Deployment")))).NotTo(Equal(base))
+
+	g.Expect(SchemaSetFingerprint()).To(Equal(schemaSetFingerprint(schema.MustGet().AllCollections())))
+
+	in := collection.SchemasFor(testService, testPod)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(result.SchemaSet).To(Equal(SchemaSetFingerprint()))
+}
@@ -0,0 +1,170 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"sort"
+
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+// SchemaSetStatsResult counts the collections and kinds of a schema set. A kind is a group and kind; a kind served
+// at several versions is counted once in the kind counts, and once per version in the version counts.
+type SchemaSetStatsResult struct {
+	Collections int
+	Kinds       int
+
+	// BuiltinKinds and CRDKinds split the kinds into builtin Kubernetes kinds and kinds defined by CRDs.
+	BuiltinKinds int
+	CRDKinds     int
+
+	// ClusterScopedKinds and NamespacedKinds split the kinds by scope.
+	ClusterScopedKinds int
+	NamespacedKinds    int
+
+	// Versions counts the collections at each version.
+	Versions map[string]int
+
+	// Groups breaks the counts down by group. The core group is keyed by the empty string.
+	Groups map[string]GroupStats
+}
+
+// GroupStats counts the collections and kinds of a single group.
+type GroupStats struct {
+	Collections int
+	Kinds       int
+
+	// Versions counts the collections of the group at each version.
+	Versions map[string]int
+}
+
+// SchemaSetStats counts the collections and kinds of schemas, for generating documentation of what istiod may
+// watch.
+func SchemaSetStats(schemas collection.Schemas) SchemaSetStatsResult {
+	c := newSchemaCounter()
+	for _, s := range schemas.All() {
+		c.add(s.Resource())
+	}
+	return c.out
+}
+
+// schemaCounter accumulates a SchemaSetStatsResult.
+type schemaCounter struct {
+	out   SchemaSetStatsResult
+	kinds map[string]struct{}
+}
+
+func newSchemaCounter() *schemaCounter {
+	return &schemaCounter{
+		out: SchemaSetStatsResult{
+			Versions: make(map[string]int),
+			Groups:   make(map[string]GroupStats),
+		},
+		kinds: make(map[string]struct{}),
+	}
+}
+
+// add counts a collection of the resource type res.
+func (c *schemaCounter) add(res resource.Schema) {
+	c.out.Collections++
+	c.out.Versions[res.Version()]++
+
+	g := c.out.Groups[res.Group()]
+	if g.Versions == nil {
+		g.Versions = make(map[string]int)
+	}
+	g.Collections++
+	g.Versions[res.Version()]++
+
+	key := TypeKey(res.Group(), res.Kind())
+	if _, ok := c.kinds[key]; !ok {
+		c.kinds[key] = struct{}{}
+		g.Kinds++
+		c.out.Kinds++
+		if isBuiltin(res) {
+			c.out.BuiltinKinds++
+		} else {
+			c.out.CRDKinds++
+		}
+		if res.IsClusterScoped() {
+			c.out.ClusterScopedKinds++
+		} else {
+			c.out.NamespacedKinds++
+		}
+	}
+	c.out.Groups[res.Group()] = g
+}
+
+// versionCount is the JSON form of a version count.
+type versionCount struct {
+	Version     string `json:"version"`
+	Collections int    `json:"collections"`
+}
+
+// MarshalJSON encodes the stats with groups and versions as lists in name order, so that the encoding of a schema
+// set is stable and reads well in a diff.
+func (r SchemaSetStatsResult) MarshalJSON() ([]byte, error) {
+	type group struct {
+		Group       string         `json:"group"`
+		Collections int            `json:"collections"`
+		Kinds       int            `json:"kinds"`
+		Versions    []versionCount `json:"versions"`
+	}
+	names := make([]string, 0, len(r.Groups))
+	for name := range r.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	groups := make([]group, 0, len(names))
+	for _, name := range names {
+		g := r.Groups[name]
+		groups = append(groups, group{
+			Group:       name,
+			Collections: g.Collections,
+			Kinds:       g.Kinds,
+			Versions:    versionCounts(g.Versions),
+		})
+	}
+	return json.Marshal(struct {
+		Collections        int            `json:"collections"`
+		Kinds              int            `json:"kinds"`
+		BuiltinKinds       int            `json:"builtinKinds"`
+		CRDKinds           int            `json:"crdKinds"`
+		ClusterScopedKinds int            `json:"clusterScopedKinds"`
+		NamespacedKinds    int            `json:"namespacedKinds"`
+		Versions           []versionCount `json:"versions"`
+		Groups             []group        `json:"groups"`
+	}{
+		Collections:        r.Collections,
+		Kinds:              r.Kinds,
+		BuiltinKinds:       r.BuiltinKinds,
+		CRDKinds:           r.CRDKinds,
+		ClusterScopedKinds: r.ClusterScopedKinds,
+		NamespacedKinds:    r.NamespacedKinds,
+		Versions:           versionCounts(r.Versions),
+		Groups:             groups,
+	})
+}
+
+func versionCounts(m map[string]int) []versionCount {
+	out := make([]versionCount, 0, len(m))
+	for v, n := range m {
+		out = append(out, versionCount{Version: v, Collections: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestSchemaSetStats(t *testing.T) {
+	g := NewWithT(t)
+
+	v1beta1 := newTestSchema("networking.istio.io", "v1beta1", "VirtualService")
+	stats := SchemaSetStats(collection.SchemasFor(testService, testNode, testVirtualService, v1beta1, testAuthzPolicy))
+	g.Expect(stats).To(Equal(SchemaSetStatsResult{
+		Collections: 5,
+		Kinds:       4,
+		// The test schemas have no proto package, so they count as CRD kinds.
+		CRDKinds:        4,
+		NamespacedKinds: 4,
+		Versions:        map[string]int{"v1": 2, "v1alpha3": 1, "v1beta1": 2},
+		Groups: map[string]GroupStats{
+			"":                    {Collections: 2, Kinds: 2, Versions: map[string]int{"v1": 2}},
+			"networking.istio.io": {Collections: 2, Kinds: 1, Versions: map[string]int{"v1alpha3": 1, "v1beta1": 1}},
+			"security.istio.io":   {Collections: 1, Kinds: 1, Versions: map[string]int{"v1beta1": 1}},
+		},
+	}))
+
+	out, err := json.Marshal(SchemaSetStats(collection.SchemasFor(testService)))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(out)).To(Equal(`{"collections":1,"kinds":1,"builtinKinds":0,"crdKinds":1,"clusterScopedKinds":0,` +
+		`"namespacedKinds":1,"versions":[{"version":"v1","collections":1}],` +
+		`"groups":[{"group":"","collections":1,"kinds":1,"versions":[{"version":"v1","collections":1}]}]}`))
+}
+
+// The stats of the builtin schema set are kept as a golden file, so that additions to the schema set show up as
+// reviewable diffs.
+func TestSchemaSetStats_Builtin(t *testing.T) {
+	g := NewWithT(t)
+
+	stats := SchemaSetStats(schema.MustGet().KubeCollections())
+	g.Expect(stats.BuiltinKinds + stats.CRDKinds).To(Equal(stats.Kinds))
+	g.Expect(stats.ClusterScopedKinds + stats.NamespacedKinds).To(Equal(stats.Kinds))
+
+	out, err := json.MarshalIndent(stats, "", "  ")
+	g.Expect(err).To(BeNil())
+	testutil.CompareContent(append(out, '\n'), "testdata/schemaset_stats.golden", t)
+}
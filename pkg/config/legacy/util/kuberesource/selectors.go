@@ -0,0 +1,188 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// SelectorError describes an invalid entry of the selectors section of a filter configuration.
+type SelectorError struct {
+	Kind   string
+	Reason string
+
+	// Line is the line of the entry in the loaded file, or 0 if it is not known.
+	Line int
+}
+
+func (e *SelectorError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: invalid selector for kind %s: %s", e.Line, e.Kind, e.Reason)
+	}
+	return fmt.Sprintf("invalid selector for kind %s: %s", e.Kind, e.Reason)
+}
+
+// alwaysSelectableFields are the fields every kind supports in field selectors.
+var alwaysSelectableFields = []string{"metadata.name", "metadata.namespace"}
+
+var (
+	selectableFieldsMu sync.RWMutex
+
+	// selectableFields maps kinds to the fields, besides alwaysSelectableFields, that their field selectors may
+	// use. Builtin kinds without an entry support alwaysSelectableFields only.
+	selectableFields = builtinSelectableFields()
+)
+
+func builtinSelectableFields() map[string][]string {
+	return map[string][]string{
+		KindNamespace: {"status.phase"},
+		KindNode:      {"spec.unschedulable"},
+		KindPod: {
+			"spec.hostNetwork", "spec.nodeName", "spec.restartPolicy", "spec.schedulerName",
+			"spec.serviceAccountName", "status.nominatedNodeName", "status.phase", "status.podIP", "status.podIPs",
+		},
+		KindSecret: {"type"},
+	}
+}
+
+// RegisterSelectableFields adds fields to those that field selector hints for kind may use, for vendor kinds
+// whose informers honor selector hints. A kind that is not builtin is only allowed in the selectors section of a
+// filter configuration once it is registered. Like selector hints, registrations are keyed by kind. Registration
+// fails with ErrRegistriesFrozen once a filter has been applied.
+func RegisterSelectableFields(kind string, fields ...string) error {
+	return register(func() {
+		selectableFieldsMu.Lock()
+		defer selectableFieldsMu.Unlock()
+		selectableFields[kind] = append(append([]string{}, selectableFields[kind]...), fields...)
+	})
+}
+
+// supportedSelectorFields returns the sorted fields field selectors for kind may use, and whether kind is known,
+// that is builtin or registered.
+func supportedSelectorFields(kind string) ([]string, bool) {
+	selectableFieldsMu.RLock()
+	extra, registered := selectableFields[kind]
+	selectableFieldsMu.RUnlock()
+	if !registered && !sets.NewString(BuiltinKinds()...).Has(kind) {
+		return nil, false
+	}
+	return sets.NewString(alwaysSelectableFields...).Insert(extra...).List(), true
+}
+
+// hintReason returns why hint is invalid for kind, or the empty string if it is valid. Label selectors are only
+// checked for syntax. Field selectors must also only use the fields kind supports, so a kind that is neither
+// builtin nor registered takes no field selector.
+func hintReason(kind string, hint SelectorHint) string {
+	if _, err := labels.Parse(hint.LabelSelector); err != nil {
+		return fmt.Sprintf("label selector %q: %v", hint.LabelSelector, err)
+	}
+	selector, err := fields.ParseSelector(hint.FieldSelector)
+	if err != nil {
+		return fmt.Sprintf("field selector %q: %v", hint.FieldSelector, err)
+	}
+	if hint.FieldSelector == "" {
+		return ""
+	}
+	supported, known := supportedSelectorFields(kind)
+	if !known {
+		return fmt.Sprintf("field selector %q: kind %s has no known selectable fields; see RegisterSelectableFields",
+			hint.FieldSelector, kind)
+	}
+	allowed := sets.NewString(supported...)
+	for _, r := range selector.Requirements() {
+		if !allowed.Has(r.Field) {
+			return fmt.Sprintf("field selector term %q: field %s is not supported for kind %s; supported fields: %s",
+				r.Field+string(r.Operator)+r.Value, r.Field, kind, strings.Join(supported, ", "))
+		}
+	}
+	return ""
+}
+
+// selectorHintErrors returns a SelectorError for every invalid selector hint of o, in kind order.
+func (o *filterOptions) selectorHintErrors() []error {
+	var errs []error
+	for _, k := range sortedKeys(o.selectorHints) {
+		if reason := hintReason(k, o.selectorHints[k]); reason != "" {
+			errs = append(errs, &SelectorError{Kind: k, Reason: reason})
+		}
+	}
+	return errs
+}
+
+// validateSelectors checks every entry of the selectors section of a filter configuration, in kind order. Besides
+// the checks made for every selector hint, entries are only allowed for builtin or registered kinds, whose
+// informers honor them, and must not be empty. lines maps kinds to the line they were configured on, if known.
+func validateSelectors(selectors map[string]SelectorHint, lines map[string]int) []error {
+	var errs []error
+	for _, k := range sortedKeys(selectors) {
+		hint := selectors[k]
+		reason := ""
+		if _, known := supportedSelectorFields(k); !known {
+			reason = "selectors are only supported for builtin kinds and kinds registered with RegisterSelectableFields"
+		} else if hint.LabelSelector == "" && hint.FieldSelector == "" {
+			reason = "no label or field selector given"
+		} else {
+			reason = hintReason(k, hint)
+		}
+		if reason != "" {
+			errs = append(errs, &SelectorError{Kind: k, Reason: reason, Line: lines[k]})
+		}
+	}
+	return errs
+}
+
+// selectorLines returns the line of every key of the mapping found at path in the YAML document data. Documents
+// that cannot be parsed, or that have no mapping at path, yield no lines.
+func selectorLines(data []byte, path ...string) map[string]int {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	node := doc.Content[0]
+	for _, key := range path {
+		node = mappingValue(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	lines := make(map[string]int)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		lines[node.Content[i].Value] = node.Content[i].Line
+	}
+	return lines
+}
+
+// mappingValue returns the value of key in the mapping node, or nil if node is not a mapping or has no such key.
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
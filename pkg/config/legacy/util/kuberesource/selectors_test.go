@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+)
+
+func TestSelectorHintErrors(t *testing.T) {
+	in := testSchemas()
+	cases := []struct {
+		name  string
+		kind  string
+		hint  SelectorHint
+		error string
+	}{
+		{
+			name: "valid",
+			kind: KindPod,
+			hint: SelectorHint{LabelSelector: "app in (foo, bar)", FieldSelector: "spec.nodeName=node-1,status.phase!=Failed"},
+		},
+		{
+			name: "metadata fields",
+			kind: KindService,
+			hint: SelectorHint{FieldSelector: "metadata.name=kubernetes"},
+		},
+		{
+			name: "label selector on a kind without selectable fields",
+			kind: "Widget",
+			hint: SelectorHint{LabelSelector: "app=foo"},
+		},
+		{
+			name: "invalid field",
+			kind: KindSecret,
+			hint: SelectorHint{FieldSelector: "type=kubernetes.io/tls,spec.nodeName=node-1"},
+			error: `invalid selector for kind Secret: field selector term "spec.nodeName=node-1": field spec.nodeName is ` +
+				`not supported for kind Secret; supported fields: metadata.name, metadata.namespace, type`,
+		},
+		{
+			name: "field selector on an unknown kind",
+			kind: "Widget",
+			hint: SelectorHint{FieldSelector: "spec.size=large"},
+			error: `invalid selector for kind Widget: field selector "spec.size=large": kind Widget has no known ` +
+				`selectable fields; see RegisterSelectableFields`,
+		},
+		{
+			name:  "malformed field selector",
+			kind:  KindPod,
+			hint:  SelectorHint{FieldSelector: "spec.nodeName"},
+			error: `invalid selector for kind Pod: field selector "spec.nodeName": `,
+		},
+		{
+			name:  "malformed label selector",
+			kind:  KindPod,
+			hint:  SelectorHint{LabelSelector: "app in (foo"},
+			error: `invalid selector for kind Pod: label selector "app in (foo": `,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+				WithSelectorHint(c.kind, c.hint))
+			if c.error == "" {
+				g.Expect(err).To(BeNil())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(c.error)))
+		})
+	}
+}
+
+func TestRegisterSelectableFields(t *testing.T) {
+	g := NewWithT(t)
+
+	AllowLateRegistration()
+	t.Cleanup(ResetRegistriesForTest)
+	g.Expect(RegisterSelectableFields("Widget", "spec.size")).To(Succeed())
+
+	in := testSchemas()
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithSelectorHint("Widget", SelectorHint{FieldSelector: "spec.size=large,metadata.name=w"}))
+	g.Expect(err).To(BeNil())
+	_, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithSelectorHint("Widget", SelectorHint{FieldSelector: "spec.color=red"}))
+	g.Expect(err).To(MatchError(ContainSubstring("supported fields: metadata.name, metadata.namespace, spec.size")))
+
+	// Registered kinds are allowed in the selectors section of a filter configuration.
+	g.Expect(validateSelectors(map[string]SelectorHint{"Widget": {FieldSelector: "spec.size=large"}}, nil)).To(BeEmpty())
+
+	ResetRegistriesForTest()
+	g.Expect(validateSelectors(map[string]SelectorHint{"Widget": {FieldSelector: "spec.size=large"}}, nil)).To(HaveLen(1))
+}
+
+// The selectable fields table only lists builtin kinds; vendor kinds are registered.
+func TestBuiltinSelectableFields(t *testing.T) {
+	g := NewWithT(t)
+
+	for kind := range builtinSelectableFields() {
+		g.Expect(BuiltinKinds()).To(ContainElement(kind))
+	}
+}
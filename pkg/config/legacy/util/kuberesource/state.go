@@ -0,0 +1,278 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// CollectionFilterState holds the current filter result for a fixed input schema set, and recomputes it
+// when the filter configuration changes at runtime.
+type CollectionFilterState struct {
+	in           collection.Schemas
+	providers    InputProviders
+	requiredCols collection.Names
+
+	mu              sync.RWMutex
+	notifyMu        sync.Mutex
+	current         *FilterResult
+	enabled         *EnabledSet
+	changed         collection.Names
+	istioConfigGVKs map[schema.GroupVersionKind]bool
+	handlers        []func(map[schema.GroupVersionKind]bool)
+	history         updateHistory
+	subscribers     []chan FilterChange
+	closed          bool
+
+	// pending is the change held back by the notification window, and stopPending stops the delivery of it.
+	pending     *FilterChange
+	stopPending func() bool
+
+	// reprobes are the undetermined resource types and their re-probe; see WithReprobeBackoff.
+	reprobes reprobeSchedule
+
+	// clock, warningSink and logger are those given by the most recent configuration that gave one, and warnings
+	// records what was logged to warningSink. A nil warningSink logs to logger.
+	clock       Clock
+	warningSink func(FilterWarning)
+	logger      Logger
+	warnings    warningLog
+
+	// opts is the most recent configuration, and referenced the kinds marked as referenced through Reference.
+	opts       []FilterOption
+	referenced []string
+}
+
+// NewCollectionFilterState applies the initial configuration to in and returns the resulting state.
+func NewCollectionFilterState(in collection.Schemas, providers InputProviders, requiredCols collection.Names,
+	opts ...FilterOption) (*CollectionFilterState, error) {
+	s := &CollectionFilterState{
+		in:           in,
+		providers:    orNoProviders(providers),
+		requiredCols: requiredCols.Clone(),
+	}
+	if _, err := s.Update(opts...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Current returns the most recent filter result.
+func (s *CollectionFilterState) Current() *FilterResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// EnabledSet returns the enabled set of the most recent filter result. The sets of successive updates share an
+// indexer, so a collection keeps its index across updates.
+func (s *CollectionFilterState) EnabledSet() *EnabledSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled
+}
+
+// Changed returns the collections whose decision or collection hint changed in the most recent update, in name
+// order. It is empty after the initial configuration has been applied.
+func (s *CollectionFilterState) Changed() collection.Names {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.changed.Clone()
+}
+
+// History returns the recorded updates, oldest first. See WithHistorySize.
+func (s *CollectionFilterState) History() []UpdateRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.history.list()
+}
+
+// StateSnapshot is the JSON representation of a CollectionFilterState served by debug endpoints.
+type StateSnapshot struct {
+	Current *FilterResult  `json:"current"`
+	History []UpdateRecord `json:"history"`
+}
+
+// Snapshot returns the current result and the update history.
+func (s *CollectionFilterState) Snapshot() StateSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return StateSnapshot{Current: s.current, History: s.history.list()}
+}
+
+// EnabledIstioConfigGVKs returns the Istio config kinds enabled by the most recent filter result.
+func (s *CollectionFilterState) EnabledIstioConfigGVKs() map[schema.GroupVersionKind]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyGVKs(s.istioConfigGVKs)
+}
+
+// OnIstioConfigGVKsChange registers fn to be called with the new set of enabled Istio config kinds whenever an
+// update changes it. Handlers are called synchronously from Update, after the new result has been published.
+func (s *CollectionFilterState) OnIstioConfigGVKsChange(fn func(gvks map[schema.GroupVersionKind]bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, fn)
+}
+
+// Update replaces the filter configuration with opts and recomputes the result. Availability determined by
+// the previous result is carried over, so only collections whose availability was undetermined are probed
+// again.
+func (s *CollectionFilterState) Update(opts ...FilterOption) (*FilterResult, error) {
+	return s.applyUpdate(opts, false, nil)
+}
+
+// applyUpdate recomputes the result with opts, or with the most recent configuration if reuseOpts is set, and
+// with referenced added to the referenced kinds.
+func (s *CollectionFilterState) applyUpdate(opts []FilterOption, reuseOpts bool, referenced []string) (*FilterResult, error) {
+	result, effects, err := s.update(opts, reuseOpts, referenced)
+	if err != nil {
+		return nil, err
+	}
+	effects.run()
+	return result, nil
+}
+
+// updateEffects are what an update reports: warnings, log lines, notifications and handler calls. They are
+// collected under the lock and run once it is released, so that a sink, logger or handler can read the state.
+type updateEffects struct {
+	// logs are the warnings and log lines, in order.
+	logs []func()
+
+	// send delivers the change to the subscribers. It is called with notifyMu held, and releases it.
+	send func()
+
+	// gvks is the new set of enabled Istio config kinds, if it changed, and handlers are called with it.
+	gvks     map[schema.GroupVersionKind]bool
+	handlers []func(map[schema.GroupVersionKind]bool)
+}
+
+func (e *updateEffects) run() {
+	// Send first, so that notifyMu is released whatever the other effects do.
+	if e.send != nil {
+		e.send()
+	}
+	for _, log := range e.logs {
+		log()
+	}
+	if e.gvks != nil {
+		for _, h := range e.handlers {
+			h(copyGVKs(e.gvks))
+		}
+	}
+}
+
+// update recomputes the result under the lock, and returns the effects to run once the lock is released.
+func (s *CollectionFilterState) update(opts []FilterOption, reuseOpts bool, referenced []string) (*FilterResult,
+	*updateEffects, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reuseOpts {
+		opts = s.opts
+	}
+	referenced = append(append([]string{}, s.referenced...), referenced...)
+	var known map[config.GroupVersionKind]bool
+	var deferred map[config.GroupVersionKind]error
+	if s.current != nil {
+		known, deferred = s.current.availability, s.reprobes.deferred(s.clock.Now())
+	}
+	f := NewCollectionFilter(s.providers, s.requiredCols, append(append([]FilterOption{}, opts...),
+		withKnownAvailability(known), withDeferredProbes(deferred), WithReferencedKinds(referenced...))...)
+	result, err := f.Apply(s.in)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.opts = append([]FilterOption{}, opts...)
+	s.referenced = referenced
+	if f.opts.clock != nil {
+		s.clock = f.opts.clock
+	} else if s.clock == nil {
+		s.clock = RealClock()
+	}
+	if f.opts.log != nil || s.logger == nil {
+		s.logger = f.opts.logger()
+	}
+	if f.opts.warningSink != nil {
+		s.warningSink = f.opts.warningSink
+	}
+	effects := &updateEffects{}
+	sink, logger := s.warningSink, s.logger
+	if sink == nil {
+		sink = func(w FilterWarning) {
+			logger.Warnf("%s", w.Message)
+		}
+	}
+	for _, w := range s.warnings.unlogged(result) {
+		w := w
+		effects.logs = append(effects.logs, func() { sink(w) })
+	}
+	if f.opts.compactDisabled {
+		// Only retain the compact form of disabled collections; they are restored if a later update enables them.
+		s.in = compactInput(s.in, result.Schemas)
+	}
+	now := s.clock.Now()
+	record := UpdateRecord{
+		Fingerprint: result.Fingerprint,
+		Timestamp:   now,
+		Sequence:    s.clock.Sequence(),
+	}
+	if delay, ok := s.reprobes.record(result, deferred, now, f.opts.reprobe); ok && !s.closed {
+		s.reprobes.stop = s.clock.AfterFunc(delay, s.reprobeNow)
+	}
+	if s.current != nil {
+		s.changed = mergeNames(s.current.Report.changedCollections(result.Report), hintChanges(s.current, result))
+		changed := joinNames(s.changed)
+		if len(s.changed) > 0 {
+			effects.logs = append(effects.logs, func() {
+				logger.Infof("collection filter: updated to configuration %s, changed %s", result.Fingerprint, changed)
+			})
+		} else {
+			effects.logs = append(effects.logs, func() {
+				logger.Debugf("collection filter: updated to configuration %s, nothing changed", result.Fingerprint)
+			})
+		}
+		record.Diff = s.current.Report.SemanticDiff(result.Report)
+		effects.send = s.notify(s.current, result, f.opts.notifyWindow)
+	}
+	s.current = result
+	var indexer *Indexer
+	if s.enabled != nil {
+		indexer = s.enabled.Indexer()
+	}
+	s.enabled = NewEnabledSet(result, indexer)
+	s.history.add(record, f.opts.historyCapacity())
+
+	gvks := EnabledIstioConfigGVKs(result.Schemas)
+	if s.istioConfigGVKs != nil && gvkSetsEqual(s.istioConfigGVKs, gvks) {
+		return result, effects, nil
+	}
+	s.istioConfigGVKs = gvks
+	effects.gvks, effects.handlers = gvks, append([]func(map[schema.GroupVersionKind]bool){}, s.handlers...)
+	return result, effects, nil
+}
+
+func copyGVKs(in map[schema.GroupVersionKind]bool) map[schema.GroupVersionKind]bool {
+	out := make(map[schema.GroupVersionKind]bool, len(in))
+	for gvk := range in {
+		out[gvk] = true
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WithStatusWriters keeps the named collections enabled although they are not upstream of the required
+// collections, because istiod writes status back to them. They are decided with ReasonKeptForStatus. Excluding a
+// status writer collection, other than through a negated entry, causes Apply to fail. Names are merged with any
+// previously set.
+func WithStatusWriters(names ...collection.Name) FilterOption {
+	return func(o *filterOptions) {
+		if o.statusWriters == nil {
+			o.statusWriters = make(map[collection.Name]struct{})
+		}
+		for _, n := range names {
+			o.statusWriters[n] = struct{}{}
+		}
+	}
+}
+
+// isStatusWriter returns true if the named collection was given to WithStatusWriters.
+func (o *filterOptions) isStatusWriter(name collection.Name) bool {
+	_, ok := o.statusWriters[name]
+	return ok
+}
+
+// statusWriterErrors returns an error for every status writer collection that is excluded. Collections missing
+// from known can only be found excluded by collection: entries.
+func (o *filterOptions) statusWriterErrors(known collection.Schemas) []error {
+	if len(o.statusWriters) == 0 {
+		return nil
+	}
+	m := compileExclusions(o.excludedResourceKinds)
+	var errs []error
+	for _, n := range sortedCollectionNames(o.statusWriters) {
+		i := -1
+		if s, ok := known.Find(n.String()); ok {
+			res := s.Resource()
+			i = m.decisive(n, res.Group(), res.Version(), res.Kind())
+		} else {
+			for j := len(m.exclusions) - 1; j >= 0 && i < 0; j-- {
+				if m.exclusions[j].Collection == n {
+					i = j
+				}
+			}
+		}
+		if i >= 0 && !m.exclusions[i].Negated {
+			errs = append(errs, fmt.Errorf("status writer collection %s is excluded by %q", n, m.exclusions[i].Entry))
+		}
+	}
+	return errs
+}
+
+func sortedCollectionNames(set map[collection.Name]struct{}) collection.Names {
+	out := make(collection.Names, 0, len(set))
+	for n := range set {
+		out = append(out, n)
+	}
+	out.Sort()
+	return out
+}
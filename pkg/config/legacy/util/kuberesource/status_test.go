@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestWithStatusWriters(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testKubeGateway, testVirtualService, testDeployment)
+	required := collection.Names{testService.Name()}
+
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, required,
+		WithStatusWriters(testKubeGateway.Name(), testVirtualService.Name()),
+		WithExcludedResourceKinds("networking.istio.io/*", "!VirtualService"),
+		WithExcludedResourceKinds("!collection:"+testKubeGateway.Name().String()))
+	g.Expect(err).To(BeNil())
+	g.Expect(result.EnabledCollectionNames()).To(ConsistOf(testService.Name(), testKubeGateway.Name(), testVirtualService.Name()))
+	g.Expect(reasonOf(result, testKubeGateway.Name())).To(Equal(ReasonKeptForStatus))
+	g.Expect(reasonOf(result, testVirtualService.Name())).To(Equal(ReasonKeptForStatus))
+	g.Expect(reasonOf(result, testDeployment.Name())).To(Equal(ReasonNotUpstream))
+	g.Expect(reasonOf(result, testService.Name())).To(Equal(ReasonEnabled))
+}
+
+func TestWithStatusWriters_Excluded(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testKubeGateway, testVirtualService)
+	required := collection.Names{testService.Name()}
+
+	// A kind exclusion is found once the schemas are known.
+	f := NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, required,
+		WithStatusWriters(testKubeGateway.Name(), testVirtualService.Name()),
+		WithExcludedResourceKinds("networking.istio.io/*", "!VirtualService"))
+	_, err := f.Apply(in)
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring(
+		`status writer collection k8s/networking.istio.io/v1alpha3/gatewaies is excluded by "networking.istio.io/*"`))
+	g.Expect(err.Error()).NotTo(ContainSubstring("virtualservices"))
+
+	// A collection exclusion is found without the schemas.
+	f = NewCollectionFilter(kuberesourcetest.ScriptedProviders{}, required,
+		WithStatusWriters(testVirtualService.Name()),
+		WithExcludedResourceKinds("collection:"+testVirtualService.Name().String()))
+	_, err = f.Apply(collection.SchemasFor())
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("status writer collection k8s/networking.istio.io/v1alpha3/virtualservices " +
+		`is excluded by "collection:k8s/networking.istio.io/v1alpha3/virtualservices"`))
+
+	g.Expect(ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{},
+		WithStatusWriters(testKubeGateway.Name()), WithExcludedResourceKinds("Gateway"))).
+		To(ConsistOf(MatchError(`status writer collection k8s/networking.istio.io/v1alpha3/gatewaies is excluded by "Gateway"`)))
+}
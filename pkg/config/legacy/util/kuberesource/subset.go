@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// SubsetCheck details which collections of a requested subset are not enabled.
+type SubsetCheck struct {
+	// Missing are collections that are not present in the schema set at all.
+	Missing collection.Names
+
+	// Disabled are collections that are present, but disabled.
+	Disabled collection.Names
+}
+
+// OK returns true if every requested collection is present and enabled.
+func (c SubsetCheck) OK() bool {
+	return len(c.Missing) == 0 && len(c.Disabled) == 0
+}
+
+// Err returns an error describing the missing and disabled collections, or nil if the check passed.
+func (c SubsetCheck) Err() error {
+	if c.OK() {
+		return nil
+	}
+	var parts []string
+	if len(c.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("missing collections: %v", c.Missing))
+	}
+	if len(c.Disabled) > 0 {
+		parts = append(parts, fmt.Sprintf("disabled collections: %v", c.Disabled))
+	}
+	return fmt.Errorf("required collections are not enabled: %s", strings.Join(parts, "; "))
+}
+
+// CheckSubsetEnabled checks that every named collection is present and enabled in schemas.
+func CheckSubsetEnabled(schemas collection.Schemas, names collection.Names) SubsetCheck {
+	var c SubsetCheck
+	for _, n := range names {
+		s, ok := schemas.Find(n.String())
+		switch {
+		case !ok:
+			c.Missing = append(c.Missing, n)
+		case s.IsDisabled():
+			c.Disabled = append(c.Disabled, n)
+		}
+	}
+	c.Missing.Sort()
+	c.Disabled.Sort()
+	return c
+}
+
+// IsSubsetEnabled returns whether every named collection is present and enabled in schemas, along with the
+// sorted names of those that are missing or disabled. Use CheckSubsetEnabled to tell the two apart.
+func IsSubsetEnabled(schemas collection.Schemas, names collection.Names) (bool, collection.Names) {
+	c := CheckSubsetEnabled(schemas, names)
+	notEnabled := append(c.Missing.Clone(), c.Disabled...)
+	notEnabled.Sort()
+	return c.OK(), notEnabled
+}
+
+// CheckFeaturesEnabled returns an error unless every input collection needed by features is enabled in the
+// filtered schemas. It is intended for readiness gating.
+func CheckFeaturesEnabled(schemas collection.Schemas, providers InputProviders, features FeatureSet) error {
+	required, err := RequiredCollectionsFor(features)
+	if err != nil {
+		return err
+	}
+	inputs := make(collection.Names, 0)
+	for n := range orNoProviders(providers).RequiredInputsFor(required) {
+		inputs = append(inputs, n)
+	}
+	return CheckSubsetEnabled(schemas, inputs).Err()
+}
@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/processor/transforms"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func TestIsSubsetEnabled(t *testing.T) {
+	schemas := collection.SchemasFor(testService, testPod.Disable(), testNode)
+	unknown := collection.NewName("k8s/example.com/v1/widgets")
+
+	cases := []struct {
+		name       string
+		names      collection.Names
+		ok         bool
+		notEnabled collection.Names
+		check      SubsetCheck
+	}{
+		{
+			name:       "all enabled",
+			names:      collection.Names{testService.Name(), testNode.Name()},
+			ok:         true,
+			notEnabled: collection.Names{},
+		},
+		{
+			name:       "disabled",
+			names:      collection.Names{testService.Name(), testPod.Name()},
+			notEnabled: collection.Names{testPod.Name()},
+			check:      SubsetCheck{Disabled: collection.Names{testPod.Name()}},
+		},
+		{
+			name:       "missing",
+			names:      collection.Names{unknown, testPod.Name(), testNode.Name()},
+			notEnabled: collection.Names{testPod.Name(), unknown},
+			check:      SubsetCheck{Missing: collection.Names{unknown}, Disabled: collection.Names{testPod.Name()}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ok, notEnabled := IsSubsetEnabled(schemas, c.names)
+			g.Expect(ok).To(Equal(c.ok))
+			g.Expect(notEnabled).To(Equal(c.notEnabled))
+
+			check := CheckSubsetEnabled(schemas, c.names)
+			g.Expect(check.OK()).To(Equal(c.ok))
+			g.Expect(check.Missing).To(ConsistOf(c.check.Missing))
+			g.Expect(check.Disabled).To(ConsistOf(c.check.Disabled))
+			if c.ok {
+				g.Expect(check.Err()).To(BeNil())
+			} else {
+				g.Expect(check.Err()).NotTo(BeNil())
+			}
+		})
+	}
+}
+
+func TestCheckFeaturesEnabled(t *testing.T) {
+	g := NewWithT(t)
+
+	m := schema.MustGet()
+	providers := transforms.Providers(m)
+	required, err := RequiredCollectionsFor(FeatureSet{FeatureTelemetry, FeatureMulticluster})
+	g.Expect(err).To(BeNil())
+
+	result, err := FilterCollections(m.KubeCollections(), providers, required)
+	g.Expect(err).To(BeNil())
+	g.Expect(CheckFeaturesEnabled(result.Schemas, providers, FeatureSet{FeatureTelemetry})).To(Succeed())
+
+	result, err = FilterCollections(m.KubeCollections(), providers, required, WithExcludedResourceKinds("Telemetry"))
+	g.Expect(err).To(BeNil())
+	g.Expect(CheckFeaturesEnabled(result.Schemas, providers, FeatureSet{FeatureTelemetry})).To(
+		MatchError(ContainSubstring("disabled collections: [" + collections.K8STelemetryIstioIoV1Alpha1Telemetries.Name().String())))
+}
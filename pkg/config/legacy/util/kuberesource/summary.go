@@ -0,0 +1,112 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// summaryWarnings is the number of warnings StartupSummary lists.
+const summaryWarnings = 3
+
+// StartupSummary renders result as a compact block for the startup log: the fingerprints, the enabled and
+// disabled collections by group, the excluded collections re-enabled for service discovery, the collections
+// watched only for service discovery, the first warnings and the sources the exclusion entries were configured
+// in, in precedence order.
+func StartupSummary(result *FilterResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Collection filter %s (schema set %s)\n", result.Fingerprint, result.SchemaSet)
+	fmt.Fprintf(&sb, "  Collections: %d total, %d enabled, %d disabled\n",
+		result.Stats.Total, result.Stats.Enabled, result.Stats.Disabled)
+
+	type counts struct{ enabled, disabled int }
+	byGroup := make(map[string]*counts)
+	var groups []string
+	var overrides []string
+	for _, e := range result.Report.Entries {
+		c, ok := byGroup[e.Group]
+		if !ok {
+			c = &counts{}
+			byGroup[e.Group] = c
+			groups = append(groups, e.Group)
+		}
+		if e.Disabled {
+			c.disabled++
+		} else {
+			c.enabled++
+		}
+		if e.Reason == ReasonRequiredForServiceDiscovery {
+			overrides = append(overrides, e.Collection.String())
+		}
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		name := g
+		if name == "" {
+			name = coreGroup
+		}
+		fmt.Fprintf(&sb, "    %s: %d enabled, %d disabled\n", name, byGroup[g].enabled, byGroup[g].disabled)
+	}
+
+	sort.Strings(overrides)
+	fmt.Fprintf(&sb, "  Discovery overrides: %s\n", listOrNone(overrides))
+	var discoveryOnly []string
+	for _, n := range result.Report.DiscoveryOnly() {
+		discoveryOnly = append(discoveryOnly, n.String())
+	}
+	fmt.Fprintf(&sb, "  Watched only for discovery: %s\n", listOrNone(discoveryOnly))
+
+	fmt.Fprintf(&sb, "  Warnings: %d\n", len(result.Warnings))
+	for i, w := range result.Warnings {
+		if i == summaryWarnings {
+			fmt.Fprintf(&sb, "    ... and %d more\n", len(result.Warnings)-summaryWarnings)
+			break
+		}
+		fmt.Fprintf(&sb, "    %s\n", w)
+	}
+
+	fmt.Fprintf(&sb, "  Config sources: %s\n", listOrNone(result.configSources()))
+	return sb.String()
+}
+
+// configSources returns the sources of the exclusion entries of the filter that computed r, in precedence order.
+func (r *FilterResult) configSources() []string {
+	if r.filter == nil {
+		return nil
+	}
+	seen := make(map[ExclusionSource]struct{})
+	var sources []ExclusionSource
+	for _, s := range r.filter.opts.exclusionSources {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			sources = append(sources, s)
+		}
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].precedence() < sources[j].precedence() })
+	out := make([]string, 0, len(sources))
+	for _, s := range sources {
+		out = append(out, string(s))
+	}
+	return out
+}
+
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,87 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestStartupSummary(t *testing.T) {
+	kube := schema.MustGet().KubeCollections()
+	providers := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		testGateway.Name(): {testKubeGateway.Name()},
+	}}
+	representative := collection.SchemasFor(testService, testNamespace, testPod, testSecret, testDeployment,
+		testConfigMap, testKubeGateway, testGateway, testVirtualService, testAuthzPolicy)
+
+	cases := []struct {
+		name      string
+		in        collection.Schemas
+		providers InputProviders
+		opts      []FilterOption
+		golden    string
+	}{
+		{
+			name:      "default",
+			in:        kube,
+			providers: kuberesourcetest.ScriptedProviders{},
+			opts: []FilterOption{
+				WithExclusionsFrom(SourceDefault, DefaultExcludedResourceKinds()...),
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+			},
+			golden: "testdata/summary_default.golden",
+		},
+		{
+			name:      "representative",
+			in:        representative,
+			providers: providers,
+			opts: []FilterOption{
+				WithExclusionsFrom(SourceDefault, DefaultExcludedResourceKinds()...),
+				WithExclusionsFrom(SourceFlag, "networking.istio.io/*", "!networking.istio.io/Gateway"),
+				WithExclusionsFrom(SourceMeshConfig, "apps/Deployment", "collection:"+testGateway.Name().String(),
+					"security.istio.io/Widget", "MeshConfig"),
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+				WithAvailabilityProbe(func(gvk config.GroupVersionKind) (bool, error) {
+					if gvk.Group == "" {
+						return true, nil
+					}
+					return false, errTransient
+				}),
+			},
+			golden: "testdata/summary_representative.golden",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			result, err := FilterCollections(c.in, c.providers, c.in.CollectionNames(), c.opts...)
+			g.Expect(err).To(BeNil())
+
+			// The fingerprints vary with the builtin schema set, so they are not part of the golden output.
+			summary := strings.Replace(StartupSummary(result), result.Fingerprint, "<fingerprint>", 1)
+			summary = strings.Replace(summary, result.SchemaSet, "<schema set>", 1)
+			testutil.CompareContent([]byte(summary), c.golden, t)
+		})
+	}
+}
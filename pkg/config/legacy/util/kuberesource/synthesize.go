@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ExclusionConfig is an exclusion configuration computed by SynthesizeExclusions.
+type ExclusionConfig struct {
+	ExcludedResourceKinds []string            `json:"excludedResourceKinds,omitempty"`
+	Features              FeatureRequirements `json:"features"`
+
+	// KubeVersionGates are the Kubernetes versions exclusion entries apply to; see FilterConfig.
+	KubeVersionGates map[string]KubeVersionRange `json:"kubeVersionGates,omitempty"`
+}
+
+// FilterConfig returns the FilterConfig that applies c. Every input collection is required, so that only the
+// exclusions and features decide which collections are enabled.
+func (c ExclusionConfig) FilterConfig() FilterConfig {
+	return FilterConfig{
+		ExcludedResourceKinds: append([]string{}, c.ExcludedResourceKinds...),
+		Features:              c.Features,
+		KubeVersionGates:      copyKubeVersionGates(c.KubeVersionGates),
+	}
+}
+
+// Conflict is a collection for which the desired state cannot be reached through exclusions.
+type Conflict struct {
+	Collection collection.Name `json:"collection"`
+
+	// Desired is true if the collection was desired to be enabled.
+	Desired bool `json:"desired"`
+
+	// Reason is the reason for the decision the filter makes instead.
+	Reason Reason `json:"reason"`
+}
+
+func (c Conflict) String() string {
+	if c.Desired {
+		return fmt.Sprintf("%s: desired enabled, but disabled (%s)", c.Collection, c.Reason)
+	}
+	return fmt.Sprintf("%s: desired disabled, but enabled (%s)", c.Collection, c.Reason)
+}
+
+// SynthesizeExclusions computes the smallest exclusion list that, applied to schemas with service discovery
+// enabled, leaves exactly the desiredEnabled collections enabled. A whole group is excluded with a single
+// group/* entry if none of its collections is desired; otherwise kinds are excluded with group/Kind entries,
+// narrowed to group/version/Kind or collection:name only where a desired collection would also match.
+//
+// Collections that no exclusion list can bring to their desired state, such as kinds required for service
+// discovery or collections not upstream of any provider output, are returned as conflicts, sorted by name.
+func SynthesizeExclusions(schemas collection.Schemas, desiredEnabled collection.Names,
+	providers InputProviders) (ExclusionConfig, []Conflict, error) {
+	desired := make(map[collection.Name]struct{}, len(desiredEnabled))
+	for _, n := range desiredEnabled {
+		if _, ok := schemas.Find(n.String()); !ok {
+			return ExclusionConfig{}, nil, fmt.Errorf("desired collection %s is not in the schema set", n)
+		}
+		desired[n] = struct{}{}
+	}
+
+	config := ExclusionConfig{Features: FeatureRequirements{ServiceDiscovery: true}}
+	baseline, err := config.FilterConfig().Apply(schemas, providers)
+	if err != nil {
+		return ExclusionConfig{}, nil, err
+	}
+
+	// Only collections that would otherwise be enabled, and that an exclusion can disable, need an entry.
+	var toExclude []collection.Schema
+	for _, s := range schemas.All() {
+		_, want := desired[s.Name()]
+		if want || baseline.Schemas.MustFind(s.Name().String()).IsDisabled() || config.Features.IsRequired(s.Resource()) {
+			continue
+		}
+		toExclude = append(toExclude, s)
+	}
+
+	// Desired collections that are enabled whether or not they are excluded, or that are disabled regardless,
+	// do not constrain the entries.
+	constraining := make(map[collection.Name]struct{}, len(desiredEnabled))
+	for _, n := range desiredEnabled {
+		s := schemas.MustFind(n.String())
+		if baseline.Schemas.MustFind(n.String()).IsDisabled() || config.Features.IsRequired(s.Resource()) {
+			continue
+		}
+		constraining[n] = struct{}{}
+	}
+	matchesDesired := func(entry string) bool {
+		m := compileExclusions([]string{entry})
+		for _, s := range baseline.Index().candidates(m.exclusions[0]) {
+			if _, ok := constraining[s.Name()]; ok && m.MatchesSchema(s) {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := make(map[string]struct{})
+	for _, s := range toExclude {
+		entry := synthesizedEntry(s, matchesDesired)
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		config.ExcludedResourceKinds = append(config.ExcludedResourceKinds, entry)
+	}
+	sort.Strings(config.ExcludedResourceKinds)
+
+	result, err := config.FilterConfig().Apply(schemas, providers)
+	if err != nil {
+		return ExclusionConfig{}, nil, err
+	}
+	var conflicts []Conflict
+	for _, e := range result.Report.Entries {
+		_, want := desired[e.Collection]
+		if want == !e.Disabled {
+			continue
+		}
+		c := Conflict{Collection: e.Collection, Desired: want, Reason: e.Reason}
+		if reason, ok := config.Features.requiredReason(schemas.MustFind(e.Collection.String()).Resource()); ok && !want {
+			// No entry is emitted for kinds the features keep enabled, so report why an entry would not help.
+			c.Reason = reason
+		}
+		conflicts = append(conflicts, c)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Collection < conflicts[j].Collection
+	})
+	return config, conflicts, nil
+}
+
+// synthesizedEntry returns the broadest entry that matches s without matching a desired collection.
+func synthesizedEntry(s collection.Schema, matchesDesired func(string) bool) string {
+	res := s.Resource()
+	group := res.Group()
+	if group == "" {
+		group = coreGroup
+	}
+	for _, entry := range []string{
+		group + "/" + anySegment,
+		group + "/" + res.Kind(),
+		group + "/" + res.Version() + "/" + res.Kind(),
+	} {
+		if !matchesDesired(entry) {
+			return entry
+		}
+	}
+	return collectionPrefix + s.Name().String()
+}
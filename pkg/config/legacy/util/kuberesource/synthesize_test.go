@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/processor/transforms"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestSynthesizeExclusions(t *testing.T) {
+	g := NewWithT(t)
+
+	deploymentV1beta1 := newNamedTestSchema("k8s/apps/v1beta1/deployments", "apps", "v1beta1", KindDeployment)
+	in := collection.SchemasFor(testService, testPod, testSecret, testDeployment, deploymentV1beta1,
+		testVirtualService, testAuthzPolicy)
+	desired := collection.Names{testPod.Name(), testDeployment.Name()}
+
+	config, conflicts, err := SynthesizeExclusions(in, desired, kuberesourcetest.ScriptedProviders{})
+	g.Expect(err).To(BeNil())
+	g.Expect(config).To(Equal(ExclusionConfig{
+		ExcludedResourceKinds: []string{"apps/v1beta1/Deployment", "networking.istio.io/*", "security.istio.io/*"},
+		Features:              FeatureRequirements{ServiceDiscovery: true},
+	}))
+	g.Expect(conflicts).To(Equal([]Conflict{
+		{Collection: testSecret.Name(), Reason: ReasonRequiredForServiceDiscovery},
+		{Collection: testService.Name(), Reason: ReasonRequiredForServiceDiscovery},
+	}))
+	g.Expect(conflicts[0].String()).To(Equal("k8s/core/v1/secrets: desired disabled, but enabled (RequiredForServiceDiscovery)"))
+
+	_, _, err = SynthesizeExclusions(in, collection.Names{"k8s/core/v1/bogus"}, kuberesourcetest.ScriptedProviders{})
+	g.Expect(err).To(MatchError("desired collection k8s/core/v1/bogus is not in the schema set"))
+}
+
+// Filtering with the synthesized configuration enables exactly the desired collections, except for the reported
+// conflicts.
+func TestSynthesizeExclusions_Randomized(t *testing.T) {
+	m := schema.MustGet()
+	in := m.KubeCollections()
+	providers := transforms.Providers(m)
+	all := in.CollectionNames()
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		var desired collection.Names
+		for _, n := range all {
+			if rng.Intn(3) == 0 {
+				desired = append(desired, n)
+			}
+		}
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			g := NewWithT(t)
+			config, conflicts, err := SynthesizeExclusions(in, desired, providers)
+			g.Expect(err).To(BeNil())
+
+			result, err := config.FilterConfig().Apply(in, providers)
+			g.Expect(err).To(BeNil())
+
+			conflicting := make(map[collection.Name]Conflict)
+			for _, c := range conflicts {
+				conflicting[c.Collection] = c
+			}
+			want := make(map[collection.Name]bool)
+			for _, n := range desired {
+				want[n] = true
+			}
+			for _, e := range result.Report.Entries {
+				c, ok := conflicting[e.Collection]
+				if !ok {
+					g.Expect(!e.Disabled).To(Equal(want[e.Collection]), e.Collection.String())
+					continue
+				}
+				g.Expect(!e.Disabled).NotTo(Equal(want[e.Collection]), e.Collection.String())
+				if c.Desired {
+					g.Expect(c.Reason).To(Equal(e.Reason))
+				} else {
+					g.Expect(c.Reason).To(Equal(ReasonRequiredForServiceDiscovery))
+				}
+				// Exclusions never disable a desired collection.
+				g.Expect(e.Reason).NotTo(Equal(ReasonExcludedKind))
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// TableRows selects the collections RenderTable lists.
+type TableRows int
+
+const (
+	// TableAll lists every collection.
+	TableAll TableRows = iota
+	// TableEnabled lists the enabled collections only.
+	TableEnabled
+	// TableDisabled lists the disabled collections only.
+	TableDisabled
+)
+
+// TableOptions configure RenderTable.
+type TableOptions struct {
+	Rows TableRows
+
+	// GroupByAPIGroup lists the collections under a heading per API group, in group order, instead of in a GROUP
+	// column.
+	GroupByAPIGroup bool
+
+	// MaxReasonWidth truncates longer reasons to that many characters, ending with "...". Zero does not truncate.
+	MaxReasonWidth int
+}
+
+// RenderTable renders the report of result as an aligned table with the columns GROUP, KIND, VERSION, STATE,
+// REASON and SOURCE, for the watched-resources command of istioctl. Collections are listed by group and kind. The
+// reason lists the secondary reasons of a disabled collection after its primary reason, and the source is where
+// the exclusion entry that decided was configured, or "-" if none did.
+func RenderTable(result *FilterResult, opts TableOptions) string {
+	var entries []ReportEntry
+	for _, e := range result.Report.Entries {
+		if (opts.Rows == TableEnabled && e.Disabled) || (opts.Rows == TableDisabled && !e.Disabled) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Group != entries[j].Group {
+			return entries[i].Group < entries[j].Group
+		}
+		return entries[i].Kind < entries[j].Kind
+	})
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 8, 2, ' ', 0)
+	if !opts.GroupByAPIGroup {
+		fmt.Fprintln(w, "GROUP\tKIND\tVERSION\tSTATE\tREASON\tSOURCE")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\n", groupName(e.Group), result.tableRow(e, opts))
+		}
+		_ = w.Flush()
+		return sb.String()
+	}
+
+	// The group headings are inserted after the rows are aligned, since a line without cells would end the
+	// column block of the writer and align each group on its own.
+	fmt.Fprintln(w, "KIND\tVERSION\tSTATE\tREASON\tSOURCE")
+	for _, e := range entries {
+		fmt.Fprintf(w, "  %s\n", result.tableRow(e, opts))
+	}
+	_ = w.Flush()
+	lines := strings.SplitAfter(sb.String(), "\n")
+	var out strings.Builder
+	out.WriteString(lines[0])
+	for i, e := range entries {
+		if i == 0 || e.Group != entries[i-1].Group {
+			fmt.Fprintf(&out, "%s:\n", groupName(e.Group))
+		}
+		out.WriteString(lines[i+1])
+	}
+	return out.String()
+}
+
+// tableRow returns the KIND to SOURCE cells of e, separated by tabs.
+func (r *FilterResult) tableRow(e ReportEntry, opts TableOptions) string {
+	state := "Enabled"
+	if e.Disabled {
+		state = "Disabled"
+	}
+	reason := string(e.Reason)
+	if len(e.SecondaryReasons) > 0 {
+		also := make([]string, 0, len(e.SecondaryReasons))
+		for _, s := range e.SecondaryReasons {
+			also = append(also, string(s))
+		}
+		reason += " (also " + strings.Join(also, ", ") + ")"
+	}
+	source := string(r.entrySource(e))
+	if source == "" {
+		source = "-"
+	}
+	return strings.Join([]string{e.Kind, e.Version, state, truncate(reason, opts.MaxReasonWidth), source}, "\t")
+}
+
+// entrySource returns the source of the exclusion entry that decided e, or "" if none did. If the entry was
+// configured in several sources, the one of highest precedence is returned, since it matches last.
+func (r *FilterResult) entrySource(e ReportEntry) ExclusionSource {
+	if e.MatchedEntry == "" || r.filter == nil {
+		return ""
+	}
+	exclusions := r.filter.exclusions.exclusions
+	for i := len(exclusions) - 1; i >= 0; i-- {
+		if exclusions[i].Entry == e.MatchedEntry && exclusions[i].Source != "" {
+			return exclusions[i].Source
+		}
+	}
+	return ""
+}
+
+func groupName(group string) string {
+	if group == "" {
+		return coreGroup
+	}
+	return group
+}
+
+// truncate returns s cut to width characters, ending with "...", if it is longer. A width of zero does not
+// truncate.
+func truncate(s string, width int) string {
+	if width <= 0 || len(s) <= width {
+		return s
+	}
+	if width <= len("...") {
+		return s[:width]
+	}
+	return s[:width-len("...")] + "..."
+}
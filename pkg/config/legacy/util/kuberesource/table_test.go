@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var tableRowsNames = map[TableRows]string{TableAll: "all", TableEnabled: "enabled", TableDisabled: "disabled"}
+
+// renderTables renders result with every combination of table options, each under a heading.
+func renderTables(result *FilterResult) string {
+	var sb strings.Builder
+	for _, rows := range []TableRows{TableAll, TableEnabled, TableDisabled} {
+		for _, grouped := range []bool{false, true} {
+			for _, width := range []int{0, 20} {
+				opts := TableOptions{Rows: rows, GroupByAPIGroup: grouped, MaxReasonWidth: width}
+				fmt.Fprintf(&sb, "=== rows=%s grouped=%t maxReasonWidth=%d\n", tableRowsNames[rows], grouped, width)
+				sb.WriteString(RenderTable(result, opts))
+			}
+		}
+	}
+	return sb.String()
+}
+
+func TestRenderTable(t *testing.T) {
+	cases := []struct {
+		name     string
+		in       collection.Schemas
+		required collection.Names
+		opts     []FilterOption
+		golden   string
+	}{
+		{
+			name: "representative",
+			in: collection.SchemasFor(testService, testNamespace, testPod, testSecret, testDeployment, testConfigMap,
+				testGateway, testVirtualService, testAuthzPolicy),
+			// AuthorizationPolicy is excluded and not required, so it has a secondary reason.
+			required: collection.Names{testService.Name(), testNamespace.Name(), testPod.Name(), testSecret.Name(),
+				testDeployment.Name(), testConfigMap.Name(), testGateway.Name(), testVirtualService.Name()},
+			opts: []FilterOption{
+				WithExclusionsFrom(SourceDefault, DefaultExcludedResourceKinds()...),
+				WithExclusionsFrom(SourceFlag, "networking.istio.io/*", "!networking.istio.io/Gateway"),
+				WithExclusionsFrom(SourceMeshConfig, "apps/Deployment", "security.istio.io/*"),
+				WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+			},
+			golden: "testdata/table_representative.golden",
+		},
+		{
+			name:     "no disabled collections",
+			in:       collection.SchemasFor(testService, testPod, testVirtualService),
+			required: collection.Names{testService.Name(), testPod.Name(), testVirtualService.Name()},
+			golden:   "testdata/table_no_disabled.golden",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			result, err := FilterCollections(c.in, kuberesourcetest.ScriptedProviders{}, c.required, c.opts...)
+			g.Expect(err).To(BeNil())
+			testutil.CompareContent([]byte(renderTables(result)), c.golden, t)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(truncate("ExcludedByUser", 0)).To(Equal("ExcludedByUser"))
+	g.Expect(truncate("ExcludedByUser", 14)).To(Equal("ExcludedByUser"))
+	g.Expect(truncate("ExcludedByUser", 10)).To(Equal("Exclude..."))
+	g.Expect(truncate("ExcludedByUser", 2)).To(Equal("Ex"))
+}
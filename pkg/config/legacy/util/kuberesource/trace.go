@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"strconv"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Trace attribute keys. TraceAttributes uses the kuberesource.* keys, CollectionAttributes the
+// kuberesource.collection.* keys.
+const (
+	AttributeEnabled            = "kuberesource.enabled"
+	AttributeDisabled           = "kuberesource.disabled"
+	AttributeLazy               = "kuberesource.lazy"
+	AttributeWarnings           = "kuberesource.warnings"
+	AttributeFingerprint        = "kuberesource.fingerprint"
+	AttributeDiscoveryOverrides = "kuberesource.discovery_overrides"
+	AttributeBudget             = "kuberesource.budget"
+
+	AttributeCollection        = "kuberesource.collection"
+	AttributeCollectionGroup   = "kuberesource.collection.group"
+	AttributeCollectionKind    = "kuberesource.collection.kind"
+	AttributeCollectionReason  = "kuberesource.collection.reason"
+	AttributeCollectionEnabled = "kuberesource.collection.enabled"
+	AttributeCollectionLazy    = "kuberesource.collection.lazy"
+)
+
+// Values of AttributeBudget.
+const (
+	BudgetUnset    = "Unset"
+	BudgetWithin   = "Within"
+	BudgetExceeded = "Exceeded"
+)
+
+// maxAttributeValueLen bounds the length of attribute values, so that a span stays small whatever the schema set.
+const maxAttributeValueLen = 128
+
+// TraceAttributes returns flat attributes summarizing result, for the span that traces istiod startup. The keys are
+// the same for every result, so the number of attributes does not grow with the number of collections; the
+// decisions for single collections are attached to their own spans, see CollectionAttributes. The map is not tied
+// to any tracing SDK.
+func TraceAttributes(result *FilterResult) map[string]string {
+	st := result.Stats
+	budget := BudgetUnset
+	if result.filter != nil && result.filter.opts.enabledBudget != nil {
+		budget = BudgetWithin
+		if result.filter.checkBudget(result.Schemas) != nil {
+			budget = BudgetExceeded
+		}
+	}
+	return boundedAttributes(map[string]string{
+		AttributeEnabled:            strconv.Itoa(st.Enabled),
+		AttributeDisabled:           strconv.Itoa(st.Disabled),
+		AttributeLazy:               strconv.Itoa(len(result.LazyCollections)),
+		AttributeWarnings:           strconv.Itoa(len(result.Warnings)),
+		AttributeFingerprint:        result.Fingerprint,
+		AttributeDiscoveryOverrides: strconv.Itoa(st.ByReason[ReasonRequiredForServiceDiscovery]),
+		AttributeBudget:             budget,
+	})
+}
+
+// CollectionAttributes returns flat attributes describing the decision made for the named collection, for the span
+// that traces the sync of its informer. It returns nil if the collection was not passed through the filter.
+func (r *FilterResult) CollectionAttributes(name collection.Name) map[string]string {
+	e, ok := r.Report.Entry(name)
+	if !ok {
+		return nil
+	}
+	return boundedAttributes(map[string]string{
+		AttributeCollection:        name.String(),
+		AttributeCollectionGroup:   e.Group,
+		AttributeCollectionKind:    e.Kind,
+		AttributeCollectionReason:  string(e.Reason),
+		AttributeCollectionEnabled: strconv.FormatBool(!e.Disabled),
+		AttributeCollectionLazy:    strconv.FormatBool(r.IsLazy(name)),
+	})
+}
+
+// boundedAttributes truncates the values of attrs to maxAttributeValueLen bytes, in place, and returns attrs.
+func boundedAttributes(attrs map[string]string) map[string]string {
+	for k, v := range attrs {
+		if len(v) > maxAttributeValueLen {
+			attrs[k] = v[:maxAttributeValueLen]
+		}
+	}
+	return attrs
+}
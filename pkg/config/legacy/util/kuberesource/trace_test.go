@@ -0,0 +1,109 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestTraceAttributes(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testDeployment, testVirtualService, testAuthzPolicy)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindService, "AuthorizationPolicy"),
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithLazyKinds(KindDeployment), WithEnabledBudget(0))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(TraceAttributes(result)).To(Equal(map[string]string{
+		AttributeEnabled:            "4",
+		AttributeDisabled:           "1",
+		AttributeLazy:               "1",
+		AttributeWarnings:           "1",
+		AttributeFingerprint:        result.Fingerprint,
+		AttributeDiscoveryOverrides: "1",
+		AttributeBudget:             BudgetExceeded,
+	}))
+
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(TraceAttributes(result)).To(HaveKeyWithValue(AttributeBudget, BudgetUnset))
+
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), WithEnabledBudget(5))
+	g.Expect(err).To(BeNil())
+	g.Expect(TraceAttributes(result)).To(HaveKeyWithValue(AttributeBudget, BudgetWithin))
+}
+
+func TestTraceAttributes_Bounded(t *testing.T) {
+	g := NewWithT(t)
+
+	// The number of attributes does not depend on the number of collections.
+	schemas := make([]collection.Schema, 0, 200)
+	for i := 0; i < 200; i++ {
+		schemas = append(schemas, newTestSchema(fmt.Sprintf("group%d.example.com", i), "v1", "Widget"))
+	}
+	in := collection.SchemasFor(schemas...)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(TraceAttributes(result)).To(HaveLen(7))
+	g.Expect(TraceAttributes(result)).To(HaveKeyWithValue(AttributeEnabled, "200"))
+
+	// Values are truncated.
+	long := newTestSchema(strings.Repeat("a", 200)+".example.com", "v1", "Widget")
+	in = collection.SchemasFor(long)
+	result, err = FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	attrs := result.CollectionAttributes(long.Name())
+	g.Expect(attrs).To(HaveLen(6))
+	for k, v := range attrs {
+		g.Expect(len(v)).To(BeNumerically("<=", maxAttributeValueLen), k)
+	}
+	g.Expect(attrs[AttributeCollectionGroup]).To(Equal(strings.Repeat("a", maxAttributeValueLen)))
+}
+
+func TestCollectionAttributes(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindNode), WithLazyKinds(KindDeployment))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(result.CollectionAttributes(testNode.Name())).To(Equal(map[string]string{
+		AttributeCollection:        testNode.Name().String(),
+		AttributeCollectionGroup:   "",
+		AttributeCollectionKind:    KindNode,
+		AttributeCollectionReason:  string(ReasonExcludedKind),
+		AttributeCollectionEnabled: "false",
+		AttributeCollectionLazy:    "false",
+	}))
+	g.Expect(result.CollectionAttributes(testDeployment.Name())).To(Equal(map[string]string{
+		AttributeCollection:        testDeployment.Name().String(),
+		AttributeCollectionGroup:   "apps",
+		AttributeCollectionKind:    KindDeployment,
+		AttributeCollectionReason:  string(ReasonLazy),
+		AttributeCollectionEnabled: "true",
+		AttributeCollectionLazy:    "true",
+	}))
+	g.Expect(result.CollectionAttributes(testVirtualService.Name())).To(BeNil())
+}
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TypeKey returns the key identifying a group/kind across versions, as used by the kind registries and the
+// availability and RBAC helpers: group/Kind, or just Kind for the core group. Keys have no version component.
+func TypeKey(group, kind string) string {
+	if group == "" {
+		return kind
+	}
+	return group + "/" + kind
+}
+
+// ParseTypeKey parses a key produced by TypeKey. The core group may also be written as "core", so Service and
+// core/Service both parse to the core group and kind Service. Keys with an empty group or kind, or with more than
+// one slash, are rejected.
+func ParseTypeKey(key string) (group, kind string, err error) {
+	parts := strings.Split(key, "/")
+	switch {
+	case len(parts) > 2:
+		return "", "", fmt.Errorf("invalid type key %q: expected Kind or group/Kind, without a version", key)
+	case len(parts) == 2 && parts[0] == "":
+		return "", "", fmt.Errorf("invalid type key %q: empty group; use %q or omit the group for the core group",
+			key, coreGroup)
+	case parts[len(parts)-1] == "":
+		return "", "", fmt.Errorf("invalid type key %q: empty kind", key)
+	case len(parts) == 2:
+		return normalizeGroup(parts[0]), parts[1], nil
+	default:
+		return "", parts[0], nil
+	}
+}
+
+// normalizeGroup maps the "core" alias of the core group to the empty group.
+func normalizeGroup(group string) string {
+	if group == coreGroup {
+		return ""
+	}
+	return group
+}
+
+// DisplayGroupKind renders a group and kind for user-facing output, as group/Kind. The core group is rendered as
+// "core", so that core kinds read as core/Service rather than as a bare or slash-prefixed kind. ParseGroupKind
+// accepts the result back.
+func DisplayGroupKind(group, kind string) string {
+	if group == "" {
+		group = coreGroup
+	}
+	return group + "/" + kind
+}
+
+// ParseGroupKind parses a group and kind as rendered by DisplayGroupKind, ignoring surrounding whitespace. It
+// accepts the same syntax as ParseTypeKey.
+func ParseGroupKind(s string) (schema.GroupKind, error) {
+	group, kind, err := ParseTypeKey(strings.TrimSpace(s))
+	if err != nil {
+		return schema.GroupKind{}, err
+	}
+	return schema.GroupKind{Group: group, Kind: kind}, nil
+}
@@ -0,0 +1,74 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestTypeKey(t *testing.T) {
+	g := NewWithT(t)
+
+	cases := []struct {
+		group, kind string
+		key         string
+	}{
+		{"", KindService, "Service"},
+		{"networking.istio.io", "Gateway", "networking.istio.io/Gateway"},
+		{"discovery.k8s.io", "EndpointSlice", "discovery.k8s.io/EndpointSlice"},
+	}
+	for _, c := range cases {
+		g.Expect(TypeKey(c.group, c.kind)).To(Equal(c.key))
+		group, kind, err := ParseTypeKey(c.key)
+		g.Expect(err).To(BeNil())
+		g.Expect([]string{group, kind}).To(Equal([]string{c.group, c.kind}))
+	}
+
+	// The core group may be spelled out.
+	group, kind, err := ParseTypeKey("core/Service")
+	g.Expect(err).To(BeNil())
+	g.Expect(TypeKey(group, kind)).To(Equal("Service"))
+
+	for _, key := range []string{"", "/Service", "core/", "networking.istio.io/v1alpha3/Gateway", "a/b/"} {
+		_, _, err := ParseTypeKey(key)
+		g.Expect(err).NotTo(BeNil(), key)
+	}
+	_, _, err = ParseTypeKey("networking.istio.io/v1alpha3/Gateway")
+	g.Expect(err).To(MatchError(`invalid type key "networking.istio.io/v1alpha3/Gateway": expected Kind or group/Kind, without a version`))
+}
+
+func TestDisplayGroupKind(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(DisplayGroupKind("", KindService)).To(Equal("core/Service"))
+	g.Expect(DisplayGroupKind("networking.istio.io", "Gateway")).To(Equal("networking.istio.io/Gateway"))
+
+	for _, gk := range []schema.GroupKind{{Kind: KindService}, {Group: "networking.istio.io", Kind: "Gateway"}} {
+		parsed, err := ParseGroupKind(DisplayGroupKind(gk.Group, gk.Kind))
+		g.Expect(err).To(BeNil())
+		g.Expect(parsed).To(Equal(gk))
+	}
+	parsed, err := ParseGroupKind(KindService)
+	g.Expect(err).To(BeNil())
+	g.Expect(parsed).To(Equal(schema.GroupKind{Kind: KindService}))
+
+	for _, s := range []string{"", "/Service", "core/", "a/v1/Service"} {
+		_, err := ParseGroupKind(s)
+		g.Expect(err).NotTo(BeNil(), s)
+	}
+}
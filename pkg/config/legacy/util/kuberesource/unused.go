@@ -0,0 +1,59 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// allFeatures enables every feature, so that a kind required by any of them is kept.
+var allFeatures = FeatureRequirements{ServiceDiscovery: true, AmbientEnabled: true, InjectionEnabled: true}
+
+// DefinitelyUnused returns the collections in schemas that are disabled by every rule at once, whatever the user
+// configuration: they are excluded by default, not required by any feature, and not an input of any provider
+// output. Such collections are candidates for removal from the default schema set. The result is sorted by name.
+func DefinitelyUnused(schemas collection.Schemas, providers InputProviders) collection.Names {
+	providers = orNoProviders(providers)
+	consumed := feeds(append(schemas.CollectionNames(), providers.SynthesizedOutputs()...), providers)
+	out := make(collection.Names, 0)
+	for _, s := range schemas.All() {
+		res := s.Resource()
+		if _, ok := consumed[s.Name()]; ok || !IsDefaultExcluded(res) || allFeatures.IsRequired(res) {
+			continue
+		}
+		out = append(out, s.Name())
+	}
+	out.Sort()
+	return out
+}
+
+// feeds maps every collection some provider reads to the outputs it produces from it, among outputs. Outputs that
+// no provider produces are reported by RequiredInputsFor as their own input, which does not make them fed.
+func feeds(outputs collection.Names, providers InputProviders) map[collection.Name]map[collection.Name]struct{} {
+	fed := make(map[collection.Name]map[collection.Name]struct{})
+	for _, out := range outputs {
+		inputs := providers.RequiredInputsFor(collection.Names{out})
+		if _, self := inputs[out]; self && len(inputs) == 1 {
+			continue
+		}
+		for in := range inputs {
+			if fed[in] == nil {
+				fed[in] = make(map[collection.Name]struct{})
+			}
+			fed[in][out] = struct{}{}
+		}
+	}
+	return fed
+}
@@ -0,0 +1,55 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestDefinitelyUnused(t *testing.T) {
+	g := NewWithT(t)
+
+	// Service discovery only watches Node at v1, and ambient does not watch Node, so these versions are required
+	// by no feature.
+	legacyNode := newTestSchema("", "v1beta1", "Node")
+	alphaNode := newTestSchema("", "v1alpha1", "Node")
+	nodeStatus := newNamedTestSchema("istio/node/v1alpha1/status", "", "v1alpha1", "NodeStatus")
+
+	in := collection.SchemasFor(testService, testNode, testSecret, testConfigMap, legacyNode, alphaNode, nodeStatus)
+	providers := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		nodeStatus.Name(): {alphaNode.Name(), testNode.Name()},
+	}}
+
+	// The legacy Node is default-excluded, not required by any feature and consumed by no provider. The alpha Node
+	// is only kept because a provider reads it, and ConfigMap is not default-excluded.
+	g.Expect(DefinitelyUnused(in, providers)).To(Equal(collection.Names{legacyNode.Name()}))
+	g.Expect(DefinitelyUnused(in, nil)).To(Equal(collection.Names{alphaNode.Name(), legacyNode.Name()}))
+
+	// The result is independent of the configuration.
+	for _, opts := range [][]FilterOption{
+		nil,
+		{WithExcludedResourceKinds("!Node")},
+		{WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true, AmbientEnabled: true})},
+	} {
+		result, err := FilterCollections(in, providers, collection.Names{nodeStatus.Name()}, opts...)
+		g.Expect(err).To(BeNil())
+		g.Expect(result.DefinitelyUnused).To(Equal(collection.Names{legacyNode.Name()}))
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/legacy/processing/transformer"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// VersionChange describes a group/kind that is watched before and after an upgrade, but at different versions.
+type VersionChange struct {
+	Group       string   `json:"group"`
+	Kind        string   `json:"kind"`
+	OldVersions []string `json:"oldVersions"`
+	NewVersions []string `json:"newVersions"`
+}
+
+// UpgradeDiff describes how the set of watched collections changes between two schema sets under the same
+// filter configuration.
+type UpgradeDiff struct {
+	// NewlyWatched are collections enabled in the new set only.
+	NewlyWatched collection.Names `json:"newlyWatched,omitempty"`
+
+	// NoLongerWatched are collections enabled in the old set only.
+	NoLongerWatched collection.Names `json:"noLongerWatched,omitempty"`
+
+	// VersionChanged are kinds watched in both sets, at different versions. Their collections are not
+	// repeated in NewlyWatched or NoLongerWatched.
+	VersionChanged []VersionChange `json:"versionChanged,omitempty"`
+
+	// Added are the collections enabled in the new set that are not enabled in the old set, including the new
+	// versions of kinds in VersionChanged. With a baseline of the enabled collections of the old set,
+	// WithDenyNewCollections disables them.
+	Added collection.Names `json:"added,omitempty"`
+
+	// OldSchemaSet and NewSchemaSet identify the two schema sets; see SchemaSetFingerprint.
+	OldSchemaSet string `json:"oldSchemaSet"`
+	NewSchemaSet string `json:"newSchemaSet"`
+}
+
+// DiffAcrossSchemaSets applies cfg to both schema sets and reports how the enabled collections differ.
+func DiffAcrossSchemaSets(oldSchemas, newSchemas collection.Schemas, cfg FilterConfig) (UpgradeDiff, error) {
+	oldResult, err := cfg.Apply(oldSchemas, transformer.Providers{})
+	if err != nil {
+		return UpgradeDiff{}, err
+	}
+	newResult, err := cfg.Apply(newSchemas, transformer.Providers{})
+	if err != nil {
+		return UpgradeDiff{}, err
+	}
+
+	oldEnabled := enabledByGroupKind(oldResult.Schemas)
+	newEnabled := enabledByGroupKind(newResult.Schemas)
+
+	diff := UpgradeDiff{
+		OldSchemaSet: schemaSetFingerprint(oldSchemas),
+		NewSchemaSet: schemaSetFingerprint(newSchemas),
+	}
+	for gk, newCols := range newEnabled {
+		oldCols, ok := oldEnabled[gk]
+		if !ok {
+			diff.NewlyWatched = append(diff.NewlyWatched, names(newCols)...)
+			continue
+		}
+		if oldVersions, newVersions := versions(oldCols), versions(newCols); !stringsEqual(oldVersions, newVersions) {
+			diff.VersionChanged = append(diff.VersionChanged, VersionChange{
+				Group:       gk.group,
+				Kind:        gk.kind,
+				OldVersions: oldVersions,
+				NewVersions: newVersions,
+			})
+		}
+	}
+	for gk, oldCols := range oldEnabled {
+		if _, ok := newEnabled[gk]; !ok {
+			diff.NoLongerWatched = append(diff.NoLongerWatched, names(oldCols)...)
+		}
+	}
+	for _, s := range newResult.Schemas.All() {
+		if old, ok := oldResult.Schemas.Find(s.Name().String()); !s.IsDisabled() && (!ok || old.IsDisabled()) {
+			diff.Added = append(diff.Added, s.Name())
+		}
+	}
+
+	diff.NewlyWatched.Sort()
+	diff.NoLongerWatched.Sort()
+	diff.Added.Sort()
+	sort.Slice(diff.VersionChanged, func(i, j int) bool {
+		return TypeKey(diff.VersionChanged[i].Group, diff.VersionChanged[i].Kind) <
+			TypeKey(diff.VersionChanged[j].Group, diff.VersionChanged[j].Kind)
+	})
+	return diff, nil
+}
+
+// UpdatedBaseline returns baseline with the collections the diff adds, sorted and without duplicates: the
+// baseline to give to WithDenyNewCollections, and to persist, once they have been reviewed.
+func (d UpgradeDiff) UpdatedBaseline(baseline []collection.Name) collection.Names {
+	set := make(map[collection.Name]struct{}, len(baseline)+len(d.Added))
+	for _, n := range resolveAliases(baseline) {
+		set[n] = struct{}{}
+	}
+	for _, n := range d.Added {
+		set[n] = struct{}{}
+	}
+	return sortedCollectionNames(set)
+}
+
+// String renders the diff for display, for example by the upgrade precheck.
+func (d UpgradeDiff) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Schema set: %s -> %s\n", d.OldSchemaSet, d.NewSchemaSet)
+	if len(d.NewlyWatched) == 0 && len(d.NoLongerWatched) == 0 && len(d.VersionChanged) == 0 {
+		sb.WriteString("No changes to watched collections.\n")
+		return sb.String()
+	}
+	if len(d.NewlyWatched) > 0 {
+		sb.WriteString("Newly watched collections:\n")
+		for _, n := range d.NewlyWatched {
+			fmt.Fprintf(&sb, "  + %s\n", n)
+		}
+	}
+	if len(d.NoLongerWatched) > 0 {
+		sb.WriteString("No longer watched collections:\n")
+		for _, n := range d.NoLongerWatched {
+			fmt.Fprintf(&sb, "  - %s\n", n)
+		}
+	}
+	if len(d.VersionChanged) > 0 {
+		sb.WriteString("Version changes:\n")
+		for _, c := range d.VersionChanged {
+			fmt.Fprintf(&sb, "  ~ %s: %s -> %s\n", DisplayGroupKind(c.Group, c.Kind),
+				strings.Join(c.OldVersions, ","), strings.Join(c.NewVersions, ","))
+		}
+	}
+	return sb.String()
+}
+
+type groupKind struct {
+	group string
+	kind  string
+}
+
+func enabledByGroupKind(s collection.Schemas) map[groupKind][]collection.Schema {
+	result := make(map[groupKind][]collection.Schema)
+	for _, schema := range s.All() {
+		if schema.IsDisabled() {
+			continue
+		}
+		gk := groupKind{group: schema.Resource().Group(), kind: schema.Resource().Kind()}
+		result[gk] = append(result[gk], schema)
+	}
+	return result
+}
+
+func names(schemas []collection.Schema) collection.Names {
+	result := make(collection.Names, 0, len(schemas))
+	for _, s := range schemas {
+		result = append(result, s.Name())
+	}
+	return result
+}
+
+func versions(schemas []collection.Schema) []string {
+	result := make([]string, 0, len(schemas))
+	for _, s := range schemas {
+		result = append(result, s.Resource().Version())
+	}
+	sort.Strings(result)
+	return result
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestDiffAcrossSchemaSets(t *testing.T) {
+	oldSchemas := collection.SchemasFor(
+		testService, testPod, testDeployment,
+		newTestSchema("networking.istio.io", "v1alpha3", "VirtualService"),
+		newTestSchema("rbac.istio.io", "v1alpha1", "ServiceRole"),
+		newTestSchema("security.istio.io", "v1beta1", "PeerAuthentication"),
+	)
+	newSchemas := collection.SchemasFor(
+		testService, testPod, testDeployment,
+		newTestSchema("networking.istio.io", "v1beta1", "VirtualService"),
+		newTestSchema("telemetry.istio.io", "v1alpha1", "Telemetry"),
+		newTestSchema("security.istio.io", "v1beta1", "PeerAuthentication"),
+	)
+	cfg := FilterConfig{ExcludedResourceKinds: []string{KindPod}}
+
+	cases := []struct {
+		name   string
+		old    collection.Schemas
+		new    collection.Schemas
+		golden string
+	}{
+		{"upgrade", oldSchemas, newSchemas, "testdata/upgrade_diff.golden"},
+		{"no change", oldSchemas, oldSchemas, "testdata/upgrade_diff_none.golden"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			diff, err := DiffAcrossSchemaSets(c.old, c.new, cfg)
+			g.Expect(err).To(BeNil())
+
+			js, err := json.MarshalIndent(diff, "", "  ")
+			g.Expect(err).To(BeNil())
+			testutil.CompareContent([]byte(diff.String()+"\n"+string(js)+"\n"), c.golden, t)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// ValidateFilterConfig checks the filter configuration in opts without filtering, and returns every problem
+// found rather than stopping at the first. It reports the same errors that make Apply fail, with corrections
+// suggested from schemas, followed by the parts of the configuration that cannot have any effect on schemas:
+// exclusion entries that match no collection or only collections synthesized by providers, selector hints for
+// kinds that are not in schemas, collection hints for collections that are not in schemas, and groups given to
+// WithOnlyGroups that are not in schemas. An entry whose kind exists in schemas under another group is reported
+// with that group suggested. Exclusion and negated entries from different sources that match the same collections
+// are reported whatever the conflict policy, naming the entry that wins. Default exclusions of kinds that service
+// discovery requires, but that the discovery override would not re-enable, are reported too.
+func ValidateFilterConfig(schemas collection.Schemas, providers InputProviders, opts ...FilterOption) []error {
+	o := newFilterOptions(opts)
+	errs := o.configErrors(schemas)
+
+	synthesized := make(map[collection.Name]struct{})
+	for _, n := range orNoProviders(providers).SynthesizedOutputs() {
+		synthesized[n] = struct{}{}
+	}
+	idx := NewKindIndex(schemas)
+	for i, entry := range o.excludedResourceKinds {
+		e, reason := parseExclusion(entry)
+		if reason != "" {
+			continue
+		}
+		m := NewExclusionMatcher([]Exclusion{e})
+		matched, kubeMatch := false, false
+		for _, s := range idx.candidates(e) {
+			// A negated entry never excludes, so match it regardless of negation.
+			if res := s.Resource(); m.decisive(s.Name(), res.Group(), res.Version(), res.Kind()) >= 0 {
+				matched = true
+				if _, ok := synthesized[s.Name()]; !ok {
+					kubeMatch = true
+				}
+			}
+		}
+		switch {
+		case !matched:
+			errs = append(errs, &ExclusionError{Index: i, Entry: entry, Reason: idx.unmatchedReason(e),
+				Position: o.positions.entryPosition(entry)})
+		case !kubeMatch:
+			errs = append(errs, &ExclusionError{Index: i, Entry: entry,
+				Reason:   "only matches synthesized collections, which are not read from Kubernetes",
+				Position: o.positions.entryPosition(entry)})
+		}
+	}
+
+	errs = append(errs, conflictErrors(o.entryConflicts(idx))...)
+	errs = append(errs, o.defaultExclusionErrors(schemas)...)
+
+	kinds := make(map[string]struct{})
+	groups := make(map[string]struct{})
+	for _, s := range schemas.All() {
+		kinds[s.Resource().Kind()] = struct{}{}
+		groups[s.Resource().Group()] = struct{}{}
+	}
+	for _, k := range sortedKeys(o.selectorHints) {
+		if _, ok := kinds[k]; !ok {
+			errs = append(errs, fmt.Errorf("selector hint for kind %s matches no collection", k))
+		}
+	}
+	for _, n := range o.collectionHintNames() {
+		if _, ok := schemas.Find(n.String()); !ok {
+			errs = append(errs, fmt.Errorf("collection hint for %s matches no collection", n))
+		}
+	}
+	var onlyGroups []string
+	for g := range o.onlyGroups {
+		onlyGroups = append(onlyGroups, g)
+	}
+	sort.Strings(onlyGroups)
+	for _, g := range onlyGroups {
+		if _, ok := groups[g]; !ok {
+			errs = append(errs, fmt.Errorf("group %q given to WithOnlyGroups matches no collection", g))
+		}
+	}
+	return errs
+}
+
+func sortedKeys(m map[string]SelectorHint) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// MissingInputError describes an input collection, needed to produce a required collection, that has no schema.
+type MissingInputError struct {
+	Input    collection.Name
+	Required collection.Name
+}
+
+func (e *MissingInputError) Error() string {
+	if e.Input == e.Required {
+		return fmt.Sprintf("required collection %s has no schema and is not produced by any provider", e.Required)
+	}
+	return fmt.Sprintf("input %s of required collection %s has no schema", e.Input, e.Required)
+}
+
+// ValidateProviderInputs cross-references the inputs providers need to produce requiredCols against schemas, and
+// returns a MissingInputError for every input without a schema, sorted by required collection and input. Such
+// an input, typically a renamed collection or a missing vendor schema, leaves its required collection empty.
+func ValidateProviderInputs(schemas collection.Schemas, providers InputProviders, requiredCols collection.Names) []error {
+	providers = orNoProviders(providers)
+	required := requiredCols.Clone()
+	required.Sort()
+	var errs []error
+	for i, r := range required {
+		if i > 0 && required[i-1] == r {
+			continue
+		}
+		var missing collection.Names
+		for in := range providers.RequiredInputsFor(collection.Names{r}) {
+			if _, ok := schemas.Find(in.String()); !ok {
+				missing = append(missing, in)
+			}
+		}
+		missing.Sort()
+		for _, in := range missing {
+			errs = append(errs, &MissingInputError{Input: in, Required: r})
+		}
+	}
+	return errs
+}
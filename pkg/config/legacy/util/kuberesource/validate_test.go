@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestValidateFilterConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testPod, testDeployment)
+	opts := []FilterOption{
+		WithExcludedResourceKinds("Pod", "/Service", "k8s/core/v1/pods", "Widget"),
+		WithDiscoveryOverrideOnly(KindService, KindDeployment),
+		WithSelectorHint("Gadget", SelectorHint{}),
+		WithCollectionHint(testDeployment.Name(), CollectionHint{PageSize: -1}),
+		WithCollectionHint("k8s/example.com/v1/gadgets", CollectionHint{PriorityClass: "high"}),
+		WithOnlyGroups("apps", "example.com"),
+	}
+
+	errs := ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{}, opts...)
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	g.Expect(messages).To(Equal([]string{
+		`invalid exclusion entry 1 "/Service": empty segment; use "core" for the core group`,
+		`invalid exclusion entry 2 "k8s/core/v1/pods": looks like a collection name; expected Kind, group/Kind, ` +
+			`group/version/Kind or collection:name; did you mean collection:k8s/core/v1/pods?`,
+		"collection hint for k8s/apps/v1/deployments: negative page size -1",
+		"discovery override kinds are not required for service discovery: Deployment",
+		`invalid exclusion entry 3 "Widget": matches no collection`,
+		"selector hint for kind Gadget matches no collection",
+		"collection hint for k8s/example.com/v1/gadgets matches no collection",
+		`group "example.com" given to WithOnlyGroups matches no collection`,
+	}))
+
+	// Apply fails on the same configuration errors, without the schema-dependent ones.
+	_, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), opts...)
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(HavePrefix("4 errors occurred"))
+
+	g.Expect(ValidateFilterConfig(in, kuberesourcetest.ScriptedProviders{}, WithExcludedResourceKinds("Pod"))).To(BeEmpty())
+}
+
+func TestValidateProviderInputs(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testConfigMap, testKubeGateway, testService)
+	providers := kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		testGateway.Name():    {testKubeGateway.Name(), "k8s/networking.istio.io/v1alpha3/renamedgateways"},
+		testMeshConfig.Name(): {testConfigMap.Name()},
+	}}
+	required := collection.Names{testMeshConfig.Name(), testGateway.Name(), testService.Name(), "istio/vendor/v1/widgets"}
+
+	errs := ValidateProviderInputs(in, providers, required)
+	g.Expect(errs).To(Equal([]error{
+		&MissingInputError{Input: "k8s/networking.istio.io/v1alpha3/renamedgateways", Required: testGateway.Name()},
+		&MissingInputError{Input: "istio/vendor/v1/widgets", Required: "istio/vendor/v1/widgets"},
+	}))
+	g.Expect(errs[1].Error()).To(Equal("required collection istio/vendor/v1/widgets has no schema and is not produced by any provider"))
+	g.Expect(errs[0].Error()).To(Equal("input k8s/networking.istio.io/v1alpha3/renamedgateways of required collection " +
+		"istio/networking/v1alpha3/gateways has no schema"))
+
+	g.Expect(ValidateProviderInputs(in, providers, collection.Names{testMeshConfig.Name(), testService.Name()})).To(BeEmpty())
+}
@@ -0,0 +1,148 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WarningCode identifies the class of a FilterWarning.
+type WarningCode string
+
+const (
+	// WarningSynthesizedCollection is reported when an exclusion entry only matches collections
+	// that are produced by transformers and never read from Kubernetes.
+	WarningSynthesizedCollection WarningCode = "SynthesizedCollection"
+
+	// WarningUndetermined is reported when the availability of a collection could not be determined.
+	WarningUndetermined WarningCode = "Undetermined"
+
+	// WarningDiscoveryImpact is reported when an exclusion disables a kind required for service discovery
+	// because the kind is not listed in WithDiscoveryOverrideOnly.
+	WarningDiscoveryImpact WarningCode = "DiscoveryImpact"
+
+	// WarningIneffectiveHint is reported for a collection hint whose collection is disabled or unknown.
+	WarningIneffectiveHint WarningCode = "IneffectiveHint"
+
+	// WarningBudgetExceeded is reported when more CRD-backed collections are enabled than WithEnabledBudget allows.
+	WarningBudgetExceeded WarningCode = "BudgetExceeded"
+
+	// WarningDeprecatedAlias is reported when a required collection or a collection: exclusion entry uses a
+	// deprecated collection name; see CollectionAliases.
+	WarningDeprecatedAlias WarningCode = "DeprecatedAlias"
+
+	// WarningConflictingEntries is reported when an exclusion entry and a negated entry from different sources
+	// match the same collections; see WithConflictPolicy.
+	WarningConflictingEntries WarningCode = "ConflictingEntries"
+
+	// WarningNamespaceDisabled is reported, with SeverityError, when an exclusion disables the core Namespace
+	// collection although service discovery is enabled; see NamespaceDependents.
+	WarningNamespaceDisabled WarningCode = "NamespaceDisabled"
+
+	// WarningDeprecatedEndpointsMode is reported when service discovery protects core Endpoints only; see
+	// EndpointsOnly.
+	WarningDeprecatedEndpointsMode WarningCode = "DeprecatedEndpointsMode"
+
+	// WarningMovedKind is reported, for information, when an exclusion entry matches a collection only because
+	// the kind moved to or from the entry's group or version; see MovedKinds.
+	WarningMovedKind WarningCode = "MovedKind"
+
+	// WarningKubeVersionGate is reported, for information, when an exclusion entry is ignored because the
+	// Kubernetes version is outside the range it is gated to, or when the version cannot be parsed; see
+	// WithEntryKubeVersions.
+	WarningKubeVersionGate WarningCode = "KubeVersionGate"
+
+	// WarningNotInBaseline is reported once, listing the collections disabled because they are not in the
+	// baseline given to WithDenyNewCollections.
+	WarningNotInBaseline WarningCode = "NotInBaseline"
+)
+
+// isDecisionWarning returns true for warnings raised while deciding a single collection, as opposed to those
+// raised while validating the configuration as a whole.
+func isDecisionWarning(c WarningCode) bool {
+	return c == WarningUndetermined || c == WarningDiscoveryImpact
+}
+
+// FilterWarning describes a non-fatal problem found while filtering collections.
+type FilterWarning struct {
+	Code WarningCode `json:"code"`
+
+	// Severity is SeverityError for warnings about a configuration that breaks features, and empty otherwise.
+	Severity Severity `json:"severity,omitempty"`
+
+	// Entry is the exclusion entry the warning relates to, if any.
+	Entry string `json:"entry,omitempty"`
+
+	// Collection is the collection the warning relates to, if any.
+	Collection collection.Name `json:"collection,omitempty"`
+
+	// Position is the position of Entry in the file it was loaded from, as file.yaml:42:7, if it is known. The
+	// message starts with it too.
+	Position string `json:"position,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (w FilterWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Code, w.Message)
+}
+
+// ValidateExclusions checks the excluded resource kinds against the input schemas and the transformer
+// graph, returning warnings for entries that cannot have any effect. An entry that only matches
+// collections synthesized by transformers (outputs that are never inputs) is reported, since those
+// collections are not read from Kubernetes.
+func ValidateExclusions(in collection.Schemas, providers InputProviders, excludedResourceKinds []string) []FilterWarning {
+	return validateExclusions(NewKindIndex(in), providers, excludedResourceKinds)
+}
+
+// validateExclusions is ValidateExclusions over the collections of idx.
+func validateExclusions(idx *KindIndex, providers InputProviders, excludedResourceKinds []string) []FilterWarning {
+	synthesized := make(map[collection.Name]struct{})
+	for _, n := range orNoProviders(providers).SynthesizedOutputs() {
+		synthesized[n] = struct{}{}
+	}
+
+	var warnings []FilterWarning
+	for _, entry := range excludedResourceKinds {
+		m := compileExclusions([]string{entry})
+		var synthMatches []collection.Schema
+		kubeMatch := false
+		for _, s := range idx.candidates(m.exclusions[0]) {
+			if !m.MatchesSchema(s) {
+				continue
+			}
+			if _, ok := synthesized[s.Name()]; ok {
+				synthMatches = append(synthMatches, s)
+			} else {
+				kubeMatch = true
+			}
+		}
+		if kubeMatch {
+			continue
+		}
+		for _, s := range synthMatches {
+			warnings = append(warnings, FilterWarning{
+				Code:       WarningSynthesizedCollection,
+				Entry:      entry,
+				Collection: s.Name(),
+				Message: fmt.Sprintf("entry %s matches synthesized collection %s which is not read from Kubernetes; "+
+					"excluding it has no effect", entry, s.Name()),
+			})
+		}
+	}
+	return warnings
+}
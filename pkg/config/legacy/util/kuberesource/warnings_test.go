@@ -0,0 +1,76 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var (
+	testConfigMap   = newTestSchema("", "v1", "ConfigMap")
+	testKubeGateway = newTestSchema("networking.istio.io", "v1alpha3", "Gateway")
+	testGateway     = newNamedTestSchema("istio/networking/v1alpha3/gateways", "networking.istio.io", "v1alpha3", "Gateway")
+	testMeshConfig  = newNamedTestSchema("istio/mesh/v1alpha1/MeshConfig", "", "v1alpha1", "MeshConfig")
+)
+
+// testProviderGraph maps Kubernetes gateways and config maps into synthesized Istio collections.
+func testProviderGraph() kuberesourcetest.ScriptedProviders {
+	return kuberesourcetest.ScriptedProviders{Inputs: map[collection.Name]collection.Names{
+		testGateway.Name():    {testKubeGateway.Name()},
+		testMeshConfig.Name(): {testConfigMap.Name()},
+	}}
+}
+
+func TestValidateExclusions_SynthesizedCollection(t *testing.T) {
+	in := collection.SchemasFor(testConfigMap, testKubeGateway, testGateway, testMeshConfig, testService)
+	providers := testProviderGraph()
+
+	cases := []struct {
+		name     string
+		excluded []string
+		expected []FilterWarning
+	}{
+		{
+			name:     "kube input",
+			excluded: []string{"Service", "ConfigMap"},
+		},
+		{
+			name:     "kube input sharing kind with output",
+			excluded: []string{"Gateway"},
+		},
+		{
+			name:     "synthesized only",
+			excluded: []string{"Service", "MeshConfig"},
+			expected: []FilterWarning{{
+				Code:       WarningSynthesizedCollection,
+				Entry:      "MeshConfig",
+				Collection: testMeshConfig.Name(),
+				Message: "entry MeshConfig matches synthesized collection istio/mesh/v1alpha1/MeshConfig which is not " +
+					"read from Kubernetes; excluding it has no effect",
+			}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ValidateExclusions(in, providers, c.excluded)).To(Equal(c.expected))
+		})
+	}
+}
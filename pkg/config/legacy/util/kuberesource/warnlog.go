@@ -0,0 +1,57 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+// WithWarningSink sets where a CollectionFilterState logs the warnings of its results. Each distinct warning is
+// logged once per configuration fingerprint, so that stable warnings are not logged again on every update; all
+// of them are logged again when the fingerprint changes. The sink given with the initial configuration is kept
+// by later updates that give none. The default logs to the logger of the state; see WithLogger. The sink is called
+// synchronously from Update once the state is unlocked, so it may read the state, for example with ActiveWarnings.
+// It has no effect on a single filter pass.
+func WithWarningSink(sink func(FilterWarning)) FilterOption {
+	return func(o *filterOptions) {
+		o.warningSink = sink
+	}
+}
+
+// warningLog records the warnings logged for the configuration fingerprint of the current result.
+type warningLog struct {
+	fingerprint string
+	logged      map[FilterWarning]struct{}
+}
+
+// unlogged returns the warnings of result that have not been logged for its fingerprint yet, and records them as
+// logged.
+func (l *warningLog) unlogged(result *FilterResult) []FilterWarning {
+	if l.logged == nil || l.fingerprint != result.Fingerprint {
+		l.fingerprint, l.logged = result.Fingerprint, make(map[FilterWarning]struct{})
+	}
+	var out []FilterWarning
+	for _, w := range result.Warnings {
+		if _, ok := l.logged[w]; !ok {
+			l.logged[w] = struct{}{}
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// ActiveWarnings returns the warnings of the most recent filter result, whether or not they were logged by the
+// most recent update.
+func (s *CollectionFilterState) ActiveWarnings() []FilterWarning {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]FilterWarning{}, s.current.Warnings...)
+}
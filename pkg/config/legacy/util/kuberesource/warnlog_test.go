@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+)
+
+func TestCollectionFilterState_WarningDedup(t *testing.T) {
+	g := NewWithT(t)
+
+	var logged []FilterWarning
+	sink := func(w FilterWarning) {
+		logged = append(logged, w)
+	}
+	in := testSchemas()
+	opts := []FilterOption{
+		WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+		WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: EndpointsOnly}),
+		WithDiscoveryOverrideOnly(KindService, KindEndpoints),
+		WithExcludedResourceKinds(KindNode),
+	}
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		append(opts, WithWarningSink(sink))...)
+	g.Expect(err).To(BeNil())
+	warnings := state.ActiveWarnings()
+	g.Expect(warnings).To(HaveLen(2))
+	g.Expect(logged).To(Equal(warnings))
+
+	// The same configuration again logs nothing, and the sink given initially is kept.
+	_, err = state.Update(opts...)
+	g.Expect(err).To(BeNil())
+	g.Expect(logged).To(HaveLen(2))
+	g.Expect(state.ActiveWarnings()).To(Equal(warnings))
+
+	// A changed configuration logs every warning again, including those it shares with the previous one.
+	changed := append(append([]FilterOption{}, opts...), WithExcludedResourceKinds(KindDeployment))
+	_, err = state.Update(changed...)
+	g.Expect(err).To(BeNil())
+	g.Expect(state.ActiveWarnings()).To(ContainElements(warnings))
+	g.Expect(logged[2:]).To(Equal(state.ActiveWarnings()))
+}
+
+func TestCollectionFilterState_CallbacksReadState(t *testing.T) {
+	g := NewWithT(t)
+
+	in := testSchemas()
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	changes := state.Notify()
+
+	// The sink and the logger read the state, as a debug endpoint would; they must not deadlock the update.
+	var active [][]FilterWarning
+	var current []*FilterResult
+	sink := WithWarningSink(func(FilterWarning) {
+		active = append(active, state.ActiveWarnings())
+	})
+	logger := &callbackLogger{fn: func() {
+		current = append(current, state.Current())
+	}}
+	done := make(chan error)
+	go func() {
+		_, err := state.Update(sink, WithLogger(logger),
+			WithFeatureRequirements(FeatureRequirements{ServiceDiscovery: true}),
+			WithDiscoveryOptions(DiscoveryOptions{EndpointsMode: EndpointsOnly}),
+			WithExcludedResourceKinds(KindNode, KindDeployment))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		g.Expect(err).To(BeNil())
+	case <-time.After(10 * time.Second):
+		t.Fatal("update deadlocked in a callback reading the state")
+	}
+
+	g.Expect(active).NotTo(BeEmpty())
+	g.Expect(active[0]).To(Equal(state.ActiveWarnings()))
+	g.Expect(current).To(Equal([]*FilterResult{state.Current()}))
+	g.Expect((<-changes).Stopped).To(ContainElement(testDeployment.Name()))
+}
+
+// callbackLogger calls fn for every line logged at info level.
+type callbackLogger struct {
+	fn func()
+}
+
+func (l *callbackLogger) Infof(string, ...interface{}) {
+	l.fn()
+}
+
+func (l *callbackLogger) Warnf(string, ...interface{}) {}
+
+func (l *callbackLogger) Debugf(string, ...interface{}) {}
@@ -0,0 +1,98 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// DiscrepancyKind classifies a Discrepancy.
+type DiscrepancyKind string
+
+const (
+	// DiscrepancyNotRunning is reported for an enabled collection that has no running informer.
+	DiscrepancyNotRunning DiscrepancyKind = "EnabledNotRunning"
+
+	// DiscrepancyNotEnabled is reported for a running informer whose collection is not enabled.
+	DiscrepancyNotEnabled DiscrepancyKind = "RunningNotEnabled"
+)
+
+// Discrepancy is a mismatch between the enabled collections and the running informers.
+type Discrepancy struct {
+	Kind             DiscrepancyKind         `json:"kind"`
+	GroupVersionKind config.GroupVersionKind `json:"groupVersionKind"`
+
+	// Collection is the collection for GroupVersionKind. It is empty for a running informer whose type is not
+	// in the expected schemas at all.
+	Collection collection.Name `json:"collection,omitempty"`
+}
+
+func (d Discrepancy) String() string {
+	if d.Collection == "" {
+		return fmt.Sprintf("%s: %s", d.Kind, d.GroupVersionKind)
+	}
+	return fmt.Sprintf("%s: %s (%s)", d.Kind, d.GroupVersionKind, d.Collection)
+}
+
+// ReconcileWatches compares the enabled collections in expected against the types with a running informer, as
+// returned by running, and returns every discrepancy, sorted by kind and type. hooks are called for each
+// discrepancy, in the same order, so that the runtime can emit metrics or log lines. ReconcileWatches has no
+// other side effects, so the runtime may call it periodically.
+func ReconcileWatches(expected collection.Schemas, running func() []config.GroupVersionKind,
+	hooks ...func(Discrepancy)) []Discrepancy {
+	enabled := make(map[config.GroupVersionKind]collection.Name)
+	known := make(map[config.GroupVersionKind]collection.Name)
+	for _, s := range expected.All() {
+		gvk := s.Resource().GroupVersionKind()
+		known[gvk] = s.Name()
+		if !s.IsDisabled() {
+			enabled[gvk] = s.Name()
+		}
+	}
+
+	started := make(map[config.GroupVersionKind]struct{})
+	for _, gvk := range running() {
+		started[gvk] = struct{}{}
+	}
+
+	var out []Discrepancy
+	for gvk, name := range enabled {
+		if _, ok := started[gvk]; !ok {
+			out = append(out, Discrepancy{Kind: DiscrepancyNotRunning, GroupVersionKind: gvk, Collection: name})
+		}
+	}
+	for gvk := range started {
+		if _, ok := enabled[gvk]; !ok {
+			out = append(out, Discrepancy{Kind: DiscrepancyNotEnabled, GroupVersionKind: gvk, Collection: known[gvk]})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].GroupVersionKind.String() < out[j].GroupVersionKind.String()
+	})
+
+	for _, d := range out {
+		for _, h := range hooks {
+			h(d)
+		}
+	}
+	return out
+}
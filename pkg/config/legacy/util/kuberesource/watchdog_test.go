@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestReconcileWatches(t *testing.T) {
+	g := NewWithT(t)
+
+	expected := collection.SchemasFor(testService, testPod.Disable(), testSecret, testDeployment)
+	unknown := config.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	running := func() []config.GroupVersionKind {
+		return []config.GroupVersionKind{
+			testService.Resource().GroupVersionKind(),
+			testPod.Resource().GroupVersionKind(),
+			unknown,
+			testService.Resource().GroupVersionKind(),
+		}
+	}
+
+	var hooked []string
+	out := ReconcileWatches(expected, running, func(d Discrepancy) { hooked = append(hooked, d.String()) })
+	g.Expect(out).To(Equal([]Discrepancy{
+		{Kind: DiscrepancyNotRunning, GroupVersionKind: testDeployment.Resource().GroupVersionKind(), Collection: testDeployment.Name()},
+		{Kind: DiscrepancyNotRunning, GroupVersionKind: testSecret.Resource().GroupVersionKind(), Collection: testSecret.Name()},
+		{Kind: DiscrepancyNotEnabled, GroupVersionKind: testPod.Resource().GroupVersionKind(), Collection: testPod.Name()},
+		{Kind: DiscrepancyNotEnabled, GroupVersionKind: unknown},
+	}))
+	g.Expect(hooked).To(Equal([]string{
+		"EnabledNotRunning: apps/v1/Deployment (k8s/apps/v1/deployments)",
+		"EnabledNotRunning: core/v1/Secret (k8s/core/v1/secrets)",
+		"RunningNotEnabled: core/v1/Pod (k8s/core/v1/pods)",
+		"RunningNotEnabled: example.com/v1/Widget",
+	}))
+}
+
+func TestReconcileWatches_InSync(t *testing.T) {
+	g := NewWithT(t)
+
+	expected := collection.SchemasFor(testService, testPod.Disable())
+	out := ReconcileWatches(expected, func() []config.GroupVersionKind {
+		return []config.GroupVersionKind{testService.Resource().GroupVersionKind()}
+	})
+	g.Expect(out).To(BeEmpty())
+}
@@ -0,0 +1,98 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// WatchSpec describes a single resource type to watch. It is the translation point between a filtered
+// schema set and the informer machinery that consumes it.
+type WatchSpec struct {
+	Collection       collection.Name         `json:"collection"`
+	GroupVersionKind config.GroupVersionKind `json:"groupVersionKind"`
+
+	// Resource is the plural resource name, as used in API paths.
+	Resource      string `json:"resource"`
+	ClusterScoped bool   `json:"clusterScoped"`
+
+	// SelectorHint optionally narrows the watched objects.
+	SelectorHint *SelectorHint `json:"selectorHint,omitempty"`
+}
+
+// GroupVersionResource returns the resource to watch, for use with dynamic and metadata informers.
+func (w WatchSpec) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    w.GroupVersionKind.Group,
+		Version:  w.GroupVersionKind.Version,
+		Resource: w.Resource,
+	}
+}
+
+// ToWatchSpecs returns a watch spec for every enabled collection in schemas, in collection name order.
+func ToWatchSpecs(schemas collection.Schemas) []WatchSpec {
+	return toWatchSpecs(schemas, nil)
+}
+
+// WatchSpecs returns a watch spec for every enabled collection, including its selector hint.
+func (r *FilterResult) WatchSpecs() []WatchSpec {
+	return toWatchSpecs(r.Schemas, r.SelectorHints)
+}
+
+func toWatchSpecs(schemas collection.Schemas, hints map[collection.Name]SelectorHint) []WatchSpec {
+	enabled := schemas.WithoutDisabledCollections()
+	specs := make([]WatchSpec, 0, len(enabled.All()))
+	for _, name := range enabled.CollectionNames() {
+		res := enabled.MustFind(name.String()).Resource()
+		spec := WatchSpec{
+			Collection:       name,
+			GroupVersionKind: res.GroupVersionKind(),
+			Resource:         res.Plural(),
+			ClusterScoped:    res.IsClusterScoped(),
+		}
+		if h, ok := hints[name]; ok {
+			h := h
+			spec.SelectorHint = &h
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// SchemasForWatchSpecs returns the schemas in all that the specs refer to, for consumers such as the legacy
+// processing pipeline that are driven by a schema set. It fails if a spec refers to a collection that is missing
+// from all, or whose resource type does not match.
+func SchemasForWatchSpecs(specs []WatchSpec, all collection.Schemas) (collection.Schemas, error) {
+	b := collection.NewSchemasBuilder()
+	for _, spec := range specs {
+		s, ok := all.Find(spec.Collection.String())
+		if !ok {
+			return collection.Schemas{}, fmt.Errorf("watch spec refers to unknown collection %s", spec.Collection)
+		}
+		if gvk := s.Resource().GroupVersionKind(); gvk != spec.GroupVersionKind {
+			return collection.Schemas{}, fmt.Errorf("watch spec for collection %s has type %s, but the schema has type %s",
+				spec.Collection, spec.GroupVersionKind, gvk)
+		}
+		if err := b.Add(s); err != nil {
+			return collection.Schemas{}, err
+		}
+	}
+	return b.Build(), nil
+}
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/pkg/config/legacy/processor/transforms"
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestToWatchSpecs_RoundTrip(t *testing.T) {
+	g := NewWithT(t)
+
+	m := schema.MustGet()
+	in := m.KubeCollections()
+	result, err := FilterCollections(in, transforms.Providers(m), m.AllCollections().CollectionNames(),
+		WithExcludedResourceKinds(DefaultExcludedResourceKinds()...))
+	g.Expect(err).To(BeNil())
+
+	specs := ToWatchSpecs(result.Schemas)
+	g.Expect(specs).To(HaveLen(len(result.EnabledCollectionNames())))
+
+	back, err := SchemasForWatchSpecs(specs, result.Schemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(back.CollectionNames()).To(Equal(result.EnabledCollectionNames()))
+	for _, spec := range specs {
+		s := in.MustFind(spec.Collection.String())
+		g.Expect(spec.GroupVersionKind).To(Equal(s.Resource().GroupVersionKind()))
+		g.Expect(spec.GroupVersionResource()).To(Equal(s.Resource().GroupVersionResource()))
+		g.Expect(spec.ClusterScoped).To(Equal(s.Resource().IsClusterScoped()))
+		g.Expect(spec.SelectorHint).To(BeNil())
+	}
+}
+
+func TestFilterResult_WatchSpecs(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testSecret, testPod)
+	result, err := FilterCollections(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(),
+		WithExcludedResourceKinds(KindPod),
+		WithSelectorHint(KindSecret, SelectorHint{FieldSelector: "type=kubernetes.io/tls"}),
+		WithSelectorHint(KindPod, SelectorHint{LabelSelector: "app=foo"}))
+	g.Expect(err).To(BeNil())
+
+	g.Expect(result.WatchSpecs()).To(Equal([]WatchSpec{
+		{
+			Collection:       testSecret.Name(),
+			GroupVersionKind: testSecret.Resource().GroupVersionKind(),
+			Resource:         "secrets",
+			SelectorHint:     &SelectorHint{FieldSelector: "type=kubernetes.io/tls"},
+		},
+		{
+			Collection:       testService.Name(),
+			GroupVersionKind: testService.Resource().GroupVersionKind(),
+			Resource:         "services",
+		},
+	}))
+}
+
+func TestSchemasForWatchSpecs_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService)
+	_, err := SchemasForWatchSpecs([]WatchSpec{{Collection: testPod.Name()}}, in)
+	g.Expect(err).To(MatchError("watch spec refers to unknown collection k8s/core/v1/pods"))
+
+	spec := ToWatchSpecs(in)[0]
+	spec.GroupVersionKind.Version = "v2"
+	_, err = SchemasForWatchSpecs([]WatchSpec{spec}, in)
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("has type core/v2/Service"))
+}
@@ -0,0 +1,54 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+const istioGroupSuffix = ".istio.io"
+
+// EnabledIstioConfigGVKs returns the kinds of Istio config (resources in an .istio.io group) that are enabled in
+// schemas. The validating webhook uses it to reject config of kinds that are not watched.
+func EnabledIstioConfigGVKs(schemas collection.Schemas) map[schema.GroupVersionKind]bool {
+	out := make(map[schema.GroupVersionKind]bool)
+	for _, s := range schemas.All() {
+		if s.IsDisabled() {
+			continue
+		}
+		res := s.Resource()
+		if !strings.HasSuffix(res.Group(), istioGroupSuffix) {
+			continue
+		}
+		out[schema.GroupVersionKind{Group: res.Group(), Version: res.Version(), Kind: res.Kind()}] = true
+	}
+	return out
+}
+
+func gvkSetsEqual(a, b map[schema.GroupVersionKind]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for gvk := range a {
+		if !b[gvk] {
+			return false
+		}
+	}
+	return true
+}
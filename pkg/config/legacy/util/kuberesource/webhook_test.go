@@ -0,0 +1,78 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kuberesource
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource/kuberesourcetest"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+var (
+	testVirtualService = newTestSchema("networking.istio.io", "v1alpha3", "VirtualService")
+	testAuthzPolicy    = newTestSchema("security.istio.io", "v1beta1", "AuthorizationPolicy")
+
+	gatewayGVK        = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1alpha3", Kind: "Gateway"}
+	virtualServiceGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1alpha3", Kind: "VirtualService"}
+	authzPolicyGVK    = schema.GroupVersionKind{Group: "security.istio.io", Version: "v1beta1", Kind: "AuthorizationPolicy"}
+)
+
+func TestEnabledIstioConfigGVKs(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testKubeGateway, testVirtualService, testAuthzPolicy)
+	out := DisableExcludedCollectionsFor(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames(), []string{"AuthorizationPolicy"}, false)
+
+	g.Expect(EnabledIstioConfigGVKs(out)).To(Equal(map[schema.GroupVersionKind]bool{
+		gatewayGVK:        true,
+		virtualServiceGVK: true,
+	}))
+}
+
+func TestCollectionFilterState_IstioConfigGVKsChange(t *testing.T) {
+	g := NewWithT(t)
+
+	in := collection.SchemasFor(testService, testKubeGateway, testVirtualService, testAuthzPolicy)
+	state, err := NewCollectionFilterState(in, kuberesourcetest.ScriptedProviders{}, in.CollectionNames())
+	g.Expect(err).To(BeNil())
+	g.Expect(state.EnabledIstioConfigGVKs()).To(Equal(map[schema.GroupVersionKind]bool{
+		gatewayGVK:        true,
+		virtualServiceGVK: true,
+		authzPolicyGVK:    true,
+	}))
+
+	var notified []map[schema.GroupVersionKind]bool
+	state.OnIstioConfigGVKsChange(func(gvks map[schema.GroupVersionKind]bool) {
+		// Handlers run after the lock is released, so reading the state must not block.
+		g.Expect(state.EnabledIstioConfigGVKs()).To(Equal(gvks))
+		notified = append(notified, gvks)
+	})
+
+	_, err = state.Update(WithExcludedResourceKinds("VirtualService"))
+	g.Expect(err).To(BeNil())
+	g.Expect(notified).To(Equal([]map[schema.GroupVersionKind]bool{{
+		gatewayGVK:     true,
+		authzPolicyGVK: true,
+	}}))
+
+	// Excluding a non-Istio kind leaves the Istio config kinds as they are.
+	_, err = state.Update(WithExcludedResourceKinds("VirtualService", KindService))
+	g.Expect(err).To(BeNil())
+	g.Expect(notified).To(HaveLen(1))
+}
@@ -47,6 +47,7 @@ type Collection struct {
 	Disabled     bool   `json:"disabled"`
 	Pilot        bool   `json:"pilot"`
 	Deprecated   bool   `json:"deprecated"`
+	OnDemand     bool   `json:"onDemand"`
 }
 
 // Snapshot metadata. Describes the snapshots that should be produced.
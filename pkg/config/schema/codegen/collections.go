@@ -131,6 +131,16 @@ var (
 		{{- end}}
 	{{- end }}
 		Build()
+
+	// OnDemand contains only collections too large or too frequently updated to be watched along with every other
+	// collection. They are watched only when an analyzer or the collection filter asks for them.
+	OnDemand = collection.NewSchemasBuilder().
+	{{- range .Entries }}
+		{{- if .Collection.OnDemand }}
+		MustAdd({{ .Collection.VariableName }}).
+		{{- end}}
+	{{- end }}
+		Build()
 )
 `
 
@@ -76,6 +76,16 @@ func (b *SchemasBuilder) MustAdd(s Schema) *SchemasBuilder {
 	return b
 }
 
+// BuildFrom returns a new Schemas holding the same Schema instances as s, in the same order. The state
+// of each Schema, including whether it is disabled, is preserved.
+func BuildFrom(s Schemas) Schemas {
+	b := NewSchemasBuilder()
+	for _, schema := range s.byAddOrder {
+		b.MustAdd(schema)
+	}
+	return b.Build()
+}
+
 // Build a new schemas from this SchemasBuilder.
 func (b *SchemasBuilder) Build() Schemas {
 	s := b.schemas
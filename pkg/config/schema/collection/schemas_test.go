@@ -421,3 +421,22 @@ func TestSchemas_Add(t *testing.T) {
 	g.Expect(schemas.Add(baz)).To(Equal(collection.SchemasFor(foo, bar, baz)))
 	g.Expect(schemas).To(Equal(collection.SchemasFor(foo, bar)))
 }
+
+func TestSchemas_BuildFrom(t *testing.T) {
+	g := NewWithT(t)
+
+	foo := collection.Builder{
+		Name:     "foo",
+		Resource: emptyResource,
+	}.MustBuild()
+	bar := collection.Builder{
+		Name:     "bar",
+		Resource: emptyResource,
+	}.MustBuild().Disable()
+
+	schemas := collection.SchemasFor(foo, bar)
+	rebuilt := collection.BuildFrom(schemas)
+	g.Expect(rebuilt).To(Equal(schemas))
+	g.Expect(rebuilt.DisabledCollectionNames()).To(Equal(collection.Names{"bar"}))
+	g.Expect(rebuilt.All()[0]).To(BeIdenticalTo(foo))
+}
@@ -410,4 +410,9 @@ var (
 	// Deprecated contains only collections used by that will soon be used by nothing.
 	Deprecated = collection.NewSchemasBuilder().
 			Build()
+
+	// OnDemand contains only collections too large or too frequently updated to be watched along with every other
+	// collection. They are watched only when an analyzer or the collection filter asks for them.
+	OnDemand = collection.NewSchemasBuilder().
+			Build()
 )
@@ -12,6 +12,7 @@ import (
 	k8sioapiadmissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	k8sioapiappsv1 "k8s.io/api/apps/v1"
 	k8sioapicorev1 "k8s.io/api/core/v1"
+	k8sioapidiscoveryv1 "k8s.io/api/discovery/v1"
 	k8sioapiextensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	k8sioapiextensionsapiserverpkgapisapiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	sigsk8siogatewayapiapisv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
@@ -517,6 +518,25 @@ var (
 		}.MustBuild(),
 	}.MustBuild()
 
+	// K8SDiscoveryK8SIoV1Endpointslices describes the collection
+	// k8s/discovery.k8s.io/v1/endpointslices
+	K8SDiscoveryK8SIoV1Endpointslices = collection.Builder{
+		Name:         "k8s/discovery.k8s.io/v1/endpointslices",
+		VariableName: "K8SDiscoveryK8SIoV1Endpointslices",
+		Disabled:     false,
+		Resource: resource.Builder{
+			Group:         "discovery.k8s.io",
+			Kind:          "EndpointSlice",
+			Plural:        "endpointslices",
+			Version:       "v1",
+			Proto:         "k8s.io.api.discovery.v1.EndpointSlice",
+			ReflectType:   reflect.TypeOf(&k8sioapidiscoveryv1.EndpointSlice{}).Elem(),
+			ProtoPackage:  "k8s.io/api/discovery/v1",
+			ClusterScoped: false,
+			ValidateProto: validation.EmptyValidate,
+		}.MustBuild(),
+	}.MustBuild()
+
 	// K8SExtensionsIstioIoV1Alpha1Wasmplugins describes the collection
 	// k8s/extensions.istio.io/v1alpha1/wasmplugins
 	K8SExtensionsIstioIoV1Alpha1Wasmplugins = collection.Builder{
@@ -944,6 +964,7 @@ var (
 		MustAdd(K8SCoreV1Pods).
 		MustAdd(K8SCoreV1Secrets).
 		MustAdd(K8SCoreV1Services).
+		MustAdd(K8SDiscoveryK8SIoV1Endpointslices).
 		MustAdd(K8SExtensionsIstioIoV1Alpha1Wasmplugins).
 		MustAdd(K8SExtensionsV1Beta1Ingresses).
 		MustAdd(K8SGatewayApiV1Alpha2Gatewayclasses).
@@ -999,6 +1020,7 @@ var (
 		MustAdd(K8SCoreV1Pods).
 		MustAdd(K8SCoreV1Secrets).
 		MustAdd(K8SCoreV1Services).
+		MustAdd(K8SDiscoveryK8SIoV1Endpointslices).
 		MustAdd(K8SExtensionsIstioIoV1Alpha1Wasmplugins).
 		MustAdd(K8SExtensionsV1Beta1Ingresses).
 		MustAdd(K8SGatewayApiV1Alpha2Gatewayclasses).
@@ -1067,4 +1089,10 @@ var (
 	// Deprecated contains only collections used by that will soon be used by nothing.
 	Deprecated = collection.NewSchemasBuilder().
 			Build()
+
+	// OnDemand contains only collections too large or too frequently updated to be watched along with every other
+	// collection. They are watched only when an analyzer or the collection filter asks for them.
+	OnDemand = collection.NewSchemasBuilder().
+			MustAdd(K8SDiscoveryK8SIoV1Endpointslices).
+			Build()
 )
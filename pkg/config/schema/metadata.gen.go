@@ -172,6 +172,12 @@ collections:
     kind: "Endpoints"
     group: ""
 
+  - name: "k8s/discovery.k8s.io/v1/endpointslices"
+    kind: "EndpointSlice"
+    group: "discovery.k8s.io"
+    # EndpointSlices change on every rollout and scale event, and large services have many of them.
+    onDemand: true
+
   - name: "k8s/core/v1/namespaces"
     kind: "Namespace"
     group: ""
@@ -358,6 +364,13 @@ resources:
     proto: "k8s.io.api.core.v1.Endpoints"
     protoPackage: "k8s.io/api/core/v1"
 
+  - kind: "EndpointSlice"
+    plural: "endpointslices"
+    group: "discovery.k8s.io"
+    version: "v1"
+    proto: "k8s.io.api.discovery.v1.EndpointSlice"
+    protoPackage: "k8s.io/api/discovery/v1"
+
   - kind: "Namespace"
     plural: "namespaces"
     version: "v1"
@@ -642,6 +655,7 @@ transforms:
       "k8s/core/v1/pods": "k8s/core/v1/pods"
       "k8s/core/v1/secrets": "k8s/core/v1/secrets"
       "k8s/core/v1/services": "k8s/core/v1/services"
+      "k8s/discovery.k8s.io/v1/endpointslices": "k8s/discovery.k8s.io/v1/endpointslices"
       "k8s/core/v1/configmaps": "k8s/core/v1/configmaps"
       "istio/mesh/v1alpha1/MeshConfig": "istio/mesh/v1alpha1/MeshConfig"
       "istio/mesh/v1alpha1/MeshNetworks": "istio/mesh/v1alpha1/MeshNetworks"
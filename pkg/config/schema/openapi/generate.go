@@ -0,0 +1,294 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package openapi generates the OpenAPI structural schemas of Istio resources from their collection metadata, and
+// validates objects against them.
+package openapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Generate returns the OpenAPI v3 schema of the objects of s, generated from the descriptor of the proto message of
+// its resource. The spec constrains the type and format of every field and the names of enum values; fields of
+// well-known types follow their proto3 JSON mapping. Every field may be null, as the proto decoding of the spec
+// allows. Fields annotated as required are not required, as in the Istio CRDs: whether they must be set is up to the
+// validation of the resource, which for example allows a delegate VirtualService without hosts. The status is
+// preserved as is. The schema is structural, as the API server requires of a CRD.
+func Generate(s collection.Schema) (*apiextensions.JSONSchemaProps, error) {
+	instance, err := s.Resource().NewInstance()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", s.Name(), err)
+	}
+	msg, ok := instance.(descriptor.Message)
+	if !ok {
+		return nil, fmt.Errorf("%v: %T has no proto descriptor", s.Name(), instance)
+	}
+	fd, path := descriptorOf(msg)
+	g := &generator{
+		files:    map[string]bool{},
+		messages: map[string]*descriptor.DescriptorProto{},
+		enums:    map[string]*descriptor.EnumDescriptorProto{},
+		visiting: map[string]bool{},
+	}
+	if err := g.addFile(fd); err != nil {
+		return nil, fmt.Errorf("%v: %v", s.Name(), err)
+	}
+	spec, err := g.message(messageName(fd, path))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", s.Name(), err)
+	}
+	out := &apiextensions.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensions.JSONSchemaProps{
+			"spec":   *spec,
+			"status": {Type: "object", XPreserveUnknownFields: boolPtr(true)},
+		},
+	}
+	structural, err := structuralschema.NewStructural(out)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", s.Name(), err)
+	}
+	if errs := structuralschema.ValidateStructural(nil, structural); len(errs) > 0 {
+		return nil, fmt.Errorf("%v: schema is not structural: %v", s.Name(), errs.ToAggregate())
+	}
+	return out, nil
+}
+
+// descriptorOf returns the file descriptor of msg, and the path of msg in it.
+func descriptorOf(msg descriptor.Message) (fd *descriptor.FileDescriptorProto, path []int) {
+	fd, _ = descriptor.ForMessage(msg)
+	_, path = msg.Descriptor()
+	return fd, path
+}
+
+// messageName returns the fully qualified name of the message at path in fd.
+func messageName(fd *descriptor.FileDescriptorProto, path []int) string {
+	name := packagePrefix(fd)
+	messages := fd.MessageType
+	for _, i := range path {
+		m := messages[i]
+		name += "." + m.GetName()
+		messages = m.NestedType
+	}
+	return name
+}
+
+func packagePrefix(fd *descriptor.FileDescriptorProto) string {
+	if fd.GetPackage() == "" {
+		return ""
+	}
+	return "." + fd.GetPackage()
+}
+
+// generator indexes the messages and enums of a proto file and of the files it depends on, by fully qualified name.
+type generator struct {
+	files    map[string]bool
+	messages map[string]*descriptor.DescriptorProto
+	enums    map[string]*descriptor.EnumDescriptorProto
+
+	// visiting are the messages whose schema is being generated, so that recursive fields are not expanded forever.
+	visiting map[string]bool
+}
+
+func (g *generator) addFile(fd *descriptor.FileDescriptorProto) error {
+	if g.files[fd.GetName()] {
+		return nil
+	}
+	g.files[fd.GetName()] = true
+	prefix := packagePrefix(fd)
+	for _, m := range fd.MessageType {
+		g.addMessage(prefix, m)
+	}
+	for _, e := range fd.EnumType {
+		g.enums[prefix+"."+e.GetName()] = e
+	}
+	for _, dep := range fd.Dependency {
+		gz := proto.FileDescriptor(dep)
+		if gz == nil {
+			// Only a type that is used and cannot be found is an error, which message reports.
+			continue
+		}
+		dfd, err := decodeFile(gz)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dep, err)
+		}
+		if err := g.addFile(dfd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) addMessage(prefix string, m *descriptor.DescriptorProto) {
+	name := prefix + "." + m.GetName()
+	g.messages[name] = m
+	for _, nested := range m.NestedType {
+		g.addMessage(name, nested)
+	}
+	for _, e := range m.EnumType {
+		g.enums[name+"."+e.GetName()] = e
+	}
+}
+
+func decodeFile(gz []byte) (*descriptor.FileDescriptorProto, error) {
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	fd := &descriptor.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, fd); err != nil {
+		return nil, err
+	}
+	return fd, nil
+}
+
+// message returns the schema of the message with the given fully qualified name.
+func (g *generator) message(name string) (*apiextensions.JSONSchemaProps, error) {
+	if s, ok := wellKnownTypes[name]; ok {
+		out := s
+		return &out, nil
+	}
+	m, ok := g.messages[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown message %s", strings.TrimPrefix(name, "."))
+	}
+	if g.visiting[name] {
+		// A recursive field is not expanded again; its content is preserved as is.
+		return &apiextensions.JSONSchemaProps{Type: "object", XPreserveUnknownFields: boolPtr(true)}, nil
+	}
+	g.visiting[name] = true
+	defer delete(g.visiting, name)
+
+	out := &apiextensions.JSONSchemaProps{Type: "object", Properties: map[string]apiextensions.JSONSchemaProps{}}
+	for _, f := range m.Field {
+		s, err := g.field(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", strings.TrimPrefix(name, "."), f.GetName(), err)
+		}
+		s.Nullable = true
+		out.Properties[f.GetJsonName()] = *s
+	}
+	return out, nil
+}
+
+// field returns the schema of the value of f.
+func (g *generator) field(f *descriptor.FieldDescriptorProto) (*apiextensions.JSONSchemaProps, error) {
+	if f.IsMessage() {
+		if entry := g.messages[f.GetTypeName()]; entry != nil && entry.GetOptions().GetMapEntry() {
+			value, err := g.field(entry.Field[1])
+			if err != nil {
+				return nil, err
+			}
+			return &apiextensions.JSONSchemaProps{
+				Type:                 "object",
+				AdditionalProperties: &apiextensions.JSONSchemaPropsOrBool{Allows: true, Schema: value},
+			}, nil
+		}
+	}
+	s, err := g.value(f)
+	if err != nil {
+		return nil, err
+	}
+	if f.IsRepeated() {
+		return &apiextensions.JSONSchemaProps{Type: "array", Items: &apiextensions.JSONSchemaPropsOrArray{Schema: s}}, nil
+	}
+	return s, nil
+}
+
+// value returns the schema of a single value of the type of f.
+func (g *generator) value(f *descriptor.FieldDescriptorProto) (*apiextensions.JSONSchemaProps, error) {
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return &apiextensions.JSONSchemaProps{Type: "string"}, nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return &apiextensions.JSONSchemaProps{Type: "string", Format: "byte"}, nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return &apiextensions.JSONSchemaProps{Type: "boolean"}, nil
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return &apiextensions.JSONSchemaProps{Type: "integer", Format: "int32"}, nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return &apiextensions.JSONSchemaProps{Type: "integer", Format: "int64", Minimum: float64Ptr(0)}, nil
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64, descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		// The proto3 JSON mapping of 64-bit integers is a string, but numbers are accepted too.
+		return &apiextensions.JSONSchemaProps{XIntOrString: true}, nil
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return &apiextensions.JSONSchemaProps{Type: "number", Format: "float"}, nil
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return &apiextensions.JSONSchemaProps{Type: "number", Format: "double"}, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		e, ok := g.enums[f.GetTypeName()]
+		if !ok {
+			return nil, fmt.Errorf("unknown enum %s", strings.TrimPrefix(f.GetTypeName(), "."))
+		}
+		out := &apiextensions.JSONSchemaProps{Type: "string"}
+		for _, v := range e.Value {
+			out.Enum = append(out.Enum, v.GetName())
+		}
+		return out, nil
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		return g.message(f.GetTypeName())
+	default:
+		return nil, fmt.Errorf("unsupported type %v", f.GetType())
+	}
+}
+
+// wellKnownTypes are the schemas of the well-known types, which follow their proto3 JSON mapping rather than their
+// fields.
+var wellKnownTypes = map[string]apiextensions.JSONSchemaProps{
+	".google.protobuf.Any":       {Type: "object", XPreserveUnknownFields: boolPtr(true)},
+	".google.protobuf.Struct":    {Type: "object", XPreserveUnknownFields: boolPtr(true)},
+	".google.protobuf.Value":     {XPreserveUnknownFields: boolPtr(true)},
+	".google.protobuf.ListValue": {Type: "array", Items: &apiextensions.JSONSchemaPropsOrArray{Schema: &apiextensions.JSONSchemaProps{XPreserveUnknownFields: boolPtr(true)}}},
+	".google.protobuf.Empty":     {Type: "object"},
+	".google.protobuf.Duration":  {Type: "string"},
+	".google.protobuf.Timestamp": {Type: "string", Format: "date-time"},
+	".google.protobuf.FieldMask": {Type: "string"},
+
+	".google.protobuf.BoolValue":   {Type: "boolean"},
+	".google.protobuf.StringValue": {Type: "string"},
+	".google.protobuf.BytesValue":  {Type: "string", Format: "byte"},
+	".google.protobuf.Int32Value":  {Type: "integer", Format: "int32"},
+	".google.protobuf.UInt32Value": {Type: "integer", Format: "int64", Minimum: float64Ptr(0)},
+	".google.protobuf.Int64Value":  {XIntOrString: true},
+	".google.protobuf.UInt64Value": {XIntOrString: true},
+	".google.protobuf.FloatValue":  {Type: "number", Format: "float"},
+	".google.protobuf.DoubleValue": {Type: "number", Format: "double"},
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
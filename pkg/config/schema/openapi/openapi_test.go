@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/yml"
+)
+
+func TestGenerate_Mock(t *testing.T) {
+	g := NewWithT(t)
+
+	props, err := Generate(collections.Mock)
+	g.Expect(err).To(BeNil())
+	str := apiextensions.JSONSchemaProps{Type: "string", Nullable: true}
+	g.Expect(props.Properties["spec"]).To(Equal(apiextensions.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensions.JSONSchemaProps{
+			"key": str,
+			"pairs": {
+				Type:     "array",
+				Nullable: true,
+				Items: &apiextensions.JSONSchemaPropsOrArray{Schema: &apiextensions.JSONSchemaProps{
+					Type:       "object",
+					Properties: map[string]apiextensions.JSONSchemaProps{"key": str, "value": str},
+				}},
+			},
+		},
+	}))
+}
+
+func TestGenerate_IstioCollections(t *testing.T) {
+	for _, s := range collections.All.All() {
+		if !IsIstioGroup(s.Resource().Group()) {
+			continue
+		}
+		t.Run(s.Name().String(), func(t *testing.T) {
+			if _, err := Generate(s); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidator_ValidateSpec(t *testing.T) {
+	v, err := NewValidator(collections.Pilot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	virtualService := collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind()
+	destinationRule := collections.IstioNetworkingV1Alpha3Destinationrules.Resource().GroupVersionKind()
+	serviceEntry := collections.IstioNetworkingV1Alpha3Serviceentries.Resource().GroupVersionKind()
+
+	cases := []struct {
+		name  string
+		gvk   config.GroupVersionKind
+		spec  string
+		valid bool
+	}{
+		{
+			name: "valid",
+			gvk:  virtualService,
+			spec: `
+hosts: [reviews]
+http:
+- timeout: 10s
+  route:
+  - destination: {host: reviews, port: {number: 9080}}
+    weight: 100
+`,
+			valid: true,
+		},
+		{
+			name:  "wrong type",
+			gvk:   virtualService,
+			spec:  `hosts: reviews`,
+			valid: false,
+		},
+		{
+			name:  "wrong type of nested field",
+			gvk:   virtualService,
+			spec:  `http: [{route: [{destination: {host: reviews}, weight: heavy}]}]`,
+			valid: false,
+		},
+		{
+			name:  "field annotated as required",
+			gvk:   virtualService,
+			spec:  `http: [{route: [{destination: {subset: v1}}]}]`,
+			valid: true,
+		},
+		{
+			name:  "unknown enum value",
+			gvk:   destinationRule,
+			spec:  `{host: reviews, trafficPolicy: {loadBalancer: {simple: FASTEST}}}`,
+			valid: false,
+		},
+		{
+			name:  "enum value",
+			gvk:   destinationRule,
+			spec:  `{host: reviews, trafficPolicy: {loadBalancer: {simple: ROUND_ROBIN}}}`,
+			valid: true,
+		},
+		{
+			name:  "negative unsigned integer",
+			gvk:   serviceEntry,
+			spec:  `{hosts: [a.example.com], ports: [{number: -1, name: http, protocol: HTTP}]}`,
+			valid: false,
+		},
+		{
+			name:  "wrapper",
+			gvk:   destinationRule,
+			spec:  `{host: reviews, trafficPolicy: {outlierDetection: {consecutive5xxErrors: 5}}}`,
+			valid: true,
+		},
+		{
+			name:  "null field",
+			gvk:   virtualService,
+			spec:  `{hosts: [reviews], http: null}`,
+			valid: true,
+		},
+		{
+			name:  "no spec",
+			gvk:   virtualService,
+			valid: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var spec map[string]interface{}
+			if err := yaml.Unmarshal([]byte(c.spec), &spec); err != nil {
+				t.Fatal(err)
+			}
+			err := v.ValidateSpec(c.gvk, spec)
+			if c.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !c.valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+// TestValidator_Testdata checks that the configurations used by the integration tests are valid.
+func TestValidator_Testdata(t *testing.T) {
+	v, err := NewValidator(collections.Pilot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(env.IstioSrc, "tests/testdata/config/*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, doc := range yml.SplitString(string(data)) {
+			var obj struct {
+				APIVersion string                 `json:"apiVersion"`
+				Kind       string                 `json:"kind"`
+				Spec       map[string]interface{} `json:"spec"`
+			}
+			if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+			for _, s := range collections.Pilot.All() {
+				r := s.Resource()
+				if r.Kind() == obj.Kind && r.APIVersion() == obj.APIVersion {
+					if err := v.ValidateSpec(r.GroupVersionKind(), obj.Spec); err != nil {
+						t.Errorf("%s: %v", filepath.Base(file), err)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// Validator validates the spec of Istio resources against the schemas generated from their collection metadata.
+type Validator struct {
+	byGVK map[config.GroupVersionKind]*validate.SchemaValidator
+}
+
+// NewValidator generates the schemas of the Istio resources in schemas, whose group is istio.io or a subdomain of
+// it. Other resources are not validated.
+func NewValidator(schemas collection.Schemas) (*Validator, error) {
+	v := &Validator{byGVK: map[config.GroupVersionKind]*validate.SchemaValidator{}}
+	for _, s := range schemas.All() {
+		if !IsIstioGroup(s.Resource().Group()) {
+			continue
+		}
+		props, err := Generate(s)
+		if err != nil {
+			return nil, err
+		}
+		sv, _, err := validation.NewSchemaValidator(&apiextensions.CustomResourceValidation{OpenAPIV3Schema: props})
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", s.Name(), err)
+		}
+		v.byGVK[s.Resource().GroupVersionKind()] = sv
+	}
+	return v, nil
+}
+
+// IsIstioGroup reports whether group is istio.io or a subdomain of it.
+func IsIstioGroup(group string) bool {
+	return group == "istio.io" || strings.HasSuffix(group, ".istio.io")
+}
+
+// ValidateSpec validates spec, the spec of an object of the resource gvk, against its schema. A nil spec is not set.
+// Resources without a schema are not validated.
+func (v *Validator) ValidateSpec(gvk config.GroupVersionKind, spec map[string]interface{}) error {
+	sv, ok := v.byGVK[gvk]
+	if !ok {
+		return nil
+	}
+	obj := map[string]interface{}{}
+	if spec != nil {
+		obj["spec"] = spec
+	}
+	return validation.ValidateCustomResource(nil, obj, sv).ToAggregate()
+}
@@ -33,6 +33,7 @@ import (
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/openapi"
 	"istio.io/istio/pkg/config/schema/resource"
 	"istio.io/istio/pkg/config/validation"
 	"istio.io/istio/pkg/kube"
@@ -103,6 +104,9 @@ type Webhook struct {
 	// pilot
 	schemas      collection.Schemas
 	domainSuffix string
+
+	// structural validates the fields of objects against the OpenAPI schemas generated from schemas.
+	structural *openapi.Validator
 }
 
 // New creates a new instance of the admission webhook server.
@@ -111,9 +115,14 @@ func New(o Options) (*Webhook, error) {
 		scope.Error("mux not set correctly")
 		return nil, errors.New("expected mux to be passed, but was not passed")
 	}
+	structural, err := openapi.NewValidator(o.Schemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate resource schemas: %v", err)
+	}
 	wh := &Webhook{
 		schemas:      o.Schemas,
 		domainSuffix: o.DomainSuffix,
+		structural:   structural,
 	}
 
 	o.Mux.HandleFunc("/validate", wh.serveValidate)
@@ -225,6 +234,12 @@ func (wh *Webhook) validate(request *kube.AdmissionRequest) *kube.AdmissionRespo
 		return toAdmissionResponse(fmt.Errorf("unrecognized type %v", obj.GroupVersionKind()))
 	}
 
+	if err := wh.structural.ValidateSpec(s.Resource().GroupVersionKind(), obj.Spec); err != nil {
+		scope.Infof("configuration does not match its schema: %v", err)
+		reportValidationFailed(request, reasonInvalidConfig)
+		return toAdmissionResponse(fmt.Errorf("configuration is invalid: %v", err))
+	}
+
 	out, err := crd.ConvertObject(s, &obj, wh.domainSuffix)
 	if err != nil {
 		scope.Infof("error decoding configuration: %v", err)
@@ -225,6 +225,58 @@ func TestAdmitPilot(t *testing.T) {
 	}
 }
 
+func TestAdmitStructural(t *testing.T) {
+	wh, err := New(Options{
+		DomainSuffix: testDomainSuffix,
+		Schemas:      collections.Istio,
+		Mux:          http.NewServeMux(),
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	virtualService := func(weight string) []byte {
+		return []byte(fmt.Sprintf(`{
+	"apiVersion": "networking.istio.io/v1alpha3",
+	"kind": "VirtualService",
+	"metadata": {"name": "reviews", "namespace": "default"},
+	"spec": {"hosts": ["reviews"], "http": [{"route": [{"destination": {"host": "reviews"}, "weight": %s}]}]}
+}`, weight))
+	}
+	cases := []struct {
+		name    string
+		raw     []byte
+		allowed bool
+	}{
+		{
+			name:    "valid",
+			raw:     virtualService(`100`),
+			allowed: true,
+		},
+		{
+			// The proto decoding accepts an integer in a string, but the schema of the field does not.
+			name:    "integer in a string",
+			raw:     virtualService(`"100"`),
+			allowed: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := wh.validate(&kube.AdmissionRequest{
+				Kind:      kubeApisMeta.GroupVersionKind{Kind: collections.IstioNetworkingV1Alpha3Virtualservices.Resource().Kind()},
+				Object:    runtime.RawExtension{Raw: c.raw},
+				Operation: kube.Create,
+			})
+			if got.Allowed != c.allowed {
+				t.Fatalf("got %v want %v: %v", got.Allowed, c.allowed, got.Result)
+			}
+			if !c.allowed && !strings.Contains(got.Result.Message, "spec.http.route.weight") {
+				t.Fatalf("unexpected message %q", got.Result.Message)
+			}
+		})
+	}
+}
+
 func makeTestReview(t *testing.T, valid bool, apiVersion string) []byte {
 	t.Helper()
 	review := kubeApiAdmission.AdmissionReview{
@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nolint: golint
+package fuzz
+
+import (
+	"strings"
+
+	fuzz "github.com/AdaLogics/go-fuzz-headers"
+
+	"istio.io/istio/pkg/config/legacy/util/kuberesource"
+	"istio.io/istio/pkg/config/schema"
+)
+
+// FuzzParseExclusions parses newline separated exclusion entries and matches the result against the
+// global schema set.
+func FuzzParseExclusions(data []byte) int {
+	exclusions, err := kuberesource.ParseExclusions(strings.Split(string(data), "\n"))
+	if err != nil {
+		return 0
+	}
+	m := kuberesource.NewExclusionMatcher(exclusions)
+	for _, s := range schema.MustGet().KubeCollections().All() {
+		_ = m.MatchesSchema(s)
+	}
+	return 1
+}
+
+func FuzzExclusionMatcher(data []byte) int {
+	f := fuzz.NewConsumer(data)
+	entries, err := f.GetString()
+	if err != nil {
+		return 0
+	}
+	group, err := f.GetString()
+	if err != nil {
+		return 0
+	}
+	version, err := f.GetString()
+	if err != nil {
+		return 0
+	}
+	kind, err := f.GetString()
+	if err != nil {
+		return 0
+	}
+	exclusions, err := kuberesource.ParseExclusions(strings.Split(entries, "\n"))
+	if err != nil {
+		return 0
+	}
+	_ = kuberesource.NewExclusionMatcher(exclusions).Matches(group, version, kind)
+	return 1
+}
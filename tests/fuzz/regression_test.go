@@ -162,6 +162,8 @@ func TestFuzzers(t *testing.T) {
 		{"FuzzParsePemEncodedCertificateChain", FuzzParsePemEncodedCertificateChain},
 		{"FuzzUpdateVerifiedKeyCertBundleFromFile", FuzzUpdateVerifiedKeyCertBundleFromFile},
 		{"FuzzJwtUtil", FuzzJwtUtil},
+		{"FuzzParseExclusions", FuzzParseExclusions},
+		{"FuzzExclusionMatcher", FuzzExclusionMatcher},
 	}
 	for _, tt := range cases {
 		if testedFuzzers.Contains(tt.name) {